@@ -0,0 +1,82 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/fadvisor/cmd/fadvisor/app/options"
+	comparatorcfg "github.com/gocrane/fadvisor/pkg/cost-comparator/config"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/diff"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/report"
+)
+
+// NewDiffCommand creates the command to diff two comparator runs
+func NewDiffCommand() *cobra.Command {
+	opts := options.NewDiffOptions()
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "diff two comparator runs",
+		Long:  `diff the workloads requests, recommended resources and costs of two comparator runs, and flag the regressions where requests grew while usage didn't`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if errs := opts.Validate(); len(errs) > 0 {
+				klog.Errorf("opts validate failed, exit: %v", errs)
+				os.Exit(255)
+			}
+			if err := RunDiff(opts); err != nil {
+				klog.Errorf("run diff failed, exit: %v", err)
+				os.Exit(255)
+			}
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+func RunDiff(opts *options.DiffOptions) error {
+	oldSnapshot, err := diff.LoadSnapshot(opts.Old)
+	if err != nil {
+		return err
+	}
+	newSnapshot, err := diff.LoadSnapshot(opts.New)
+	if err != nil {
+		return err
+	}
+	result := diff.Diff(oldSnapshot, newSnapshot, opts.Threshold)
+
+	// output modes are the same as the comparator
+	outputCfg := comparatorcfg.Config{OutputMode: opts.OutputMode}
+	writeFile := func(name string, write func(f *os.File) error) error {
+		f, err := os.Create(filepath.Join(opts.DataPath, name))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return write(f)
+	}
+	for _, table := range result.Tables {
+		fmt.Printf("Reporting, %v.............................................\n", table.Title)
+		if outputCfg.OutputEnabled(comparatorcfg.OutputModeStdOut) {
+			report.WriteTable(os.Stdout, table)
+		}
+		if outputCfg.OutputEnabled(comparatorcfg.OutputModeCsv) {
+			table := table
+			if err = writeFile(table.Name+".csv", func(f *os.File) error {
+				return report.WriteCsv(f, table)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	if outputCfg.OutputEnabled(comparatorcfg.OutputModeJson) {
+		return writeFile("diff-report.json", func(f *os.File) error {
+			return report.WriteJson(f, result)
+		})
+	}
+	return nil
+}
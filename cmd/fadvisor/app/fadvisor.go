@@ -5,7 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"strings"
+	"sync"
 	"time"
 
 	"gopkg.in/gcfg.v1"
@@ -14,6 +14,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/scale"
 	"k8s.io/client-go/tools/events"
@@ -22,21 +23,29 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 
 	"github.com/gocrane/fadvisor/cmd/fadvisor/app/options"
+	"github.com/gocrane/fadvisor/pkg/billing"
 	"github.com/gocrane/fadvisor/pkg/cache"
 	"github.com/gocrane/fadvisor/pkg/cloud"
 	_ "github.com/gocrane/fadvisor/pkg/cloudproviders/default"
 	_ "github.com/gocrane/fadvisor/pkg/cloudproviders/qcloud"
 	costcomparator "github.com/gocrane/fadvisor/pkg/cost-comparator"
+	comparatorcfg "github.com/gocrane/fadvisor/pkg/cost-comparator/config"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/controller"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/report"
 	exporter "github.com/gocrane/fadvisor/pkg/cost-exporter"
 	"github.com/gocrane/fadvisor/pkg/cost-exporter/cloudcost"
 	"github.com/gocrane/fadvisor/pkg/cost-exporter/store/prometheus"
 	"github.com/gocrane/fadvisor/pkg/datasource"
 	"github.com/gocrane/fadvisor/pkg/datasource-providers/metricserver"
+	"github.com/gocrane/fadvisor/pkg/datasource-providers/mock"
 	"github.com/gocrane/fadvisor/pkg/datasource-providers/prom"
 	"github.com/gocrane/fadvisor/pkg/datasource-providers/qcloudmonitor"
 	_ "github.com/gocrane/fadvisor/pkg/querybuilder-providers/metricserver"
+	_ "github.com/gocrane/fadvisor/pkg/querybuilder-providers/mock"
 	_ "github.com/gocrane/fadvisor/pkg/querybuilder-providers/prometheus"
+	promquerybuilder "github.com/gocrane/fadvisor/pkg/querybuilder-providers/prometheus"
 	_ "github.com/gocrane/fadvisor/pkg/querybuilder-providers/qcloudmonitor"
+	"github.com/gocrane/fadvisor/pkg/snapshot"
 	"github.com/gocrane/fadvisor/pkg/util"
 	"github.com/gocrane/fadvisor/pkg/util/target"
 )
@@ -75,11 +84,17 @@ func NewExporterCommand(ctx context.Context) *cobra.Command {
 
 	cmd.Flags().AddGoFlagSet(flag.CommandLine)
 	opts.AddFlags(cmd.Flags())
+	cmd.AddCommand(NewDiffCommand())
+	cmd.AddCommand(NewSnapshotCommand(ctx))
 	return cmd
 }
 
-// once task analyze
+// once task analyze, or run as a service which analyzes on schedule if comparator service mode is enabled
 func RunComparator(ctx context.Context, opts *options.Options) error {
+	if opts.ComparatorOptions.SnapshotFile != "" {
+		return RunOfflineComparator(opts)
+	}
+
 	creator, err := util.CreateK8sClient(opts.ClientConfig, opts.MaxIdleConnsPerClient)
 	if err != nil {
 		return err
@@ -106,16 +121,8 @@ func RunComparator(ctx context.Context, opts *options.Options) error {
 	}
 	opts.ComparatorOptions.DataSourceQMonitorConfig = cfg
 
-	priceConfig := cloud.NewProviderConfig(&opts.ComparatorOptions.CustomPrice)
-	cloudProvider, err := cloud.InitCloudProvider(opts.ComparatorOptions.CloudConfig, priceConfig, &k8sCache)
+	cloudProvider, err := initComparatorCloudProvider(opts, k8sCache, kubeClient, ctx.Done())
 	if err != nil {
-		klog.Fatalf("Cloud provider could not be initialized: %v", err)
-	}
-	if cloudProvider == nil {
-		klog.Fatalf("Failed to initialize cloud provider")
-	}
-
-	if err = cloudProvider.WarmUp(); err != nil {
 		return err
 	}
 	restConfig, err := util.NewK8sConfig(opts.ClientConfig, opts.MaxIdleConnsPerClient)
@@ -143,7 +150,8 @@ func RunComparator(ctx context.Context, opts *options.Options) error {
 	)
 	targetFetcher := target.NewTargetInfoFetcher(restMapper, scaleClient, kubeClient)
 
-	_, _, hybrid := initializationDataSource(opts, restConfig)
+	_, _, hybrid := initializationDataSource(ctx, opts, restConfig)
+	opts.ComparatorOptions.Config.DataSource = opts.ComparatorOptions.DataSource
 
 	fmt.Println(opts.ComparatorOptions.Config)
 	comparator := costcomparator.NewComparator(opts.ComparatorOptions.Config,
@@ -155,44 +163,201 @@ func RunComparator(ctx context.Context, opts *options.Options) error {
 		cloudProvider,
 		hybrid)
 
-	comparator.Init()
-	comparator.DoAnalysis()
-	return nil
+	// the analyses of the CostAnalysis controller and the comparator service share the cache, the cloud provider and the datasource,
+	// they run one by one
+	analysisLock := &sync.Mutex{}
+	if opts.ComparatorOptions.Config.EnableCostAnalysisController {
+		// each CostAnalysis is analyzed by a new comparator with its own config
+		analyze := func(cfg comparatorcfg.Config) (*report.Report, error) {
+			return costcomparator.NewComparator(cfg,
+				dynamicKubeClient,
+				discoveryClient,
+				restMapper,
+				targetFetcher,
+				k8sCache,
+				cloudProvider,
+				hybrid).Analyze()
+		}
+		costAnalysisController := controller.NewCostAnalysisController(dynamicKubeClient, kubeClient, opts.ComparatorOptions.Config, analyze, analysisLock)
+		go costAnalysisController.Run(ctx.Done())
+	}
+
+	if opts.ComparatorOptions.Config.Service.Enabled {
+		serviceCfg := opts.ComparatorOptions.Config.Service
+		service := costcomparator.NewService(comparator.Analyze, serviceCfg.Interval, serviceCfg.MaxRuns, analysisLock)
+		service.Start(ctx.Done())
+
+		server := costcomparator.NewServer(service, opts.BindAddr, opts.Debugging)
+		server.RegisterHandlers()
+		stoppedCh, err := server.Serve(ctx.Done())
+		if err != nil {
+			return err
+		}
+		<-stoppedCh
+		return nil
+	}
+	if opts.ComparatorOptions.Config.EnableCostAnalysisController {
+		<-ctx.Done()
+		return nil
+	}
+
+	if err = comparator.Init(); err != nil {
+		return err
+	}
+	return comparator.DoAnalysis()
 }
 
-func initializationDataSource(opts *options.Options, restConfig *rest.Config) (datasource.RealTime, datasource.History, datasource.Interface) {
-	var realtimeDataSource datasource.RealTime
-	var historyDataSource datasource.History
-	var hybridDataSource datasource.Interface
+// RunOfflineComparator analyzes from the cluster snapshot and the time series checkpoints, no api server and datasource are needed
+func RunOfflineComparator(opts *options.Options) error {
+	s, err := snapshot.Read(opts.ComparatorOptions.SnapshotFile)
+	if err != nil {
+		return err
+	}
+	k8sCache := s.Cache()
+
+	opts.ComparatorOptions.CustomPrice = opts.CustomPrice
+	opts.ComparatorOptions.CloudConfig = opts.CloudConfig
+	// the cloud api is not called, the prices come from the persisted pricing caches and the custom pricing
+	opts.ComparatorOptions.CloudConfig.Offline = true
+	cloudProvider, err := initComparatorCloudProvider(opts, k8sCache, nil, nil)
+	if err != nil {
+		return err
+	}
+	dynamicKubeClient, restMapper, err := s.DynamicClient()
+	if err != nil {
+		return err
+	}
+
+	cfg := opts.ComparatorOptions.Config
+	// the checkpoints must be of the same datasource as they were taken, and they are read only in offline mode
+	cfg.DataSource = opts.ComparatorOptions.DataSource
+	cfg.EnableContainerCheckpoint = false
+	cfg.EnableWorkloadCheckpoint = false
+	cfg.Offline = true
+	if cfg.History.EndTime == "" {
+		cfg.History.EndTime = s.Metadata.CreatedAt.Format(time.RFC3339)
+	}
+
+	comparator := costcomparator.NewComparator(cfg,
+		dynamicKubeClient,
+		nil,
+		restMapper,
+		s.TargetInfoFetcher(),
+		k8sCache,
+		cloudProvider,
+		snapshot.NewOfflineDataSource())
+	if err = comparator.Init(); err != nil {
+		return err
+	}
+	return comparator.DoAnalysis()
+}
+
+// initComparatorCloudProvider initializes the cloud provider of the comparator, the kube client is nil in offline mode.
+// the background work of the cloud provider stops when stopCh is closed
+func initComparatorCloudProvider(opts *options.Options, k8sCache cache.Cache, kubeClient kubernetes.Interface, stopCh <-chan struct{}) (cloud.Cloud, error) {
+	store, err := cloud.NewPricingCacheStore(opts.ComparatorOptions.CloudConfig, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	opts.ComparatorOptions.CloudConfig.PricingCacheStore = store
+	opts.ComparatorOptions.CloudConfig.KubeClient = kubeClient
+	opts.ComparatorOptions.CloudConfig.StopCh = stopCh
+	priceConfig := cloud.NewProviderConfig(&opts.ComparatorOptions.CustomPrice)
+	cloudProvider, err := cloud.InitCloudProvider(opts.ComparatorOptions.CloudConfig, priceConfig, &k8sCache)
+	if err != nil {
+		klog.Fatalf("Cloud provider could not be initialized: %v", err)
+	}
+	if cloudProvider == nil {
+		klog.Fatalf("Failed to initialize cloud provider")
+	}
+
+	if err = cloudProvider.WarmUp(); err != nil {
+		return nil, err
+	}
+	return cloudProvider, nil
+}
+
+func initializationDataSource(ctx context.Context, opts *options.Options, restConfig *rest.Config) (datasource.RealTime, datasource.History, datasource.Interface) {
 	datasourceStr := opts.ComparatorOptions.DataSource
-	switch strings.ToLower(datasourceStr) {
-	case "metricserver", "ms":
+	datasourceType, err := datasource.ParseDataSourceType(datasourceStr)
+	if err != nil {
+		// default is prom
+		datasourceType = datasource.PrometheusDataSource
+	}
+	realtimeDataSource, historyDataSource := newDataSourceProvider(ctx, opts, restConfig, datasourceType)
+	var hybridDataSource datasource.Interface
+	if provider, ok := historyDataSource.(datasource.Interface); ok {
+		hybridDataSource = provider
+	}
+	if len(opts.ComparatorOptions.HistoryDataSources) == 0 {
+		return realtimeDataSource, historyDataSource, hybridDataSource
+	}
+
+	proxyConfig, err := opts.ComparatorOptions.HistoryProxyConfig()
+	if err != nil {
+		klog.Exitf("invalid history datasources config, err: %v", err)
+	}
+	historyProviders := make(map[datasource.DataSourceType]datasource.History)
+	for _, name := range proxyConfig.Priority {
+		if name == datasourceType && historyDataSource != nil {
+			historyProviders[name] = historyDataSource
+			continue
+		}
+		_, historyProviders[name] = newDataSourceProvider(ctx, opts, restConfig, name)
+	}
+	klog.Infof("History datasources %v, policy %v", proxyConfig.Priority, proxyConfig.Policy)
+	historyProxy := datasource.NewHistoryDataProxyWithConfig(historyProviders, proxyConfig)
+	return realtimeDataSource, historyProxy, datasource.NewHybridDataProxy(realtimeDataSource, historyProxy)
+}
+
+// newDataSourceProvider creates the provider of the datasource type, the history is nil if the provider has no history data
+func newDataSourceProvider(ctx context.Context, opts *options.Options, restConfig *rest.Config, datasourceType datasource.DataSourceType) (datasource.RealTime, datasource.History) {
+	switch datasourceType {
+	case datasource.MetricServerDataSource:
+		if opts.ComparatorOptions.DataSourceMetricServerConfig.RecorderEnabled {
+			provider, err := metricserver.NewRecorderProvider(ctx, restConfig, &opts.ComparatorOptions.DataSourceMetricServerConfig)
+			if err != nil {
+				klog.Exitf("unable to create datasource provider %v, err: %v", datasourceType, err)
+			}
+			return provider, provider
+		}
 		provider, err := metricserver.NewProvider(restConfig)
 		if err != nil {
-			klog.Exitf("unable to create datasource provider %v, err: %v", datasourceStr, err)
+			klog.Exitf("unable to create datasource provider %v, err: %v", datasourceType, err)
 		}
-		realtimeDataSource = provider
-	case "qmonitor", "qcloudmonitor", "qm":
-		provider, err := qcloudmonitor.NewProvider(&opts.ComparatorOptions.DataSourceQMonitorConfig)
+		return provider, nil
+	case datasource.QCloudMonitorDataSource:
+		kubeClient, err := kubernetes.NewForConfig(restConfig)
 		if err != nil {
-			klog.Exitf("unable to create datasource provider %v, err: %v", datasourceStr, err)
+			klog.Exitf("unable to create datasource provider %v, err: %v", datasourceType, err)
 		}
-		hybridDataSource = provider
-		realtimeDataSource = provider
-		historyDataSource = provider
-	case "prometheus", "prom":
-		fallthrough
+		provider, err := qcloudmonitor.NewProvider(&opts.ComparatorOptions.DataSourceQMonitorConfig, kubeClient, ctx.Done())
+		if err != nil {
+			klog.Exitf("unable to create datasource provider %v, err: %v", datasourceType, err)
+		}
+		return provider, provider
+	case datasource.MockDataSource:
+		provider, err := mock.NewProvider(&opts.ComparatorOptions.DataSourceMockConfig)
+		if err != nil {
+			klog.Exitf("unable to create datasource provider %v, err: %v", datasourceType, err)
+		}
+		return provider, provider
 	default:
-		// default is prom
-		provider, err := prom.NewProvider(&opts.ComparatorOptions.DataSourcePromConfig)
+		promConfig := opts.ComparatorOptions.DataSourcePromConfig
+		if opts.ComparatorOptions.PromQueryTemplatesFile != "" {
+			templates, err := promquerybuilder.LoadTemplates(opts.ComparatorOptions.PromQueryTemplatesFile)
+			if err != nil {
+				klog.Exitf("unable to load prometheus query templates %v, err: %v", opts.ComparatorOptions.PromQueryTemplatesFile, err)
+			}
+			promConfig.QueryTemplates = templates
+			klog.Infof("Loaded %v prometheus query templates from %v", len(templates), opts.ComparatorOptions.PromQueryTemplatesFile)
+		}
+		provider, err := prom.NewProvider(&promConfig)
 		if err != nil {
-			klog.Exitf("unable to create datasource provider %v, err: %v", datasourceStr, err)
+			klog.Exitf("unable to create datasource provider %v, err: %v", datasourceType, err)
 		}
-		hybridDataSource = provider
-		realtimeDataSource = provider
-		historyDataSource = provider
+		return provider, provider
 	}
-	return realtimeDataSource, historyDataSource, hybridDataSource
 }
 
 // Run runs the fadvisor with options. This should never exit.
@@ -210,6 +375,12 @@ func Run(ctx context.Context, opts *options.Options) error {
 	k8sCache.WaitForCacheSync(ctx.Done())
 
 	// initialize cloud provider with the cloud provider name and config file provided
+	opts.CloudConfig.PricingCacheStore, err = cloud.NewPricingCacheStore(opts.CloudConfig, kubeClient)
+	if err != nil {
+		return err
+	}
+	opts.CloudConfig.KubeClient = kubeClient
+	opts.CloudConfig.StopCh = ctx.Done()
 	priceConfig := cloud.NewProviderConfig(&opts.CustomPrice)
 	cloudPrice, err := cloud.InitCloudProvider(opts.CloudConfig, priceConfig, &k8sCache)
 	if err != nil {
@@ -224,14 +395,31 @@ func Run(ctx context.Context, opts *options.Options) error {
 	}
 	go wait.Until(cloudPrice.Refresh, 30*time.Minute, ctx.Done())
 	model := cloudcost.NewCloudCost(k8sCache, cloudPrice)
+	if opts.BillingPath != "" {
+		reconciler := billing.NewReconciler(opts.BillingPath)
+		// the bills are in the currency of the provider, the factors are the same for the converted prices
+		billedCloud := cloud.UnconvertedCloud(cloudPrice)
+		reconcile := func() {
+			nodes, err := billedCloud.GetNodesCost()
+			if err != nil {
+				klog.Errorf("Failed to get nodes cost to reconcile: %v", err)
+				return
+			}
+			if err = reconciler.Reconcile(nodes); err != nil {
+				klog.Errorf("Failed to reconcile with bills %v: %v", opts.BillingPath, err)
+			}
+		}
+		go wait.Until(reconcile, opts.BillingReconcileInterval, ctx.Done())
+		model = cloudcost.NewAdjustedCloudCost(k8sCache, cloudPrice, reconciler)
+	}
 
-	metricEmitter := prometheus.NewCostMetricEmitter(model, opts.MetricUpdateInterval, ctx.Done())
+	metricEmitter := prometheus.NewCostMetricEmitter(model, opts.MetricUpdateInterval, ctx.Done(), opts.OpenCost, opts.MetricCurrencyLabel)
 
 	// metrics do not allow multiple instances at the same time
 	run := func(ctx context.Context) {
 		go metricEmitter.Start()
 
-		server := exporter.NewServer(model, opts.BindAddr, opts.Debugging)
+		server := exporter.NewServer(model, opts.BindAddr, opts.Debugging, opts.OpenCost)
 		server.RegisterHandlers()
 		serverStopedCh := server.Serve(ctx.Done())
 
@@ -1,13 +1,17 @@
 package options
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/gocrane/fadvisor/pkg/cloud"
 	comparatorcfg "github.com/gocrane/fadvisor/pkg/cost-comparator/config"
 	"github.com/gocrane/fadvisor/pkg/datasource"
+	"github.com/gocrane/fadvisor/pkg/metricquery"
 )
 
 // ComparatorOptions used for fadvisor cost comparator
@@ -18,8 +22,28 @@ type ComparatorOptions struct {
 	CloudConfig cloud.CloudConfig
 	// DataSourcePromConfig is the prometheus datasource config
 	DataSourcePromConfig datasource.PromConfig
+	// PromQueryTemplatesFile is the config file of the templates overriding the built-in prometheus queries
+	PromQueryTemplatesFile string
 	// DataSourceQMonitorConfig is the tencent cloud monitor datasource config
 	DataSourceQMonitorConfig datasource.QCloudMonitorConfig
+	// DataSourceMetricServerConfig is the metrics server datasource config
+	DataSourceMetricServerConfig datasource.MetricServerConfig
+	// DataSourceMockConfig is the mock datasource config
+	DataSourceMockConfig datasource.MockConfig
+	// HistoryDataSources are the datasources of the history proxy in priority order, only the datasource of DataSource is used if empty
+	HistoryDataSources []string
+	// HistoryPolicy is the policy of the history proxy, failover or merge
+	HistoryPolicy string
+	// HistoryFailoverOnEmpty skips the datasource returning no time series of the failover policy
+	HistoryFailoverOnEmpty bool
+	// HistoryMergeIgnoredLabels are the labels not compared when merging the time series, such as the external labels
+	HistoryMergeIgnoredLabels []string
+	// HistoryRoutes are the datasources of the metric types, such as node=qcloudmonitor, the datasources are separated by |
+	HistoryRoutes map[string]string
+	// HistoryRetentions are the retention of the datasources, such as prom=360h
+	HistoryRetentions map[string]string
+	// SnapshotFile is the cluster snapshot archive, the comparator runs offline from it and the time series checkpoints if specified
+	SnapshotFile string
 }
 
 func NewComparatorOptions() *ComparatorOptions {
@@ -32,9 +56,85 @@ func (o *ComparatorOptions) Complete() error {
 
 func (o *ComparatorOptions) Validate() []error {
 	var errors []error
+	if ms := o.DataSourceMetricServerConfig; ms.RecorderEnabled && (ms.RecorderInterval <= 0 || ms.RecorderRetention < ms.RecorderInterval) {
+		errors = append(errors, fmt.Errorf("metrics server recorder interval must be positive and not larger than the retention"))
+	}
+	if ms := o.DataSourceMetricServerConfig; ms.RecorderEnabled && ms.RecorderDataFile != "" && ms.RecorderFlushInterval <= 0 {
+		errors = append(errors, fmt.Errorf("metrics server recorder flush interval must be positive"))
+	}
+	if _, err := o.HistoryProxyConfig(); err != nil {
+		errors = append(errors, err)
+	}
+	if o.SnapshotFile != "" && (o.Config.Service.Enabled || o.Config.EnableCostAnalysisController) {
+		errors = append(errors, fmt.Errorf("comparator snapshot file can not be used with the service mode or the CostAnalysis controller"))
+	}
+	if o.SnapshotFile != "" {
+		for _, platform := range o.Config.Platforms {
+			if comparatorcfg.NeedsDataSource(platform) {
+				errors = append(errors, fmt.Errorf("platform %v needs the node metrics of the datasource, it can not be analyzed from the comparator snapshot file", platform))
+			}
+		}
+	}
 	return errors
 }
 
+// HistoryProxyConfig returns the config of the history proxy of the history datasources
+func (o *ComparatorOptions) HistoryProxyConfig() (datasource.HistoryProxyConfig, error) {
+	config := datasource.HistoryProxyConfig{
+		Policy:             datasource.HistoryPolicy(strings.ToLower(o.HistoryPolicy)),
+		FailoverOnEmpty:    o.HistoryFailoverOnEmpty,
+		MergeIgnoredLabels: o.HistoryMergeIgnoredLabels,
+		Routes:             make(map[metricquery.MetricType][]datasource.DataSourceType),
+		Retentions:         make(map[datasource.DataSourceType]time.Duration),
+	}
+	switch config.Policy {
+	case "", datasource.HistoryPolicyFailover, datasource.HistoryPolicyMerge:
+	default:
+		return config, fmt.Errorf("unknown history datasource policy %v, only failover and merge are supported", o.HistoryPolicy)
+	}
+	configured := sets.NewString()
+	for _, name := range o.HistoryDataSources {
+		t, err := datasource.ParseDataSourceType(name)
+		if err != nil {
+			return config, err
+		}
+		if t == datasource.MetricServerDataSource && !o.DataSourceMetricServerConfig.RecorderEnabled {
+			return config, fmt.Errorf("datasource %v has no history data without the metrics server recorder", name)
+		}
+		config.Priority = append(config.Priority, t)
+		configured.Insert(string(t))
+	}
+	for metricType, names := range o.HistoryRoutes {
+		switch metricquery.MetricType(metricType) {
+		case metricquery.WorkloadMetricType, metricquery.ContainerMetricType, metricquery.PodMetricType, metricquery.NodeMetricType, metricquery.PromQLMetricType:
+		default:
+			return config, fmt.Errorf("unknown metric type %v of history datasource routes", metricType)
+		}
+		for _, name := range strings.Split(names, "|") {
+			t, err := datasource.ParseDataSourceType(name)
+			if err != nil {
+				return config, err
+			}
+			if !configured.Has(string(t)) {
+				return config, fmt.Errorf("datasource %v of the route of %v is not in the history datasources", name, metricType)
+			}
+			config.Routes[metricquery.MetricType(metricType)] = append(config.Routes[metricquery.MetricType(metricType)], t)
+		}
+	}
+	for name, value := range o.HistoryRetentions {
+		t, err := datasource.ParseDataSourceType(name)
+		if err != nil {
+			return config, err
+		}
+		retention, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid retention %v of datasource %v: %v", value, name, err)
+		}
+		config.Retentions[t] = retention
+	}
+	return config, nil
+}
+
 func (o *ComparatorOptions) AddFlags(fs *pflag.FlagSet) {
 	if fs == nil {
 		return
@@ -47,13 +147,31 @@ func (o *ComparatorOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.Config.ClusterName, "comparator-cluster-name", "default", "cluster name the comparator running base on")
 	fs.StringVar(&o.Config.ClusterId, "comparator-cluster-id", "default", "cluster id the comparator running base on")
 	fs.Float64Var(&o.Config.Discount, "comparator-discount", 1.0, "discount used to compute costs")
-	fs.StringVar(&o.Config.OutputMode, "comparator-output-mode", "", "results output mode, includes stdout, csv. if no specified, both will output. including csv file and a table print")
+	fs.StringVar(&o.Config.OutputMode, "comparator-output-mode", "", "results output mode, comma separated list of stdout, csv, json, parquet, html. if no specified, stdout and csv will output. including csv file and a table print")
 	fs.BoolVar(&o.Config.EnableContainerCheckpoint, "comparator-enable-container-ts-checkpoint", false, "enable container time series data checkpoint")
 	fs.BoolVar(&o.Config.EnableWorkloadTimeSeries, "comparator-enable-workload-ts", false, "enable workload time series fetching, it will fetch workload time series data")
 	fs.BoolVar(&o.Config.EnableWorkloadCheckpoint, "comparator-enable-workload-ts-checkpoint", false, "enable workload time series data checkpoint")
 	fs.StringVar(&o.Config.DataPath, "comparator-data-path", ".", "data path of the report and checkpoint data stored")
+	fs.Uint64Var(&o.Config.SpotMinReplicas, "comparator-spot-min-replicas", 2, "min replicas of the stateless workload which is safe to run on spot instances")
+	fs.Float64Var(&o.Config.ReservedBaselinePercentile, "comparator-reserved-baseline-percentile", 0.1, "percentile of the node count history by instance type used as the stable baseline of reserved instances recommendation")
+	fs.StringVar(&o.Config.InstanceCatalogFile, "comparator-instance-catalog-file", "", "json file of the instance types pricing list used as candidates of node rightsizing and bin packing, default is the standard pricing of the cloud provider")
+
+	fs.BoolVar(&o.Config.Service.Enabled, "comparator-service-mode", false, "run the comparator as a long-lived service, it analyzes on schedule and serves the results at /comparator/runs")
+	fs.DurationVar(&o.Config.Service.Interval, "comparator-service-interval", 24*time.Hour, "interval of the scheduled analysis in service mode, 0 means only run on demand by POST /comparator/runs")
+	fs.IntVar(&o.Config.Service.MaxRuns, "comparator-service-max-runs", 10, "max number of the analysis runs kept in memory in service mode")
+	fs.BoolVar(&o.Config.Service.Output, "comparator-service-output", false, "write the report files and print the tables of each run by the output mode in service mode, by default the results are only served at /comparator/runs")
+
+	fs.BoolVar(&o.Config.EnableCostAnalysisController, "comparator-enable-costanalysis-controller", false, "enable the controller which runs the comparator analysis for the CostAnalysis custom resources")
+
+	fs.StringVar(&o.SnapshotFile, "comparator-snapshot-file", "", "cluster snapshot archive taken by the snapshot command, if specified, the comparator runs offline from it and the time series checkpoints without the api server")
 
-	fs.StringVar(&o.DataSource, "datasource", "prom", "data source of the estimator, prom, qmonitor is available")
+	fs.StringVar(&o.DataSource, "datasource", "prom", "data source of the estimator, prom, qmonitor, mock is available")
+	fs.StringSliceVar(&o.HistoryDataSources, "history-datasources", nil, "comma separated datasources of the history time series in priority order, such as prom,qcloudmonitor, if no specified, only the datasource is used")
+	fs.StringVar(&o.HistoryPolicy, "history-datasource-policy", "failover", "policy of the history datasources, failover returns the first datasource without error, merge fills the gaps of the higher priority datasource by the lower ones of the same labels")
+	fs.BoolVar(&o.HistoryFailoverOnEmpty, "history-datasource-failover-on-empty", false, "fail over to the next history datasource if the datasource returns no time series of the failover policy")
+	fs.StringSliceVar(&o.HistoryMergeIgnoredLabels, "history-datasource-merge-ignored-labels", nil, "comma separated labels not compared when merging the time series of the merge policy, such as the external labels replica,prometheus,cluster added by thanos or mimir")
+	fs.StringToStringVar(&o.HistoryRoutes, "history-datasource-routes", nil, "history datasources of the metric types, such as node=qcloudmonitor,container=prom|qcloudmonitor")
+	fs.StringToStringVar(&o.HistoryRetentions, "history-datasource-retentions", nil, "retention of the history datasources, a datasource is only queried for the window in its retention, such as prom=360h")
 	fs.StringVar(&o.DataSourcePromConfig.Address, "prometheus-address", "", "prometheus address")
 	fs.StringVar(&o.DataSourcePromConfig.Auth.Username, "prometheus-auth-username", "", "prometheus auth username")
 	fs.StringVar(&o.DataSourcePromConfig.Auth.Password, "prometheus-auth-password", "", "prometheus auth password")
@@ -67,5 +185,21 @@ func (o *ComparatorOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&o.DataSourcePromConfig.FederatedClusterScope, "prometheus-federated-cluster-scope", false, "prometheus support federated clusters query")
 	fs.BoolVar(&o.DataSourcePromConfig.ThanosPartial, "prometheus-thanos-partial", false, "prometheus api to query thanos data source, hacking way, denote the thanos partial response query")
 	fs.BoolVar(&o.DataSourcePromConfig.ThanosDedup, "prometheus-thanos-dedup", false, "prometheus api to query thanos data source, hacking way, denote the thanos deduplicate query")
+	fs.StringVar(&o.DataSourcePromConfig.Backend, "prometheus-backend", "", "prometheus compatible backend, prometheus, thanos, mimir, cortex or victoriametrics, it is detected if no specified")
+	fs.StringVar(&o.DataSourcePromConfig.TenantID, "prometheus-tenant-id", "", "tenant id of the multi-tenant backend, X-Scope-OrgID header for mimir and cortex, THANOS-TENANT header for thanos, AccountID and ProjectID headers for victoriametrics with format accountID[:projectID]")
+	fs.StringVar(&o.DataSourcePromConfig.TenantHeader, "prometheus-tenant-header", "", "header name of the tenant id, overrides the tenant header of the backend")
+	fs.StringToStringVar(&o.DataSourcePromConfig.Headers, "prometheus-headers", nil, "custom headers sent with each prometheus request, such as X-Custom=value,X-Other=value")
+	fs.StringVar(&o.DataSourcePromConfig.TLS.CAFile, "prometheus-tls-ca-file", "", "ca file to verify the prometheus server certificate")
+	fs.StringVar(&o.DataSourcePromConfig.TLS.CertFile, "prometheus-tls-cert-file", "", "client certificate file for prometheus mTLS")
+	fs.StringVar(&o.DataSourcePromConfig.TLS.KeyFile, "prometheus-tls-key-file", "", "client key file for prometheus mTLS")
+	fs.StringVar(&o.DataSourcePromConfig.TLS.ServerName, "prometheus-tls-server-name", "", "server name to verify the prometheus server certificate")
+	fs.BoolVar(&o.DataSourcePromConfig.ExportEnabled, "prometheus-export-enabled", true, "use the victoriametrics export api for the series selector which is too long for one range query, only for the victoriametrics backend")
+	fs.StringVar(&o.PromQueryTemplatesFile, "prometheus-query-templates-file", "", "yaml or json file of the go templates overriding the built-in promql of the workload, container, pod and node metrics, such as templates: {workload.cpu: ...}")
+	fs.BoolVar(&o.DataSourceMetricServerConfig.RecorderEnabled, "metricserver-recorder-enabled", false, "record the metrics server on an interval to provide the history time series of the metricserver datasource")
+	fs.DurationVar(&o.DataSourceMetricServerConfig.RecorderInterval, "metricserver-recorder-interval", time.Minute, "interval of the metrics server recorder sampling the metrics server")
+	fs.DurationVar(&o.DataSourceMetricServerConfig.RecorderRetention, "metricserver-recorder-retention", 7*24*time.Hour, "retention of the samples of the metrics server recorder")
+	fs.DurationVar(&o.DataSourceMetricServerConfig.RecorderFlushInterval, "metricserver-recorder-flush-interval", 10*time.Minute, "interval of persisting the samples of the metrics server recorder to the data file")
+	fs.StringVar(&o.DataSourceMetricServerConfig.RecorderDataFile, "metricserver-recorder-data-file", "metricserver-recorder.json.gz", "data file of the samples of the metrics server recorder, the samples are loaded at startup, if it is empty, the samples are only kept in memory")
+	fs.StringVar(&o.DataSourceMockConfig.SeedFile, "mock-seed-file", "", "csv, json or openmetrics file of the time series, or a directory of them, used by the mock data source")
 
 }
@@ -0,0 +1,44 @@
+package options
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+)
+
+// DiffOptions used for diffing two comparator runs
+type DiffOptions struct {
+	// Old and New are json reports or workload time series checkpoints of the comparator
+	Old        string
+	New        string
+	Threshold  float64
+	DataPath   string
+	OutputMode string
+}
+
+func NewDiffOptions() *DiffOptions {
+	return &DiffOptions{}
+}
+
+func (o *DiffOptions) Validate() []error {
+	var errors []error
+	if o.Old == "" || o.New == "" {
+		errors = append(errors, fmt.Errorf("both --old and --new are required"))
+	}
+	if o.Threshold < 0 {
+		errors = append(errors, fmt.Errorf("threshold %v is negative", o.Threshold))
+	}
+	return errors
+}
+
+func (o *DiffOptions) AddFlags(fs *pflag.FlagSet) {
+	if fs == nil {
+		return
+	}
+
+	fs.StringVar(&o.Old, "old", "", "the old comparator json report or workload time series checkpoint")
+	fs.StringVar(&o.New, "new", "", "the new comparator json report or workload time series checkpoint")
+	fs.Float64Var(&o.Threshold, "threshold", 0.1, "growth ratio of the requests below which the change is ignored")
+	fs.StringVar(&o.DataPath, "data-path", ".", "data path of the diff outputs stored")
+	fs.StringVar(&o.OutputMode, "output-mode", "", "comma separated output modes of the diff, stdout, csv and json, default is stdout and csv")
+}
@@ -1,6 +1,8 @@
 package options
 
 import (
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/spf13/pflag"
@@ -12,6 +14,7 @@ import (
 
 	"github.com/gocrane/fadvisor/pkg/cloud"
 	"github.com/gocrane/fadvisor/pkg/consts"
+	"github.com/gocrane/fadvisor/pkg/cost-exporter/opencost"
 )
 
 // Options hold the command-line options about crane manager
@@ -31,8 +34,18 @@ type Options struct {
 	MaxIdleConnsPerClient int
 
 	MetricUpdateInterval time.Duration
+	// MetricCurrencyLabel adds the currency label to the cost metrics
+	MetricCurrencyLabel bool
 
 	CustomPrice cloud.CustomPricing
+	// CurrencyRates is the rate table of the currencies to the display currency, the values are parsed to the cloud config
+	CurrencyRates map[string]string
+
+	OpenCost opencost.Config
+
+	// BillingPath is the billing exports to reconcile the estimated node costs
+	BillingPath              string
+	BillingReconcileInterval time.Duration
 
 	ComparatorMode    bool
 	ComparatorOptions *ComparatorOptions
@@ -55,12 +68,30 @@ func NewOptions() *Options {
 
 // Complete completes all the required options.
 func (o *Options) Complete() error {
+	if o.OpenCost.Provisioner == "" {
+		o.OpenCost.Provisioner = o.CloudConfig.Provider
+	}
+	if len(o.CurrencyRates) > 0 {
+		o.CloudConfig.CurrencyRates = make(map[string]float64, len(o.CurrencyRates))
+		for currency, value := range o.CurrencyRates {
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid rate %v of currency %v: %v", value, currency, err)
+			}
+			o.CloudConfig.CurrencyRates[currency] = rate
+		}
+	}
 	return o.ComparatorOptions.Complete()
 }
 
 // Validate all required options.
 func (o *Options) Validate() []error {
-	return o.ComparatorOptions.Validate()
+	errors := o.ComparatorOptions.Validate()
+	// the comparator discount would compound with the discount rules applied to the provider prices
+	if o.CloudConfig.DiscountRulesFile != "" && o.ComparatorOptions.Config.Discount != 1 {
+		errors = append(errors, fmt.Errorf("comparator discount can not be used with the discount rules file, add a rule of the percentage instead"))
+	}
+	return errors
 }
 
 func (o *Options) ApplyTo() {
@@ -89,6 +120,9 @@ func (o *Options) AddFlags(flags *pflag.FlagSet) {
 
 	flags.StringVar(&o.CloudConfig.Provider, "provider", "default", "cloud provider the fadvisor running on, now support default and qcloud only.")
 	flags.StringVar(&o.CloudConfig.CloudConfigFile, "cloudConfigFile", "", "cloudConfigFile specifies path for the cloud configuration.")
+	flags.StringVar(&o.CloudConfig.PricingCacheFile, "pricing-cache-file", "", "file persisting the pricing caches of the cloud provider, the persisted prices are loaded at startup and served when the cloud api fails")
+	flags.StringVar(&o.CloudConfig.PricingCacheConfigMap, "pricing-cache-configmap", "", "configmap of namespace/name persisting the pricing caches of the cloud provider, it is used if the pricing-cache-file is not specified")
+	flags.StringVar(&o.CloudConfig.DiscountRulesFile, "discount-rules-file", "", "yaml or json file of the discount rules matching the provider, region, zone, instance family, charge type and effective dates, the first matched rule sets the node and pod prices by a percentage or a fixed hourly price")
 
 	flags.StringVar(&o.ClientConfig.Kubeconfig, "kubeconfig",
 		o.ClientConfig.Kubeconfig, "Path to kubeconfig file with authorization and master location information.")
@@ -107,11 +141,23 @@ func (o *Options) AddFlags(flags *pflag.FlagSet) {
 		true, "Enable lock contention profiling, if profiling is enabled")
 
 	flags.DurationVar(&o.MetricUpdateInterval, "metric-update-interval", 5*time.Minute, "metric update interval for prometheus")
+	flags.BoolVar(&o.MetricCurrencyLabel, "metric-currency-label", false, "add the currency label to the cost metrics, it changes the series of node_*_hourly_cost")
 
 	flags.StringVar(&o.CustomPrice.Description, "custom-price-desc", "default pricing", "custom pricing config description")
 	flags.StringVar(&o.CustomPrice.Provider, "custom-price-provider", "default", "custom pricing config provider")
 	flags.Float64Var(&o.CustomPrice.CpuHourlyPrice, "custom-price-cpu", 0.031611, "cpu hourly unit price of one core")
 	flags.Float64Var(&o.CustomPrice.RamGBHourlyPrice, "custom-price-ram", 0.004237, "ram gb hourly unit price")
+	flags.Float64Var(&o.CustomPrice.StorageGBHourlyPrice, "custom-price-storage", 0.00005479452, "persistent volume gb hourly unit price")
+	flags.StringVar(&o.CustomPrice.Currency, "custom-price-currency", "", "currency of the custom prices, such as USD, if no specified, it is the display currency")
+	flags.StringVar(&o.CloudConfig.Currency, "currency", "", "display currency of the reports and metrics, such as USD, the prices are converted to it by the currency rates, if no specified, the prices are not converted")
+	flags.StringToStringVar(&o.CurrencyRates, "currency-rates", nil, "value of one unit of each currency in the display currency, such as CNY=0.14")
+
+	flags.StringVar(&o.BillingPath, "billing-path", "", "billing export file or directory of qcloud bill csv or aws cur csv, the node costs are adjusted by the bills if specified")
+	flags.DurationVar(&o.BillingReconcileInterval, "billing-reconcile-interval", time.Hour, "interval to reload the billing exports and reconcile the node costs")
+
+	flags.BoolVar(&o.OpenCost.Enabled, "opencost-compatible", false, "serve the opencost allocation api /allocation/compute and emit the opencost metrics")
+	flags.StringVar(&o.OpenCost.ClusterID, "opencost-cluster-id", "cluster-one", "cluster id of the opencost allocations")
+	flags.StringVar(&o.OpenCost.Provisioner, "opencost-provisioner-name", "", "provisioner_name label of the opencost cluster management cost metric, default is the provider")
 
 	flags.BoolVar(&o.ComparatorMode, "comparator-mode", false, "run as fadvisor cost comparator mode, it is an offline analysis tool")
 	o.ComparatorOptions.AddFlags(flags)
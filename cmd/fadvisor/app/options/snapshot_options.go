@@ -0,0 +1,40 @@
+package options
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+	componentbaseconfig "k8s.io/component-base/config"
+)
+
+// SnapshotOptions used for taking the cluster snapshot of the offline comparator
+type SnapshotOptions struct {
+	ClientConfig          componentbaseconfig.ClientConnectionConfiguration
+	MaxIdleConnsPerClient int
+	// Output is the snapshot archive file
+	Output string
+}
+
+func NewSnapshotOptions() *SnapshotOptions {
+	return &SnapshotOptions{}
+}
+
+func (o *SnapshotOptions) Validate() []error {
+	var errors []error
+	if o.Output == "" {
+		errors = append(errors, fmt.Errorf("--output is required"))
+	}
+	return errors
+}
+
+func (o *SnapshotOptions) AddFlags(fs *pflag.FlagSet) {
+	if fs == nil {
+		return
+	}
+
+	fs.StringVar(&o.ClientConfig.Kubeconfig, "kubeconfig", "", "Path to kubeconfig file with authorization and master location information.")
+	fs.Float32Var(&o.ClientConfig.QPS, "kube-api-qps", o.ClientConfig.QPS, "QPS to use while talking with kubernetes apiserver.")
+	fs.Int32Var(&o.ClientConfig.Burst, "kube-api-burst", o.ClientConfig.Burst, "Burst to use while talking with kubernetes apiserver.")
+	fs.IntVar(&o.MaxIdleConnsPerClient, "kube-client-max-idle-conns", o.MaxIdleConnsPerClient, "MaxIdleConnsPerHost of each k8s or custom clients")
+	fs.StringVar(&o.Output, "output", "cluster-snapshot.tar.gz", "the snapshot archive file")
+}
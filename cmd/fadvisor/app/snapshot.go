@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/scale"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/gocrane/fadvisor/cmd/fadvisor/app/options"
+	"github.com/gocrane/fadvisor/pkg/cache"
+	"github.com/gocrane/fadvisor/pkg/snapshot"
+	"github.com/gocrane/fadvisor/pkg/util"
+	"github.com/gocrane/fadvisor/pkg/util/target"
+)
+
+// NewSnapshotCommand creates the command to take the cluster snapshot used by the offline comparator
+func NewSnapshotCommand(ctx context.Context) *cobra.Command {
+	opts := options.NewSnapshotOptions()
+
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "take a snapshot of the cluster for the offline comparator",
+		Long:  `dump the pods, nodes, workloads, hpas, pdbs, the owners of the pods and the replicas of the workloads to an archive, the comparator can run from the archive and the time series checkpoints without the api server`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if errs := opts.Validate(); len(errs) > 0 {
+				klog.Errorf("opts validate failed, exit: %v", errs)
+				os.Exit(255)
+			}
+			if err := RunSnapshot(ctx, opts); err != nil {
+				klog.Errorf("run snapshot failed, exit: %v", err)
+				os.Exit(255)
+			}
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+func RunSnapshot(ctx context.Context, opts *options.SnapshotOptions) error {
+	creator, err := util.CreateK8sClient(opts.ClientConfig, opts.MaxIdleConnsPerClient)
+	if err != nil {
+		return err
+	}
+	kubeClient := creator("fadvisor-snapshot")
+
+	k8sCache := cache.NewCache(kubeClient)
+	k8sCache.WaitForCacheSync(ctx.Done())
+
+	restConfig, err := util.NewK8sConfig(opts.ClientConfig, opts.MaxIdleConnsPerClient)
+	if err != nil {
+		return err
+	}
+	dynamicKubeClient, err := dynamic.NewForConfig(rest.AddUserAgent(restConfig, "fadvisor-snapshot-dynamic"))
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	restMapper, err := apiutil.NewDynamicRESTMapper(restConfig)
+	if err != nil {
+		return err
+	}
+	scaleClient := scale.New(
+		discoveryClient.RESTClient(), restMapper,
+		dynamic.LegacyAPIPathResolverFunc,
+		scale.NewDiscoveryScaleKindResolver(discoveryClient),
+	)
+	targetFetcher := target.NewTargetInfoFetcher(restMapper, scaleClient, kubeClient)
+
+	s := snapshot.Capture(ctx, k8sCache, restMapper, dynamicKubeClient, targetFetcher)
+	if err = snapshot.Write(opts.Output, s); err != nil {
+		return err
+	}
+	klog.Infof("Snapshot of %v pods, %v nodes and %v owners written to %v", len(s.Pods), len(s.Nodes), len(s.Owners), opts.Output)
+	return nil
+}
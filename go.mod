@@ -4,6 +4,7 @@ go 1.16
 
 require (
 	github.com/evanphx/json-patch v4.11.0+incompatible
+	github.com/fsnotify/fsnotify v1.5.1
 	github.com/go-logr/logr v0.4.0
 	github.com/gocrane/crane v0.3.0
 	github.com/json-iterator/go v1.1.12
@@ -30,6 +31,7 @@ require (
 	k8s.io/metrics v0.22.3
 	k8s.io/utils v0.0.0-20210819203725-bdf08cb9a70a
 	sigs.k8s.io/controller-runtime v0.10.2
+	sigs.k8s.io/yaml v1.2.0
 )
 
 replace (
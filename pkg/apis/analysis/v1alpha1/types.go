@@ -0,0 +1,103 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	GroupName = "analysis.fadvisor.crane.io"
+	Version   = "v1alpha1"
+	Kind      = "CostAnalysis"
+	Resource  = "costanalyses"
+)
+
+var (
+	GroupVersion = schema.GroupVersion{Group: GroupName, Version: Version}
+	// CostAnalysisResource is used by the dynamic client
+	CostAnalysisResource = GroupVersion.WithResource(Resource)
+)
+
+type CostAnalysisPhase string
+
+const (
+	CostAnalysisPending   CostAnalysisPhase = "Pending"
+	CostAnalysisRunning   CostAnalysisPhase = "Running"
+	CostAnalysisSucceeded CostAnalysisPhase = "Succeeded"
+	CostAnalysisFailed    CostAnalysisPhase = "Failed"
+)
+
+// CostAnalysis requests the comparator to analyze the cost of the cluster, the analysis runs again when the spec changes
+type CostAnalysis struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CostAnalysisSpec   `json:"spec,omitempty"`
+	Status CostAnalysisStatus `json:"status,omitempty"`
+}
+
+type CostAnalysisSpec struct {
+	// History is the window of the history metrics to analyze, default is the comparator flags
+	// +optional
+	History HistoryWindow `json:"history,omitempty"`
+	// Scope limits the workloads to analyze, all workloads if empty
+	// +optional
+	Scope Scope `json:"scope,omitempty"`
+	// Estimator is the config of the resource estimator, such as percentile and marginFraction
+	// +optional
+	Estimator Estimator `json:"estimator,omitempty"`
+	// TargetPlatforms are the platforms to compare with the original cost, includes serverless, binpacking, rightsizing, spot and reserved. all if empty
+	// +optional
+	TargetPlatforms []string `json:"targetPlatforms,omitempty"`
+	// TimeSpanSeconds is the time span of the costs, default is the comparator flags
+	// +optional
+	TimeSpanSeconds int64 `json:"timeSpanSeconds,omitempty"`
+}
+
+type HistoryWindow struct {
+	// +optional
+	Length *metav1.Duration `json:"length,omitempty"`
+	// +optional
+	Step *metav1.Duration `json:"step,omitempty"`
+	// End of the window, default is now
+	// +optional
+	End *metav1.Time `json:"end,omitempty"`
+}
+
+type Scope struct {
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+type Estimator struct {
+	// Config of the statistic estimator, the values are float strings
+	// +optional
+	Config map[string]string `json:"config,omitempty"`
+}
+
+type CostAnalysisStatus struct {
+	// +optional
+	Phase CostAnalysisPhase `json:"phase,omitempty"`
+	// ObservedGeneration is the generation of the spec analyzed
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Costs is the total cost of each platform in the time span
+	// +optional
+	Costs []CostSummary `json:"costs,omitempty"`
+	// ReportLocation is the configmap in the namespace of the CostAnalysis storing the full report as gzip compressed json,
+	// such as configmap/<name>-report. the configmap is deleted with the CostAnalysis
+	// +optional
+	ReportLocation string `json:"reportLocation,omitempty"`
+}
+
+type CostSummary struct {
+	Name string `json:"name"`
+	// Cost is a decimal string
+	Cost string `json:"cost"`
+}
@@ -0,0 +1,81 @@
+package billing
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	awsLineItemSavingsPlanCovered = "SavingsPlanCoveredUsage"
+	awsLineItemDiscounted         = "DiscountedUsage"
+)
+
+// the columns of the aws cost and usage report
+var awsCURColumns = map[string][]string{
+	"resource":       {"lineItem/ResourceId"},
+	"type":           {"lineItem/LineItemType"},
+	"start":          {"lineItem/UsageStartDate"},
+	"end":            {"lineItem/UsageEndDate"},
+	"unblended":      {"lineItem/UnblendedCost"},
+	"netUnblended":   {"lineItem/NetUnblendedCost"},
+	"savingsPlan":    {"savingsPlan/SavingsPlanEffectiveCost"},
+	"netSavingsPlan": {"savingsPlan/NetSavingsPlanEffectiveCost"},
+	"reservation":    {"reservation/EffectiveCost"},
+	"netReservation": {"reservation/NetEffectiveCost"},
+	"currency":       {"lineItem/CurrencyCode"},
+}
+
+// awsCURFormat is the aws cost and usage report, the effective cost of the savings plans and the reservations
+// is used for the covered usage, and the net cost is used if the report includes the discounts
+type awsCURFormat struct {
+	columns columns
+}
+
+func newAWSCURFormat() format {
+	return &awsCURFormat{}
+}
+
+func (f *awsCURFormat) name() string {
+	return "aws-cur"
+}
+
+func (f *awsCURFormat) match(header []string) bool {
+	f.columns = newColumns(header, awsCURColumns)
+	return f.columns.has("resource", "start", "end", "unblended")
+}
+
+func (f *awsCURFormat) parse(row []string) (LineItem, bool, error) {
+	id := f.columns.get(row, "resource")
+	// only the ec2 instances are reconciled
+	if !strings.HasPrefix(id, "i-") {
+		return LineItem{}, false, nil
+	}
+	var costs []string
+	switch f.columns.get(row, "type") {
+	case awsLineItemSavingsPlanCovered:
+		costs = []string{"netSavingsPlan", "savingsPlan"}
+	case awsLineItemDiscounted:
+		costs = []string{"netReservation", "reservation"}
+	default:
+		costs = []string{"netUnblended", "unblended"}
+	}
+	var cost float64
+	for _, field := range costs {
+		if v := f.columns.get(row, field); v != "" {
+			var err error
+			if cost, err = parseCost(v); err != nil {
+				return LineItem{}, false, fmt.Errorf("invalid %v of %v: %v", field, id, err)
+			}
+			break
+		}
+	}
+	start, err := parseTime(f.columns.get(row, "start"))
+	if err != nil {
+		return LineItem{}, false, err
+	}
+	end, err := parseTime(f.columns.get(row, "end"))
+	if err != nil {
+		return LineItem{}, false, err
+	}
+	return LineItem{ResourceID: id, Start: start, End: end, Cost: cost, Currency: f.columns.get(row, "currency")}, true, nil
+}
@@ -0,0 +1,196 @@
+package billing
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// LineItem is the cost of a resource in a time range of the bill
+type LineItem struct {
+	ResourceID string
+	Start      time.Time
+	End        time.Time
+	// Cost is the cost paid after the discounts
+	Cost float64
+	// Currency is the currency of the cost, such as USD, empty if the bill has no currency
+	Currency string
+}
+
+// format parses the rows of a billing export
+type format interface {
+	// name of the format
+	name() string
+	// match returns true if the header is the header of the format
+	match(header []string) bool
+	// parse parses the row to the line item, ok is false if the row is not a line item of an instance
+	parse(row []string) (item LineItem, ok bool, err error)
+}
+
+// newFormats are the constructors of the supported formats, the format keeps the columns of the header it matched
+var newFormats = []func() format{newQCloudFormat, newAWSCURFormat}
+
+// LoadBills loads the line items of the billing exports in the path, the path is a file or a directory,
+// the csv and gzip compressed csv files are loaded
+func LoadBills(path string) ([]LineItem, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, entry := range entries {
+			if !entry.IsDir() && (strings.HasSuffix(entry.Name(), ".csv") || strings.HasSuffix(entry.Name(), ".csv.gz")) {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	var items []LineItem
+	for _, file := range files {
+		fileItems, err := loadBill(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load bill %v: %v", file, err)
+		}
+		items = append(items, fileItems...)
+	}
+	return items, nil
+}
+
+func loadBill(file string) ([]LineItem, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return ParseBill(r)
+}
+
+// ParseBill parses the billing export csv, the format is detected by the header.
+// the rows before the header, such as the summary of the qcloud bill, are skipped.
+func ParseBill(r io.Reader) ([]LineItem, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var f format
+	var items []LineItem
+	line := 0
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line++
+		if f == nil {
+			if line == 1 && len(row) > 0 {
+				row[0] = strings.TrimPrefix(row[0], "\ufeff")
+			}
+			for _, newFormat := range newFormats {
+				if candidate := newFormat(); candidate.match(row) {
+					f = candidate
+					klog.V(4).Infof("Detected bill format %v at line %v", f.name(), line)
+					break
+				}
+			}
+			continue
+		}
+		item, ok, err := f.parse(row)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", line, err)
+		}
+		if ok {
+			items = append(items, item)
+		}
+	}
+	if f == nil {
+		return nil, fmt.Errorf("unknown bill format, no header of the qcloud bill or aws cur is found")
+	}
+	return items, nil
+}
+
+// columns is the index of the fields in the row
+type columns map[string]int
+
+// newColumns returns the index of the first header matching each of the names, the names are case insensitive
+func newColumns(header []string, names map[string][]string) columns {
+	results := make(columns)
+	for field, aliases := range names {
+		for _, alias := range aliases {
+			for i, h := range header {
+				if strings.EqualFold(strings.TrimSpace(h), alias) {
+					results[field] = i
+					break
+				}
+			}
+			if _, ok := results[field]; ok {
+				break
+			}
+		}
+	}
+	return results
+}
+
+// has returns true if all the fields are found in the header
+func (c columns) has(fields ...string) bool {
+	for _, field := range fields {
+		if _, ok := c[field]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// get returns the value of the field in the row
+func (c columns) get(row []string, field string) string {
+	index, ok := c[field]
+	if !ok || index >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[index])
+}
+
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05Z", "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+func parseCost(s string) (float64, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	if s == "" || s == "-" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
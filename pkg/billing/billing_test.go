@@ -0,0 +1,87 @@
+package billing
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gocrane/fadvisor/pkg/cloud"
+)
+
+const qcloudBill = "\ufeff账单月份,2022-04\n" +
+	"资源ID,产品名称,计费模式,使用开始时间,使用结束时间,原价,优惠后总价\n" +
+	"ins-2jv4wpmr,云服务器CVM,按量计费,2022-04-01 00:00:00,2022-04-01 12:00:00,1.20,1.02\n" +
+	"ins-2jv4wpmr,云服务器CVM,按量计费,2022-04-01 12:00:00,2022-04-02 00:00:00,1.20,1.02\n" +
+	"disk-8g6b4tqk,云硬盘CBS,按量计费,2022-04-01 00:00:00,2022-04-02 00:00:00,0.50,0.50\n"
+
+const awsCUR = "identity/LineItemId,lineItem/LineItemType,lineItem/UsageStartDate,lineItem/UsageEndDate,lineItem/ResourceId,lineItem/UnblendedCost,lineItem/CurrencyCode,savingsPlan/SavingsPlanEffectiveCost\n" +
+	"1,Usage,2022-04-01T00:00:00Z,2022-04-01T01:00:00Z,i-0abc,0.10,USD,\n" +
+	"2,SavingsPlanCoveredUsage,2022-04-01T01:00:00Z,2022-04-01T02:00:00Z,i-0abc,0.10,USD,0.06\n" +
+	"3,Usage,2022-04-01T01:00:00Z,2022-04-01T02:00:00Z,vol-0abc,0.01,USD,\n" +
+	"4,Usage,2022-04-01T00:00:00Z,2022-04-01T01:00:00Z,i-0def,0.10,USD,\n"
+
+func TestParseBill(t *testing.T) {
+	items, err := ParseBill(strings.NewReader(qcloudBill))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[0].ResourceID != "ins-2jv4wpmr" || items[0].Cost != 1.02 {
+		t.Errorf("unexpected qcloud line items %+v", items)
+	}
+
+	items, err = ParseBill(strings.NewReader(awsCUR))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the savings plan effective cost is used for the covered usage, the volumes are not reconciled
+	if len(items) != 3 || items[1].Cost != 0.06 || items[1].Currency != "USD" {
+		t.Errorf("unexpected aws line items %+v", items)
+	}
+
+	if _, err = ParseBill(strings.NewReader("a,b,c\n1,2,3\n")); err == nil {
+		t.Errorf("expect error of unknown format")
+	}
+}
+
+func TestReconciler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "billing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = ioutil.WriteFile(filepath.Join(dir, "qcloud.csv"), []byte(qcloudBill), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "cur.csv"), []byte(awsCUR), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := map[string]*cloud.Node{
+		// 2 cores and 4GB, 0.1 per hour
+		"10.0.0.1": {BaseInstancePrice: cloud.BaseInstancePrice{Cpu: "2", CpuHourlyCost: "0.03", Ram: "4", RamGBHourlyCost: "0.01", ProviderID: "qcloud:///800005/ins-2jv4wpmr"}},
+		"10.0.0.2": {BaseInstancePrice: cloud.BaseInstancePrice{Cpu: "2", CpuHourlyCost: "0.05", ProviderID: "aws:///us-east-1a/i-0abc"}},
+		"10.0.0.3": {BaseInstancePrice: cloud.BaseInstancePrice{Cpu: "2", CpuHourlyCost: "0.05", ProviderID: "qcloud:///800005/ins-notbilled"}},
+		"10.0.0.4": {BaseInstancePrice: cloud.BaseInstancePrice{Cpu: "2", CpuHourlyCost: "0.05", ProviderID: "aws:///us-east-1a/i-0def", Currency: "CNY"}},
+	}
+	r := NewReconciler(dir)
+	if err = r.Reconcile(nodes); err != nil {
+		t.Fatal(err)
+	}
+	// 2.04 for 24 hours
+	if factor, ok := r.Factor("qcloud:///800005/ins-2jv4wpmr"); !ok || math.Abs(factor-0.85) > 1e-9 {
+		t.Errorf("expect factor 0.85, got %v, %v", factor, ok)
+	}
+	// 0.16 for 2 hours
+	if factor, ok := r.Factor("aws:///us-east-1a/i-0abc"); !ok || math.Abs(factor-0.8) > 1e-9 {
+		t.Errorf("expect factor 0.8, got %v, %v", factor, ok)
+	}
+	if _, ok := r.Factor("qcloud:///800005/ins-notbilled"); ok {
+		t.Errorf("expect no factor of the node without bill")
+	}
+	if _, ok := r.Factor("aws:///us-east-1a/i-0def"); ok {
+		t.Errorf("expect no factor of the node billed in another currency")
+	}
+}
@@ -0,0 +1,51 @@
+package billing
+
+import (
+	"fmt"
+)
+
+// the column names of the qcloud bill in chinese and english, and of the bill api
+var qcloudColumns = map[string][]string{
+	"resource": {"资源ID", "Resource ID", "ResourceId", "实例ID", "Instance ID", "InstanceId"},
+	"cost":     {"优惠后总价", "折扣后总价", "Total Amount After Discount", "RealTotalCost"},
+	"start":    {"使用开始时间", "费用起始时间", "Usage Start Time", "FeeBeginTime"},
+	"end":      {"使用结束时间", "费用结束时间", "Usage End Time", "FeeEndTime"},
+}
+
+// qcloudFormat is the resource bill of tencent cloud, the cost after the discounts of each resource is used
+type qcloudFormat struct {
+	columns columns
+}
+
+func newQCloudFormat() format {
+	return &qcloudFormat{}
+}
+
+func (f *qcloudFormat) name() string {
+	return "qcloud"
+}
+
+func (f *qcloudFormat) match(header []string) bool {
+	f.columns = newColumns(header, qcloudColumns)
+	return f.columns.has("resource", "cost", "start", "end")
+}
+
+func (f *qcloudFormat) parse(row []string) (LineItem, bool, error) {
+	id := f.columns.get(row, "resource")
+	if id == "" {
+		return LineItem{}, false, nil
+	}
+	cost, err := parseCost(f.columns.get(row, "cost"))
+	if err != nil {
+		return LineItem{}, false, fmt.Errorf("invalid cost of %v: %v", id, err)
+	}
+	start, err := parseTime(f.columns.get(row, "start"))
+	if err != nil {
+		return LineItem{}, false, err
+	}
+	end, err := parseTime(f.columns.get(row, "end"))
+	if err != nil {
+		return LineItem{}, false, err
+	}
+	return LineItem{ResourceID: id, Start: start, End: end, Cost: cost}, true, nil
+}
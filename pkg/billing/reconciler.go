@@ -0,0 +1,172 @@
+package billing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/fadvisor/pkg/cloud"
+)
+
+// Reconciliation is the billed cost and the estimated cost of a node
+type Reconciliation struct {
+	Node                string  `json:"node"`
+	InstanceID          string  `json:"instanceId"`
+	BilledHours         float64 `json:"billedHours"`
+	BilledHourlyCost    float64 `json:"billedHourlyCost"`
+	EstimatedHourlyCost float64 `json:"estimatedHourlyCost"`
+	// Factor is the billed hourly cost divided by the estimated hourly cost
+	Factor float64 `json:"factor"`
+}
+
+// Reconciler reconciles the estimated node costs with the billing exports, the factors of the nodes
+// are used to adjust the breakdown prices, so that the allocated costs are close to the invoice.
+type Reconciler struct {
+	path string
+
+	lock            sync.RWMutex
+	reconciliations map[string]*Reconciliation
+}
+
+// NewReconciler returns a reconciler of the billing exports in the path
+func NewReconciler(path string) *Reconciler {
+	return &Reconciler{
+		path:            path,
+		reconciliations: make(map[string]*Reconciliation),
+	}
+}
+
+// Reconcile loads the billing exports and computes the factors of the nodes, the nodes cost is the estimated cost without adjustment.
+func (r *Reconciler) Reconcile(nodes map[string]*cloud.Node) error {
+	items, err := LoadBills(r.path)
+	if err != nil {
+		return err
+	}
+	reconciliations := Reconcile(items, nodes)
+	klog.Infof("Reconciled %v of %v nodes with %v bill line items", len(reconciliations), len(nodes), len(items))
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.reconciliations = reconciliations
+	return nil
+}
+
+// Factor returns the adjustment factor of the instance of the provider id
+func (r *Reconciler) Factor(providerID string) (float64, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	rec, ok := r.reconciliations[cloud.InstanceID(providerID)]
+	if !ok {
+		return 0, false
+	}
+	return rec.Factor, true
+}
+
+// Reconcile joins the line items to the nodes by the instance id of the provider id, key of the result is the instance id.
+// the billed hourly cost is the total cost divided by the hours covered by the line items of the instance.
+func Reconcile(items []LineItem, nodes map[string]*cloud.Node) map[string]*Reconciliation {
+	instanceItems := make(map[string][]LineItem)
+	for _, item := range items {
+		instanceItems[item.ResourceID] = append(instanceItems[item.ResourceID], item)
+	}
+
+	results := make(map[string]*Reconciliation)
+	for name, node := range nodes {
+		id := cloud.InstanceID(node.ProviderID)
+		lineItems, ok := instanceItems[id]
+		if id == "" || !ok {
+			continue
+		}
+		estimated, err := estimatedHourlyCost(node)
+		if err != nil || estimated <= 0 {
+			klog.Warningf("Skip reconciliation of node %v, the estimated cost is invalid: %v", name, err)
+			continue
+		}
+		if currency, ok := billCurrency(lineItems, node.Currency); !ok {
+			klog.Warningf("Skip reconciliation of node %v, the bill currency %v differs from the estimated currency %v", name, currency, node.Currency)
+			continue
+		}
+		var cost float64
+		for _, item := range lineItems {
+			cost += item.Cost
+		}
+		hours := coveredHours(lineItems)
+		if hours <= 0 {
+			continue
+		}
+		rec := &Reconciliation{
+			Node:                name,
+			InstanceID:          id,
+			BilledHours:         hours,
+			BilledHourlyCost:    cost / hours,
+			EstimatedHourlyCost: estimated,
+		}
+		rec.Factor = rec.BilledHourlyCost / estimated
+		klog.V(2).Infof("Reconciled node %v, instance %v, billed %v, estimated %v, factor %v", name, id, rec.BilledHourlyCost, estimated, rec.Factor)
+		results[id] = rec
+	}
+	return results
+}
+
+// billCurrency returns the currency of the line items, ok is false if the line items are not all in the currency of the node.
+// the line items or the node without currency are assumed in the same currency.
+func billCurrency(items []LineItem, currency string) (string, bool) {
+	for _, item := range items {
+		if item.Currency == "" {
+			continue
+		}
+		if currency == "" {
+			currency = item.Currency
+		} else if !strings.EqualFold(item.Currency, currency) {
+			return item.Currency, false
+		}
+	}
+	return currency, true
+}
+
+// estimatedHourlyCost returns the hourly cost of the node by the cpu and ram prices, the same as the node_total_hourly_cost
+func estimatedHourlyCost(node *cloud.Node) (float64, error) {
+	cpu, err := strconv.ParseFloat(node.Cpu, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cpu %q", node.Cpu)
+	}
+	cpuCost, err := strconv.ParseFloat(node.CpuHourlyCost, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cpu hourly cost %q", node.CpuHourlyCost)
+	}
+	// the ram price is empty if the node price is broken down to the cpu only
+	ram, _ := strconv.ParseFloat(node.Ram, 64)
+	ramCost, _ := strconv.ParseFloat(node.RamGBHourlyCost, 64)
+	cost := cpu*cpuCost + ram*ramCost
+	if math.IsNaN(cost) || math.IsInf(cost, 0) {
+		return 0, fmt.Errorf("invalid cost %v", cost)
+	}
+	return cost, nil
+}
+
+// coveredHours returns the hours of the union of the ranges of the line items
+func coveredHours(items []LineItem) float64 {
+	sorted := make([]LineItem, len(items))
+	copy(sorted, items)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+	var total time.Duration
+	var start, end time.Time
+	for i, item := range sorted {
+		if i == 0 || item.Start.After(end) {
+			total += end.Sub(start)
+			start, end = item.Start, item.End
+		} else if item.End.After(end) {
+			end = item.End
+		}
+	}
+	total += end.Sub(start)
+	return total.Hours()
+}
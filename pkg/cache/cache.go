@@ -6,12 +6,14 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	appslister "k8s.io/client-go/listers/apps/v1"
 	autoscalinglister "k8s.io/client-go/listers/autoscaling/v1"
 	lister "k8s.io/client-go/listers/core/v1"
+	policylister "k8s.io/client-go/listers/policy/v1beta1"
 	clientcache "k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 )
@@ -23,6 +25,9 @@ type Cache interface {
 	GetDeployments() []*appsv1.Deployment
 	GetPods() []*v1.Pod
 	GetNodes() []*v1.Node
+	GetPodDisruptionBudgets() []*policyv1beta1.PodDisruptionBudget
+	GetPersistentVolumes() []*v1.PersistentVolume
+	GetPersistentVolumeClaims() []*v1.PersistentVolumeClaim
 	WaitForCacheSync(stopCh <-chan struct{})
 }
 
@@ -38,6 +43,9 @@ type cache struct {
 	daemonsetLister  appslister.DaemonSetLister
 	stsLister        appslister.StatefulSetLister
 	hpaLister        autoscalinglister.HorizontalPodAutoscalerLister
+	pdbLister        policylister.PodDisruptionBudgetLister
+	pvLister         lister.PersistentVolumeLister
+	pvcLister        lister.PersistentVolumeClaimLister
 }
 
 func (c *cache) GetStatefulSets() []*appsv1.StatefulSet {
@@ -83,6 +91,9 @@ func (c *cache) WaitForCacheSync(stopCh <-chan struct{}) {
 	c.daemonsetLister = c.sharedInformer.Apps().V1().DaemonSets().Lister()
 	c.stsLister = c.sharedInformer.Apps().V1().StatefulSets().Lister()
 	c.hpaLister = c.sharedInformer.Autoscaling().V1().HorizontalPodAutoscalers().Lister()
+	c.pdbLister = c.sharedInformer.Policy().V1beta1().PodDisruptionBudgets().Lister()
+	c.pvLister = c.sharedInformer.Core().V1().PersistentVolumes().Lister()
+	c.pvcLister = c.sharedInformer.Core().V1().PersistentVolumeClaims().Lister()
 
 	c.sharedInformer.Start(stopCh)
 	c.sharedInformer.WaitForCacheSync(stopCh)
@@ -114,3 +125,30 @@ func (c *cache) GetNodes() []*v1.Node {
 	}
 	return nodeList
 }
+
+func (c *cache) GetPodDisruptionBudgets() []*policyv1beta1.PodDisruptionBudget {
+	pdbList, err := c.pdbLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to GetPodDisruptionBudgets in cache: %v", err)
+		return pdbList
+	}
+	return pdbList
+}
+
+func (c *cache) GetPersistentVolumes() []*v1.PersistentVolume {
+	pvList, err := c.pvLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to GetPersistentVolumes in cache: %v", err)
+		return pvList
+	}
+	return pvList
+}
+
+func (c *cache) GetPersistentVolumeClaims() []*v1.PersistentVolumeClaim {
+	pvcList, err := c.pvcLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to GetPersistentVolumeClaims in cache: %v", err)
+		return pvcList
+	}
+	return pvcList
+}
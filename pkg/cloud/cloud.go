@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
 
 	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud"
 	"github.com/gocrane/fadvisor/pkg/spec"
@@ -44,6 +45,39 @@ type NodeSpecConverter interface {
 type CloudConfig struct {
 	CloudConfigFile string `json:"cloudConfigFile"`
 	Provider        string `json:"provider"`
+	// DiscountRulesFile is the yaml or json file of the discount rules applied to the node and pod prices
+	DiscountRulesFile string `json:"discountRulesFile,omitempty"`
+	// Currency is the display currency the prices are converted to, empty means no conversion
+	Currency string `json:"currency,omitempty"`
+	// CurrencyRates is the value of one unit of each currency in the display currency
+	CurrencyRates map[string]float64 `json:"currencyRates,omitempty"`
+	// PricingCacheFile and PricingCacheConfigMap persist the pricing caches of the cloud provider, the configmap is namespace/name
+	PricingCacheFile      string `json:"pricingCacheFile,omitempty"`
+	PricingCacheConfigMap string `json:"pricingCacheConfigMap,omitempty"`
+	// PricingCacheStore is the store of the pricing cache file or configmap
+	PricingCacheStore PricingCacheStore `json:"-"`
+	// KubeClient is passed to the cloud provider which reads its credential through the api server, it is nil in offline mode
+	KubeClient kubernetes.Interface `json:"-"`
+	// StopCh stops the background work of the cloud provider, such as refreshing its credential
+	StopCh <-chan struct{} `json:"-"`
+	// Offline means the cloud api is not called, the prices come from the persisted pricing caches and the custom pricing
+	Offline bool `json:"-"`
+}
+
+// KubeClientSetter is the cloud provider which needs the kube client, such as reading the credential of a kubernetes secret,
+// the background work started with the client stops when stopCh is closed
+type KubeClientSetter interface {
+	SetKubeClient(client kubernetes.Interface, stopCh <-chan struct{}) error
+}
+
+// OfflineSetter is the cloud provider which calls the cloud api, it serves the prices without the cloud api in offline mode
+type OfflineSetter interface {
+	SetOffline()
+}
+
+// CurrencyGetter is the cloud provider which quotes the prices in its own currency rather than the currency of the custom pricing
+type CurrencyGetter interface {
+	Currency() string
 }
 
 type CustomPricing struct {
@@ -52,6 +86,10 @@ type CustomPricing struct {
 	Description      string  `json:"description"`
 	CpuHourlyPrice   float64 `json:"cpuHourlyPrice"`
 	RamGBHourlyPrice float64 `json:"ramGBHourlyPrice"`
+	// StorageGBHourlyPrice is the hourly price of one GB of persistent volume
+	StorageGBHourlyPrice float64 `json:"storageGBHourlyPrice"`
+	// Currency is the currency of the custom prices, such as USD
+	Currency string `json:"currency"`
 }
 
 type PriceConfig struct {
@@ -86,6 +124,15 @@ func DetectProvider(node *v1.Node) ProviderKind {
 	}
 }
 
+// InstanceID returns the instance id of the node from the provider id, which is the last segment of the provider id,
+// such as ins-2jv4wpmr of qcloud:///800005/ins-2jv4wpmr and i-0abc of aws:///us-east-1a/i-0abc
+func InstanceID(providerID string) string {
+	if !strings.Contains(providerID, "://") {
+		return ""
+	}
+	return providerID[strings.LastIndex(providerID, "/")+1:]
+}
+
 func NewProviderConfig(customPricing *CustomPricing) *PriceConfig {
 	return &PriceConfig{
 		customPricing: customPricing,
@@ -0,0 +1,235 @@
+package cloud
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/fadvisor/pkg/spec"
+)
+
+// CurrencyConverter converts the prices to the display currency by the rate table
+type CurrencyConverter struct {
+	// Currency is the display currency
+	Currency string
+	// Rates is the value of one unit of each currency in the display currency
+	Rates map[string]float64
+	// Default is the currency of the prices without currency, which is the currency of the custom pricing
+	Default string
+}
+
+// NewCurrencyConverter returns the converter to the display currency, the currency codes are case insensitive.
+// the currencies are the currencies of the provider prices, the default currency and them must have the rates.
+func NewCurrencyConverter(currency string, rates map[string]float64, defaultCurrency string, currencies ...string) (*CurrencyConverter, error) {
+	c := &CurrencyConverter{
+		Currency: strings.ToUpper(currency),
+		Rates:    make(map[string]float64, len(rates)),
+		Default:  strings.ToUpper(defaultCurrency),
+	}
+	for code, rate := range rates {
+		if rate <= 0 {
+			return nil, fmt.Errorf("invalid rate %v of currency %v, it must be positive", rate, code)
+		}
+		c.Rates[strings.ToUpper(code)] = rate
+	}
+	for _, code := range append([]string{c.Default}, currencies...) {
+		if code == "" {
+			continue
+		}
+		if _, err := c.Rate(code); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Rate returns the factor converting the prices of the currency to the display currency
+func (c *CurrencyConverter) Rate(currency string) (float64, error) {
+	currency = strings.ToUpper(currency)
+	if currency == "" {
+		currency = c.Default
+	}
+	if currency == "" || currency == c.Currency {
+		return 1, nil
+	}
+	rate, ok := c.Rates[currency]
+	if !ok {
+		return 0, fmt.Errorf("no rate of currency %v to %v", currency, c.Currency)
+	}
+	return rate, nil
+}
+
+// Convert returns the amount in the currency converted to the display currency
+func (c *CurrencyConverter) Convert(amount float64, currency string) (float64, error) {
+	rate, err := c.Rate(currency)
+	if err != nil {
+		return amount, err
+	}
+	return amount * rate, nil
+}
+
+// PricingConverter converts the pricing list which does not come from the cloud provider, such as the instance catalog
+type PricingConverter interface {
+	ConvertPricing(pricing map[string]*Price) map[string]*Price
+}
+
+// currencyCloud converts the prices of the cloud provider to the display currency
+type currencyCloud struct {
+	Cloud
+	converter *CurrencyConverter
+}
+
+// NewCurrencyCloud returns the cloud which converts the node, pod, platform prices and the pricing list to the display currency
+func NewCurrencyCloud(c Cloud, converter *CurrencyConverter) Cloud {
+	return &currencyCloud{
+		Cloud:     c,
+		converter: converter,
+	}
+}
+
+// convert returns a copy of the price converted to the display currency, the price is kept in its currency if no rate
+func (c *currencyCloud) convert(price BaseInstancePrice) BaseInstancePrice {
+	rate, err := c.converter.Rate(price.Currency)
+	if err != nil {
+		klog.Errorf("Failed to convert the price of %v: %v", price.ProviderID, err)
+		if price.Currency == "" {
+			price.Currency = c.converter.Default
+		}
+		return price
+	}
+	price.Scale(rate)
+	scale := func(cost string) string {
+		value, err := strconv.ParseFloat(cost, 64)
+		if err != nil {
+			return cost
+		}
+		return strconv.FormatFloat(value*rate, 'f', -1, 64)
+	}
+	price.DefaultCpuPrice = scale(price.DefaultCpuPrice)
+	price.DefaultRamPrice = scale(price.DefaultRamPrice)
+	price.Currency = c.converter.Currency
+	return price
+}
+
+// UnconvertedCloud returns the cloud under the currency conversion, whose prices are in the currencies of the provider
+func UnconvertedCloud(c Cloud) Cloud {
+	if converted, ok := c.(*currencyCloud); ok {
+		return converted.Cloud
+	}
+	return c
+}
+
+func (c *currencyCloud) NodePrice(spec spec.CloudNodeSpec) (*Node, error) {
+	node, err := c.Cloud.NodePrice(spec)
+	if err != nil || node == nil {
+		return node, err
+	}
+	return &Node{BaseInstancePrice: c.convert(node.BaseInstancePrice)}, nil
+}
+
+func (c *currencyCloud) ServerlessPodPrice(spec spec.CloudPodSpec) (*Pod, error) {
+	pod, err := c.Cloud.ServerlessPodPrice(spec)
+	if err != nil || pod == nil {
+		return pod, err
+	}
+	return &Pod{BaseInstancePrice: c.convert(pod.BaseInstancePrice)}, nil
+}
+
+func (c *currencyCloud) GetNodesCost() (map[string]*Node, error) {
+	nodes, err := c.Cloud.GetNodesCost()
+	if err != nil {
+		return nodes, err
+	}
+	results := make(map[string]*Node, len(nodes))
+	for name, node := range nodes {
+		results[name] = &Node{BaseInstancePrice: c.convert(node.BaseInstancePrice)}
+	}
+	return results, nil
+}
+
+func (c *currencyCloud) GetPodsCost() (map[string]*Pod, error) {
+	pods, err := c.Cloud.GetPodsCost()
+	if err != nil {
+		return pods, err
+	}
+	results := make(map[string]*Pod, len(pods))
+	for key, pod := range pods {
+		results[key] = &Pod{BaseInstancePrice: c.convert(pod.BaseInstancePrice)}
+	}
+	return results, nil
+}
+
+func (c *currencyCloud) PlatformPrice(cp PlatformParameter) *Prices {
+	prices := c.Cloud.PlatformPrice(cp)
+	if prices == nil {
+		return prices
+	}
+	rate, err := c.converter.Rate(prices.Currency)
+	if err != nil {
+		klog.Errorf("Failed to convert the platform price: %v", err)
+		return prices
+	}
+	converted := &Prices{TotalPrice: prices.TotalPrice * rate, Currency: c.converter.Currency}
+	if prices.DiscountPrice != nil {
+		discountPrice := *prices.DiscountPrice * rate
+		converted.DiscountPrice = &discountPrice
+	}
+	return converted
+}
+
+// GetConfig returns a copy of the custom pricing converted to the display currency
+func (c *currencyCloud) GetConfig() (*CustomPricing, error) {
+	cfg, err := c.Cloud.GetConfig()
+	if err != nil || cfg == nil {
+		return cfg, err
+	}
+	rate, err := c.converter.Rate(cfg.Currency)
+	if err != nil {
+		return cfg, err
+	}
+	converted := *cfg
+	converted.CpuHourlyPrice *= rate
+	converted.RamGBHourlyPrice *= rate
+	converted.StorageGBHourlyPrice *= rate
+	converted.Currency = c.converter.Currency
+	return &converted, nil
+}
+
+func (c *currencyCloud) GetNodesPricing() (map[string]*Price, error) {
+	pricing, err := c.Cloud.GetNodesPricing()
+	if err != nil {
+		return pricing, err
+	}
+	return c.ConvertPricing(pricing), nil
+}
+
+func (c *currencyCloud) GetStandardPricing() (map[string]*Price, error) {
+	pricing, err := c.Cloud.GetStandardPricing()
+	if err != nil {
+		return pricing, err
+	}
+	return c.ConvertPricing(pricing), nil
+}
+
+// ConvertPricing returns a copy of the pricing list converted to the display currency
+func (c *currencyCloud) ConvertPricing(pricing map[string]*Price) map[string]*Price {
+	results := make(map[string]*Price, len(pricing))
+	for key, price := range pricing {
+		results[key] = price
+		if price == nil || price.CvmPrice == nil {
+			continue
+		}
+		rate, err := c.converter.Rate(price.CvmPrice.Currency)
+		if err != nil {
+			klog.Errorf("Failed to convert the price of %v: %v", price.InstanceType, err)
+			continue
+		}
+		converted := *price
+		converted.CvmPrice = price.CvmPrice.scale(rate)
+		converted.CvmPrice.Currency = c.converter.Currency
+		results[key] = &converted
+	}
+	return results
+}
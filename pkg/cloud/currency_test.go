@@ -0,0 +1,81 @@
+package cloud
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/gocrane/fadvisor/pkg/spec"
+)
+
+func TestCurrencyConverter(t *testing.T) {
+	if _, err := NewCurrencyConverter("USD", map[string]float64{"CNY": 0.14}, "EUR"); err == nil {
+		t.Errorf("expect error of the custom pricing currency without rate")
+	}
+	if _, err := NewCurrencyConverter("USD", map[string]float64{"EUR": 1.1}, "EUR", "CNY"); err == nil {
+		t.Errorf("expect error of the provider currency without rate")
+	}
+	if _, err := NewCurrencyConverter("USD", map[string]float64{"CNY": 0}, ""); err == nil {
+		t.Errorf("expect error of the invalid rate")
+	}
+	c, err := NewCurrencyConverter("usd", map[string]float64{"cny": 0.14}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		currency string
+		expected float64
+	}{{"CNY", 14}, {"USD", 100}, {"", 100}} {
+		amount, err := c.Convert(100, test.currency)
+		if err != nil || math.Abs(amount-test.expected) > 1e-9 {
+			t.Errorf("expect %v of %v, got %v, %v", test.expected, test.currency, amount, err)
+		}
+	}
+	if _, err = c.Convert(100, "JPY"); err == nil {
+		t.Errorf("expect error of the currency without rate")
+	}
+}
+
+func TestCurrencyCloud(t *testing.T) {
+	converter, err := NewCurrencyConverter("USD", map[string]float64{"CNY": 0.14}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unitPrice := 1.
+	inner := &fakeCloud{
+		node: &Node{BaseInstancePrice: BaseInstancePrice{Cost: "1", Cpu: "2", CpuHourlyCost: "0.4", Ram: "4", RamGBHourlyCost: "0.05", DefaultCpuPrice: "0.2", Currency: "CNY"}},
+		standard: map[string]*Price{
+			"cny": {InstanceType: "S5.MEDIUM4", CvmPrice: &PriceItem{UnitPrice: &unitPrice, Currency: "CNY"}},
+			"jpy": {InstanceType: "S5.LARGE8", CvmPrice: &PriceItem{UnitPrice: &unitPrice, Currency: "JPY"}},
+		},
+	}
+	c := NewCurrencyCloud(inner, converter)
+
+	node, err := c.NodePrice(spec.CloudNodeSpec{InstanceType: "S5.MEDIUM4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cost, _ := strconv.ParseFloat(node.Cost, 64)
+	defaultCpu, _ := strconv.ParseFloat(node.DefaultCpuPrice, 64)
+	if node.Currency != "USD" || math.Abs(cost-0.14) > 1e-6 || math.Abs(defaultCpu-0.028) > 1e-6 {
+		t.Errorf("unexpected converted node price %+v", node.BaseInstancePrice)
+	}
+	if inner.node.Currency != "CNY" {
+		t.Errorf("expect the provider price not changed")
+	}
+	if UnconvertedCloud(c) != Cloud(inner) || UnconvertedCloud(inner) != Cloud(inner) {
+		t.Errorf("expect the provider under the conversion")
+	}
+
+	pricing, err := c.GetStandardPricing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price := pricing["cny"].CvmPrice; price.Currency != "USD" || math.Abs(*price.UnitPrice-0.14) > 1e-9 {
+		t.Errorf("unexpected converted standard price %v %v", *price.UnitPrice, price.Currency)
+	}
+	// the price without rate is kept in its currency
+	if price := pricing["jpy"].CvmPrice; price.Currency != "JPY" || *price.UnitPrice != 1 {
+		t.Errorf("expect the price without rate not converted, got %v %v", *price.UnitPrice, price.Currency)
+	}
+}
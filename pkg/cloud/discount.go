@@ -0,0 +1,395 @@
+package cloud
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"github.com/gocrane/fadvisor/pkg/cache"
+	"github.com/gocrane/fadvisor/pkg/spec"
+	"github.com/gocrane/fadvisor/pkg/util"
+)
+
+// DiscountRule is a discount of the contract with the cloud provider. the rule matches the instances by all the non-empty
+// conditions, and sets the price of the matched instances by a percentage of the provider price or a fixed hourly price.
+type DiscountRule struct {
+	Name     string   `json:"name"`
+	Provider string   `json:"provider,omitempty"`
+	Regions  []string `json:"regions,omitempty"`
+	Zones    []string `json:"zones,omitempty"`
+	// InstanceFamilies is the prefix of the instance type before the dot, such as S5 of S5.LARGE8
+	InstanceFamilies []string `json:"instanceFamilies,omitempty"`
+	ChargeTypes      []string `json:"chargeTypes,omitempty"`
+	// StartDate and EndDate are the effective dates of the rule, the format is 2006-01-02 or RFC3339, the end date is inclusive
+	StartDate string `json:"startDate,omitempty"`
+	EndDate   string `json:"endDate,omitempty"`
+	// Percentage is the percent of the provider price to pay, such as 80 for 20% off
+	Percentage *float64 `json:"percentage,omitempty"`
+	// HourlyPrice overrides the hourly price of the instance
+	HourlyPrice *float64 `json:"hourlyPrice,omitempty"`
+
+	start time.Time
+	end   time.Time
+}
+
+// DiscountRules is the discount rules config, the first matched rule is applied
+type DiscountRules struct {
+	Rules []DiscountRule `json:"rules"`
+}
+
+// DiscountAttributes is the attributes of an instance matched by the discount rules
+type DiscountAttributes struct {
+	Provider     ProviderKind
+	Region       string
+	Zone         string
+	InstanceType string
+	ChargeType   string
+}
+
+// LoadDiscountRules loads and validates the discount rules config file, yaml or json
+func LoadDiscountRules(file string) (*DiscountRules, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var rules DiscountRules
+	if err = yaml.UnmarshalStrict(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid discount rules config %v: %v", file, err)
+	}
+	if err = rules.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid discount rules config %v: %v", file, err)
+	}
+	return &rules, nil
+}
+
+// Validate validates the rules and parses the effective dates
+func (d *DiscountRules) Validate() error {
+	for i := range d.Rules {
+		rule := &d.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i)
+		}
+		if (rule.Percentage == nil) == (rule.HourlyPrice == nil) {
+			return fmt.Errorf("rule %v: exactly one of percentage and hourlyPrice is required", rule.Name)
+		}
+		if rule.Percentage != nil && *rule.Percentage <= 0 {
+			return fmt.Errorf("rule %v: percentage must be positive", rule.Name)
+		}
+		if rule.HourlyPrice != nil && *rule.HourlyPrice < 0 {
+			return fmt.Errorf("rule %v: hourlyPrice must not be negative", rule.Name)
+		}
+		var err error
+		if rule.StartDate != "" {
+			if rule.start, _, err = parseDiscountDate(rule.StartDate); err != nil {
+				return fmt.Errorf("rule %v: %v", rule.Name, err)
+			}
+		}
+		if rule.EndDate != "" {
+			var dateOnly bool
+			if rule.end, dateOnly, err = parseDiscountDate(rule.EndDate); err != nil {
+				return fmt.Errorf("rule %v: %v", rule.Name, err)
+			}
+			if dateOnly {
+				rule.end = rule.end.AddDate(0, 0, 1)
+			}
+		}
+		if !rule.start.IsZero() && !rule.end.IsZero() && !rule.start.Before(rule.end) {
+			return fmt.Errorf("rule %v: start date %v is after end date %v", rule.Name, rule.StartDate, rule.EndDate)
+		}
+	}
+	return nil
+}
+
+func parseDiscountDate(s string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, false, fmt.Errorf("invalid date %q, the format is 2006-01-02 or RFC3339", s)
+	}
+	return t, false, nil
+}
+
+// Match returns the first rule matching the attributes at the time, nil if no rule matched
+func (d *DiscountRules) Match(attrs DiscountAttributes, now time.Time) *DiscountRule {
+	if d == nil {
+		return nil
+	}
+	for i := range d.Rules {
+		if d.Rules[i].matches(attrs, now) {
+			return &d.Rules[i]
+		}
+	}
+	return nil
+}
+
+func (r *DiscountRule) matches(attrs DiscountAttributes, now time.Time) bool {
+	if !r.start.IsZero() && now.Before(r.start) {
+		return false
+	}
+	if !r.end.IsZero() && !now.Before(r.end) {
+		return false
+	}
+	if r.Provider != "" && !strings.EqualFold(r.Provider, string(attrs.Provider)) {
+		return false
+	}
+	return matchAny(r.Regions, attrs.Region) && matchAny(r.Zones, attrs.Zone) &&
+		matchAny(r.InstanceFamilies, InstanceFamily(attrs.InstanceType)) && matchAny(r.ChargeTypes, attrs.ChargeType)
+}
+
+// matchAny returns true if the values is empty or the value is one of the values, case insensitive
+func matchAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Factor returns the factor of the price by the rule, hourlyCost is the hourly cost of the provider price
+func (r *DiscountRule) Factor(hourlyCost float64) (float64, bool) {
+	if r.Percentage != nil {
+		return *r.Percentage / 100., true
+	}
+	if hourlyCost <= 0 {
+		return 0, false
+	}
+	return *r.HourlyPrice / hourlyCost, true
+}
+
+// InstanceFamily returns the family of the instance type, which is the prefix before the dot, such as S5 of S5.LARGE8 and m5 of m5.xlarge
+func InstanceFamily(instanceType string) string {
+	return strings.SplitN(instanceType, ".", 2)[0]
+}
+
+// discountedCloud applies the discount rules to the prices of the cloud provider
+type discountedCloud struct {
+	Cloud
+	provider ProviderKind
+	rules    *DiscountRules
+	cache    *cache.Cache
+	now      func() time.Time
+}
+
+// NewDiscountedCloud returns the cloud which applies the discount rules to the node and pod prices of the cloud provider
+func NewDiscountedCloud(c Cloud, provider ProviderKind, rules *DiscountRules, cache *cache.Cache) Cloud {
+	return &discountedCloud{
+		Cloud:    c,
+		provider: provider,
+		rules:    rules,
+		cache:    cache,
+		now:      time.Now,
+	}
+}
+
+// discount returns a copy of the price discounted by the first matched rule
+func (d *discountedCloud) discount(price BaseInstancePrice, attrs DiscountAttributes) BaseInstancePrice {
+	rule := d.rules.Match(attrs, d.now())
+	if rule == nil {
+		return price
+	}
+	hourlyCost, _ := price.HourlyCost()
+	factor, ok := rule.Factor(hourlyCost)
+	if !ok {
+		klog.V(4).Infof("Skip discount rule %v of instance %v, the hourly cost %v is invalid", rule.Name, attrs.InstanceType, hourlyCost)
+		return price
+	}
+	klog.V(6).Infof("Discount rule %v applied to %+v, factor %v", rule.Name, attrs, factor)
+	price.Scale(factor)
+	return price
+}
+
+func (d *discountedCloud) NodePrice(spec spec.CloudNodeSpec) (*Node, error) {
+	node, err := d.Cloud.NodePrice(spec)
+	if err != nil || node == nil {
+		return node, err
+	}
+	attrs := DiscountAttributes{
+		Provider:     d.provider,
+		Region:       firstNonEmpty(spec.Region, node.Region),
+		Zone:         spec.Zone,
+		InstanceType: firstNonEmpty(spec.InstanceType, node.InstanceType),
+		ChargeType:   firstNonEmpty(spec.ChargeType, node.UsageType),
+	}
+	if spec.NodeRef != nil {
+		attrs = d.nodeAttributes(spec.NodeRef, attrs)
+	}
+	return &Node{BaseInstancePrice: d.discount(node.BaseInstancePrice, attrs)}, nil
+}
+
+func (d *discountedCloud) ServerlessPodPrice(spec spec.CloudPodSpec) (*Pod, error) {
+	pod, err := d.Cloud.ServerlessPodPrice(spec)
+	if err != nil || pod == nil {
+		return pod, err
+	}
+	attrs := DiscountAttributes{
+		Provider:     d.provider,
+		Region:       firstNonEmpty(pod.Region, d.clusterRegion()),
+		Zone:         spec.Zone,
+		InstanceType: pod.InstanceType,
+		ChargeType:   firstNonEmpty(spec.PodChargeType, pod.UsageType),
+	}
+	return &Pod{BaseInstancePrice: d.discount(pod.BaseInstancePrice, attrs)}, nil
+}
+
+func (d *discountedCloud) GetNodesCost() (map[string]*Node, error) {
+	nodes, err := d.Cloud.GetNodesCost()
+	if err != nil {
+		return nodes, err
+	}
+	nodeRefs := d.nodes()
+	results := make(map[string]*Node, len(nodes))
+	for name, node := range nodes {
+		attrs := DiscountAttributes{Provider: d.provider, Region: node.Region, InstanceType: node.InstanceType, ChargeType: node.UsageType}
+		if ref, ok := nodeRefs[name]; ok {
+			attrs = d.nodeAttributes(ref, attrs)
+		}
+		results[name] = &Node{BaseInstancePrice: d.discount(node.BaseInstancePrice, attrs)}
+	}
+	return results, nil
+}
+
+func (d *discountedCloud) GetPodsCost() (map[string]*Pod, error) {
+	pods, err := d.Cloud.GetPodsCost()
+	if err != nil {
+		return pods, err
+	}
+	nodeRefs := d.nodes()
+	podNodes := make(map[string]string)
+	if d.cache != nil && *d.cache != nil {
+		for _, pod := range (*d.cache).GetPods() {
+			podNodes[klog.KObj(pod).String()] = pod.Spec.NodeName
+		}
+	}
+	results := make(map[string]*Pod, len(pods))
+	for key, pod := range pods {
+		attrs := DiscountAttributes{Provider: d.provider, Region: pod.Region, InstanceType: pod.InstanceType, ChargeType: pod.UsageType}
+		if ref, ok := nodeRefs[podNodes[key]]; ok {
+			attrs = d.nodeAttributes(ref, attrs)
+		}
+		results[key] = &Pod{BaseInstancePrice: d.discount(pod.BaseInstancePrice, attrs)}
+	}
+	return results, nil
+}
+
+func (d *discountedCloud) GetNodesPricing() (map[string]*Price, error) {
+	pricing, err := d.Cloud.GetNodesPricing()
+	if err != nil {
+		return pricing, err
+	}
+	return d.discountPricing(pricing), nil
+}
+
+func (d *discountedCloud) GetStandardPricing() (map[string]*Price, error) {
+	pricing, err := d.Cloud.GetStandardPricing()
+	if err != nil {
+		return pricing, err
+	}
+	return d.discountPricing(pricing), nil
+}
+
+// discountPricing returns a copy of the pricing list discounted by the rules, the instances are in the cluster region
+func (d *discountedCloud) discountPricing(pricing map[string]*Price) map[string]*Price {
+	region := d.clusterRegion()
+	now := d.now()
+	results := make(map[string]*Price, len(pricing))
+	for key, price := range pricing {
+		results[key] = price
+		if price == nil || price.CvmPrice == nil {
+			continue
+		}
+		rule := d.rules.Match(DiscountAttributes{
+			Provider:     d.provider,
+			Region:       region,
+			Zone:         price.Zone,
+			InstanceType: price.InstanceType,
+			ChargeType:   price.ChargeType,
+		}, now)
+		if rule == nil {
+			continue
+		}
+		hourlyCost, _ := price.HourlyCost()
+		factor, ok := rule.Factor(hourlyCost)
+		if !ok {
+			continue
+		}
+		discounted := *price
+		discounted.CvmPrice = price.CvmPrice.scale(factor)
+		results[key] = &discounted
+	}
+	return results
+}
+
+// scale returns a copy of the price item with the prices multiplied by the factor, the discount rates are kept
+func (p *PriceItem) scale(factor float64) *PriceItem {
+	scaled := *p
+	for _, price := range []**float64{
+		&scaled.UnitPrice, &scaled.OriginalPrice, &scaled.DiscountPrice, &scaled.UnitPriceDiscount,
+		&scaled.UnitPriceSecondStep, &scaled.UnitPriceDiscountSecondStep, &scaled.UnitPriceThirdStep, &scaled.UnitPriceDiscountThirdStep,
+		&scaled.OriginalPriceOneYear, &scaled.DiscountPriceOneYear, &scaled.OriginalPriceThreeYear, &scaled.DiscountPriceThreeYear,
+		&scaled.OriginalPriceFiveYear, &scaled.DiscountPriceFiveYear,
+	} {
+		if *price != nil {
+			value := **price * factor
+			*price = &value
+		}
+	}
+	return &scaled
+}
+
+// nodeAttributes fills the attributes by the labels of the node
+func (d *discountedCloud) nodeAttributes(node *v1.Node, attrs DiscountAttributes) DiscountAttributes {
+	if attrs.Zone == "" {
+		attrs.Zone, _ = util.GetZone(node.Labels)
+	}
+	if attrs.Region == "" {
+		attrs.Region = DetectRegion(node)
+	}
+	if attrs.InstanceType == "" {
+		attrs.InstanceType, _ = util.GetInstanceType(node.Labels)
+	}
+	return attrs
+}
+
+func (d *discountedCloud) nodes() map[string]*v1.Node {
+	results := make(map[string]*v1.Node)
+	if d.cache == nil || *d.cache == nil {
+		return results
+	}
+	for _, node := range (*d.cache).GetNodes() {
+		results[node.Name] = node
+	}
+	return results
+}
+
+// clusterRegion returns the region of the first node with the region label
+func (d *discountedCloud) clusterRegion() string {
+	if d.cache == nil || *d.cache == nil {
+		return ""
+	}
+	for _, node := range (*d.cache).GetNodes() {
+		if region := DetectRegion(node); region != "" {
+			return region
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
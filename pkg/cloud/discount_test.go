@@ -0,0 +1,148 @@
+package cloud
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gocrane/fadvisor/pkg/spec"
+)
+
+const discountRules = `
+rules:
+- name: s5-contract
+  provider: qcloud
+  regions: [ap-guangzhou]
+  instanceFamilies: [S5]
+  chargeTypes: [POSTPAID_BY_HOUR]
+  startDate: "2022-01-01"
+  endDate: "2022-12-31"
+  percentage: 70
+- name: sa2-fixed
+  instanceFamilies: [sa2]
+  hourlyPrice: 0.2
+- name: all
+  percentage: 90
+`
+
+type fakeCloud struct {
+	Cloud
+	node     *Node
+	standard map[string]*Price
+}
+
+func (f *fakeCloud) NodePrice(spec spec.CloudNodeSpec) (*Node, error) {
+	node := *f.node
+	node.InstanceType = spec.InstanceType
+	return &node, nil
+}
+
+func (f *fakeCloud) GetStandardPricing() (map[string]*Price, error) {
+	return f.standard, nil
+}
+
+func TestDiscountRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "discount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "rules.yaml")
+	if err = ioutil.WriteFile(file, []byte(discountRules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadDiscountRules(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attrs := DiscountAttributes{Provider: TencentCloud, Region: "ap-guangzhou", InstanceType: "S5.LARGE8", ChargeType: "POSTPAID_BY_HOUR"}
+	// the end date is inclusive
+	if rule := rules.Match(attrs, time.Date(2022, 12, 31, 23, 0, 0, 0, time.UTC)); rule == nil || rule.Name != "s5-contract" {
+		t.Errorf("expect rule s5-contract, got %+v", rule)
+	}
+	if rule := rules.Match(attrs, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)); rule == nil || rule.Name != "all" {
+		t.Errorf("expect rule all after the end date, got %+v", rule)
+	}
+	attrs.ChargeType = "PREPAID"
+	if rule := rules.Match(attrs, time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)); rule == nil || rule.Name != "all" {
+		t.Errorf("expect rule all of the prepaid instance, got %+v", rule)
+	}
+
+	for _, invalid := range []string{
+		"rules:\n- percentage: 80\n  hourlyPrice: 0.1\n",
+		"rules:\n- name: a\n",
+		"rules:\n- percentage: 80\n  startDate: 2022/01/01\n",
+		"rules:\n- percentage: 80\n  unknown: 1\n",
+	} {
+		if err = ioutil.WriteFile(file, []byte(invalid), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = LoadDiscountRules(file); err == nil {
+			t.Errorf("expect error of rules %q", invalid)
+		}
+	}
+}
+
+func TestDiscountedCloud(t *testing.T) {
+	percentage, hourlyPrice := 70., 0.2
+	rules := &DiscountRules{Rules: []DiscountRule{
+		{Name: "s5", InstanceFamilies: []string{"S5"}, Percentage: &percentage},
+		{Name: "sa2", InstanceFamilies: []string{"SA2"}, HourlyPrice: &hourlyPrice},
+	}}
+	if err := rules.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	unitPrice, discount := 0.5, 80.
+	inner := &fakeCloud{
+		node: &Node{BaseInstancePrice: BaseInstancePrice{Cost: "0.5", Cpu: "2", CpuHourlyCost: "0.2", Ram: "4", RamGBHourlyCost: "0.025"}},
+		standard: map[string]*Price{
+			"ap-guangzhou-3,SA2.MEDIUM4,POSTPAID_BY_HOUR": {InstanceType: "SA2.MEDIUM4", ChargeType: "POSTPAID_BY_HOUR", CvmPrice: &PriceItem{UnitPrice: &unitPrice, Discount: &discount}},
+			"ap-guangzhou-3,C3.LARGE8,POSTPAID_BY_HOUR":   {InstanceType: "C3.LARGE8", ChargeType: "POSTPAID_BY_HOUR", CvmPrice: &PriceItem{UnitPrice: &unitPrice}},
+		},
+	}
+	c := NewDiscountedCloud(inner, TencentCloud, rules, nil)
+
+	node, err := c.NodePrice(spec.CloudNodeSpec{InstanceType: "S5.MEDIUM4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost, _ := strconv.ParseFloat(node.Cost, 64); math.Abs(cost-0.35) > 1e-6 {
+		t.Errorf("expect discounted cost 0.35, got %v", node.Cost)
+	}
+	if cpuCost, _ := strconv.ParseFloat(node.CpuHourlyCost, 64); math.Abs(cpuCost-0.14) > 1e-6 {
+		t.Errorf("expect discounted cpu cost 0.14, got %v", node.CpuHourlyCost)
+	}
+	if inner.node.Cost != "0.5" {
+		t.Errorf("expect the provider price not changed, got %v", inner.node.Cost)
+	}
+	node, _ = c.NodePrice(spec.CloudNodeSpec{InstanceType: "SA2.MEDIUM4"})
+	if cost, _ := strconv.ParseFloat(node.Cost, 64); math.Abs(cost-0.2) > 1e-6 {
+		t.Errorf("expect overridden cost 0.2, got %v", node.Cost)
+	}
+
+	pricing, err := c.GetStandardPricing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sa2 := pricing["ap-guangzhou-3,SA2.MEDIUM4,POSTPAID_BY_HOUR"]
+	if cost, _ := sa2.HourlyCost(); math.Abs(cost-0.2) > 1e-6 || *sa2.CvmPrice.Discount != 80 {
+		t.Errorf("expect overridden standard price 0.2, got %v", cost)
+	}
+	if cost, _ := pricing["ap-guangzhou-3,C3.LARGE8,POSTPAID_BY_HOUR"].HourlyCost(); cost != 0.5 || unitPrice != 0.5 {
+		t.Errorf("expect the standard price not discounted, got %v", cost)
+	}
+}
+
+func TestScale(t *testing.T) {
+	price := BaseInstancePrice{Cost: "0.00001", CpuHourlyCost: "3", RamGBHourlyCost: "-"}
+	price.Scale(0.5)
+	// tiny prices keep their precision, the invalid costs are kept
+	if price.Cost != "0.000005" || price.CpuHourlyCost != "1.5" || price.RamGBHourlyCost != "-" {
+		t.Errorf("unexpected scaled price %+v", price)
+	}
+}
@@ -77,5 +77,55 @@ func InitCloudProvider(CloudOpts CloudConfig, priceConfig *PriceConfig, cache *c
 		return nil, fmt.Errorf("unknown price provider %q", CloudOpts.Provider)
 	}
 
+	var providerCurrencies []string
+	if getter, ok := cloud.(CurrencyGetter); ok {
+		providerCurrencies = append(providerCurrencies, getter.Currency())
+	}
+
+	// the provider must know it is offline before its credential is created, which may call the cloud api
+	if CloudOpts.Offline {
+		if setter, ok := cloud.(OfflineSetter); ok {
+			setter.SetOffline()
+		}
+	}
+
+	if setter, ok := cloud.(KubeClientSetter); ok {
+		if err = setter.SetKubeClient(CloudOpts.KubeClient, CloudOpts.StopCh); err != nil {
+			return nil, fmt.Errorf("could not init price provider %q: %v", CloudOpts.Provider, err)
+		}
+	}
+
+	if CloudOpts.PricingCacheStore != nil {
+		if persister, ok := cloud.(PricingCachePersister); ok {
+			persister.SetPricingCacheStore(CloudOpts.PricingCacheStore)
+		} else {
+			klog.Warningf("Price provider %q does not persist the pricing caches", CloudOpts.Provider)
+		}
+	}
+
+	if CloudOpts.DiscountRulesFile != "" {
+		rules, err := LoadDiscountRules(CloudOpts.DiscountRulesFile)
+		if err != nil {
+			return nil, err
+		}
+		klog.Infof("Loaded %v discount rules from %v", len(rules.Rules), CloudOpts.DiscountRulesFile)
+		cloud = NewDiscountedCloud(cloud, ProviderKind(CloudOpts.Provider), rules, cache)
+	}
+
+	// the discount rules are applied in the currency of the provider, then the prices are converted to the display currency
+	if CloudOpts.Currency != "" {
+		var customCurrency string
+		if priceConfig != nil {
+			if cfg, _ := priceConfig.GetConfig(); cfg != nil {
+				customCurrency = cfg.Currency
+			}
+		}
+		converter, err := NewCurrencyConverter(CloudOpts.Currency, CloudOpts.CurrencyRates, customCurrency, providerCurrencies...)
+		if err != nil {
+			return nil, err
+		}
+		cloud = NewCurrencyCloud(cloud, converter)
+	}
+
 	return cloud, nil
 }
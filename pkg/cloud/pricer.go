@@ -8,6 +8,8 @@ import (
 type Prices struct {
 	TotalPrice    float64
 	DiscountPrice *float64
+	// Currency is the currency of the prices, empty means the currency of the custom pricing
+	Currency string
 }
 
 type Pricer interface {
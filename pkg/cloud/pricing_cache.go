@@ -0,0 +1,248 @@
+package cloud
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// PricingCacheStore persists the pricing caches of the cloud provider, the persisted prices are served at startup
+// and when the cloud api fails, so that the provider does not fall back to the default pricing.
+type PricingCacheStore interface {
+	// Load loads the cache of the name into v, updated is the time the cache was refreshed from the cloud api, ok is false if no cache
+	Load(name string, v interface{}) (updated time.Time, ok bool, err error)
+	// Save saves the cache of the name refreshed at the updated time
+	Save(name string, v interface{}, updated time.Time) error
+}
+
+// PricingCachePersister is the cloud provider which persists its pricing caches in the store
+type PricingCachePersister interface {
+	SetPricingCacheStore(store PricingCacheStore)
+}
+
+// NewPricingCacheStore returns the store of the pricing cache file or configmap of the config, nil if neither is specified.
+// the configmap is namespace/name, the client is required by it.
+func NewPricingCacheStore(config CloudConfig, client kubernetes.Interface) (PricingCacheStore, error) {
+	if config.PricingCacheFile != "" {
+		return NewFilePricingCacheStore(config.PricingCacheFile), nil
+	}
+	if config.PricingCacheConfigMap == "" {
+		return nil, nil
+	}
+	parts := strings.Split(config.PricingCacheConfigMap, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid pricing cache configmap %q, the format is namespace/name", config.PricingCacheConfigMap)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("pricing cache configmap %v needs the api server", config.PricingCacheConfigMap)
+	}
+	return NewConfigMapPricingCacheStore(client, parts[0], parts[1]), nil
+}
+
+type pricingCacheEntry struct {
+	Updated time.Time       `json:"updated"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// pricingCaches is the content of the store, the caches of all names are gzip compressed json of one blob
+type pricingCaches struct {
+	lock    sync.Mutex
+	entries map[string]*pricingCacheEntry
+	// read returns the blob, nil if not exist
+	read func() ([]byte, error)
+	// write persists the blob
+	write func(data []byte) error
+}
+
+func (p *pricingCaches) ensureLoaded() error {
+	if p.entries != nil {
+		return nil
+	}
+	data, err := p.read()
+	if err != nil {
+		return err
+	}
+	entries := make(map[string]*pricingCacheEntry)
+	if len(data) > 0 {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		if err = json.NewDecoder(gz).Decode(&entries); err != nil {
+			return err
+		}
+	}
+	p.entries = entries
+	return nil
+}
+
+func (p *pricingCaches) Load(name string, v interface{}) (time.Time, bool, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if err := p.ensureLoaded(); err != nil {
+		return time.Time{}, false, err
+	}
+	entry, ok := p.entries[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	if err := json.Unmarshal(entry.Data, v); err != nil {
+		return time.Time{}, false, err
+	}
+	return entry.Updated, true, nil
+}
+
+func (p *pricingCaches) Save(name string, v interface{}, updated time.Time) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if err = p.ensureLoaded(); err != nil {
+		// the broken store is overwritten
+		klog.Warningf("Failed to load the pricing caches, they are overwritten: %v", err)
+		p.entries = make(map[string]*pricingCacheEntry)
+	}
+	p.entries[name] = &pricingCacheEntry{Updated: updated, Data: data}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err = json.NewEncoder(gz).Encode(p.entries); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	return p.write(buf.Bytes())
+}
+
+// NewFilePricingCacheStore returns the store persisting the pricing caches in the file
+func NewFilePricingCacheStore(file string) PricingCacheStore {
+	return &pricingCaches{
+		read: func() ([]byte, error) {
+			data, err := ioutil.ReadFile(file)
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return data, err
+		},
+		write: func(data []byte) error {
+			// write to a temp file then rename it, so that the file is never partially written
+			tmp, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".tmp")
+			if err != nil {
+				return err
+			}
+			defer os.Remove(tmp.Name())
+			if _, err = tmp.Write(data); err != nil {
+				tmp.Close()
+				return err
+			}
+			if err = tmp.Close(); err != nil {
+				return err
+			}
+			return os.Rename(tmp.Name(), file)
+		},
+	}
+}
+
+const pricingCacheConfigMapKey = "pricing-cache.json.gz"
+
+// NewConfigMapPricingCacheStore returns the store persisting the pricing caches in the binary data of the configmap, the configmap is created if not exist
+func NewConfigMapPricingCacheStore(client kubernetes.Interface, namespace, name string) PricingCacheStore {
+	return &pricingCaches{
+		read: func() ([]byte, error) {
+			cm, err := client.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			return cm.BinaryData[pricingCacheConfigMapKey], nil
+		},
+		write: func(data []byte) error {
+			configMaps := client.CoreV1().ConfigMaps(namespace)
+			cm, err := configMaps.Get(context.TODO(), name, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				cm = &v1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+					BinaryData: map[string][]byte{pricingCacheConfigMapKey: data},
+				}
+				_, err = configMaps.Create(context.TODO(), cm, metav1.CreateOptions{})
+				return err
+			}
+			if err != nil {
+				return err
+			}
+			if cm.BinaryData == nil {
+				cm.BinaryData = make(map[string][]byte)
+			}
+			cm.BinaryData[pricingCacheConfigMapKey] = data
+			_, err = configMaps.Update(context.TODO(), cm, metav1.UpdateOptions{})
+			return err
+		},
+	}
+}
+
+var (
+	pricingCacheStalenessDesc = prometheus.NewDesc("fadvisor_pricing_cache_staleness_seconds",
+		"Seconds since the pricing cache of the cloud provider was refreshed from the cloud api", []string{"provider", "cache"}, nil)
+
+	defaultPricingFallbackCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "fadvisor_pricing_default_fallback_total",
+		Help: "Number of the node prices computed by the default pricing because no price of the cloud api is cached",
+	}, []string{"provider"})
+
+	pricingCacheCollector = &pricingCacheStaleness{updated: make(map[[2]string]time.Time)}
+)
+
+func init() {
+	prometheus.MustRegister(pricingCacheCollector, defaultPricingFallbackCounter)
+}
+
+// pricingCacheStaleness collects the staleness of the pricing caches at the scraping time
+type pricingCacheStaleness struct {
+	lock    sync.Mutex
+	updated map[[2]string]time.Time
+}
+
+func (p *pricingCacheStaleness) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pricingCacheStalenessDesc
+}
+
+func (p *pricingCacheStaleness) Collect(ch chan<- prometheus.Metric) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for key, updated := range p.updated {
+		ch <- prometheus.MustNewConstMetric(pricingCacheStalenessDesc, prometheus.GaugeValue, time.Since(updated).Seconds(), key[0], key[1])
+	}
+}
+
+// ObservePricingCache records the time the pricing cache of the provider was refreshed from the cloud api
+func ObservePricingCache(provider ProviderKind, cache string, updated time.Time) {
+	pricingCacheCollector.lock.Lock()
+	defer pricingCacheCollector.lock.Unlock()
+	pricingCacheCollector.updated[[2]string{string(provider), cache}] = updated
+}
+
+// ObserveDefaultPricingFallback counts the node price computed by the default pricing of the provider
+func ObserveDefaultPricingFallback(provider ProviderKind) {
+	defaultPricingFallbackCounter.WithLabelValues(string(provider)).Inc()
+}
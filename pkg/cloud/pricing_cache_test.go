@@ -0,0 +1,63 @@
+package cloud
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testPricingCacheStore(t *testing.T, newStore func() PricingCacheStore) {
+	store := newStore()
+	var prices map[string]float64
+	if _, ok, err := store.Load("standardPricing", &prices); err != nil || ok {
+		t.Fatalf("expect no cache, got %v, %v", ok, err)
+	}
+	updated := time.Date(2022, 4, 16, 12, 0, 0, 0, time.UTC)
+	if err := store.Save("standardPricing", map[string]float64{"S5.MEDIUM4": 0.5}, updated); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("instancesPricing", map[string]float64{"ins-1": 0.4}, updated.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new store loads the persisted caches
+	store = newStore()
+	loaded, ok, err := store.Load("standardPricing", &prices)
+	if err != nil || !ok || !loaded.Equal(updated) || prices["S5.MEDIUM4"] != 0.5 {
+		t.Errorf("unexpected standard pricing cache %v at %v, %v, %v", prices, loaded, ok, err)
+	}
+	loaded, ok, err = store.Load("instancesPricing", &prices)
+	if err != nil || !ok || !loaded.Equal(updated.Add(time.Minute)) || prices["ins-1"] != 0.4 {
+		t.Errorf("unexpected instances pricing cache %v at %v, %v, %v", prices, loaded, ok, err)
+	}
+}
+
+func TestFilePricingCacheStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pricing-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "pricing-cache.json.gz")
+	testPricingCacheStore(t, func() PricingCacheStore {
+		return NewFilePricingCacheStore(file)
+	})
+}
+
+func TestConfigMapPricingCacheStore(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	testPricingCacheStore(t, func() PricingCacheStore {
+		return NewConfigMapPricingCacheStore(client, "crane-system", "fadvisor-pricing-cache")
+	})
+
+	if _, err := NewPricingCacheStore(CloudConfig{PricingCacheConfigMap: "fadvisor-pricing-cache"}, client); err == nil {
+		t.Errorf("expect error of the configmap without namespace")
+	}
+	if _, err := NewPricingCacheStore(CloudConfig{PricingCacheConfigMap: "crane-system/fadvisor-pricing-cache"}, nil); err == nil {
+		t.Errorf("expect error of the configmap without client")
+	}
+}
@@ -1,6 +1,8 @@
 package cloud
 
 import (
+	"strconv"
+
 	v1 "k8s.io/api/core/v1"
 )
 
@@ -24,14 +26,39 @@ type PriceItem struct {
 	OriginalPriceOneYear        *float64 `json:"originalPriceOneYear,omitempty" name:"originalPriceOneYear"`
 	DiscountPriceOneYear        *float64 `json:"discountPriceOneYear,omitempty" name:"discountPriceOneYear"`
 	DiscountOneYear             *float64 `json:"discountOneYear,omitempty" name:"discountOneYear"`
+	// Currency is the currency of the prices, such as CNY and USD
+	Currency string `json:"currency,omitempty" name:"currency"`
 }
 
 type Price struct {
-	InstanceType string     `json:"instanceType"`
-	ChargeType   string     `json:"chargeType"`
-	Memory       string     `json:"memory"`
-	VCpu         string     `json:"vcpu"`
-	CvmPrice     *PriceItem `json:"cvmPrice,omitempty"`
+	InstanceType   string     `json:"instanceType"`
+	InstanceFamily string     `json:"instanceFamily,omitempty"`
+	ChargeType     string     `json:"chargeType"`
+	Zone           string     `json:"zone,omitempty"`
+	Memory         string     `json:"memory"`
+	VCpu           string     `json:"vcpu"`
+	CvmPrice       *PriceItem `json:"cvmPrice,omitempty"`
+	// Monthly means the original price is for one month, such as the prepaid instances, it is set by the provider
+	Monthly bool `json:"monthly,omitempty"`
+	// Spot means the instance is a spot instance which may be reclaimed, it is set by the provider
+	Spot bool `json:"spot,omitempty"`
+}
+
+// HourlyCost return the hourly cost of the instance, monthly price is divided by 30*24 hours to compute an avg hourly cost.
+func (p *Price) HourlyCost() (float64, bool) {
+	if p == nil || p.CvmPrice == nil {
+		return 0, false
+	}
+	if p.Monthly {
+		if p.CvmPrice.OriginalPrice == nil {
+			return 0, false
+		}
+		return *p.CvmPrice.OriginalPrice / float64(30*24), true
+	}
+	if p.CvmPrice.UnitPrice == nil {
+		return 0, false
+	}
+	return *p.CvmPrice.UnitPrice, true
 }
 
 // cross cloud pricing
@@ -81,6 +108,10 @@ type CloudPrice interface {
 	Refresh()
 
 	GetNodesPricing() (map[string]*Price, error)
+
+	// GetStandardPricing return the standard price of all instance types sold by the provider in the zones of the cluster region.
+	// key is zone,instanceType,chargeType
+	GetStandardPricing() (map[string]*Price, error)
 }
 
 type ChargeType string
@@ -103,6 +134,43 @@ type BaseInstancePrice struct {
 	InstanceType string `json:"instanceType,omitempty"`
 	Region       string `json:"region,omitempty"`
 	ProviderID   string `json:"providerID,omitempty"`
+	// Currency is the currency of the costs, empty means the currency of the custom pricing
+	Currency string `json:"currency,omitempty"`
+	// Spot means the node is a spot instance which may be reclaimed, it is set by the provider
+	Spot bool `json:"spot,omitempty"`
+}
+
+// Scale multiplies the costs of the price by the factor, the costs which can not be parsed are kept
+func (p *BaseInstancePrice) Scale(factor float64) {
+	scale := func(cost string) string {
+		value, err := strconv.ParseFloat(cost, 64)
+		if err != nil {
+			return cost
+		}
+		return strconv.FormatFloat(value*factor, 'f', -1, 64)
+	}
+	p.Cost = scale(p.Cost)
+	p.DiscountedCost = scale(p.DiscountedCost)
+	p.CpuHourlyCost = scale(p.CpuHourlyCost)
+	p.RamGBHourlyCost = scale(p.RamGBHourlyCost)
+}
+
+// HourlyCost returns the hourly cost of the instance, if the cost is not provided, it is computed by the cpu and ram prices
+func (p *BaseInstancePrice) HourlyCost() (float64, bool) {
+	if cost, err := strconv.ParseFloat(p.Cost, 64); err == nil {
+		return cost, true
+	}
+	cpu, err := strconv.ParseFloat(p.Cpu, 64)
+	if err != nil {
+		return 0, false
+	}
+	cpuCost, err := strconv.ParseFloat(p.CpuHourlyCost, 64)
+	if err != nil {
+		return 0, false
+	}
+	ram, _ := strconv.ParseFloat(p.Ram, 64)
+	ramCost, _ := strconv.ParseFloat(p.RamGBHourlyCost, 64)
+	return cpu*cpuCost + ram*ramCost, true
 }
 
 type Node struct {
@@ -44,8 +44,9 @@ func (tc *DefaultCloud) PodPrice(spec spec.CloudPodSpec) (*cloud.Pod, error) {
 	panic("implement me")
 }
 
+// PlatformPrice returns zero, there is no platform fee of the self managed cluster
 func (tc *DefaultCloud) PlatformPrice(cp cloud.PlatformParameter) *cloud.Prices {
-	panic("implement me")
+	return &cloud.Prices{}
 }
 
 func (tc *DefaultCloud) Pod2Spec(pod *v1.Pod) spec.CloudPodSpec {
@@ -116,6 +117,7 @@ func (tc *DefaultCloud) getDefaultNodePrice(cfg *cloud.CustomPricing, node *v1.N
 			InstanceType:     insType,
 			ProviderID:       node.Spec.ProviderID,
 			Region:           region,
+			Currency:         cfg.Currency,
 		},
 	}, nil
 }
@@ -186,3 +188,8 @@ func (tc *DefaultCloud) GetNodesPricing() (map[string]*cloud.Price, error) {
 	results := make(map[string]*cloud.Price)
 	return results, nil
 }
+
+func (tc *DefaultCloud) GetStandardPricing() (map[string]*cloud.Price, error) {
+	results := make(map[string]*cloud.Price)
+	return results, nil
+}
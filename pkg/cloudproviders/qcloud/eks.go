@@ -19,6 +19,7 @@ func (ep *EKSPlatform) PlatformCost(cp cloud.PlatformParameter) *cloud.Prices {
 	return &cloud.Prices{
 		TotalPrice:    0,
 		DiscountPrice: pointer.Float64(0),
+		Currency:      Currency,
 	}
 }
 
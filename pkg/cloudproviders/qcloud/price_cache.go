@@ -1,6 +1,7 @@
 package qcloud
 
 import (
+	"fmt"
 	"regexp"
 	"time"
 
@@ -10,9 +11,15 @@ import (
 
 	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
 
+	"github.com/gocrane/fadvisor/pkg/cloud"
 	sdkcvm "github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/cvm"
 )
 
+const (
+	standardPricingCache  = "standardPricing"
+	instancesPricingCache = "instancesPricing"
+)
+
 var QCloudProviderIdRegex = regexp.MustCompile("qcloud:///([^/]+)/([^/]+)") // It's of the form qcloud:///800005/ins-2jv4wpmr and we want ins-2jv4wpmr, if it exists
 
 // fill the charge type. because tencent cloud provider tke do not label the k8s node its charge type directly, must query from cloud cvm service.
@@ -29,6 +36,7 @@ func (tc *TencentCloud) refreshPricingCache() error {
 		klog.Errorf("UpdateCachedInstancesStandardPrice failed: %v", err)
 		return err
 	}
+	var snapshot map[string]*cvm.InstanceTypeQuotaItem
 	func() {
 		tc.lock.Lock()
 		defer tc.lock.Unlock()
@@ -39,8 +47,15 @@ func (tc *TencentCloud) refreshPricingCache() error {
 			key := zone + "," + insType + "," + insChargeType
 			tc.standardPricing[key] = item
 		}
+		tc.standardPricingUpdated = now
+		snapshot = make(map[string]*cvm.InstanceTypeQuotaItem, len(tc.standardPricing))
+		for key, item := range tc.standardPricing {
+			snapshot[key] = item
+		}
 		klog.V(3).Infof("UpdateCachedInstancesStandardPrice success")
 	}()
+	cloud.ObservePricingCache(cloud.TencentCloud, standardPricingCache, now)
+	tc.savePricingCache(tc.pricingCacheKey(standardPricingCache), snapshot, now)
 	return nil
 }
 
@@ -89,7 +104,14 @@ func (pc *TencentCloud) refreshInstancePricingCache(nodeList []*v1.Node) error {
 			delete(pc.instances, insid)
 		}
 	}
+	pc.instancesUpdated = now
+	snapshot := make(map[string]*sdkcvm.QCloudInstancePrice, len(pc.instances))
+	for insid, insPrice := range pc.instances {
+		snapshot[insid] = insPrice
+	}
 	pc.instanceLock.Unlock()
+	cloud.ObservePricingCache(cloud.TencentCloud, instancesPricingCache, now)
+	pc.savePricingCache(pc.pricingCacheKey(instancesPricingCache), snapshot, now)
 	return nil
 }
 
@@ -107,7 +129,78 @@ func ParseID(id string) string {
 	return match[2]
 }
 
+// SetPricingCacheStore sets the store persisting the standard and instance pricing caches
+func (tc *TencentCloud) SetPricingCacheStore(store cloud.PricingCacheStore) {
+	tc.store = store
+}
+
+// pricingCacheKey returns the key of the persisted cache, the standard prices are of the region and the instance prices
+// are of the cluster, so that the clusters can share the store
+func (tc *TencentCloud) pricingCacheKey(name string) string {
+	if name == instancesPricingCache {
+		return name + "/" + tc.region + "/" + tc.clusterID
+	}
+	return name + "/" + tc.region
+}
+
+func (tc *TencentCloud) savePricingCache(name string, v interface{}, updated time.Time) {
+	if tc.store == nil {
+		return
+	}
+	if err := tc.store.Save(name, v, updated); err != nil {
+		klog.Errorf("Failed to persist the pricing cache %v: %v", name, err)
+	}
+}
+
+// loadPricingCaches loads the persisted pricing caches, it returns true if both the standard and instance pricing caches are loaded
+func (tc *TencentCloud) loadPricingCaches() bool {
+	if tc.store == nil {
+		return false
+	}
+	standardPricing := make(map[string]*cvm.InstanceTypeQuotaItem)
+	standardUpdated, standardOk, err := tc.store.Load(tc.pricingCacheKey(standardPricingCache), &standardPricing)
+	if err != nil {
+		klog.Errorf("Failed to load the persisted pricing cache %v: %v", standardPricingCache, err)
+	}
+	instances := make(map[string]*sdkcvm.QCloudInstancePrice)
+	instancesUpdated, instancesOk, err := tc.store.Load(tc.pricingCacheKey(instancesPricingCache), &instances)
+	if err != nil {
+		klog.Errorf("Failed to load the persisted pricing cache %v: %v", instancesPricingCache, err)
+	}
+	if standardOk {
+		tc.lock.Lock()
+		tc.standardPricing = standardPricing
+		tc.standardPricingUpdated = standardUpdated
+		tc.lock.Unlock()
+		cloud.ObservePricingCache(cloud.TencentCloud, standardPricingCache, standardUpdated)
+		klog.Infof("Loaded %v persisted standard prices updated at %v", len(standardPricing), standardUpdated)
+	}
+	if instancesOk {
+		tc.instanceLock.Lock()
+		tc.instances = instances
+		tc.instancesUpdated = instancesUpdated
+		tc.instanceLock.Unlock()
+		cloud.ObservePricingCache(cloud.TencentCloud, instancesPricingCache, instancesUpdated)
+		klog.Infof("Loaded %v persisted instance prices updated at %v", len(instances), instancesUpdated)
+	}
+	return standardOk && instancesOk
+}
+
+// WarmUp loads the persisted pricing caches and refreshes them in the background, if no persisted caches,
+// it refreshes the caches from the cloud api. In offline mode, the persisted caches are required and never refreshed.
 func (tc *TencentCloud) WarmUp() error {
+	if tc.offline {
+		if !tc.loadPricingCaches() {
+			return fmt.Errorf("no persisted pricing caches in offline mode, persist them by an online run with the same pricing cache file or configmap")
+		}
+		klog.Info("Serving the persisted pricing caches in offline mode")
+		return nil
+	}
+	if tc.loadPricingCaches() {
+		klog.Info("Serving the persisted pricing caches, refresh them in the background")
+		go tc.Refresh()
+		return nil
+	}
 	nodes := tc.cache.GetNodes()
 	klog.Info("refreshPricingCache")
 	err := tc.refreshPricingCache()
@@ -122,16 +215,26 @@ func (tc *TencentCloud) WarmUp() error {
 	return err
 }
 
+// Refresh refreshes the pricing caches from the cloud api, the cached prices are kept and served if the cloud api fails
 func (pc *TencentCloud) Refresh() {
+	if pc.offline {
+		return
+	}
 	nodes := pc.cache.GetNodes()
 	err := pc.refreshPricingCache()
 	if err != nil {
-		klog.Errorf("Failed to refresh: %v", err)
+		pc.lock.Lock()
+		updated := pc.standardPricingUpdated
+		pc.lock.Unlock()
+		klog.Errorf("Failed to refresh, serving the standard prices updated at %v: %v", updated, err)
 		return
 	}
 	err = pc.refreshInstancePricingCache(nodes)
 	if err != nil {
-		klog.Errorf("Failed to refresh: %v", err)
+		pc.instanceLock.RLock()
+		updated := pc.instancesUpdated
+		pc.instanceLock.RUnlock()
+		klog.Errorf("Failed to refresh, serving the instance prices updated at %v: %v", updated, err)
 		return
 	}
 	return
@@ -166,6 +269,9 @@ func (pc *TencentCloud) OnNodeDelete(node *v1.Node) error {
 }
 
 func (pc *TencentCloud) OnNodeAdd(node *v1.Node) error {
+	if pc.offline {
+		return nil
+	}
 	if pc.IsVirtualNode(node) {
 		klog.Warningf("node %v is virtual node", node.Name)
 		return nil
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -19,6 +20,7 @@ import (
 
 	"github.com/gocrane/fadvisor/pkg/cache"
 	qcloudsdk "github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud"
+	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/credential"
 	sdkcvm "github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/cvm"
 	sdktke "github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/tke"
 
@@ -28,17 +30,19 @@ import (
 	"github.com/gocrane/fadvisor/pkg/util"
 )
 
+// Currency is the currency of the prices of the tencent cloud api
+const Currency = "CNY"
+
 type CloudConfig struct {
 	Credentials   `name:"credentials" value:"optional"`
 	ClientProfile `name:"clientProfile" value:"optional"`
 }
 
-// Credentials use user defined SecretId and SecretKey
+// Credentials use user defined SecretId and SecretKey, or the secret, the sts role and the cam role of the credential config
 type Credentials struct {
 	ClusterId string
 	AppId     string
-	SecretId  string
-	SecretKey string
+	credential.Config
 }
 
 type ClientProfile struct {
@@ -52,12 +56,10 @@ type ClientProfile struct {
 }
 
 type qcloudKey struct {
-	SpotLabelName  string
-	SpotLabelValue string
-	Labels         map[string]string
-	ProviderID     string
-	Zone           string
-	ChargeType     string
+	Labels     map[string]string
+	ProviderID string
+	Zone       string
+	ChargeType string
 }
 
 func (k *qcloudKey) GPUType() string {
@@ -97,8 +99,8 @@ var _ cloud.Cloud = &TencentCloud{}
 
 type TencentCloud struct {
 	cache cache.Cache
-	cvm   *sdkcvm.CVMClient
-	tke   *sdktke.TKEClient
+	cvm   sdkcvm.CVM
+	tke   sdktke.TKE
 
 	priceConfig *cloud.PriceConfig
 
@@ -108,20 +110,42 @@ type TencentCloud struct {
 	// key is (zone + instanceType + instanceChargeType) for node;
 	standardPricing map[string]*cvm.InstanceTypeQuotaItem
 
+	standardPricingUpdated time.Time
+
 	// cached instances
 	instanceLock sync.RWMutex
 	// key is ins id
-	instances map[string]*sdkcvm.QCloudInstancePrice
+	instances        map[string]*sdkcvm.QCloudInstancePrice
+	instancesUpdated time.Time
+
+	// store persists the pricing caches, the persisted prices are served when the cloud api fails
+	store cloud.PricingCacheStore
 
 	eksPlatformer *EKSPlatform
 	tkePlatformer *TKEPlatform
 	eksConverter  Pod2EKSSpecConverter
+
+	// region is the region of the sdk clients, which is the cluster region
+	region string
+	// clusterID is the ClusterId of the credentials, the persisted instance prices are of the cluster
+	clusterID string
+
+	// credentialConfig is the credential source, the credential is created when the kube client is set
+	credentialConfig credential.Config
+
+	// offline means no cloud api is called, see SetOffline
+	offline bool
 }
 
 func NewTencentCloud(qcloudConf *qcloudsdk.QCloudClientConfig, config *cloud.PriceConfig, cache cache.Cache) cloud.Cloud {
+	return newTencentCloud(qcloudConf, config, cache)
+}
+
+func newTencentCloud(qcloudConf *qcloudsdk.QCloudClientConfig, config *cloud.PriceConfig, cache cache.Cache) *TencentCloud {
 	cvmClient := sdkcvm.NewCVMClient(qcloudConf)
 	tkeClient := sdktke.NewTKEClient(qcloudConf)
 	return &TencentCloud{
+		region:          qcloudConf.Region,
 		cvm:             cvmClient,
 		tke:             tkeClient,
 		priceConfig:     config,
@@ -179,10 +203,6 @@ func CloudPodSpec2EKSPriceRequest(spec spec.CloudPodSpec) *tke.GetPriceRequest {
 }
 
 func (tc *TencentCloud) ServerlessPodPrice(spec spec.CloudPodSpec) (*cloud.Pod, error) {
-	price, err := tc.tke.GetEKSPodPrice(CloudPodSpec2EKSPriceRequest(spec))
-	if err != nil {
-		return nil, err
-	}
 	cfg, err := tc.priceConfig.GetConfig()
 	if err != nil {
 		return nil, err
@@ -192,10 +212,20 @@ func (tc *TencentCloud) ServerlessPodPrice(spec spec.CloudPodSpec) (*cloud.Pod,
 	ram := float64(spec.Mem.Value())
 
 	var cost, discountCost float64
-	// price unit is cent
-	if price.Response != nil && price.Response.Cost != nil {
-		discountCost = float64(*price.Response.Cost) / 100.
-		cost = float64(*price.Response.TotalCost) / 100.
+	if tc.offline {
+		// the serverless prices depend on the pod spec and are not persisted, they are estimated by the custom pricing in offline mode
+		cost = (cpu*cfg.CpuHourlyPrice + ram/consts.GB*cfg.RamGBHourlyPrice) * float64(spec.GoodsNum) * float64(spec.TimeSpan) / 3600.
+		discountCost = cost
+	} else {
+		price, err := tc.tke.GetEKSPodPrice(CloudPodSpec2EKSPriceRequest(spec))
+		if err != nil {
+			return nil, err
+		}
+		// price unit is cent
+		if price.Response != nil && price.Response.Cost != nil {
+			discountCost = float64(*price.Response.Cost) / 100.
+			cost = float64(*price.Response.TotalCost) / 100.
+		}
 	}
 	newCnode := &cloud.Pod{
 		BaseInstancePrice: cloud.BaseInstancePrice{
@@ -204,6 +234,7 @@ func (tc *TencentCloud) ServerlessPodPrice(spec spec.CloudPodSpec) (*cloud.Pod,
 			Cpu:            fmt.Sprintf("%f", cpu),
 			Ram:            fmt.Sprintf("%f", ram/consts.GB),
 			RamBytes:       fmt.Sprintf("%f", ram),
+			Currency:       Currency,
 		},
 	}
 
@@ -364,7 +395,8 @@ func (tc *TencentCloud) Pod2ServerlessSpec(pod *v1.Pod) spec.CloudPodSpec {
 		reqs[v1.ResourceMemory] = *memorySize
 		lims[v1.ResourceCPU] = *cores
 		lims[v1.ResourceMemory] = *memorySize
-	} else {
+	} else if !tc.offline {
+		// the eks spec is got from the cloud api, the default sum way is used in offline mode
 		resourceList, err := tc.eksConverter.Pod2EKSSpecConverter(pod)
 		if err != nil {
 			klog.Errorf("Failed to convert pod %v to eks spec: %v, use default sum way", klog.KObj(pod), err)
@@ -408,7 +440,7 @@ func (tc *TencentCloud) Pod2Spec(pod *v1.Pod) spec.CloudPodSpec {
 	}
 	if tc.IsServerlessPod(pod) {
 		isServerless = true
-		if qosClass != v1.PodQOSBestEffort {
+		if qosClass != v1.PodQOSBestEffort && !tc.offline {
 			resourceList, err := tc.eksConverter.Pod2EKSSpecConverter(pod)
 			if err != nil {
 				klog.Errorf("Failed to convert pod %v to eks spec: %v, use default sum way", klog.KObj(pod), err)
@@ -514,6 +546,7 @@ func (tc *TencentCloud) getDefaultNodePrice(cfg *cloud.CustomPricing, node *v1.N
 			InstanceType:     insType,
 			ProviderID:       node.Spec.ProviderID,
 			Region:           region,
+			Currency:         cfg.Currency,
 		},
 	}, nil
 }
@@ -542,7 +575,8 @@ func (tc *TencentCloud) getCloudInstancePrice(node *v1.Node) (*cloud.Node, error
 	insId := ParseID(node.Spec.ProviderID)
 	insPrice := tc.GetInstancePrice(insId)
 	if insPrice == nil || insPrice.Instance == nil || insPrice.Price.InstancePrice == nil {
-		klog.Warningf("node (%v, %v) got no cache price", node.Name, insId)
+		klog.Warningf("node (%v, %v) got no cache price, fall back to the default pricing", node.Name, insId)
+		cloud.ObserveDefaultPricingFallback(cloud.TencentCloud)
 		return tc.getDefaultNodePrice(cfg, node)
 	}
 
@@ -578,6 +612,7 @@ func (tc *TencentCloud) getCloudInstancePrice(node *v1.Node) (*cloud.Node, error
 				InstanceType:    insType,
 				Region:          region,
 				ProviderID:      node.Spec.ProviderID,
+				Currency:        Currency,
 			},
 		}, nil
 	} else if usageType == qcloudsdk.INSTANCECHARGETYPE_POSTPAID_BY_HOUR {
@@ -594,6 +629,7 @@ func (tc *TencentCloud) getCloudInstancePrice(node *v1.Node) (*cloud.Node, error
 				InstanceType:    insType,
 				Region:          region,
 				ProviderID:      node.Spec.ProviderID,
+				Currency:        Currency,
 			},
 		}, nil
 	} else if usageType == qcloudsdk.INSTANCECHARGETYPE_SPOTPAID {
@@ -611,6 +647,8 @@ func (tc *TencentCloud) getCloudInstancePrice(node *v1.Node) (*cloud.Node, error
 				InstanceType:    insType,
 				Region:          region,
 				ProviderID:      node.Spec.ProviderID,
+				Currency:        Currency,
+				Spot:            true,
 			},
 		}, nil
 	} else {
@@ -862,34 +900,84 @@ func (tc *TencentCloud) GetNodesPricing() (map[string]*cloud.Price, error) {
 	defer tc.instanceLock.RUnlock()
 	results := make(map[string]*cloud.Price)
 	for id, insPrice := range tc.instances {
-		qPrice := insPrice.Price.InstancePrice
+		zone := ""
+		if insPrice.Instance.Placement != nil && insPrice.Instance.Placement.Zone != nil {
+			zone = *insPrice.Instance.Placement.Zone
+		}
 		results[id] = &cloud.Price{
 			InstanceType: *insPrice.Instance.InstanceType,
 			ChargeType:   *insPrice.Instance.InstanceChargeType,
+			Zone:         zone,
+			Monthly:      *insPrice.Instance.InstanceChargeType == qcloudsdk.INSTANCECHARGETYPE_PREPAID,
+			Spot:         *insPrice.Instance.InstanceChargeType == qcloudsdk.INSTANCECHARGETYPE_SPOTPAID,
 			VCpu:         fmt.Sprintf("%v", *insPrice.Instance.CPU),
 			Memory:       fmt.Sprintf("%v", *insPrice.Instance.Memory),
-			CvmPrice: &cloud.PriceItem{
-				UnitPrice:                   qPrice.UnitPrice,
-				ChargeUnit:                  qPrice.ChargeUnit,
-				OriginalPrice:               qPrice.OriginalPrice,
-				DiscountPrice:               qPrice.DiscountPrice,
-				Discount:                    qPrice.Discount,
-				UnitPriceDiscount:           qPrice.UnitPriceDiscount,
-				UnitPriceSecondStep:         qPrice.UnitPriceSecondStep,
-				UnitPriceDiscountSecondStep: qPrice.UnitPriceDiscountSecondStep,
-				UnitPriceThirdStep:          qPrice.UnitPriceThirdStep,
-				UnitPriceDiscountThirdStep:  qPrice.UnitPriceDiscountThirdStep,
-				OriginalPriceThreeYear:      qPrice.OriginalPriceThreeYear,
-				DiscountPriceThreeYear:      qPrice.DiscountPriceThreeYear,
-				DiscountThreeYear:           qPrice.DiscountThreeYear,
-				OriginalPriceFiveYear:       qPrice.OriginalPriceFiveYear,
-				DiscountPriceFiveYear:       qPrice.DiscountPriceFiveYear,
-				DiscountFiveYear:            qPrice.DiscountFiveYear,
-				OriginalPriceOneYear:        qPrice.OriginalPriceOneYear,
-				DiscountPriceOneYear:        qPrice.DiscountPriceOneYear,
-				DiscountOneYear:             qPrice.DiscountOneYear,
-			},
+			CvmPrice:     itemPrice2PriceItem(insPrice.Price.InstancePrice),
+		}
+	}
+	return results, nil
+}
+
+func (tc *TencentCloud) GetStandardPricing() (map[string]*cloud.Price, error) {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+	results := make(map[string]*cloud.Price)
+	for key, item := range tc.standardPricing {
+		if item.InstanceType == nil || item.InstanceChargeType == nil || item.Cpu == nil || item.Memory == nil || item.Price == nil {
+			continue
+		}
+		// sold out instance type can not be purchased now
+		if item.Status != nil && *item.Status != "SELL" {
+			continue
+		}
+		// the zones of other regions are skipped, the zone name is prefixed by its region, such as ap-guangzhou-3
+		if tc.region != "" && item.Zone != nil && !strings.HasPrefix(*item.Zone, tc.region+"-") {
+			continue
+		}
+		price := &cloud.Price{
+			InstanceType: *item.InstanceType,
+			ChargeType:   *item.InstanceChargeType,
+			Monthly:      *item.InstanceChargeType == qcloudsdk.INSTANCECHARGETYPE_PREPAID,
+			Spot:         *item.InstanceChargeType == qcloudsdk.INSTANCECHARGETYPE_SPOTPAID,
+			VCpu:         fmt.Sprintf("%v", *item.Cpu),
+			Memory:       fmt.Sprintf("%v", *item.Memory),
+			CvmPrice:     itemPrice2PriceItem(item.Price),
+		}
+		if item.Zone != nil {
+			price.Zone = *item.Zone
 		}
+		if item.InstanceFamily != nil {
+			price.InstanceFamily = *item.InstanceFamily
+		}
+		results[key] = price
 	}
 	return results, nil
 }
+
+func itemPrice2PriceItem(qPrice *cvm.ItemPrice) *cloud.PriceItem {
+	if qPrice == nil {
+		return nil
+	}
+	return &cloud.PriceItem{
+		UnitPrice:                   qPrice.UnitPrice,
+		ChargeUnit:                  qPrice.ChargeUnit,
+		OriginalPrice:               qPrice.OriginalPrice,
+		DiscountPrice:               qPrice.DiscountPrice,
+		Discount:                    qPrice.Discount,
+		UnitPriceDiscount:           qPrice.UnitPriceDiscount,
+		UnitPriceSecondStep:         qPrice.UnitPriceSecondStep,
+		UnitPriceDiscountSecondStep: qPrice.UnitPriceDiscountSecondStep,
+		UnitPriceThirdStep:          qPrice.UnitPriceThirdStep,
+		UnitPriceDiscountThirdStep:  qPrice.UnitPriceDiscountThirdStep,
+		OriginalPriceThreeYear:      qPrice.OriginalPriceThreeYear,
+		DiscountPriceThreeYear:      qPrice.DiscountPriceThreeYear,
+		DiscountThreeYear:           qPrice.DiscountThreeYear,
+		OriginalPriceFiveYear:       qPrice.OriginalPriceFiveYear,
+		DiscountPriceFiveYear:       qPrice.DiscountPriceFiveYear,
+		DiscountFiveYear:            qPrice.DiscountFiveYear,
+		OriginalPriceOneYear:        qPrice.OriginalPriceOneYear,
+		DiscountPriceOneYear:        qPrice.DiscountPriceOneYear,
+		DiscountOneYear:             qPrice.DiscountOneYear,
+		Currency:                    Currency,
+	}
+}
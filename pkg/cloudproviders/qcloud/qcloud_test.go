@@ -0,0 +1,371 @@
+package qcloud
+
+import (
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+
+	"github.com/gocrane/fadvisor/pkg/cache"
+	"github.com/gocrane/fadvisor/pkg/cloud"
+	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/replay"
+	"github.com/gocrane/fadvisor/pkg/spec"
+)
+
+type fakeCache struct {
+	cache.Cache
+	nodes []*v1.Node
+}
+
+func (c *fakeCache) GetNodes() []*v1.Node {
+	return c.nodes
+}
+
+func node(name, providerID, instanceType, cpu, mem string) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{
+			v1.LabelInstanceTypeStable: instanceType,
+			v1.LabelTopologyRegion:     "gz",
+			v1.LabelTopologyZone:       "100003",
+		}},
+		Spec: v1.NodeSpec{ProviderID: providerID},
+		Status: v1.NodeStatus{Capacity: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(cpu),
+			v1.ResourceMemory: resource.MustParse(mem),
+		}},
+	}
+}
+
+func parseFloat(t *testing.T, value string) float64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		t.Fatalf("invalid float %q: %v", value, err)
+	}
+	return f
+}
+
+// checkBreakdown checks the cpu and ram costs sum up to the total cost
+func checkBreakdown(t *testing.T, name string, price cloud.BaseInstancePrice) {
+	cost := parseFloat(t, price.Cost)
+	sum := parseFloat(t, price.Cpu)*parseFloat(t, price.CpuHourlyCost) + parseFloat(t, price.Ram)*parseFloat(t, price.RamGBHourlyCost)
+	if math.Abs(sum-cost) > 1e-5 {
+		t.Errorf("%v: expect the cpu and ram costs sum up to %v, got %v", name, cost, sum)
+	}
+}
+
+func newReplayTencentCloud(t *testing.T, nodes ...*v1.Node) (*TencentCloud, *replay.Server) {
+	server, err := replay.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	priceConfig := cloud.NewProviderConfig(&cloud.CustomPricing{CpuHourlyPrice: 0.1, RamGBHourlyPrice: 0.025, Currency: Currency})
+	return NewTencentCloud(server.ClientConfig("ap-guangzhou"), priceConfig, &fakeCache{nodes: nodes}).(*TencentCloud), server
+}
+
+func TestGetNodesCost(t *testing.T) {
+	virtual := node("eklet-subnet-1", "", ValueNodeTypeEKLet, "16", "64Gi")
+	virtual.Labels[labelNodeInstanceVersion] = valueNodeInstanceVersionV2
+	tc, server := newReplayTencentCloud(t,
+		node("10.0.0.12", "qcloud:///100003/ins-2jv4wpmr", "S5.MEDIUM4", "2", "4Gi"),
+		node("10.0.0.13", "qcloud:///100003/ins-5ab3x7kq", "S5.LARGE8", "4", "8Gi"),
+		node("10.0.0.14", "qcloud:///100003/ins-9terminated", "S5.MEDIUM4", "2", "4Gi"),
+		virtual,
+	)
+	defer server.Close()
+
+	if err := tc.WarmUp(); err != nil {
+		t.Fatal(err)
+	}
+	if len(tc.standardPricing) != 3 {
+		t.Errorf("expect 3 standard prices, got %v", len(tc.standardPricing))
+	}
+
+	nodes, err := tc.GetNodesCost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 3 {
+		t.Fatalf("expect the costs of 3 real nodes, got %v", nodes)
+	}
+	for _, test := range []struct {
+		name        string
+		cost        float64
+		usageType   string
+		usesDefault bool
+	}{
+		{"10.0.0.12", 0.36, "POSTPAID_BY_HOUR", false},
+		// the prepaid monthly price is averaged by 30 days
+		{"10.0.0.13", 0.8, "PREPAID", false},
+		// the terminated instance is not returned by the cloud api, it falls back to the default pricing
+		{"10.0.0.14", 0.3, "Default", true},
+	} {
+		price := nodes[test.name].BaseInstancePrice
+		if cost := parseFloat(t, price.Cost); math.Abs(cost-test.cost) > 1e-6 {
+			t.Errorf("%v: expect cost %v, got %v", test.name, test.cost, cost)
+		}
+		if price.UsageType != test.usageType || price.UsesDefaultPrice != test.usesDefault || price.Currency != Currency {
+			t.Errorf("%v: unexpected price %+v", test.name, price)
+		}
+		checkBreakdown(t, test.name, price)
+	}
+}
+
+func TestGetStandardPricing(t *testing.T) {
+	tc, server := newReplayTencentCloud(t)
+	defer server.Close()
+	if err := tc.WarmUp(); err != nil {
+		t.Fatal(err)
+	}
+	// the instance types of other regions are not returned
+	var item cvm.InstanceTypeQuotaItem
+	for _, quota := range tc.standardPricing {
+		item = *quota
+		break
+	}
+	item.Zone = common.StringPtr("ap-shanghai-2")
+	tc.standardPricing["ap-shanghai-2"] = &item
+
+	pricing, err := tc.GetStandardPricing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pricing) != 3 {
+		t.Errorf("expect 3 standard prices of ap-guangzhou, got %v", pricing)
+	}
+	for key, price := range pricing {
+		if price.Zone != "ap-guangzhou-3" || price.Monthly != (price.ChargeType == "PREPAID") {
+			t.Errorf("%v: unexpected price %+v", key, price)
+		}
+	}
+}
+
+func TestServerlessPodPrice(t *testing.T) {
+	tc, server := newReplayTencentCloud(t)
+	defer server.Close()
+
+	pod, err := tc.ServerlessPodPrice(spec.CloudPodSpec{
+		PodRef:      &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nginx-6799fc88d8-2kx7p"}},
+		Cpu:         resource.MustParse("1"),
+		Mem:         resource.MustParse("2Gi"),
+		GoodsNum:    1,
+		TimeSpan:    3600,
+		MachineArch: "intel",
+		Serverless:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the prices of the cloud api are in cents
+	if cost, discounted := parseFloat(t, pod.Cost), parseFloat(t, pod.DiscountedCost); cost != 0.31 || discounted != 0.25 || pod.Currency != Currency {
+		t.Errorf("unexpected pod price %+v", pod.BaseInstancePrice)
+	}
+	checkBreakdown(t, "pod", pod.BaseInstancePrice)
+	if calls := server.Calls("tke", "GetPrice"); len(calls) != 1 {
+		t.Errorf("expect 1 GetPrice call, got %v", calls)
+	}
+
+	if _, err = tc.ServerlessPodPrice(spec.CloudPodSpec{Cpu: resource.MustParse("3"), Mem: resource.MustParse("5Gi"), GoodsNum: 1, TimeSpan: 3600, MachineArch: "intel"}); err == nil {
+		t.Errorf("expect error of the spec without recorded price")
+	}
+}
+
+func TestSecretCredential(t *testing.T) {
+	qcc, credConfig, err := buildClientConfig(strings.NewReader(`
+[credentials]
+secretName = crane-system/qcloud
+refreshIntervalSeconds = 30
+[clientProfile]
+region = ap-guangzhou
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credConfig.SecretName != "crane-system/qcloud" || credConfig.RefreshIntervalSeconds != 30 || qcc.Region != "ap-guangzhou" {
+		t.Fatalf("unexpected credential config %+v", credConfig)
+	}
+
+	tc, server := newReplayTencentCloud(t)
+	defer server.Close()
+	tc.credentialConfig = credConfig.Config
+	if err = tc.SetKubeClient(nil, nil); err == nil {
+		t.Errorf("expect error of the secret credential without the kube client")
+	}
+	client := fake.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "crane-system", Name: "qcloud"},
+		Data:       map[string][]byte{"secretId": []byte("secret-id"), "secretKey": []byte("secret-key")},
+	})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err = tc.SetKubeClient(client, stopCh); err != nil {
+		t.Fatal(err)
+	}
+	if err = tc.WarmUp(); err != nil {
+		t.Fatal(err)
+	}
+	// the requests are signed by the keys of the secret
+	calls := server.Calls("cvm", "DescribeZoneInstanceConfigInfos")
+	if len(calls) == 0 || !strings.Contains(calls[0].Header.Get("Authorization"), "Credential=secret-id/") {
+		t.Errorf("expect the requests signed by the secret, got %v", calls)
+	}
+}
+
+func TestOffline(t *testing.T) {
+	// the nodes of the recorded responses
+	nodes := []*v1.Node{
+		node("10.0.0.12", "qcloud:///100003/ins-2jv4wpmr", "S5.MEDIUM4", "2", "4Gi"),
+		node("10.0.0.13", "qcloud:///100003/ins-5ab3x7kq", "S5.LARGE8", "4", "8Gi"),
+		node("10.0.0.14", "qcloud:///100003/ins-9terminated", "S5.MEDIUM4", "2", "4Gi"),
+	}
+	store := cloud.NewFilePricingCacheStore(filepath.Join(t.TempDir(), "pricing.json"))
+	tc, server := newReplayTencentCloud(t, nodes...)
+	defer server.Close()
+	tc.credentialConfig.RoleArn = "qcs::cam::uin/100000000001:roleName/fadvisor"
+	tc.SetOffline()
+	// the role credential calls the sts api, it is not created offline
+	if err := tc.SetKubeClient(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	tc.SetPricingCacheStore(store)
+	if err := tc.WarmUp(); err == nil {
+		t.Errorf("expect error of no persisted pricing caches offline")
+	}
+
+	// the pricing caches persisted by an online run are served offline
+	online, onlineServer := newReplayTencentCloud(t, nodes...)
+	defer onlineServer.Close()
+	online.SetPricingCacheStore(store)
+	if err := online.WarmUp(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.WarmUp(); err != nil {
+		t.Fatal(err)
+	}
+	costs, err := tc.GetNodesCost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price := costs["10.0.0.12"].BaseInstancePrice; parseFloat(t, price.Cost) != 0.36 || price.UsesDefaultPrice {
+		t.Errorf("expect the persisted price, got %+v", price)
+	}
+
+	pod, err := tc.ServerlessPodPrice(spec.CloudPodSpec{Cpu: resource.MustParse("3"), Mem: resource.MustParse("5Gi"), GoodsNum: 1, TimeSpan: 3600, MachineArch: "intel"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// estimated by the custom pricing
+	if cost := parseFloat(t, pod.Cost); math.Abs(cost-0.425) > 1e-6 {
+		t.Errorf("expect the pod cost 0.425 of the custom pricing, got %v", cost)
+	}
+	checkBreakdown(t, "pod", pod.BaseInstancePrice)
+	tc.Refresh()
+	if err = tc.OnNodeAdd(nodes[0]); err != nil {
+		t.Fatal(err)
+	}
+	for _, call := range [][2]string{{"cvm", "DescribeZoneInstanceConfigInfos"}, {"cvm", "DescribeInstances"}, {"tke", "GetPrice"}, {"sts", "AssumeRole"}} {
+		if calls := server.Calls(call[0], call[1]); len(calls) != 0 {
+			t.Errorf("expect no %v called offline, got %v", call, calls)
+		}
+	}
+}
+
+func TestPersistedPricingCaches(t *testing.T) {
+	nodes := []*v1.Node{
+		node("10.0.0.12", "qcloud:///100003/ins-2jv4wpmr", "S5.MEDIUM4", "2", "4Gi"),
+		node("10.0.0.13", "qcloud:///100003/ins-5ab3x7kq", "S5.LARGE8", "4", "8Gi"),
+		node("10.0.0.14", "qcloud:///100003/ins-9terminated", "S5.MEDIUM4", "2", "4Gi"),
+	}
+	store := cloud.NewFilePricingCacheStore(filepath.Join(t.TempDir(), "pricing.json"))
+	online, onlineServer := newReplayTencentCloud(t, nodes...)
+	defer onlineServer.Close()
+	online.clusterID = "cls-one"
+	online.SetPricingCacheStore(store)
+	if err := online.WarmUp(); err != nil {
+		t.Fatal(err)
+	}
+	// the persisted standard prices were refreshed two hours ago
+	standardKey := online.pricingCacheKey(standardPricingCache)
+	standardPricing := make(map[string]*cvm.InstanceTypeQuotaItem)
+	if _, ok, err := store.Load(standardKey, &standardPricing); err != nil || !ok {
+		t.Fatalf("expect the persisted standard prices, got %v, %v", ok, err)
+	}
+	updated := time.Now().Add(-2 * time.Hour)
+	if err := store.Save(standardKey, standardPricing, updated); err != nil {
+		t.Fatal(err)
+	}
+
+	// the instance prices of another cluster are not loaded
+	other, otherServer := newReplayTencentCloud(t, nodes...)
+	defer otherServer.Close()
+	other.clusterID = "cls-two"
+	other.SetPricingCacheStore(store)
+	if other.loadPricingCaches() {
+		t.Errorf("expect no persisted instance prices of another cluster")
+	}
+
+	// the persisted prices are loaded on start and served when the cloud api fails
+	tc, server := newReplayTencentCloud(t, nodes...)
+	defer server.Close()
+	tc.clusterID = "cls-one"
+	tc.SetPricingCacheStore(store)
+	apiError := map[string]interface{}{"Response": map[string]interface{}{
+		"Error":     map[string]string{"Code": "InternalError", "Message": "the cloud api is down"},
+		"RequestId": "replay",
+	}}
+	for _, action := range []string{"DescribeZoneInstanceConfigInfos", "DescribeInstances"} {
+		if err := server.Record("cvm", action, nil, apiError); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !tc.loadPricingCaches() {
+		t.Fatalf("expect the persisted pricing caches loaded")
+	}
+	tc.Refresh()
+	if calls := server.Calls("cvm", "DescribeZoneInstanceConfigInfos"); len(calls) == 0 {
+		t.Errorf("expect the refresh called the cloud api")
+	}
+	costs, err := tc.GetNodesCost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price := costs["10.0.0.12"].BaseInstancePrice; parseFloat(t, price.Cost) != 0.36 || price.UsesDefaultPrice {
+		t.Errorf("expect the stale persisted price, got %+v", price)
+	}
+	if len(tc.standardPricing) != 3 || !tc.standardPricingUpdated.Equal(updated) {
+		t.Errorf("expect the stale standard prices updated at %v, got %v at %v", updated, len(tc.standardPricing), tc.standardPricingUpdated)
+	}
+
+	// the staleness is since the persisted refresh time
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var staleness float64
+	for _, family := range families {
+		if family.GetName() != "fadvisor_pricing_cache_staleness_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["provider"] == string(cloud.TencentCloud) && labels["cache"] == standardPricingCache {
+				staleness = metric.GetGauge().GetValue()
+			}
+		}
+	}
+	if staleness < 2*time.Hour.Seconds() || staleness > 3*time.Hour.Seconds() {
+		t.Errorf("expect the staleness of the standard prices about two hours, got %v seconds", staleness)
+	}
+}
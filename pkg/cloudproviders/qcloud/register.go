@@ -7,6 +7,7 @@ import (
 
 	gcfg "gopkg.in/gcfg.v1"
 
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/util/flowcontrol"
 	"k8s.io/klog/v2"
 
@@ -19,8 +20,9 @@ import (
 
 func registerTencent(cloudConfig io.Reader, priceConfig *cloud.PriceConfig, cache *cache.Cache) (cloud.Cloud, error) {
 	var qcloudClientConfig *qcloudsdk.QCloudClientConfig
+	var credentials Credentials
 	var err error
-	if qcloudClientConfig, err = buildClientConfig(cloudConfig); err != nil {
+	if qcloudClientConfig, credentials, err = buildClientConfig(cloudConfig); err != nil {
 		return nil, err
 	}
 	if qcloudClientConfig.Region == "" {
@@ -38,15 +40,19 @@ func registerTencent(cloudConfig io.Reader, priceConfig *cloud.PriceConfig, cach
 		return nil, fmt.Errorf("no region info found. must specify region for provider %v", qcloudClientConfig.Region)
 	}
 	klog.V(4).Infof("Cloud config detail: %+v", qcloudClientConfig.QCloudClientProfile)
-	p := NewTencentCloud(qcloudClientConfig, priceConfig, *cache)
+	p := newTencentCloud(qcloudClientConfig, priceConfig, *cache)
+	p.credentialConfig = credentials.Config
+	p.clusterID = credentials.ClusterId
 	return p, nil
 }
 
-func buildClientConfig(cloudConfig io.Reader) (*qcloudsdk.QCloudClientConfig, error) {
+// buildClientConfig returns the client config with the static credential and the credentials of the config,
+// the other credentials are created when the kube client is set, so that no cloud api is called in offline mode.
+func buildClientConfig(cloudConfig io.Reader) (*qcloudsdk.QCloudClientConfig, Credentials, error) {
 	var cfg CloudConfig
 	if err := gcfg.FatalOnly(gcfg.ReadInto(&cfg, cloudConfig)); err != nil {
 		klog.Errorf("Failed to read TencentCloud configuration file: %v", err)
-		return nil, err
+		return nil, Credentials{}, err
 	}
 	qccp := qcloudsdk.QCloudClientProfile{
 		Debug:           cfg.Debug,
@@ -58,14 +64,43 @@ func buildClientConfig(cloudConfig io.Reader) (*qcloudsdk.QCloudClientConfig, er
 		Scheme:          cfg.Scheme,
 	}
 
-	cred := credential.NewQCloudCredential(cfg.ClusterId, cfg.AppId, cfg.SecretId, cfg.SecretKey, 1*time.Hour)
+	credentials := cfg.Credentials
+	credentials.DomainSuffix = cfg.DomainSuffix
+	credentials.Scheme = cfg.Scheme
+	cred := credential.NewQCloudCredential(cfg.ClusterId, cfg.AppId, cfg.SecretId, cfg.SecretKey)
 	qcc := &qcloudsdk.QCloudClientConfig{
 		RateLimiter:         flowcontrol.NewTokenBucketRateLimiter(5, 1),
 		DefaultRetryCnt:     consts.MAXRETRY,
 		QCloudClientProfile: qccp,
 		Credential:          cred,
 	}
-	return qcc, nil
+	return qcc, credentials, nil
+}
+
+// SetKubeClient creates the credential of the credential config and updates the clients with it,
+// the client is required by the kubernetes secret. No credential is created in offline mode.
+// the credential is refreshed until stopCh is closed.
+func (tc *TencentCloud) SetKubeClient(client kubernetes.Interface, stopCh <-chan struct{}) error {
+	if tc.offline || tc.credentialConfig.Static() {
+		return nil
+	}
+	cred, err := credential.NewCredential(tc.credentialConfig, client, stopCh)
+	if err != nil {
+		return err
+	}
+	tc.cvm.UpdateCredential(cred)
+	tc.tke.UpdateCredential(cred)
+	return nil
+}
+
+// SetOffline stops calling the cloud api, the prices are served from the persisted pricing caches and the custom pricing
+func (tc *TencentCloud) SetOffline() {
+	tc.offline = true
+}
+
+// Currency returns the currency of the prices of the tencent cloud api
+func (tc *TencentCloud) Currency() string {
+	return Currency
 }
 
 func init() {
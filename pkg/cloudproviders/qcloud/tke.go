@@ -32,6 +32,7 @@ func (tp *TKEPlatform) PlatformCost(cp cloud.PlatformParameter) *cloud.Prices {
 	if cp.Nodes == nil {
 		return &cloud.Prices{
 			TotalPrice: defaultClusterPriceModel[0].PriceHourly,
+			Currency:   Currency,
 		}
 	}
 	clusterRealNodes := *cp.Nodes
@@ -39,10 +40,12 @@ func (tp *TKEPlatform) PlatformCost(cp cloud.PlatformParameter) *cloud.Prices {
 		if clusterRealNodes <= unit.Nodes {
 			return &cloud.Prices{
 				TotalPrice: unit.PriceHourly,
+				Currency:   Currency,
 			}
 		}
 	}
 	return &cloud.Prices{
 		TotalPrice: defaultClusterPriceModel[len(defaultClusterPriceModel)-1].PriceHourly,
+		Currency:   Currency,
 	}
 }
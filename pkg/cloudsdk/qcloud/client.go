@@ -1,6 +1,7 @@
 package qcloud
 
 import (
+	"net/http"
 	"time"
 
 	"k8s.io/client-go/util/flowcontrol"
@@ -589,6 +590,8 @@ type QCloudClientConfig struct {
 	RateLimiter     flowcontrol.RateLimiter
 	DefaultRetryCnt int
 	Credential      credential.QCloudCredential
+	// Transport is the http transport of the sdk clients, the default transport is used if nil
+	Transport http.RoundTripper
 	QCloudClientProfile
 }
 
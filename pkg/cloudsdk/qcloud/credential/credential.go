@@ -1,10 +1,16 @@
 package credential
 
 import (
+	"fmt"
+	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/consts"
 )
 
 type QCloudCredential interface {
@@ -14,6 +20,88 @@ type QCloudCredential interface {
 	// for test
 }
 
+// Config is the credential source of the [credentials] section of the cloud config.
+// the keys come from the kubernetes secret, the mounted secret files or the static SecretId and SecretKey, in this order;
+// if RoleArn is set, the keys assume the role and its temporary credential is used. CamRoleName needs no keys.
+type Config struct {
+	SecretId  string
+	SecretKey string
+	// SecretDir is the directory of the mounted secret which has the secretId, secretKey and optional token files
+	SecretDir string
+	// SecretName is the namespace/name of the kubernetes secret which has the secretId, secretKey and optional token keys
+	SecretName string
+	// RefreshIntervalSeconds is the interval the secret files or the kubernetes secret are reloaded, default 60.
+	// it is the rotation latency of the kubernetes secret, the secret files are also reloaded when they change
+	RefreshIntervalSeconds int
+	// RoleArn is the cam role assumed by the sts api, such as qcs::cam::uin/100000000001:roleName/fadvisor
+	RoleArn             string
+	RoleSessionName     string
+	RoleDurationSeconds int
+	// CamRoleName is the cam role bound to the cvm, its temporary credential is got from the metadata server
+	CamRoleName string
+
+	// DomainSuffix, Scheme and Transport are of the sts api, they are the same as the sdk clients
+	DomainSuffix string            `gcfg:"-"`
+	Scheme       string            `gcfg:"-"`
+	Transport    http.RoundTripper `gcfg:"-"`
+}
+
+// Static returns true if the credential is the static SecretId and SecretKey, the other credentials read the secret
+// or call the cloud api when they are created
+func (c Config) Static() bool {
+	return c.CamRoleName == "" && c.RoleArn == "" && c.SecretName == "" && c.SecretDir == ""
+}
+
+// NewCredential returns the credential of the config, the client is required by the kubernetes secret.
+// the credential is refreshed in the background until stopCh is closed.
+func NewCredential(config Config, client kubernetes.Interface, stopCh <-chan struct{}) (QCloudCredential, error) {
+	interval := time.Duration(config.RefreshIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+	if config.CamRoleName != "" {
+		return NewCAMRoleCredential(config.CamRoleName, DefaultMetadataURL, stopCh)
+	}
+
+	var keys QCloudCredential
+	var err error
+	switch {
+	case config.SecretName != "":
+		parts := strings.Split(config.SecretName, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid credential secret %q, the format is namespace/name", config.SecretName)
+		}
+		if client == nil {
+			return nil, fmt.Errorf("credential secret %v needs the api server", config.SecretName)
+		}
+		keys, err = NewSecretCredential(client, parts[0], parts[1], interval, stopCh)
+	case config.SecretDir != "":
+		keys, err = NewFileCredential(config.SecretDir, interval, stopCh)
+	default:
+		keys = NewQCloudCredential("", "", config.SecretId, config.SecretKey)
+	}
+	if err != nil || config.RoleArn == "" {
+		return keys, err
+	}
+
+	duration := time.Duration(config.RoleDurationSeconds) * time.Second
+	if duration <= 0 {
+		duration = consts.EXPIRED
+	}
+	endpoint := ""
+	if config.DomainSuffix != "" {
+		endpoint = "sts." + config.DomainSuffix
+	}
+	return NewAssumeRoleCredential(keys, AssumeRoleConfig{
+		RoleArn:         config.RoleArn,
+		RoleSessionName: config.RoleSessionName,
+		Duration:        duration,
+		Endpoint:        endpoint,
+		Scheme:          config.Scheme,
+		Transport:       config.Transport,
+	}, stopCh)
+}
+
 // CustomCredential use user defined SecretId and SecretKey
 type CustomCredential struct {
 	lock              sync.Mutex
@@ -21,7 +109,8 @@ type CustomCredential struct {
 	customedSecretKey string
 }
 
-func NewQCloudCredential(clusterId, appId, secretId, secretKey string, expiredDuration time.Duration) QCloudCredential {
+// NewQCloudCredential returns the static SecretId and SecretKey, they never expire
+func NewQCloudCredential(clusterId, appId, secretId, secretKey string) QCloudCredential {
 	return &CustomCredential{
 		customedSecretId:  secretId,
 		customedSecretKey: secretKey,
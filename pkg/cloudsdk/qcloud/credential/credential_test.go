@@ -1 +1,313 @@
 package credential
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func writeSecretFiles(t *testing.T, dir, secretId, secretKey string) {
+	for name, value := range map[string]string{SecretIdKey: secretId, SecretKeyKey: secretKey} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(value+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// eventually returns true if the condition is met in 3 seconds
+func eventually(condition func() bool) bool {
+	for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+	return condition()
+}
+
+func TestFileCredential(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	dir := t.TempDir()
+	if _, err := NewFileCredential(dir, time.Hour, stopCh); err == nil {
+		t.Errorf("expect error of the empty secret directory")
+	}
+
+	writeSecretFiles(t, dir, "id-1", "key-1")
+	// the files are reloaded when they change, long before the interval
+	cred, err := NewFileCredential(dir, time.Hour, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := cred.GetQCloudCredential(); c.SecretId != "id-1" || c.SecretKey != "key-1" || c.Token != "" {
+		t.Errorf("unexpected credential %+v", c)
+	}
+
+	// the rotated secret is reloaded
+	writeSecretFiles(t, dir, "id-2", "key-2")
+	if err = ioutil.WriteFile(filepath.Join(dir, TokenKey), []byte("token-2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	rotated := func() bool {
+		c := cred.GetQCloudCredential()
+		return c.SecretId == "id-2" && c.SecretKey == "key-2" && c.Token == "token-2"
+	}
+	if !eventually(rotated) {
+		t.Errorf("expect the rotated credential, got %+v", cred.GetQCloudCredential())
+	}
+	// the sdk clients read the rotated credential
+	if sdkCred := SDKCredential(cred.GetQCloudCredential); sdkCred.GetSecretId() != "id-2" || sdkCred.GetToken() != "token-2" {
+		t.Errorf("expect the rotated credential of the sdk, got %v", sdkCred.GetSecretId())
+	}
+
+	// the cached credential is used if the reload fails
+	if err = os.Remove(filepath.Join(dir, SecretKeyKey)); err != nil {
+		t.Fatal(err)
+	}
+	if c := cred.GetQCloudCredential(); c.SecretId != "id-2" || c.SecretKey != "key-2" {
+		t.Errorf("expect the cached credential, got %+v", c)
+	}
+}
+
+func TestBackgroundRefresh(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	var loads int32
+	loading := make(chan struct{})
+	cred, err := newRefreshingCredential("test", func() (*common.Credential, time.Time, error) {
+		n := atomic.AddInt32(&loads, 1)
+		if n > 1 {
+			<-loading
+		}
+		return &common.Credential{SecretId: fmt.Sprintf("id-%v", n), SecretKey: "key"}, time.Now().Add(10 * time.Millisecond), nil
+	}, nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !eventually(func() bool { return atomic.LoadInt32(&loads) > 1 }) {
+		t.Fatalf("expect the credential refreshed in the background")
+	}
+	// the cached credential is served while loading
+	got := make(chan *common.Credential)
+	go func() {
+		got <- cred.GetQCloudCredential()
+	}()
+	select {
+	case c := <-got:
+		if c.SecretId != "id-1" {
+			t.Errorf("expect the cached credential, got %+v", c)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expect the credential not blocked by the refresh")
+	}
+	close(loading)
+	if !eventually(func() bool { return cred.GetQCloudCredential().SecretId != "id-1" }) {
+		t.Errorf("expect the refreshed credential, got %+v", cred.GetQCloudCredential())
+	}
+}
+
+func TestRefreshStopped(t *testing.T) {
+	var loads int32
+	stopCh := make(chan struct{})
+	_, err := newRefreshingCredential("test", func() (*common.Credential, time.Time, error) {
+		atomic.AddInt32(&loads, 1)
+		return &common.Credential{SecretId: "id", SecretKey: "key"}, time.Now().Add(time.Millisecond), nil
+	}, nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !eventually(func() bool { return atomic.LoadInt32(&loads) > 1 }) {
+		t.Fatalf("expect the credential refreshed in the background")
+	}
+	close(stopCh)
+	// the refresh in progress may finish after the stop
+	time.Sleep(50 * time.Millisecond)
+	stopped := atomic.LoadInt32(&loads)
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&loads); n != stopped {
+		t.Errorf("expect no refresh after stopped, got %v loads", n-stopped)
+	}
+}
+
+func TestSecretCredential(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "crane-system", Name: "qcloud"},
+		Data:       map[string][]byte{SecretIdKey: []byte("id-1"), SecretKeyKey: []byte("key-1")},
+	}
+	client := fake.NewSimpleClientset(secret)
+	cred, err := NewCredential(Config{SecretName: "crane-system/qcloud"}, client, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := cred.GetQCloudCredential(); c.SecretId != "id-1" || c.SecretKey != "key-1" {
+		t.Errorf("unexpected credential %+v", c)
+	}
+
+	secret.Data[SecretKeyKey] = []byte("key-2")
+	if _, err = client.CoreV1().Secrets("crane-system").Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// the secret is reloaded after the refresh interval
+	cred.(*refreshingCredential).setRefreshAt(time.Time{})
+	if !eventually(func() bool { return cred.GetQCloudCredential().SecretKey == "key-2" }) {
+		t.Errorf("expect the rotated credential, got %+v", cred.GetQCloudCredential())
+	}
+
+	if _, err = NewCredential(Config{SecretName: "crane-system/missing"}, client, stopCh); err == nil {
+		t.Errorf("expect error of the missing secret")
+	}
+}
+
+func TestNewCredential(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	for _, config := range []Config{
+		{SecretName: "qcloud"},
+		{SecretName: "crane-system/qcloud"},
+		{SecretDir: filepath.Join(t.TempDir(), "missing")},
+		{SecretId: "id", SecretKey: "key", RoleArn: "qcs::cam::uin/100000000001:roleName/fadvisor", RoleDurationSeconds: 86400},
+	} {
+		if _, err := NewCredential(config, nil, stopCh); err == nil {
+			t.Errorf("expect error of the config %+v", config)
+		}
+	}
+
+	cred, err := NewCredential(Config{SecretId: "id", SecretKey: "key"}, nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := cred.GetQCloudCredential(); c.SecretId != "id" || c.SecretKey != "key" {
+		t.Errorf("unexpected static credential %+v", c)
+	}
+}
+
+// stsServer is the sts api returning a new temporary credential for each AssumeRole call
+type stsServer struct {
+	lock    sync.Mutex
+	calls   int
+	request map[string]interface{}
+	expiry  time.Duration
+}
+
+func (s *stsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if action := r.Header.Get("X-TC-Action"); action != "AssumeRole" {
+		http.Error(w, "unexpected action "+action, http.StatusBadRequest)
+		return
+	}
+	s.calls++
+	json.NewDecoder(r.Body).Decode(&s.request)
+	json.NewEncoder(w).Encode(map[string]interface{}{"Response": map[string]interface{}{
+		"Credentials": map[string]string{
+			"TmpSecretId":  fmt.Sprintf("tmp-id-%v", s.calls),
+			"TmpSecretKey": fmt.Sprintf("tmp-key-%v", s.calls),
+			"Token":        fmt.Sprintf("token-%v", s.calls),
+		},
+		"ExpiredTime": time.Now().Add(s.expiry).Unix(),
+		"RequestId":   "sts",
+	}})
+}
+
+func TestAssumeRoleCredential(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	sts := &stsServer{expiry: time.Hour}
+	server := httptest.NewServer(sts)
+	defer server.Close()
+
+	keys := NewQCloudCredential("", "", "id", "key")
+	config := AssumeRoleConfig{
+		RoleArn:  "qcs::cam::uin/100000000001:roleName/fadvisor",
+		Duration: time.Hour,
+		Endpoint: strings.TrimPrefix(server.URL, "http://"),
+		Scheme:   "http",
+	}
+	cred, err := NewAssumeRoleCredential(keys, config, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := cred.GetQCloudCredential(); c.SecretId != "tmp-id-1" || c.SecretKey != "tmp-key-1" || c.Token != "token-1" {
+		t.Errorf("unexpected temporary credential %+v", c)
+	}
+	if sts.request["RoleArn"] != config.RoleArn || sts.request["RoleSessionName"] != defaultSessionName || sts.request["DurationSeconds"] != float64(3600) {
+		t.Errorf("unexpected AssumeRole request %v", sts.request)
+	}
+	// the temporary credential is cached before expiry
+	cred.GetQCloudCredential()
+	sts.lock.Lock()
+	if sts.calls != 1 {
+		t.Errorf("expect 1 AssumeRole call, got %v", sts.calls)
+	}
+	sts.lock.Unlock()
+
+	// the role is assumed again with the updated keys
+	if c := cred.UpdateQCloudCustomCredential("id-2", "key-2"); c.SecretId != "tmp-id-2" {
+		t.Errorf("expect the credential assumed by the updated keys, got %+v", c)
+	}
+
+	// the credential expiring soon is refreshed at half of its lifetime, ahead of the expiry in the background
+	sts.lock.Lock()
+	sts.expiry = 2 * time.Second
+	sts.lock.Unlock()
+	rc := cred.(*refreshingCredential)
+	rc.setRefreshAt(time.Time{})
+	if !eventually(func() bool { return cred.GetQCloudCredential().SecretId == "tmp-id-3" }) {
+		t.Fatalf("expect the credential refreshed in the background, got %+v", cred.GetQCloudCredential())
+	}
+	rc.lock.Lock()
+	refreshAt := rc.refreshAt
+	rc.lock.Unlock()
+	if time.Until(refreshAt) > time.Second {
+		t.Errorf("expect refresh in 1 second, got %v", refreshAt)
+	}
+	if !eventually(func() bool { return cred.GetQCloudCredential().SecretId == "tmp-id-4" }) {
+		t.Errorf("expect the credential refreshed before expiry, got %+v", cred.GetQCloudCredential())
+	}
+}
+
+func TestCAMRoleCredential(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/latest/meta-data/cam/security-credentials/fadvisor" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(camRoleResponse{
+			TmpSecretId:  "tmp-id",
+			TmpSecretKey: "tmp-key",
+			Token:        "token",
+			ExpiredTime:  time.Now().Add(time.Hour).Unix(),
+			Code:         "Success",
+		})
+	}))
+	defer server.Close()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	cred, err := NewCAMRoleCredential("fadvisor", server.URL+"/latest/meta-data/", stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := cred.GetQCloudCredential(); c.SecretId != "tmp-id" || c.SecretKey != "tmp-key" || c.Token != "token" {
+		t.Errorf("unexpected cam role credential %+v", c)
+	}
+	if _, err = NewCAMRoleCredential("unbound", server.URL+"/latest/meta-data/", stopCh); err == nil {
+		t.Errorf("expect error of the role not bound to the cvm")
+	}
+}
@@ -0,0 +1,234 @@
+package credential
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// SecretIdKey, SecretKeyKey and TokenKey are the file names of the mounted secret and the keys of the kubernetes secret
+	SecretIdKey  = "secretId"
+	SecretKeyKey = "secretKey"
+	TokenKey     = "token"
+
+	// DefaultRefreshInterval is the interval the secret files or the kubernetes secret are reloaded. it is the rotation
+	// latency of the kubernetes secret, the secret files are also reloaded when they change
+	DefaultRefreshInterval = time.Minute
+	// retryInterval is the interval to retry the failed reload, the cached credential is used meanwhile
+	retryInterval = 10 * time.Second
+	// watchDelay is the delay to reload the changed secret files, so the files written together are read together
+	watchDelay = time.Second
+)
+
+// loadFunc loads the credential and returns the time it must be reloaded
+type loadFunc func() (*common.Credential, time.Time, error)
+
+// refreshingCredential caches the credential of the source and reloads it in the background at the refresh time,
+// which is ahead of the expiry of the temporary credentials. the cached credential is kept if the reload fails.
+// the background refresh exits when stopCh is closed.
+type refreshingCredential struct {
+	lock      sync.Mutex
+	source    string
+	load      loadFunc
+	cred      *common.Credential
+	refreshAt time.Time
+	// keys is the credential assuming the role, the custom keys update it
+	keys QCloudCredential
+
+	// refreshLock serializes the loads, the cached credential is served while loading
+	refreshLock sync.Mutex
+	// wake wakes the background refresh when the refresh time is changed
+	wake chan struct{}
+}
+
+func newRefreshingCredential(source string, load loadFunc, keys QCloudCredential, stopCh <-chan struct{}) (*refreshingCredential, error) {
+	c := &refreshingCredential{source: source, load: load, keys: keys, wake: make(chan struct{}, 1)}
+	if err := c.refresh(); err != nil {
+		return nil, fmt.Errorf("failed to load the credential of %v: %v", source, err)
+	}
+	go c.run(stopCh)
+	return c, nil
+}
+
+// refresh loads the credential and caches it, the lock is not held while loading
+func (c *refreshingCredential) refresh() error {
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+	cred, refreshAt, err := c.load()
+	if err != nil {
+		return err
+	}
+	if cred.SecretId == "" || cred.SecretKey == "" {
+		return fmt.Errorf("empty secret id or key")
+	}
+
+	c.lock.Lock()
+	if c.cred != nil && (c.cred.SecretId != cred.SecretId || c.cred.SecretKey != cred.SecretKey || c.cred.Token != cred.Token) {
+		klog.Infof("Rotated the credential of %v", c.source)
+	}
+	c.cred = cred
+	c.lock.Unlock()
+	c.setRefreshAt(refreshAt)
+	return nil
+}
+
+// run refreshes the credential at the refresh time until stopCh is closed, the refresh is retried if it fails
+func (c *refreshingCredential) run(stopCh <-chan struct{}) {
+	for {
+		c.lock.Lock()
+		timer := time.NewTimer(time.Until(c.refreshAt))
+		c.lock.Unlock()
+		select {
+		case <-timer.C:
+			if err := c.refresh(); err != nil {
+				klog.Errorf("Failed to refresh the credential of %v, use the cached one: %v", c.source, err)
+				c.lock.Lock()
+				c.refreshAt = time.Now().Add(retryInterval)
+				c.lock.Unlock()
+			}
+		case <-c.wake:
+			timer.Stop()
+		case <-stopCh:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// watch reloads the credential shortly after the files of the directory change until stopCh is closed.
+// kubernetes updates the mounted secret by swapping the ..data symlink, which is an event of the directory.
+func (c *refreshingCredential) watch(dir string, stopCh <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err = watcher.Add(dir); err != nil {
+		watcher.Close()
+		return err
+	}
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				klog.V(4).Infof("The %v is changed: %v", c.source, event)
+				c.setRefreshAt(time.Now().Add(watchDelay))
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				klog.Errorf("Failed to watch the %v: %v", c.source, err)
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// setRefreshAt changes the refresh time and wakes the background refresh
+func (c *refreshingCredential) setRefreshAt(refreshAt time.Time) {
+	c.lock.Lock()
+	c.refreshAt = refreshAt
+	c.lock.Unlock()
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (c *refreshingCredential) GetQCloudCredential() *common.Credential {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return &common.Credential{SecretId: c.cred.SecretId, SecretKey: c.cred.SecretKey, Token: c.cred.Token}
+}
+
+// UpdateQCloudCustomCredential updates the keys assuming the role, other sources are overridden until the next reload
+func (c *refreshingCredential) UpdateQCloudCustomCredential(secretId, secretKey string) *common.Credential {
+	if c.keys != nil {
+		// assume the role again with the new keys
+		c.keys.UpdateQCloudCustomCredential(secretId, secretKey)
+		if err := c.refresh(); err != nil {
+			klog.Errorf("Failed to assume the role of %v with the updated keys, use the cached credential: %v", c.source, err)
+		}
+		return c.GetQCloudCredential()
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	klog.Warningf("The credential of %v is overridden until the next reload", c.source)
+	c.cred = &common.Credential{SecretId: secretId, SecretKey: secretKey}
+	return &common.Credential{SecretId: secretId, SecretKey: secretKey}
+}
+
+// NewFileCredential returns the credential of the mounted secret directory, the files are reloaded when they change
+// and every interval in case a change is missed, so the rotated secret is used without restart.
+func NewFileCredential(dir string, interval time.Duration, stopCh <-chan struct{}) (QCloudCredential, error) {
+	read := func(name string, optional bool) (string, error) {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if optional && os.IsNotExist(err) {
+			return "", nil
+		}
+		return strings.TrimSpace(string(data)), err
+	}
+	c, err := newRefreshingCredential("secret directory "+dir, func() (*common.Credential, time.Time, error) {
+		cred := &common.Credential{}
+		var err error
+		if cred.SecretId, err = read(SecretIdKey, false); err != nil {
+			return nil, time.Time{}, err
+		}
+		if cred.SecretKey, err = read(SecretKeyKey, false); err != nil {
+			return nil, time.Time{}, err
+		}
+		if cred.Token, err = read(TokenKey, true); err != nil {
+			return nil, time.Time{}, err
+		}
+		return cred, time.Now().Add(interval), nil
+	}, nil, stopCh)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.watch(dir, stopCh); err != nil {
+		klog.Warningf("Failed to watch the secret directory %v, it is reloaded every %v: %v", dir, interval, err)
+	}
+	return c, nil
+}
+
+// NewSecretCredential returns the credential of the kubernetes secret, the secret is reloaded through the api server every interval,
+// so the interval is the latency of using the rotated secret.
+func NewSecretCredential(client kubernetes.Interface, namespace, name string, interval time.Duration, stopCh <-chan struct{}) (QCloudCredential, error) {
+	return newRefreshingCredential("secret "+namespace+"/"+name, func() (*common.Credential, time.Time, error) {
+		secret, err := client.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		cred := &common.Credential{
+			SecretId:  strings.TrimSpace(string(secret.Data[SecretIdKey])),
+			SecretKey: strings.TrimSpace(string(secret.Data[SecretKeyKey])),
+			Token:     strings.TrimSpace(string(secret.Data[TokenKey])),
+		}
+		return cred, time.Now().Add(interval), nil
+	}, nil, stopCh)
+}
+
+// SDKCredential is set to the sdk clients once, the sdk calls it for the current credential on each request,
+// so the rotated or updated credential is used without changing the shared clients
+type SDKCredential func() *common.Credential
+
+func (f SDKCredential) GetSecretId() string  { return f().SecretId }
+func (f SDKCredential) GetSecretKey() string { return f().SecretKey }
+func (f SDKCredential) GetToken() string     { return f().Token }
@@ -0,0 +1,143 @@
+package credential
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/regions"
+)
+
+const (
+	// DefaultMetadataURL is the metadata server of the cvm
+	DefaultMetadataURL = "http://metadata.tencentyun.com/latest/meta-data/"
+
+	stsEndpoint        = "sts.tencentcloudapi.com"
+	stsVersion         = "2018-08-13"
+	defaultSessionName = "fadvisor"
+	// refreshBeforeExpiry is the time the temporary credential is refreshed before it expires
+	refreshBeforeExpiry = 5 * time.Minute
+)
+
+// refreshTime returns the time to refresh the temporary credential expiring at the time, it is at most half of the lifetime ahead
+func refreshTime(expiration time.Time) time.Time {
+	ahead := refreshBeforeExpiry
+	if lifetime := time.Until(expiration); lifetime < 2*ahead {
+		ahead = lifetime / 2
+	}
+	return expiration.Add(-ahead)
+}
+
+// AssumeRoleConfig is the role assumed by the sts api
+type AssumeRoleConfig struct {
+	RoleArn         string
+	RoleSessionName string
+	// Duration is the lifetime of the temporary credential, at most 12 hours
+	Duration time.Duration
+	// Endpoint and Scheme of the sts api, default https://sts.tencentcloudapi.com
+	Endpoint  string
+	Scheme    string
+	Transport http.RoundTripper
+}
+
+type assumeRoleResponse struct {
+	Response struct {
+		Credentials struct {
+			Token        string `json:"Token"`
+			TmpSecretId  string `json:"TmpSecretId"`
+			TmpSecretKey string `json:"TmpSecretKey"`
+		} `json:"Credentials"`
+		ExpiredTime int64 `json:"ExpiredTime"`
+	} `json:"Response"`
+}
+
+// NewAssumeRoleCredential returns the temporary credential of the role assumed by the keys, it is refreshed before expiry
+func NewAssumeRoleCredential(keys QCloudCredential, config AssumeRoleConfig, stopCh <-chan struct{}) (QCloudCredential, error) {
+	if config.Duration <= 0 || config.Duration > 12*time.Hour {
+		return nil, fmt.Errorf("invalid duration %v of role %v, it must be in (0, 12h]", config.Duration, config.RoleArn)
+	}
+	if config.RoleSessionName == "" {
+		config.RoleSessionName = defaultSessionName
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = stsEndpoint
+	}
+	return newRefreshingCredential("role "+config.RoleArn, func() (*common.Credential, time.Time, error) {
+		prof := profile.NewClientProfile()
+		prof.HttpProfile.Endpoint = config.Endpoint
+		if config.Scheme != "" {
+			prof.HttpProfile.Scheme = config.Scheme
+		}
+		client := common.NewCommonClient(keys.GetQCloudCredential(), regions.Guangzhou, prof)
+		if config.Transport != nil {
+			client.WithHttpTransport(config.Transport)
+		}
+		request := tchttp.NewCommonRequest("sts", stsVersion, "AssumeRole")
+		err := request.SetActionParameters(map[string]interface{}{
+			"RoleArn":         config.RoleArn,
+			"RoleSessionName": config.RoleSessionName,
+			"DurationSeconds": int64(config.Duration.Seconds()),
+		})
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		response := tchttp.NewCommonResponse()
+		if err = client.Send(request, response); err != nil {
+			return nil, time.Time{}, err
+		}
+		var resp assumeRoleResponse
+		if err = json.Unmarshal(response.GetBody(), &resp); err != nil {
+			return nil, time.Time{}, err
+		}
+		cred := &common.Credential{
+			SecretId:  resp.Response.Credentials.TmpSecretId,
+			SecretKey: resp.Response.Credentials.TmpSecretKey,
+			Token:     resp.Response.Credentials.Token,
+		}
+		return cred, refreshTime(time.Unix(resp.Response.ExpiredTime, 0)), nil
+	}, keys, stopCh)
+}
+
+type camRoleResponse struct {
+	TmpSecretId  string `json:"TmpSecretId"`
+	TmpSecretKey string `json:"TmpSecretKey"`
+	Token        string `json:"Token"`
+	ExpiredTime  int64  `json:"ExpiredTime"`
+	Code         string `json:"Code"`
+}
+
+// NewCAMRoleCredential returns the temporary credential of the cam role bound to the cvm, it is got from the metadata server
+// and refreshed before expiry.
+func NewCAMRoleCredential(roleName, metadataURL string, stopCh <-chan struct{}) (QCloudCredential, error) {
+	url := strings.TrimSuffix(metadataURL, "/") + "/cam/security-credentials/" + roleName
+	client := &http.Client{Timeout: 10 * time.Second}
+	return newRefreshingCredential("cam role "+roleName, func() (*common.Credential, time.Time, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, time.Time{}, fmt.Errorf("metadata server returns %v, the role may be not bound to the cvm: %s", resp.StatusCode, body)
+		}
+		var role camRoleResponse
+		if err = json.Unmarshal(body, &role); err != nil {
+			return nil, time.Time{}, err
+		}
+		if role.Code != "Success" {
+			return nil, time.Time{}, fmt.Errorf("metadata server returns code %v", role.Code)
+		}
+		cred := &common.Credential{SecretId: role.TmpSecretId, SecretKey: role.TmpSecretKey, Token: role.Token}
+		return cred, refreshTime(time.Unix(role.ExpiredTime, 0)), nil
+	}, nil, stopCh)
+}
@@ -20,10 +20,18 @@ import (
 	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/metrics"
 )
 
+// CVM is the cvm apis used by the cloud provider
 type CVM interface {
+	UpdateCredential(cred credential.QCloudCredential)
 	DescribeZoneInstanceConfigInfos() ([]*cvm.InstanceTypeQuotaItem, error)
+	GetAllZoneInstanceConfigInfos() ([]*cvm.InstanceTypeQuotaItem, error)
+	GetCVMInstances(instanceIds []*string) ([]*cvm.Instance, error)
+	GetCVMInstancesPrice(cvmInstances []*cvm.Instance) ([]*QCloudInstancePrice, error)
+	GetCVMInstancesInquiryPrice(cvmInstances []*cvm.Instance) ([]*QCloudInstancePrice, error)
 }
 
+var _ CVM = &CVMClient{}
+
 type CVMClient struct {
 	clientLock    sync.Mutex
 	client        *cvm.Client
@@ -103,6 +111,14 @@ func (qcc *CVMClient) getQCloudCredential() *common.Credential {
 	return qcc.config.Credential.GetQCloudCredential()
 }
 
+// currentCredential return the current credential to the sdk client on each request, the credential may be rotated
+// or updated since the client is created
+func (qcc *CVMClient) currentCredential() *common.Credential {
+	qcc.clientLock.Lock()
+	defer qcc.clientLock.Unlock()
+	return qcc.getQCloudCredential()
+}
+
 // GetAllRegions
 func (qcc *CVMClient) getAllRegions() ([]*cvm.RegionInfo, error) {
 	req := cvm.NewDescribeRegionsRequest()
@@ -119,12 +135,12 @@ func (qcc *CVMClient) getAllRegions() ([]*cvm.RegionInfo, error) {
 }
 
 func (qcc *CVMClient) getDefaultClient() (*cvm.Client, error) {
-	cred := qcc.getQCloudCredential()
-	var err error
-
 	qcc.clientLock.Lock()
 	defer qcc.clientLock.Unlock()
 
+	cred := qcc.getQCloudCredential()
+	var err error
+
 	if qcc.defaultClient == nil {
 		prof := profile.NewClientProfile()
 		prof.Language = qcc.config.DefaultLanguage
@@ -135,8 +151,11 @@ func (qcc *CVMClient) getDefaultClient() (*cvm.Client, error) {
 		if err != nil {
 			return qcc.defaultClient, err
 		}
+		if qcc.config.Transport != nil {
+			qcc.defaultClient.WithHttpTransport(qcc.config.Transport)
+		}
+		qcc.defaultClient.WithCredential(credential.SDKCredential(qcc.currentCredential))
 	}
-	qcc.defaultClient.WithCredential(cred)
 	if qcc.config.Debug {
 		SecretId := cred.GetSecretId()
 		SecretKey := cred.GetSecretKey()
@@ -162,6 +181,10 @@ func (qcc *CVMClient) getClient() (*cvm.Client, error) {
 		if err != nil {
 			return qcc.client, err
 		}
+		if qcc.config.Transport != nil {
+			qcc.client.WithHttpTransport(qcc.config.Transport)
+		}
+		qcc.client.WithCredential(credential.SDKCredential(qcc.currentCredential))
 	}
 	if qcc.config.Debug {
 		SecretId := cred.GetSecretId()
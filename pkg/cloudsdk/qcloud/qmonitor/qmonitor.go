@@ -79,6 +79,12 @@ var (
 	LabelWorkloadKind  = "workload_kind"
 	LabelWorkloadName  = "workload_name"
 
+	// cvm instance metrics, the dimension is the instance id
+	CVMNamespace      = "QCE/CVM"
+	CVMCpuUsageMetric = "CPUUsage"
+	CVMMemUsedMetric  = "MemUsed"
+	LabelInstanceId   = "InstanceId"
+
 	// View
 	ViewK8sContainer = "k8s_container"
 	ViewK8sCluster   = "k8s_cluster"
@@ -88,6 +94,16 @@ var (
 	ViewK8sWorkload  = "k8s_workload"
 )
 
+// QCloudMonitor is the cloud monitor apis used by the data source
+type QCloudMonitor interface {
+	UpdateCredential(cred credential.QCloudCredential)
+	EnableDebug() bool
+	DescribeStatisticData(ctx context.Context, req *GetDataParam) (*GetDataResult, error)
+	GetInstanceMonitorData(ctx context.Context, req *GetInstanceDataParam) (*GetDataResult, error)
+}
+
+var _ QCloudMonitor = &QCloudMonitorClient{}
+
 type QCloudMonitorClient struct {
 	clientLock sync.Mutex
 	client     *cm.Client
@@ -163,6 +179,14 @@ func (qcc *QCloudMonitorClient) getQCloudCredential() *common.Credential {
 	return qcc.config.Credential.GetQCloudCredential()
 }
 
+// currentCredential return the current credential to the sdk client on each request, the credential may be rotated
+// or updated since the client is created
+func (qcc *QCloudMonitorClient) currentCredential() *common.Credential {
+	qcc.clientLock.Lock()
+	defer qcc.clientLock.Unlock()
+	return qcc.getQCloudCredential()
+}
+
 func (qcc *QCloudMonitorClient) getClient() (*cm.Client, error) {
 	qcc.clientLock.Lock()
 	defer qcc.clientLock.Unlock()
@@ -179,6 +203,10 @@ func (qcc *QCloudMonitorClient) getClient() (*cm.Client, error) {
 		if err != nil {
 			return qcc.client, err
 		}
+		if qcc.config.Transport != nil {
+			qcc.client.WithHttpTransport(qcc.config.Transport)
+		}
+		qcc.client.WithCredential(credential.SDKCredential(qcc.currentCredential))
 	}
 	if qcc.config.Debug {
 		SecretId := cred.GetSecretId()
@@ -313,6 +341,45 @@ func (qcc *QCloudMonitorClient) DescribeStatisticData(ctx context.Context, req *
 	return result, nil
 }
 
+// GetInstanceMonitorData gets the metric of the cloud product instances, such as the cvm instances by the InstanceId dimension
+func (qcc *QCloudMonitorClient) GetInstanceMonitorData(ctx context.Context, req *GetInstanceDataParam) (*GetDataResult, error) {
+	cli, err := qcc.getClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := qcc.GetMonitorDataWithRetry(cli, req.Convert2SDKRequest())
+	if err != nil {
+		return nil, err
+	}
+	result := &GetDataResult{StartTime: req.StartTime, EndTime: req.EndTime, Period: req.Period}
+	if resp.Response == nil {
+		klog.Error(fmt.Errorf("qcloudClient tencent cloud api nil response, req: %+v, resp: %v", *req, resp.ToJsonString()))
+		return result, nil
+	}
+	if resp.Response.Period != nil {
+		result.Period = *resp.Response.Period
+	}
+	datum := MetricDatum{MetricName: req.MetricName}
+	for _, dataPoint := range resp.Response.DataPoints {
+		var point MetricDataPoint
+		for _, dim := range dataPoint.Dimensions {
+			if dim.Name != nil && dim.Value != nil {
+				point.Dimensions = append(point.Dimensions, Dimension{Name: *dim.Name, Value: *dim.Value})
+			}
+		}
+		for i, ts := range dataPoint.Timestamps {
+			if ts == nil || i >= len(dataPoint.Values) || dataPoint.Values[i] == nil {
+				continue
+			}
+			timestamp := uint64(*ts)
+			point.Values = append(point.Values, Point{Timestamp: &timestamp, Value: dataPoint.Values[i]})
+		}
+		datum.Points = append(datum.Points, point)
+	}
+	result.Data = []MetricDatum{datum}
+	return result, nil
+}
+
 func (qcc *QCloudMonitorClient) PutMonitorData(ctx context.Context, req *cm.PutMonitorDataRequest) (*cm.PutMonitorDataResponse, error) {
 	cli, err := qcc.getClient()
 	if err != nil {
@@ -332,6 +399,33 @@ type GetDataParam struct {
 	GroupBys    []string
 }
 
+// GetInstanceDataParam is the param to get the metric of the instances, the instances are the dimensions of the instances
+type GetInstanceDataParam struct {
+	Namespace  string
+	MetricName string
+	Instances  [][]Dimension
+	Period     uint64
+	StartTime  string
+	EndTime    string
+}
+
+func (p *GetInstanceDataParam) Convert2SDKRequest() *cm.GetMonitorDataRequest {
+	req := cm.NewGetMonitorDataRequest()
+	req.Namespace = &p.Namespace
+	req.MetricName = &p.MetricName
+	req.Period = &p.Period
+	req.StartTime = &p.StartTime
+	req.EndTime = &p.EndTime
+	for i := range p.Instances {
+		instance := &cm.Instance{}
+		for j := range p.Instances[i] {
+			instance.Dimensions = append(instance.Dimensions, &cm.Dimension{Name: &p.Instances[i][j].Name, Value: &p.Instances[i][j].Value})
+		}
+		req.Instances = append(req.Instances, instance)
+	}
+	return req
+}
+
 type PutDataParam struct {
 	// 一组指标和数据
 	Metrics []MetricDatum `json:"Metrics,omitempty" name:"Metrics"`
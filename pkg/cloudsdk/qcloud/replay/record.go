@@ -0,0 +1,103 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// RecordingTransport sends the requests by the base transport and appends the exchanges to the recordings in the dir,
+// the recordings are named by service.Action.json and replayed by the server. The authorization header is not recorded,
+// but the responses may have the account data such as the instance ids and ips, review them before committing.
+type RecordingTransport struct {
+	base http.RoundTripper
+	dir  string
+
+	lock sync.Mutex
+}
+
+// NewRecordingTransport returns the transport recording the exchanges in the dir, the default transport is used if base is nil
+func NewRecordingTransport(base http.RoundTripper, dir string) *RecordingTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RecordingTransport{base: base, dir: dir}
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	r := req.Clone(req.Context())
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		return resp, err
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	service := strings.SplitN(host, ".", 2)[0]
+	action := req.Header.Get("X-TC-Action")
+	if values := req.Header["X-TC-Action"]; action == "" && len(values) > 0 {
+		// the sdk sets the header without canonicalizing the key
+		action = values[0]
+	}
+	if err = t.record(service, action, body, data); err != nil {
+		klog.Errorf("Failed to record %v.%v: %v", service, action, err)
+	}
+	return resp, nil
+}
+
+// record appends the exchange to the recording of the action
+func (t *RecordingTransport) record(service, action string, request, response []byte) error {
+	if service == "" || action == "" {
+		return fmt.Errorf("no service or action of the request")
+	}
+	if !json.Valid(response) {
+		return fmt.Errorf("the response is not json")
+	}
+	exchange := Exchange{Response: response}
+	if len(request) > 0 && json.Valid(request) {
+		exchange.Request = request
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	file := filepath.Join(t.dir, service+"."+action+".json")
+	var exchanges []Exchange
+	data, err := ioutil.ReadFile(file)
+	if err == nil {
+		if err = json.Unmarshal(data, &exchanges); err != nil {
+			return fmt.Errorf("failed to parse recording %v: %v", file, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	exchanges = append(exchanges, exchange)
+	if data, err = json.MarshalIndent(exchanges, "", "  "); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(data, '\n'), 0644)
+}
@@ -0,0 +1,244 @@
+// Package replay is a local stand-in of the tencent cloud api, it replays the recorded responses of the api actions,
+// so that the sdk clients and the cloud provider can be tested offline.
+//
+// The recordings were written after the response examples of the tencent cloud api documentation, trimmed to the fields
+// read by fadvisor, and the ids and prices were made up. To capture the responses of the real cloud api, remove the
+// recordings to replace and run
+//
+//	FADVISOR_RECORD_SECRET_ID=... FADVISOR_RECORD_SECRET_KEY=... FADVISOR_RECORD_REGION=ap-guangzhou \
+//	FADVISOR_RECORD_INSTANCES=ins-xxx,ins-yyy go test ./pkg/cloudsdk/qcloud/replay -run TestRecordCloudAPI
+//
+// or set RecordingTransport as the transport of the client config of any other calls.
+package replay
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/flowcontrol"
+
+	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud"
+	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/consts"
+	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/credential"
+)
+
+// DomainSuffix is the domain suffix of the client config, the requests to all services are sent to the server
+const DomainSuffix = "tencentcloudapi.com"
+
+// recordings are named by service.Action.json, such as cvm.DescribeInstances.json
+//
+//go:embed recordings/*.json
+var recordings embed.FS
+
+// Exchange is a recorded api call, the response is replayed for the requests which contain all the fields of the recorded request
+type Exchange struct {
+	// Request is the fields the request body must contain, nil matches all requests
+	Request json.RawMessage `json:"request,omitempty"`
+	// Response is the body of the response, which is {"Response": {...}}
+	Response json.RawMessage `json:"response"`
+}
+
+// Call is the request received by the server
+type Call struct {
+	Service string
+	Action  string
+	Header  http.Header
+	Body    json.RawMessage
+}
+
+// Server replays the recorded responses, the service is the first label of the request host and the action is the X-TC-Action header
+type Server struct {
+	*httptest.Server
+
+	lock      sync.Mutex
+	exchanges map[string][]Exchange
+	calls     []Call
+}
+
+// NewServer starts the server replaying the embedded recordings
+func NewServer() (*Server, error) {
+	s := &Server{exchanges: make(map[string][]Exchange)}
+	files, err := recordings.ReadDir("recordings")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), ".json")
+		parts := strings.SplitN(name, ".", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid recording %v, the name must be service.Action.json", file.Name())
+		}
+		data, err := recordings.ReadFile(path.Join("recordings", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var exchanges []Exchange
+		if err = json.Unmarshal(data, &exchanges); err != nil {
+			return nil, fmt.Errorf("failed to parse recording %v: %v", file.Name(), err)
+		}
+		s.exchanges[name] = exchanges
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s, nil
+}
+
+// Record adds the exchange of the action, it takes precedence over the recorded exchanges
+func (s *Server) Record(service, action string, request, response interface{}) error {
+	exchange := Exchange{}
+	if request != nil {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		exchange.Request = data
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	exchange.Response = data
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := service + "." + action
+	s.exchanges[key] = append([]Exchange{exchange}, s.exchanges[key]...)
+	return nil
+}
+
+// Calls returns the requests of the action received by the server
+func (s *Server) Calls(service, action string) []Call {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var calls []Call
+	for _, call := range s.calls {
+		if call.Service == service && call.Action == action {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	service := strings.SplitN(r.Host, ".", 2)[0]
+	action := r.Header.Get("X-TC-Action")
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, "InternalError", err.Error())
+		return
+	}
+	if len(body) == 0 {
+		body = []byte("{}")
+	}
+	var request interface{}
+	if err = json.Unmarshal(body, &request); err != nil {
+		writeError(w, "InvalidParameter", fmt.Sprintf("the request body is not json: %v", err))
+		return
+	}
+
+	s.lock.Lock()
+	s.calls = append(s.calls, Call{Service: service, Action: action, Header: r.Header, Body: body})
+	exchanges := s.exchanges[service+"."+action]
+	s.lock.Unlock()
+
+	for _, exchange := range exchanges {
+		if exchange.Request != nil {
+			var recorded interface{}
+			if err = json.Unmarshal(exchange.Request, &recorded); err != nil || !contains(request, recorded) {
+				continue
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(exchange.Response)
+		return
+	}
+	writeError(w, "ResourceNotFound", fmt.Sprintf("no recorded response of %v.%v for %s", service, action, body))
+}
+
+// contains returns true if the actual json value contains all the fields of the recorded one, arrays must have the same length
+func contains(actual, recorded interface{}) bool {
+	switch r := recorded.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range r {
+			if !contains(a[key], value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(a) != len(r) {
+			return false
+		}
+		for i := range r {
+			if !contains(a[i], r[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(actual, recorded)
+	}
+}
+
+// writeError writes the error response of the tencent cloud api, which is parsed as TencentCloudSDKError by the sdk
+func writeError(w http.ResponseWriter, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"Response": map[string]interface{}{
+			"Error":     map[string]string{"Code": code, "Message": message},
+			"RequestId": "replay",
+		},
+	})
+}
+
+// Transport returns the transport sending the requests of all services to the server, the request host is kept
+func (s *Server) Transport() http.RoundTripper {
+	target, _ := url.Parse(s.URL)
+	return &transport{target: target, base: s.Client().Transport}
+}
+
+type transport struct {
+	target *url.URL
+	base   http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	if r.Host == "" {
+		r.Host = req.URL.Host
+	}
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+	return t.base.RoundTrip(r)
+}
+
+// ClientConfig returns the config of the sdk clients calling the server
+func (s *Server) ClientConfig(region string) *qcloud.QCloudClientConfig {
+	return &qcloud.QCloudClientConfig{
+		RateLimiter: flowcontrol.NewFakeAlwaysRateLimiter(),
+		Credential:  credential.NewFakeCred("replay-secret-id", "replay-secret-key", time.Hour),
+		Transport:   s.Transport(),
+		QCloudClientProfile: qcloud.QCloudClientProfile{
+			DefaultLimit:    consts.LIMITS,
+			DefaultLanguage: consts.LANGUAGE,
+			DefaultTimeout:  consts.TIMEOUT,
+			Region:          region,
+			DomainSuffix:    DomainSuffix,
+			Scheme:          "http",
+		},
+	}
+}
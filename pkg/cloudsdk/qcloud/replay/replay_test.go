@@ -0,0 +1,159 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+	"k8s.io/client-go/util/flowcontrol"
+
+	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud"
+	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/consts"
+	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/credential"
+	sdkcvm "github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/cvm"
+	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/qmonitor"
+)
+
+func TestReplayCVM(t *testing.T) {
+	server, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	client := sdkcvm.NewCVMClient(server.ClientConfig("ap-guangzhou"))
+
+	instances, err := client.GetCVMInstances(common.StringPtrs([]string{"ins-2jv4wpmr", "ins-5ab3x7kq", "ins-9terminated"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 2 || *instances[0].InstanceId != "ins-2jv4wpmr" || *instances[1].InstanceChargeType != "PREPAID" {
+		t.Fatalf("unexpected instances %v", instances)
+	}
+	calls := server.Calls("cvm", "DescribeInstances")
+	if len(calls) != 1 || !strings.Contains(string(calls[0].Body), `"Limit":100`) {
+		t.Errorf("unexpected DescribeInstances calls %v", calls)
+	}
+
+	prices, err := client.GetCVMInstancesInquiryPrice(instances)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prices) != 2 || *prices[0].Price.InstancePrice.UnitPrice != 0.36 || *prices[1].Price.InstancePrice.OriginalPrice != 576 {
+		t.Errorf("unexpected inquiry prices %v", prices)
+	}
+
+	// the request without recorded response gets the error of the cloud api
+	if _, err = client.GetCVMInstances(common.StringPtrs([]string{"ins-unknown"})); err == nil || !strings.Contains(err.Error(), "ResourceNotFound") {
+		t.Errorf("expect ResourceNotFound error, got %v", err)
+	}
+
+	// the recorded exchange takes precedence
+	instanceType := "S5.SMALL2"
+	err = server.Record("cvm", "DescribeInstances", map[string]interface{}{"InstanceIds": []string{"ins-unknown"}},
+		map[string]interface{}{"Response": map[string]interface{}{
+			"InstanceSet": []*cvm.Instance{{InstanceId: common.StringPtr("ins-unknown"), InstanceType: &instanceType}},
+		}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	instances, err = client.GetCVMInstances(common.StringPtrs([]string{"ins-unknown"}))
+	if err != nil || len(instances) != 1 || *instances[0].InstanceType != instanceType {
+		t.Errorf("unexpected recorded instances %v, %v", instances, err)
+	}
+}
+
+func TestReplayMonitor(t *testing.T) {
+	server, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	client := qmonitor.NewQCloudMonitorClient(server.ClientConfig("ap-guangzhou"))
+
+	req := &qmonitor.GetDataParam{
+		Module:      qmonitor.MetricsModule,
+		Namespace:   qmonitor.MetricsNamespace,
+		MetricNames: []string{qmonitor.K8sWorkloadCpuCoreUsedMetric},
+		StartTime:   "2022-04-16T12:00:00+08:00",
+		EndTime:     "2022-04-16T12:02:00+08:00",
+		Period:      60,
+	}
+	req.AppendCondition(qmonitor.LabelClusterId, "=", []string{"cls-7ldf3a1c"})
+	result, err := client.DescribeStatisticData(context.TODO(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Period != 60 || len(result.Data) != 1 || len(result.Data[0].Points) != 1 {
+		t.Fatalf("unexpected statistic data %+v", result)
+	}
+	point := result.Data[0].Points[0]
+	if point.Dimensions2Map()[qmonitor.LabelWorkloadName] != "nginx" || len(point.Values) != 2 || *point.Values[1].Value != 0.1325 {
+		t.Errorf("unexpected data point %+v", point)
+	}
+}
+
+func TestRecordingTransport(t *testing.T) {
+	server, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	dir := t.TempDir()
+	config := server.ClientConfig("ap-guangzhou")
+	config.Transport = NewRecordingTransport(server.Transport(), dir)
+	client := sdkcvm.NewCVMClient(config)
+
+	if _, err = client.GetCVMInstances(common.StringPtrs([]string{"ins-2jv4wpmr", "ins-5ab3x7kq", "ins-9terminated"})); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "cvm.DescribeInstances.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exchanges []Exchange
+	if err = json.Unmarshal(data, &exchanges); err != nil {
+		t.Fatal(err)
+	}
+	if len(exchanges) != 1 || !strings.Contains(string(exchanges[0].Request), "ins-2jv4wpmr") || !strings.Contains(string(exchanges[0].Response), "InstanceSet") {
+		t.Errorf("unexpected recorded exchanges %s", data)
+	}
+}
+
+// TestRecordCloudAPI captures the responses of the real cloud api into the recordings, it is skipped unless
+// FADVISOR_RECORD_SECRET_ID, FADVISOR_RECORD_SECRET_KEY, FADVISOR_RECORD_REGION and FADVISOR_RECORD_INSTANCES are set.
+func TestRecordCloudAPI(t *testing.T) {
+	secretId, secretKey := os.Getenv("FADVISOR_RECORD_SECRET_ID"), os.Getenv("FADVISOR_RECORD_SECRET_KEY")
+	region, instanceIds := os.Getenv("FADVISOR_RECORD_REGION"), os.Getenv("FADVISOR_RECORD_INSTANCES")
+	if secretId == "" || secretKey == "" || region == "" || instanceIds == "" {
+		t.Skip("no credential of the cloud api to record")
+	}
+	client := sdkcvm.NewCVMClient(&qcloud.QCloudClientConfig{
+		RateLimiter: flowcontrol.NewTokenBucketRateLimiter(5, 1),
+		Credential:  credential.NewQCloudCredential("", "", secretId, secretKey),
+		Transport:   NewRecordingTransport(nil, "recordings"),
+		QCloudClientProfile: qcloud.QCloudClientProfile{
+			DefaultLimit:    consts.LIMITS,
+			DefaultLanguage: consts.LANGUAGE,
+			DefaultTimeout:  consts.TIMEOUT,
+			Region:          region,
+			DomainSuffix:    DomainSuffix,
+			Scheme:          "https",
+		},
+	})
+	if _, err := client.GetAllZoneInstanceConfigInfos(); err != nil {
+		t.Fatal(err)
+	}
+	instances, err := client.GetCVMInstances(common.StringPtrs(strings.Split(instanceIds, ",")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.GetCVMInstancesPrice(instances); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -20,6 +20,16 @@ import (
 	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/metrics"
 )
 
+// TKE is the tke apis used by the cloud provider
+type TKE interface {
+	UpdateCredential(cred credential.QCloudCredential)
+	GetEKSPodPrice(req *tke.GetPriceRequest) (*tke.GetPriceResponse, error)
+	GetPodSpecification(req *tke.GetPodSpecificationRequest) (*tke.GetPodSpecificationResponse, error)
+	Pod2EKSSpecConverter(pod *v1.Pod) (v1.ResourceList, error)
+}
+
+var _ TKE = &TKEClient{}
+
 type TKEClient struct {
 	clientLock sync.Mutex
 	client     *tke.Client
@@ -100,6 +110,14 @@ func (qcc *TKEClient) getQCloudCredential() *common.Credential {
 	return qcc.config.Credential.GetQCloudCredential()
 }
 
+// currentCredential return the current credential to the sdk client on each request, the credential may be rotated
+// or updated since the client is created
+func (qcc *TKEClient) currentCredential() *common.Credential {
+	qcc.clientLock.Lock()
+	defer qcc.clientLock.Unlock()
+	return qcc.getQCloudCredential()
+}
+
 func (qcc *TKEClient) getClient() (*tke.Client, error) {
 	qcc.clientLock.Lock()
 	defer qcc.clientLock.Unlock()
@@ -116,6 +134,10 @@ func (qcc *TKEClient) getClient() (*tke.Client, error) {
 		if err != nil {
 			return qcc.client, err
 		}
+		if qcc.config.Transport != nil {
+			qcc.client.WithHttpTransport(qcc.config.Transport)
+		}
+		qcc.client.WithCredential(credential.SDKCredential(qcc.currentCredential))
 	}
 	if qcc.config.Debug {
 		SecretId := cred.GetSecretId()
@@ -19,6 +19,9 @@ const (
 	GB = 1024 * 1024 * 1024
 )
 
+// ResourceGPU is the extended resource name of the nvidia gpu
+const ResourceGPU = "nvidia.com/gpu"
+
 //Tags/Dimensions/Labels
 // this is an abstract inter-mediate labels name, different data source has different label naming which point to the same meaning
 const (
@@ -46,4 +49,7 @@ const (
 	MetricMemLimit   = "mem_limit"
 
 	MetricWorkloadReplicas = "replicas"
+
+	// MetricNodeCount is the node count by instance type of the cluster
+	MetricNodeCount = "node_count"
 )
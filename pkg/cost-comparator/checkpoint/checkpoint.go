@@ -0,0 +1,405 @@
+package checkpoint
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gocrane/crane/pkg/common"
+
+	"github.com/gocrane/fadvisor/pkg/datasource-providers/prom"
+)
+
+// Version of the checkpoint format, a checkpoint of another version is refused
+const Version = 1
+
+type Kind string
+
+const (
+	KindContainer Kind = "container"
+	KindWorkload  Kind = "workload"
+)
+
+// metric names of the time series in the entries
+const (
+	MetricCpu         = "cpu"
+	MetricMem         = "mem"
+	MetricCpuRequests = "cpuRequests"
+	MetricMemRequests = "memRequests"
+	MetricCpuLimits   = "cpuLimits"
+	MetricMemLimits   = "memLimits"
+	MetricReplicas    = "replicas"
+)
+
+// Header identifies the data of the checkpoint, it is the first json line of the file.
+// The data follows as gzip members, one member for each appended window.
+type Header struct {
+	Version    int           `json:"version"`
+	Kind       Kind          `json:"kind"`
+	ClusterId  string        `json:"clusterId"`
+	DataSource string        `json:"dataSource"`
+	Step       time.Duration `json:"step"`
+}
+
+// Compatible returns error if the data of the checkpoint can not be used by the run with the expected header
+func (h Header) Compatible(expected Header) error {
+	switch {
+	case h.Version != Version:
+		return fmt.Errorf("checkpoint version %v is not supported, expected %v", h.Version, Version)
+	case h.Kind != expected.Kind:
+		return fmt.Errorf("checkpoint kind %v mismatch, expected %v", h.Kind, expected.Kind)
+	case h.ClusterId != expected.ClusterId:
+		return fmt.Errorf("checkpoint cluster %v mismatch, expected %v", h.ClusterId, expected.ClusterId)
+	case h.DataSource != expected.DataSource:
+		return fmt.Errorf("checkpoint datasource %v mismatch, expected %v", h.DataSource, expected.DataSource)
+	case h.Step != expected.Step:
+		return fmt.Errorf("checkpoint step %v mismatch, expected %v", h.Step, expected.Step)
+	}
+	return nil
+}
+
+// Window is the time range of the data appended at once
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Entry is the time series of a workload, or a container of a workload, keyed by metric name
+type Entry struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Container string
+	Metrics   map[string][]*common.TimeSeries
+}
+
+func (e *Entry) key() string {
+	return strings.Join([]string{e.Kind, e.Namespace, e.Name, e.Container}, "/")
+}
+
+// Checkpoint is the merged data of all the windows
+type Checkpoint struct {
+	Header  Header
+	Windows []Window
+	Entries []*Entry
+}
+
+// Range returns the earliest start and the latest end of the windows
+func (c *Checkpoint) Range() (time.Time, time.Time) {
+	var start, end time.Time
+	for i, w := range c.Windows {
+		if i == 0 || w.Start.Before(start) {
+			start = w.Start
+		}
+		if i == 0 || w.End.After(end) {
+			end = w.End
+		}
+	}
+	return start, end
+}
+
+// Covers returns true if the windows cover the time range without gaps longer than the step
+func (c *Checkpoint) Covers(start, end time.Time) bool {
+	windows := append([]Window{}, c.Windows...)
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].Start.Before(windows[j].Start)
+	})
+	covered := start
+	for _, w := range windows {
+		if w.Start.After(covered.Add(c.Header.Step)) {
+			break
+		}
+		if w.End.After(covered) {
+			covered = w.End
+		}
+	}
+	return !covered.Add(c.Header.Step).Before(end)
+}
+
+// series is stored by columns, the timestamps are delta encoded
+type series struct {
+	Labels     []common.Label
+	Timestamps []int64
+	Values     []float64
+}
+
+type entry struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Container string
+	Metrics   map[string][]series
+}
+
+// Write creates the checkpoint file with the data of the window, the existing file is replaced
+func Write(file string, header Header, window Window, entries []*Entry) error {
+	header.Version = Version
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	return replaceFile(file, func(w io.Writer) error {
+		if _, err := w.Write(append(headerBytes, '\n')); err != nil {
+			return err
+		}
+		return writeSegment(w, window, entries)
+	})
+}
+
+// Append appends the data of a new window to the existing checkpoint file, the header must be compatible.
+// The existing data is copied to a new file with the new window, so the checkpoint is never partially written.
+func Append(file string, header Header, window Window, entries []*Entry) error {
+	existing, err := readHeader(file)
+	if err != nil {
+		return err
+	}
+	if err = existing.Compatible(header); err != nil {
+		return err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return replaceFile(file, func(w io.Writer) error {
+		if _, err := io.Copy(w, f); err != nil {
+			return err
+		}
+		return writeSegment(w, window, entries)
+	})
+}
+
+// replaceFile writes to a temp file then renames it to the file
+func replaceFile(file string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err = write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+func writeSegment(w io.Writer, window Window, entries []*Entry) error {
+	zw := gzip.NewWriter(w)
+	encoder := gob.NewEncoder(zw)
+	if err := encoder.Encode(window); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := encoder.Encode(encodeEntry(e)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func readHeader(file string) (Header, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return Header{}, err
+	}
+	defer f.Close()
+	header, _, err := decodeHeader(bufio.NewReader(f))
+	return header, err
+}
+
+func decodeHeader(r *bufio.Reader) (Header, *bufio.Reader, error) {
+	var header Header
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return header, r, fmt.Errorf("failed to read checkpoint header: %v", err)
+	}
+	if err = json.Unmarshal(line, &header); err != nil {
+		return header, r, fmt.Errorf("invalid checkpoint header: %v", err)
+	}
+	return header, r, nil
+}
+
+// Read loads the checkpoint and merges the windows, it is refused before loading the data if the header is not compatible with the expected one.
+// The header is not checked if expected is nil.
+func Read(file string, expected *Header) (*Checkpoint, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header, r, err := decodeHeader(bufio.NewReaderSize(f, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if expected != nil {
+		if err = header.Compatible(*expected); err != nil {
+			return nil, err
+		}
+	} else if header.Version != Version {
+		return nil, fmt.Errorf("checkpoint version %v is not supported, expected %v", header.Version, Version)
+	}
+
+	cp := &Checkpoint{Header: header}
+	entries := make(map[string]*Entry)
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		zr.Multistream(false)
+		decoder := gob.NewDecoder(zr)
+		var window Window
+		if err = decoder.Decode(&window); err != nil {
+			return nil, fmt.Errorf("failed to decode checkpoint window: %v", err)
+		}
+		cp.Windows = append(cp.Windows, window)
+		for {
+			var e entry
+			if err = decoder.Decode(&e); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to decode checkpoint entry: %v", err)
+			}
+			mergeEntry(entries, decodeEntry(&e))
+		}
+
+		if err = zr.Reset(r); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	cp.Entries = sortedEntries(entries)
+	return cp, nil
+}
+
+// Merge merges the entries of the same workload or container, the time series of the same labels are merged and the samples of the same timestamp are deduplicated
+func Merge(entryLists ...[]*Entry) []*Entry {
+	entries := make(map[string]*Entry)
+	for _, list := range entryLists {
+		for _, e := range list {
+			mergeEntry(entries, e)
+		}
+	}
+	return sortedEntries(entries)
+}
+
+// Trim drops the samples out of the time range [start, end]
+func Trim(entries []*Entry, start, end time.Time) {
+	for _, e := range entries {
+		for _, tsList := range e.Metrics {
+			for _, ts := range tsList {
+				if ts == nil {
+					continue
+				}
+				samples := ts.Samples[:0]
+				for _, sample := range ts.Samples {
+					if sample.Timestamp >= start.Unix() && sample.Timestamp <= end.Unix() {
+						samples = append(samples, sample)
+					}
+				}
+				ts.Samples = samples
+			}
+		}
+	}
+}
+
+func sortedEntries(entries map[string]*Entry) []*Entry {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	results := make([]*Entry, 0, len(keys))
+	for _, key := range keys {
+		results = append(results, entries[key])
+	}
+	return results
+}
+
+// mergeEntry merges the time series of the same labels of the entry in different windows
+func mergeEntry(entries map[string]*Entry, e *Entry) {
+	existing, ok := entries[e.key()]
+	if !ok {
+		entries[e.key()] = e
+		return
+	}
+	for metric, tsList := range e.Metrics {
+		for _, ts := range tsList {
+			if ts == nil {
+				continue
+			}
+			merged := false
+			for i, existingTs := range existing.Metrics[metric] {
+				if existingTs != nil && labelsKey(existingTs.Labels) == labelsKey(ts.Labels) {
+					existing.Metrics[metric][i] = prom.MergeSortedTimeSeries(existingTs, ts)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				existing.Metrics[metric] = append(existing.Metrics[metric], ts)
+			}
+		}
+	}
+}
+
+func labelsKey(labels []common.Label) string {
+	pairs := make([]string, 0, len(labels))
+	for _, label := range labels {
+		pairs = append(pairs, label.Name+"="+label.Value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func encodeEntry(e *Entry) *entry {
+	result := &entry{Kind: e.Kind, Namespace: e.Namespace, Name: e.Name, Container: e.Container, Metrics: make(map[string][]series, len(e.Metrics))}
+	for metric, tsList := range e.Metrics {
+		for _, ts := range tsList {
+			if ts == nil {
+				continue
+			}
+			s := series{Labels: ts.Labels, Timestamps: make([]int64, len(ts.Samples)), Values: make([]float64, len(ts.Samples))}
+			var last int64
+			for i, sample := range ts.Samples {
+				s.Timestamps[i] = sample.Timestamp - last
+				s.Values[i] = sample.Value
+				last = sample.Timestamp
+			}
+			result.Metrics[metric] = append(result.Metrics[metric], s)
+		}
+	}
+	return result
+}
+
+func decodeEntry(e *entry) *Entry {
+	result := &Entry{Kind: e.Kind, Namespace: e.Namespace, Name: e.Name, Container: e.Container, Metrics: make(map[string][]*common.TimeSeries, len(e.Metrics))}
+	for metric, seriesList := range e.Metrics {
+		for _, s := range seriesList {
+			ts := common.NewTimeSeries()
+			ts.SetLabels(s.Labels)
+			ts.Samples = make([]common.Sample, len(s.Timestamps))
+			var last int64
+			for i, delta := range s.Timestamps {
+				last += delta
+				ts.Samples[i] = common.Sample{Timestamp: last, Value: s.Values[i]}
+			}
+			result.Metrics[metric] = append(result.Metrics[metric], ts)
+		}
+	}
+	return result
+}
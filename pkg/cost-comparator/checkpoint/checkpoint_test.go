@@ -0,0 +1,71 @@
+package checkpoint
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gocrane/crane/pkg/common"
+)
+
+func testEntry(name string, start int64, values ...float64) *Entry {
+	ts := common.NewTimeSeries()
+	ts.SetLabels([]common.Label{{Name: "container", Value: "app"}})
+	for i, v := range values {
+		ts.AppendSample(start+int64(i)*60, v)
+	}
+	return &Entry{Kind: "Deployment", Namespace: "default", Name: name, Container: "app", Metrics: map[string][]*common.TimeSeries{MetricCpu: {ts}}}
+}
+
+func TestWriteAppendRead(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "cls-1-workloads-container-timeseries.ckpt")
+	header := Header{Kind: KindContainer, ClusterId: "cls-1", DataSource: "prom", Step: time.Minute}
+	t0 := time.Unix(0, 0)
+
+	if err := Write(file, header, Window{Start: t0, End: t0.Add(2 * time.Minute)}, []*Entry{testEntry("nginx", 0, 1, 2, 3)}); err != nil {
+		t.Fatal(err)
+	}
+	// the overlapped sample at 120 is deduplicated
+	if err := Append(file, header, Window{Start: t0.Add(2 * time.Minute), End: t0.Add(4 * time.Minute)},
+		[]*Entry{testEntry("nginx", 120, 3, 4, 5), testEntry("redis", 120, 1)}); err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err := Read(file, &header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cp.Windows) != 2 || len(cp.Entries) != 2 {
+		t.Fatalf("unexpected checkpoint %+v", cp)
+	}
+	samples := cp.Entries[0].Metrics[MetricCpu][0].Samples
+	if len(samples) != 5 || samples[4].Timestamp != 240 || samples[4].Value != 5 {
+		t.Fatalf("unexpected merged samples %v", samples)
+	}
+	if cp.Entries[0].Metrics[MetricCpu][0].Labels[0].Value != "app" {
+		t.Fatalf("labels lost")
+	}
+	if !cp.Covers(t0, t0.Add(4*time.Minute)) || cp.Covers(t0, t0.Add(10*time.Minute)) {
+		t.Fatalf("unexpected coverage of windows %v", cp.Windows)
+	}
+	if start, end := cp.Range(); !start.Equal(t0) || !end.Equal(t0.Add(4*time.Minute)) {
+		t.Fatalf("unexpected range %v, %v", start, end)
+	}
+
+	other := header
+	other.Step = 5 * time.Minute
+	if _, err = Read(file, &other); err == nil {
+		t.Fatalf("expected incompatible step refused")
+	}
+	other = header
+	other.ClusterId = "cls-2"
+	if err = Append(file, other, Window{}, nil); err == nil {
+		t.Fatalf("expected append to incompatible checkpoint refused")
+	}
+	// the appended file is renamed from a temp file, no temp file is left
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Fatalf("expected only the checkpoint file, got %v", files)
+	}
+}
@@ -2,11 +2,8 @@ package cost_comparator
 
 import (
 	"context"
-	"encoding/csv"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
@@ -27,9 +24,12 @@ import (
 	"github.com/gocrane/fadvisor/pkg/cache"
 	"github.com/gocrane/fadvisor/pkg/cloud"
 	"github.com/gocrane/fadvisor/pkg/consts"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/checkpoint"
 	"github.com/gocrane/fadvisor/pkg/cost-comparator/config"
 	"github.com/gocrane/fadvisor/pkg/cost-comparator/coster"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/datafetcher"
 	"github.com/gocrane/fadvisor/pkg/cost-comparator/estimator"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/report"
 	"github.com/gocrane/fadvisor/pkg/datasource"
 	"github.com/gocrane/fadvisor/pkg/metricnaming"
 	"github.com/gocrane/fadvisor/pkg/spec"
@@ -52,10 +52,15 @@ type Comparator struct {
 	workloadsTimeSeriesDataCache  map[string] /*kind*/ map[types.NamespacedName] /*namespace-name*/ *RawWorkloadTimeSeriesData
 
 	dataSource     datasource.Interface
+	metricFetcher  datafetcher.MetricFetcher
 	estimateConfig map[string]interface{}
 	estimator      estimator.Estimator
 	// this is your baseline estimate cloud provider, such as a tencent cloud tke cluster which is your current using cluster
 	baselineCloud cloud.Cloud
+	// report of the last analysis
+	report *report.Report
+	// outputErr is the first error of writing the report files of the last analysis
+	outputErr error
 }
 
 func NewComparator(config config.Config,
@@ -66,8 +71,12 @@ func NewComparator(config config.Config,
 	clusterCache cache.Cache,
 	baselineCloud cloud.Cloud,
 	dataSource datasource.Interface) *Comparator {
+	estimateConfig := config.EstimateConfig
+	if estimateConfig == nil {
+		estimateConfig = make(map[string]interface{})
+	}
 	return &Comparator{
-		estimateConfig:      make(map[string]interface{}),
+		estimateConfig:      estimateConfig,
 		estimator:           estimator.NewStatisticEstimator(),
 		config:              config,
 		kubeDynamicClient:   kubeDynamicClient,
@@ -76,53 +85,113 @@ func NewComparator(config config.Config,
 		targetInfoFetcher:   fetcher,
 		clusterCache:        clusterCache,
 		dataSource:          dataSource,
+		metricFetcher:       datafetcher.NewMetricFetcher(config.ClusterId, dataSource, clusterCache),
 		baselineCloud:       baselineCloud,
 	}
 }
 
 // Init initialize some cached data and time series data, Must call before DoAnalysis
-func (c *Comparator) Init() {
+func (c *Comparator) Init() error {
 	c.initWorkloadsSpec()
 	err := c.ContainerTsDataInit()
 	if err != nil {
-		klog.Fatalf("Failed to init container time series data: %v", err)
+		return fmt.Errorf("failed to init container time series data: %v", err)
 	}
 	if c.config.EnableWorkloadTimeSeries {
 		err = c.WorkloadTsDataInit()
 		if err != nil {
-			klog.Fatalf("Failed to init workload time series data: %v", err)
+			return fmt.Errorf("failed to init workload time series data: %v", err)
 		}
 	}
+	return nil
 }
 
 // Now it will fetch full data to do once analysis, so it is a time consuming offline computing task, also it will consuming memory because it will do time series analysis.
 // todo: refactor to online service model when used for online deploy, split the services to online service & offline computing job.
 // ??? offline computing jobs like spark by operator way VS. online service by deployment way
-func (c *Comparator) DoAnalysis() {
-	podsSpec := c.GetAllPodsSpec()
-	nodesSpec := c.GetAllNodesSpec()
-
-	workloadsRecs := c.GetAllWorkloadRecommendedData()
-
-	costerCtx := &coster.CosterContext{
-		TimeSpanSeconds:  c.config.TimeSpanSeconds,
-		Discount:         &c.config.Discount,
-		PodsSpec:         podsSpec,
-		NodesSpec:        nodesSpec,
-		WorkloadsRecSpec: workloadsRecs,
-		WorkloadsSpec:    c.workloadsSpecCache,
-		Pricer:           c.baselineCloud,
+// The error of pricing the pods in scope or writing the report files is returned.
+func (c *Comparator) DoAnalysis() error {
+	costerCtx, err := c.newCosterContext()
+	if err != nil {
+		return err
 	}
 
+	c.newReport()
+	c.outputErr = nil
 	c.ReportOriginalResourceSummary()
 	c.ReportOriginalCostSummary(costerCtx)
-	c.ReportRawServerlessCostSummary(costerCtx)
-	c.ReportRecommendedResourceSummary(costerCtx)
-	c.ReportRecommendedCostSummary(costerCtx)
+	if c.config.PlatformEnabled(config.PlatformServerless) {
+		c.ReportRawServerlessCostSummary(costerCtx)
+		c.ReportRecommendedResourceSummary(costerCtx)
+		c.ReportRecommendedCostSummary(costerCtx)
+	}
+	if c.config.PlatformEnabled(config.PlatformBinPacking) {
+		c.ReportRecommendedBinPackingCostSummary(costerCtx)
+	}
+	// the nodes are shared by all the namespaces, so the node level analysis is not done if it is scoped by namespaces
+	if c.config.PlatformEnabled(config.PlatformRightsizing) {
+		if c.config.Scoped() {
+			klog.Warningf("Nodes rightsizing is skipped, it can not be scoped by namespaces %v", c.config.Namespaces)
+		} else if c.config.Offline {
+			klog.Warningf("Nodes rightsizing is skipped, it needs the node metrics of the datasource")
+		} else {
+			c.ReportNodesRightsizing(costerCtx)
+		}
+	}
+	if c.config.PlatformEnabled(config.PlatformSpot) {
+		c.ReportSpotSavings(costerCtx)
+	}
+	if c.config.PlatformEnabled(config.PlatformReserved) {
+		if c.config.Scoped() {
+			klog.Warningf("Reserved instances recommendation is skipped, it can not be scoped by namespaces %v", c.config.Namespaces)
+		} else if c.config.Offline {
+			klog.Warningf("Reserved instances recommendation is skipped, it needs the node metrics of the datasource")
+		} else {
+			c.ReportReservedInstances(costerCtx)
+		}
+	}
 
 	c.ReportOriginalWorkloadsResourceDistribution(costerCtx)
 	c.ReportRecommendedWorkloadsResourceDistribution(costerCtx)
 
+	c.writeReport()
+	return c.outputErr
+}
+
+// Analyze fetches the latest time series data from the datasource without checkpoint, then does analysis and return the report.
+// It is used by the comparator service to run the analysis periodically.
+func (c *Comparator) Analyze() (*report.Report, error) {
+	c.initWorkloadsSpec()
+	qRange := c.getQueryRange()
+	c.containersTimeSeriesDataCache = c.fetchContainerData(c.workloadsSpecCache, qRange)
+	if c.config.EnableWorkloadTimeSeries {
+		c.workloadsTimeSeriesDataCache = c.fetchWorkloadMetricData(c.workloadsSpecCache, qRange)
+	}
+	if err := c.DoAnalysis(); err != nil {
+		return nil, err
+	}
+	return c.report, nil
+}
+
+// newCosterContext builds the coster context of the pods, nodes and workloads in scope
+func (c *Comparator) newCosterContext() (*coster.CosterContext, error) {
+	costerCtx := &coster.CosterContext{
+		TimeSpanSeconds:  c.config.TimeSpanSeconds,
+		Discount:         &c.config.Discount,
+		PodsSpec:         c.GetAllPodsSpec(),
+		NodesSpec:        c.GetAllNodesSpec(),
+		WorkloadsRecSpec: c.GetAllWorkloadRecommendedData(),
+		WorkloadsSpec:    c.workloadsSpecCache,
+		Pricer:           c.baselineCloud,
+	}
+	if c.config.Scoped() {
+		podsCost, err := c.baselineCloud.GetPodsCost()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pods cost of the namespaces in scope: %v", err)
+		}
+		costerCtx.PodsCost = podsCost
+	}
+	return costerCtx, nil
 }
 
 func Int642Str(a int64) string {
@@ -147,9 +216,24 @@ func (c *Comparator) getQueryRange() promapiv1.Range {
 	}
 }
 
+// podsInScope returns the pods of the namespaces in scope, all the pod and workload sources of the analysis use it
+func (c *Comparator) podsInScope() []*v1.Pod {
+	pods := c.clusterCache.GetPods()
+	if !c.config.Scoped() {
+		return pods
+	}
+	var results []*v1.Pod
+	for _, pod := range pods {
+		if c.config.InScope(pod.Namespace) {
+			results = append(results, pod)
+		}
+	}
+	return results
+}
+
 func (c *Comparator) GetAllPodsSpec() map[string] /*namespace-name*/ spec.CloudPodSpec {
 	res := make(map[string]spec.CloudPodSpec)
-	pods := c.clusterCache.GetPods()
+	pods := c.podsInScope()
 	for _, pod := range pods {
 		res[klog.KObj(pod).String()] = c.baselineCloud.Pod2Spec(pod)
 	}
@@ -169,7 +253,7 @@ func (c *Comparator) GetAllNodesSpec() map[string] /*nodename*/ spec.CloudNodeSp
 // build workloads by inverted-index pods
 func (c *Comparator) initWorkloadsSpec() map[string] /*kind*/ map[types.NamespacedName] /*namespace-name*/ spec.CloudPodSpec {
 	workloads := make(map[string]map[types.NamespacedName]spec.CloudPodSpec)
-	pods := c.clusterCache.GetPods()
+	pods := c.podsInScope()
 	for _, pod := range pods {
 		unstruct, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
 		if err != nil {
@@ -221,7 +305,7 @@ func (c *Comparator) initWorkloadsSpec() map[string] /*kind*/ map[types.Namespac
 
 func (c *Comparator) GetAllWorkloads() []*unstructured.Unstructured {
 	var workloads []*unstructured.Unstructured
-	pods := c.clusterCache.GetPods()
+	pods := c.podsInScope()
 	for _, pod := range pods {
 		unstruct, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
 		if err != nil {
@@ -269,931 +353,28 @@ func PathExists(path string) (bool, error) {
 	return false, err
 }
 
+// ContainerTsDataInit loads the container time series data from the checkpoint, and only fetches the data not in the checkpoint from the datasource
 func (c *Comparator) ContainerTsDataInit() error {
-	data, err := c.LoadContainerTimeSeriesDataFromCheckpoint()
-	if err == nil {
-		c.containersTimeSeriesDataCache = data
-		return nil
-	}
-	klog.Errorf("Failed to load workload container data from check point: %v, init from datasource", err)
-	data = c.fetchContainerData()
-	c.containersTimeSeriesDataCache = data
-	klog.V(2).Infof("Succeed to load workload container data from data source, checkpointing it")
-	if c.config.EnableContainerCheckpoint {
-		err = c.ContainerDataCheckpoint()
-		return err
-	}
-	return nil
+	entries, err := c.loadIncrementally(c.containerTimeSeriesDataCheckpointName(), checkpoint.KindContainer, c.getQueryRange(), c.config.EnableContainerCheckpoint,
+		func(workloads map[string]map[types.NamespacedName]spec.CloudPodSpec, qRange promapiv1.Range) []*checkpoint.Entry {
+			return containerDataToEntries(c.fetchContainerData(workloads, qRange))
+		})
+	c.containersTimeSeriesDataCache = containerDataFromEntries(entries)
+	return err
 }
 
+// WorkloadTsDataInit loads the workload time series data from the checkpoint, and only fetches the data not in the checkpoint from the datasource
 func (c *Comparator) WorkloadTsDataInit() error {
-	data, err := c.LoadWorkloadTimeSeriesDataFromCheckpoint()
-	if err == nil {
-		c.workloadsTimeSeriesDataCache = data
-		return nil
-	}
-	klog.Errorf("Failed to load workload time series data from check point: %v, init from datasource", err)
-	data = c.fetchWorkloadMetricData()
-	c.workloadsTimeSeriesDataCache = data
-	klog.V(2).Infof("Succeed to load workload time series data from data source, checkpointing it")
-	if c.config.EnableWorkloadCheckpoint {
-		err = c.workloadTimeSeriesDataCheckpoint()
-	}
+	entries, err := c.loadIncrementally(c.workloadTimeSeriesDataCheckpointName(), checkpoint.KindWorkload, c.getQueryRange(), c.config.EnableWorkloadCheckpoint,
+		func(workloads map[string]map[types.NamespacedName]spec.CloudPodSpec, qRange promapiv1.Range) []*checkpoint.Entry {
+			return workloadDataToEntries(c.fetchWorkloadMetricData(workloads, qRange))
+		})
+	c.workloadsTimeSeriesDataCache = workloadDataFromEntries(entries)
 	return err
 }
 
-func (c *Comparator) LoadContainerTimeSeriesDataFromCheckpoint() (map[string] /*kind*/ map[types.NamespacedName] /*namespace-name*/ map[string] /*container*/ *RawContainerTimeSeriesData, error) {
-	checkPointFile := c.containerTimeSeriesDataCheckpointName()
-	result := make(map[string] /*kind*/ map[types.NamespacedName] /*namespace-name*/ map[string] /*container*/ *RawContainerTimeSeriesData)
-
-	fileData, err := os.Open(checkPointFile)
-	if err != nil {
-		return result, err
-	}
-	reader := csv.NewReader(fileData)
-	reader.Comma = '\t'
-	lines, err := reader.ReadAll()
-	if err != nil {
-		return result, err
-	}
-
-	if len(lines) == 0 || len(lines[0]) <= 4 {
-		return nil, fmt.Errorf("wrong fmt csv, header is wrong")
-	}
-
-	cpuUsageHeaders := make([]string, 0)
-	memUsageHeaders := make([]string, 0)
-	cpuRequestHeaders := make([]string, 0)
-	memRequestHeaders := make([]string, 0)
-	cpuLimitHeaders := make([]string, 0)
-	memLimitHeaders := make([]string, 0)
-
-	headers := lines[0][0:4]
-
-	for _, field := range lines[0] {
-		if strings.HasPrefix(field, "CpuUsage-") {
-			cpuUsageHeaders = append(cpuUsageHeaders, field)
-		}
-		if strings.HasPrefix(field, "MemUsage-") {
-			memUsageHeaders = append(memUsageHeaders, field)
-		}
-		if strings.HasPrefix(field, "CpuRequest-") {
-			cpuRequestHeaders = append(cpuRequestHeaders, field)
-		}
-		if strings.HasPrefix(field, "MemRequest-") {
-			memRequestHeaders = append(memRequestHeaders, field)
-		}
-		if strings.HasPrefix(field, "CpuLimit-") {
-			cpuLimitHeaders = append(cpuLimitHeaders, field)
-		}
-		if strings.HasPrefix(field, "MemLimit-") {
-			memLimitHeaders = append(memLimitHeaders, field)
-		}
-	}
-
-	headers = append(headers, cpuUsageHeaders...)
-	headers = append(headers, memUsageHeaders...)
-	headers = append(headers, cpuRequestHeaders...)
-	headers = append(headers, memRequestHeaders...)
-	headers = append(headers, cpuLimitHeaders...)
-	headers = append(headers, memLimitHeaders...)
-
-	// skip header
-	for line, row := range lines[1:] {
-		if len(row) < len(headers) {
-			klog.Warningf("wrong length row")
-		}
-		kind := row[0]
-		namespace := row[1]
-		name := row[2]
-		container := row[3]
-
-		kindNN, ok := result[kind]
-		if !ok {
-			kindNN = make(map[types.NamespacedName]map[string]*RawContainerTimeSeriesData)
-			result[kind] = kindNN
-		}
-		nn := types.NamespacedName{Namespace: namespace, Name: name}
-		containersMap, ok := kindNN[nn]
-		if !ok {
-			containersMap = make(map[string]*RawContainerTimeSeriesData)
-			kindNN[nn] = containersMap
-		}
-
-		cpuTs := common.NewTimeSeries()
-		memTs := common.NewTimeSeries()
-		CpuRequestTs := common.NewTimeSeries()
-		MemRequestTs := common.NewTimeSeries()
-		CpuLimitTs := common.NewTimeSeries()
-		MemLimitTs := common.NewTimeSeries()
-
-		row := row[4:]
-		indexStart := 0
-		for i, colName := range cpuUsageHeaders {
-			var ts int64
-			var err error
-			splits := strings.Split(colName, "-")
-			if len(splits) >= 3 {
-				ts, err = strconv.ParseInt(splits[2], 10, 64)
-				if err != nil {
-					klog.Warningf("line %v col %v parsed failed", line, colName)
-				}
-			}
-			// null value ignored
-			if row[indexStart+i] == "null" {
-				continue
-			}
-			val, err := strconv.ParseFloat(row[indexStart+i], 64)
-			if err != nil {
-				continue
-			}
-			cpuTs.AppendSample(ts, val)
-		}
-		indexStart += len(cpuUsageHeaders)
-		for i, colName := range memUsageHeaders {
-			var ts int64
-			var err error
-			splits := strings.Split(colName, "-")
-			if len(splits) >= 3 {
-				ts, err = strconv.ParseInt(splits[2], 10, 64)
-				if err != nil {
-					klog.Warningf("line %v col %v parsed failed", line, colName)
-				}
-			}
-			// null value ignored
-			if row[indexStart+i] == "null" {
-				continue
-			}
-			val, err := strconv.ParseFloat(row[indexStart+i], 64)
-			if err != nil {
-				continue
-			}
-			memTs.AppendSample(ts, val)
-		}
-		indexStart += len(memUsageHeaders)
-		for i, colName := range cpuRequestHeaders {
-			var ts int64
-			var err error
-			splits := strings.Split(colName, "-")
-			if len(splits) >= 3 {
-				ts, err = strconv.ParseInt(splits[2], 10, 64)
-				if err != nil {
-					klog.Warningf("line %v col %v parsed failed", line, colName)
-				}
-			}
-			// null value ignored
-			if row[indexStart+i] == "null" {
-				continue
-			}
-			val, err := strconv.ParseFloat(row[indexStart+i], 64)
-			if err != nil {
-				continue
-			}
-			CpuRequestTs.AppendSample(ts, val)
-		}
-		indexStart += len(cpuRequestHeaders)
-		for i, colName := range memRequestHeaders {
-			var ts int64
-			var err error
-			splits := strings.Split(colName, "-")
-			if len(splits) >= 3 {
-				ts, err = strconv.ParseInt(splits[2], 10, 64)
-				if err != nil {
-					klog.Warningf("line %v col %v parsed failed", line, colName)
-				}
-			}
-			// null value ignored
-			if row[indexStart+i] == "null" {
-				continue
-			}
-			val, err := strconv.ParseFloat(row[indexStart+i], 64)
-			if err != nil {
-				continue
-			}
-			MemRequestTs.AppendSample(ts, val)
-		}
-		indexStart += len(memRequestHeaders)
-		for i, colName := range cpuLimitHeaders {
-			var ts int64
-			var err error
-			splits := strings.Split(colName, "-")
-			if len(splits) >= 3 {
-				ts, err = strconv.ParseInt(splits[2], 10, 64)
-				if err != nil {
-					klog.Warningf("line %v col %v parsed failed", line, colName)
-				}
-			}
-			// null value ignored
-			if row[indexStart+i] == "null" {
-				continue
-			}
-			val, err := strconv.ParseFloat(row[indexStart+i], 64)
-			if err != nil {
-				continue
-			}
-			CpuLimitTs.AppendSample(ts, val)
-		}
-		indexStart += len(cpuLimitHeaders)
-		for i, colName := range memLimitHeaders {
-			var ts int64
-			var err error
-			splits := strings.Split(colName, "-")
-			if len(splits) >= 3 {
-				ts, err = strconv.ParseInt(splits[2], 10, 64)
-				if err != nil {
-					klog.Warningf("line %v col %v parsed failed", line, colName)
-				}
-			}
-			// null value ignored
-			if row[indexStart+i] == "null" {
-				continue
-			}
-			val, err := strconv.ParseFloat(row[indexStart+i], 64)
-			if err != nil {
-				continue
-			}
-			MemLimitTs.AppendSample(ts, val)
-		}
-		containersMap[container] = &RawContainerTimeSeriesData{
-			Cpu:         []*common.TimeSeries{cpuTs},
-			Mem:         []*common.TimeSeries{memTs},
-			CpuRequests: []*common.TimeSeries{CpuRequestTs},
-			MemRequests: []*common.TimeSeries{MemRequestTs},
-			CpuLimits:   []*common.TimeSeries{CpuLimitTs},
-			MemLimits:   []*common.TimeSeries{MemLimitTs},
-		}
-	}
-
-	return result, nil
-	//file, err := os.Open(checkPointFile)
-	//defer file.Close()
-	//decoder := json.NewDecoder(file)
-	//// Read the array open bracket
-	//decoder.Token()
-	//for decoder.More() {
-	//	decoder.Decode(&data)
-	//}
-	//return data, nil
-}
-
-func (c *Comparator) LoadWorkloadTimeSeriesDataFromCheckpoint() (map[string] /*kind*/ map[types.NamespacedName] /*namespace-name*/ *RawWorkloadTimeSeriesData, error) {
-	checkPointFile := c.workloadTimeSeriesDataCheckpointName()
-	result := make(map[string] /*kind*/ map[types.NamespacedName] /*namespace-name*/ *RawWorkloadTimeSeriesData)
-
-	fileData, err := os.Open(checkPointFile)
-	if err != nil {
-		return result, err
-	}
-	reader := csv.NewReader(fileData)
-	reader.Comma = '\t'
-	lines, err := reader.ReadAll()
-	if err != nil {
-		return result, err
-	}
-
-	if len(lines) == 0 || len(lines[0]) <= 4 {
-		return nil, fmt.Errorf("wrong fmt csv, header is wrong")
-	}
-
-	cpuUsageHeaders := make([]string, 0)
-	memUsageHeaders := make([]string, 0)
-	cpuRequestHeaders := make([]string, 0)
-	memRequestHeaders := make([]string, 0)
-	cpuLimitHeaders := make([]string, 0)
-	memLimitHeaders := make([]string, 0)
-	replicaHeaders := make([]string, 0)
-
-	headers := lines[0][0:3]
-
-	for _, field := range lines[0] {
-		if strings.HasPrefix(field, "CpuUsage-") {
-			cpuUsageHeaders = append(cpuUsageHeaders, field)
-		}
-		if strings.HasPrefix(field, "MemUsage-") {
-			memUsageHeaders = append(memUsageHeaders, field)
-		}
-		if strings.HasPrefix(field, "CpuRequest-") {
-			cpuRequestHeaders = append(cpuRequestHeaders, field)
-		}
-		if strings.HasPrefix(field, "MemRequest-") {
-			memRequestHeaders = append(memRequestHeaders, field)
-		}
-		if strings.HasPrefix(field, "CpuLimit-") {
-			cpuLimitHeaders = append(cpuLimitHeaders, field)
-		}
-		if strings.HasPrefix(field, "MemLimit-") {
-			memLimitHeaders = append(memLimitHeaders, field)
-		}
-		if strings.HasPrefix(field, "Replicas-") {
-			replicaHeaders = append(replicaHeaders, field)
-		}
-	}
-
-	headers = append(headers, cpuUsageHeaders...)
-	headers = append(headers, memUsageHeaders...)
-	headers = append(headers, cpuRequestHeaders...)
-	headers = append(headers, memRequestHeaders...)
-	headers = append(headers, cpuLimitHeaders...)
-	headers = append(headers, memLimitHeaders...)
-	headers = append(headers, replicaHeaders...)
-
-	// skip header
-	for line, row := range lines[1:] {
-		if len(row) < len(headers) {
-			klog.Warningf("wrong length row")
-		}
-		kind := row[0]
-		namespace := row[1]
-		name := row[2]
-
-		kindNN, ok := result[kind]
-		if !ok {
-			kindNN = make(map[types.NamespacedName]*RawWorkloadTimeSeriesData)
-			result[kind] = kindNN
-		}
-		nn := types.NamespacedName{Namespace: namespace, Name: name}
-
-		cpuTs := common.NewTimeSeries()
-		memTs := common.NewTimeSeries()
-		CpuRequestTs := common.NewTimeSeries()
-		MemRequestTs := common.NewTimeSeries()
-		CpuLimitTs := common.NewTimeSeries()
-		MemLimitTs := common.NewTimeSeries()
-		ReplicaTs := common.NewTimeSeries()
-
-		row := row[3:]
-		indexStart := 0
-		for i, colName := range cpuUsageHeaders {
-			var ts int64
-			var err error
-			splits := strings.Split(colName, "-")
-			if len(splits) >= 3 {
-				ts, err = strconv.ParseInt(splits[2], 10, 64)
-				if err != nil {
-					klog.Warningf("line %v col %v parsed failed", line, colName)
-				}
-			}
-			// null value ignored
-			if row[indexStart+i] == "null" {
-				continue
-			}
-			val, err := strconv.ParseFloat(row[indexStart+i], 64)
-			if err != nil {
-				continue
-			}
-			cpuTs.AppendSample(ts, val)
-		}
-		indexStart += len(cpuUsageHeaders)
-		for i, colName := range memUsageHeaders {
-			var ts int64
-			var err error
-			splits := strings.Split(colName, "-")
-			if len(splits) >= 3 {
-				ts, err = strconv.ParseInt(splits[2], 10, 64)
-				if err != nil {
-					klog.Warningf("line %v col %v parsed failed", line, colName)
-				}
-			}
-			// null value ignored
-			if row[indexStart+i] == "null" {
-				continue
-			}
-			val, err := strconv.ParseFloat(row[indexStart+i], 64)
-			if err != nil {
-				continue
-			}
-			memTs.AppendSample(ts, val)
-		}
-		indexStart += len(memUsageHeaders)
-		for i, colName := range cpuRequestHeaders {
-			var ts int64
-			var err error
-			splits := strings.Split(colName, "-")
-			if len(splits) >= 3 {
-				ts, err = strconv.ParseInt(splits[2], 10, 64)
-				if err != nil {
-					klog.Warningf("line %v col %v parsed failed", line, colName)
-				}
-			}
-			// null value ignored
-			if row[indexStart+i] == "null" {
-				continue
-			}
-			val, err := strconv.ParseFloat(row[indexStart+i], 64)
-			if err != nil {
-				continue
-			}
-			CpuRequestTs.AppendSample(ts, val)
-		}
-		indexStart += len(cpuRequestHeaders)
-		for i, colName := range memRequestHeaders {
-			var ts int64
-			var err error
-			splits := strings.Split(colName, "-")
-			if len(splits) >= 3 {
-				ts, err = strconv.ParseInt(splits[2], 10, 64)
-				if err != nil {
-					klog.Warningf("line %v col %v parsed failed", line, colName)
-				}
-			}
-			// null value ignored
-			if row[indexStart+i] == "null" {
-				continue
-			}
-			val, err := strconv.ParseFloat(row[indexStart+i], 64)
-			if err != nil {
-				continue
-			}
-			MemRequestTs.AppendSample(ts, val)
-		}
-		for i, colName := range cpuLimitHeaders {
-			var ts int64
-			var err error
-			splits := strings.Split(colName, "-")
-			if len(splits) >= 3 {
-				ts, err = strconv.ParseInt(splits[2], 10, 64)
-				if err != nil {
-					klog.Warningf("line %v col %v parsed failed", line, colName)
-				}
-			}
-			// null value ignored
-			if row[indexStart+i] == "null" {
-				continue
-			}
-			val, err := strconv.ParseFloat(row[indexStart+i], 64)
-			if err != nil {
-				continue
-			}
-			CpuLimitTs.AppendSample(ts, val)
-		}
-		indexStart += len(cpuLimitHeaders)
-		for i, colName := range memLimitHeaders {
-			var ts int64
-			var err error
-			splits := strings.Split(colName, "-")
-			if len(splits) >= 3 {
-				ts, err = strconv.ParseInt(splits[2], 10, 64)
-				if err != nil {
-					klog.Warningf("line %v col %v parsed failed", line, colName)
-				}
-			}
-			// null value ignored
-			if row[indexStart+i] == "null" {
-				continue
-			}
-			val, err := strconv.ParseFloat(row[indexStart+i], 64)
-			if err != nil {
-				continue
-			}
-			MemLimitTs.AppendSample(ts, val)
-		}
-		indexStart += len(memLimitHeaders)
-		for i, colName := range replicaHeaders {
-			var ts int64
-			var err error
-			splits := strings.Split(colName, "-")
-			if len(splits) >= 3 {
-				ts, err = strconv.ParseInt(splits[2], 10, 64)
-				if err != nil {
-					klog.Warningf("line %v col %v parsed failed", line, colName)
-				}
-			}
-			// null value ignored
-			if row[indexStart+i] == "null" {
-				continue
-			}
-			val, err := strconv.ParseFloat(row[indexStart+i], 64)
-			if err != nil {
-				continue
-			}
-			ReplicaTs.AppendSample(ts, val)
-		}
-		kindNN[nn] = &RawWorkloadTimeSeriesData{
-			Cpu:         []*common.TimeSeries{cpuTs},
-			Mem:         []*common.TimeSeries{memTs},
-			CpuRequests: []*common.TimeSeries{CpuRequestTs},
-			MemRequests: []*common.TimeSeries{MemRequestTs},
-			CpuLimits:   []*common.TimeSeries{CpuLimitTs},
-			MemLimits:   []*common.TimeSeries{MemLimitTs},
-			Replicas:    []*common.TimeSeries{ReplicaTs},
-		}
-	}
-
-	return result, nil
-}
-
-func (c *Comparator) containerTimeSeriesDataCheckpointName() string {
-	return filepath.Join(c.config.DataPath, c.config.ClusterId+"-workloads-container-timeseries.csv")
-}
-
-func (c *Comparator) workloadTimeSeriesDataCheckpointName() string {
-	return filepath.Join(c.config.DataPath, c.config.ClusterId+"-workloads-timeseries.csv")
-}
-
-func (c *Comparator) ContainerDataCheckpoint() error {
-	//checkPointFile := c.checkpointName()
-	//var json = jsoniter.ConfigCompatibleWithStandardLibrary
-	//file, _ := os.OpenFile(checkPointFile, os.O_CREATE, os.ModePerm)
-	//defer file.Close()
-	//encoder := json.NewEncoder(file)
-	//return encoder.Encode(data)
-
-	maxHeaderLen := 0
-	maxCpuUsageHeaderLen := 0
-	maxMemUsageHeaderLen := 0
-	maxCpuRequestHeaderLen := 0
-	maxMemRequestHeaderLen := 0
-	maxCpuLimitHeaderLen := 0
-	maxMemLimitHeaderLen := 0
-	for _, kindWorkloads := range c.containersTimeSeriesDataCache {
-		for _, workloadContainers := range kindWorkloads {
-			for _, data := range workloadContainers {
-
-				cpuUsageLen := 0
-				memUsageLen := 0
-				cpuRequestLen := 0
-				memRequestLen := 0
-				cpuLimitLen := 0
-				memLimitLen := 0
-
-				if len(data.Cpu) > 0 && data.Cpu[0] != nil {
-					cpuUsageLen = len(data.Cpu[0].Samples)
-				}
-				if len(data.Mem) > 0 && data.Mem[0] != nil {
-					memUsageLen = len(data.Mem[0].Samples)
-				}
-				if len(data.CpuRequests) > 0 && data.CpuRequests[0] != nil {
-					cpuRequestLen = len(data.CpuRequests[0].Samples)
-				}
-				if len(data.MemRequests) > 0 && data.MemRequests[0] != nil {
-					memRequestLen = len(data.MemRequests[0].Samples)
-				}
-				if len(data.CpuLimits) > 0 && data.CpuLimits[0] != nil {
-					cpuLimitLen = len(data.CpuLimits[0].Samples)
-				}
-				if len(data.MemLimits) > 0 && data.MemLimits[0] != nil {
-					memLimitLen = len(data.MemLimits[0].Samples)
-				}
-
-				totalHeaderLen := cpuUsageLen + memUsageLen + cpuRequestLen + memRequestLen + cpuLimitLen + memLimitLen
-				if maxHeaderLen < totalHeaderLen {
-					maxHeaderLen = totalHeaderLen
-				}
-
-				if maxCpuUsageHeaderLen < cpuUsageLen {
-					maxCpuUsageHeaderLen = cpuUsageLen
-				}
-				if maxMemUsageHeaderLen < memUsageLen {
-					maxMemUsageHeaderLen = memUsageLen
-				}
-				if maxCpuRequestHeaderLen < cpuRequestLen {
-					maxCpuRequestHeaderLen = cpuRequestLen
-				}
-				if maxMemRequestHeaderLen < memRequestLen {
-					maxMemRequestHeaderLen = memRequestLen
-				}
-				if maxCpuLimitHeaderLen < cpuLimitLen {
-					maxCpuLimitHeaderLen = cpuLimitLen
-				}
-				if maxMemLimitHeaderLen < memLimitLen {
-					maxMemLimitHeaderLen = memLimitLen
-				}
-			}
-		}
-	}
-
-	headers := []string{"Kind", "Namespace", "Name", "Container"}
-	cpuUsageHeaders := make([]string, maxCpuUsageHeaderLen)
-	memUsageHeaders := make([]string, maxMemUsageHeaderLen)
-	cpuRequestHeaders := make([]string, maxCpuRequestHeaderLen)
-	memRequestHeaders := make([]string, maxMemRequestHeaderLen)
-	cpuLimitHeaders := make([]string, maxCpuLimitHeaderLen)
-	memLimitHeaders := make([]string, maxMemLimitHeaderLen)
-
-	for i := 0; i < maxCpuUsageHeaderLen; i++ {
-		cpuUsageHeaders[i] = fmt.Sprintf("CpuUsage-%v", i)
-	}
-	for i := 0; i < maxMemUsageHeaderLen; i++ {
-		memUsageHeaders[i] = fmt.Sprintf("MemUsage-%v", i)
-	}
-	for i := 0; i < maxCpuRequestHeaderLen; i++ {
-		cpuRequestHeaders[i] = fmt.Sprintf("CpuRequest-%v", i)
-	}
-	for i := 0; i < maxMemRequestHeaderLen; i++ {
-		memRequestHeaders[i] = fmt.Sprintf("MemRequest-%v", i)
-	}
-	for i := 0; i < maxCpuLimitHeaderLen; i++ {
-		cpuLimitHeaders[i] = fmt.Sprintf("CpuLimit-%v", i)
-	}
-	for i := 0; i < maxMemLimitHeaderLen; i++ {
-		memLimitHeaders[i] = fmt.Sprintf("MemLimit-%v", i)
-	}
-
-	var data [][]string
-	for kind, kindWorkloads := range c.containersTimeSeriesDataCache {
-		for nn, workloadContainers := range kindWorkloads {
-			for container, containerData := range workloadContainers {
-				row := []string{kind, nn.Namespace, nn.Name, container}
-
-				for i := range cpuUsageHeaders {
-					if len(containerData.Cpu) > 0 && i < len(containerData.Cpu[0].Samples) {
-						sample := containerData.Cpu[0].Samples[i]
-						row = append(row, Float642Str(sample.Value))
-						splits := strings.Split(cpuUsageHeaders[i], "-")
-						cpuUsageHeaders[i] = fmt.Sprintf("%v-%v", splits[0]+"-"+splits[1], sample.Timestamp)
-					} else {
-						row = append(row, "null")
-					}
-				}
-				for i := range memUsageHeaders {
-					if len(containerData.Mem) > 0 && i < len(containerData.Mem[0].Samples) {
-						sample := containerData.Mem[0].Samples[i]
-						row = append(row, Float642Str(sample.Value))
-						splits := strings.Split(memUsageHeaders[i], "-")
-						memUsageHeaders[i] = fmt.Sprintf("%v-%v", splits[0]+"-"+splits[1], sample.Timestamp)
-					} else {
-						row = append(row, "null")
-					}
-				}
-				for i := range cpuRequestHeaders {
-					if len(containerData.CpuRequests) > 0 && i < len(containerData.CpuRequests[0].Samples) {
-						sample := containerData.CpuRequests[0].Samples[i]
-						row = append(row, Float642Str(sample.Value))
-						splits := strings.Split(cpuRequestHeaders[i], "-")
-						cpuRequestHeaders[i] = fmt.Sprintf("%v-%v", splits[0]+"-"+splits[1], sample.Timestamp)
-					} else {
-						row = append(row, "null")
-					}
-				}
-				for i := range memRequestHeaders {
-					if len(containerData.MemRequests) > 0 && i < len(containerData.MemRequests[0].Samples) {
-						sample := containerData.MemRequests[0].Samples[i]
-						row = append(row, Float642Str(sample.Value))
-						splits := strings.Split(memRequestHeaders[i], "-")
-						memRequestHeaders[i] = fmt.Sprintf("%v-%v", splits[0]+"-"+splits[1], sample.Timestamp)
-					} else {
-						row = append(row, "null")
-					}
-				}
-				for i := range cpuLimitHeaders {
-					if len(containerData.CpuLimits) > 0 && i < len(containerData.CpuLimits[0].Samples) {
-						sample := containerData.CpuLimits[0].Samples[i]
-						row = append(row, Float642Str(sample.Value))
-						splits := strings.Split(cpuLimitHeaders[i], "-")
-						cpuLimitHeaders[i] = fmt.Sprintf("%v-%v", splits[0]+"-"+splits[1], sample.Timestamp)
-					} else {
-						row = append(row, "null")
-					}
-				}
-				for i := range memLimitHeaders {
-					if len(containerData.MemLimits) > 0 && i < len(containerData.MemLimits[0].Samples) {
-						sample := containerData.MemLimits[0].Samples[i]
-						row = append(row, Float642Str(sample.Value))
-						splits := strings.Split(memLimitHeaders[i], "-")
-						memLimitHeaders[i] = fmt.Sprintf("%v-%v", splits[0]+"-"+splits[1], sample.Timestamp)
-					} else {
-						row = append(row, "null")
-					}
-				}
-				data = append(data, row)
-			}
-		}
-	}
-
-	headers = append(headers, cpuUsageHeaders...)
-	headers = append(headers, memUsageHeaders...)
-	headers = append(headers, cpuRequestHeaders...)
-	headers = append(headers, memRequestHeaders...)
-	headers = append(headers, cpuLimitHeaders...)
-	headers = append(headers, memLimitHeaders...)
-
-	csvFile, err := os.Create(c.containerTimeSeriesDataCheckpointName())
-	if err != nil {
-		return err
-	}
-	csvW := csv.NewWriter(csvFile)
-	csvW.Comma = '\t'
-	err = csvW.Write(headers)
-	if err != nil {
-		return err
-	}
-	err = csvW.WriteAll(data)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (c *Comparator) workloadTimeSeriesDataCheckpoint() error {
-	maxHeaderLen := 0
-	maxCpuUsageHeaderLen := 0
-	maxMemUsageHeaderLen := 0
-	maxCpuRequestHeaderLen := 0
-	maxMemRequestHeaderLen := 0
-	maxCpuLimitHeaderLen := 0
-	maxMemLimitHeaderLen := 0
-	maxReplicaHeaderLen := 0
-	for _, kindWorkloads := range c.workloadsTimeSeriesDataCache {
-		for _, workloadTSData := range kindWorkloads {
-
-			cpuUsageLen := 0
-			memUsageLen := 0
-			cpuRequestLen := 0
-			memRequestLen := 0
-			cpuLimitLen := 0
-			memLimitLen := 0
-			replicaLen := 0
-
-			if len(workloadTSData.Cpu) > 0 && workloadTSData.Cpu[0] != nil {
-				cpuUsageLen = len(workloadTSData.Cpu[0].Samples)
-			}
-			if len(workloadTSData.Mem) > 0 && workloadTSData.Mem[0] != nil {
-				memUsageLen = len(workloadTSData.Mem[0].Samples)
-			}
-			if len(workloadTSData.CpuRequests) > 0 && workloadTSData.CpuRequests[0] != nil {
-				cpuRequestLen = len(workloadTSData.CpuRequests[0].Samples)
-			}
-			if len(workloadTSData.MemRequests) > 0 && workloadTSData.MemRequests[0] != nil {
-				memRequestLen = len(workloadTSData.MemRequests[0].Samples)
-			}
-			if len(workloadTSData.CpuLimits) > 0 && workloadTSData.CpuLimits[0] != nil {
-				cpuLimitLen = len(workloadTSData.CpuLimits[0].Samples)
-			}
-			if len(workloadTSData.MemLimits) > 0 && workloadTSData.MemLimits[0] != nil {
-				memLimitLen = len(workloadTSData.MemLimits[0].Samples)
-			}
-			if len(workloadTSData.Replicas) > 0 && workloadTSData.Replicas[0] != nil {
-				replicaLen = len(workloadTSData.Replicas[0].Samples)
-			}
-
-			totalHeaderLen := cpuUsageLen + memUsageLen + cpuRequestLen + memRequestLen + cpuLimitLen + memLimitLen + replicaLen
-			if maxHeaderLen < totalHeaderLen {
-				maxHeaderLen = totalHeaderLen
-			}
-
-			if maxCpuUsageHeaderLen < cpuUsageLen {
-				maxCpuUsageHeaderLen = cpuUsageLen
-			}
-			if maxMemUsageHeaderLen < memUsageLen {
-				maxMemUsageHeaderLen = memUsageLen
-			}
-			if maxCpuRequestHeaderLen < cpuRequestLen {
-				maxCpuRequestHeaderLen = cpuRequestLen
-			}
-			if maxMemRequestHeaderLen < memRequestLen {
-				maxMemRequestHeaderLen = memRequestLen
-			}
-			if maxCpuLimitHeaderLen < cpuLimitLen {
-				maxCpuLimitHeaderLen = cpuLimitLen
-			}
-			if maxMemLimitHeaderLen < memLimitLen {
-				maxMemLimitHeaderLen = memLimitLen
-			}
-			if maxReplicaHeaderLen < replicaLen {
-				maxReplicaHeaderLen = replicaLen
-			}
-		}
-	}
-
-	headers := []string{"Kind", "Namespace", "Name"}
-	cpuUsageHeaders := make([]string, maxCpuUsageHeaderLen)
-	memUsageHeaders := make([]string, maxMemUsageHeaderLen)
-	cpuRequestHeaders := make([]string, maxCpuRequestHeaderLen)
-	memRequestHeaders := make([]string, maxMemRequestHeaderLen)
-	cpuLimitHeaders := make([]string, maxCpuLimitHeaderLen)
-	memLimitHeaders := make([]string, maxMemLimitHeaderLen)
-	replicaHeaders := make([]string, maxReplicaHeaderLen)
-
-	for i := 0; i < maxCpuUsageHeaderLen; i++ {
-		cpuUsageHeaders[i] = fmt.Sprintf("CpuUsage-%v", i)
-	}
-	for i := 0; i < maxMemUsageHeaderLen; i++ {
-		memUsageHeaders[i] = fmt.Sprintf("MemUsage-%v", i)
-	}
-	for i := 0; i < maxCpuRequestHeaderLen; i++ {
-		cpuRequestHeaders[i] = fmt.Sprintf("CpuRequest-%v", i)
-	}
-	for i := 0; i < maxMemRequestHeaderLen; i++ {
-		memRequestHeaders[i] = fmt.Sprintf("MemRequest-%v", i)
-	}
-	for i := 0; i < maxCpuLimitHeaderLen; i++ {
-		cpuLimitHeaders[i] = fmt.Sprintf("CpuLimit-%v", i)
-	}
-	for i := 0; i < maxMemLimitHeaderLen; i++ {
-		memLimitHeaders[i] = fmt.Sprintf("MemLimit-%v", i)
-	}
-	for i := 0; i < maxReplicaHeaderLen; i++ {
-		replicaHeaders[i] = fmt.Sprintf("Replicas-%v", i)
-	}
-
-	var data [][]string
-	for kind, kindWorkloads := range c.workloadsTimeSeriesDataCache {
-		for nn, workload := range kindWorkloads {
-			row := []string{kind, nn.Namespace, nn.Name}
-
-			for i := range cpuUsageHeaders {
-				if len(workload.Cpu) > 0 && i < len(workload.Cpu[0].Samples) {
-					sample := workload.Cpu[0].Samples[i]
-					row = append(row, Float642Str(sample.Value))
-					splits := strings.Split(cpuUsageHeaders[i], "-")
-					cpuUsageHeaders[i] = fmt.Sprintf("%v-%v", splits[0]+"-"+splits[1], sample.Timestamp)
-				} else {
-					row = append(row, "null")
-				}
-			}
-			for i := range memUsageHeaders {
-				if len(workload.Mem) > 0 && i < len(workload.Mem[0].Samples) {
-					sample := workload.Mem[0].Samples[i]
-					row = append(row, Float642Str(sample.Value))
-					splits := strings.Split(memUsageHeaders[i], "-")
-					memUsageHeaders[i] = fmt.Sprintf("%v-%v", splits[0]+"-"+splits[1], sample.Timestamp)
-				} else {
-					row = append(row, "null")
-				}
-			}
-			for i := range cpuRequestHeaders {
-				if len(workload.CpuRequests) > 0 && i < len(workload.CpuRequests[0].Samples) {
-					sample := workload.CpuRequests[0].Samples[i]
-					row = append(row, Float642Str(sample.Value))
-					splits := strings.Split(cpuRequestHeaders[i], "-")
-					cpuRequestHeaders[i] = fmt.Sprintf("%v-%v", splits[0]+"-"+splits[1], sample.Timestamp)
-				} else {
-					row = append(row, "null")
-				}
-			}
-			for i := range memRequestHeaders {
-				if len(workload.MemRequests) > 0 && i < len(workload.MemRequests[0].Samples) {
-					sample := workload.MemRequests[0].Samples[i]
-					row = append(row, Float642Str(sample.Value))
-					splits := strings.Split(memRequestHeaders[i], "-")
-					memRequestHeaders[i] = fmt.Sprintf("%v-%v", splits[0]+"-"+splits[1], sample.Timestamp)
-				} else {
-					row = append(row, "null")
-				}
-			}
-			for i := range cpuLimitHeaders {
-				if len(workload.CpuLimits) > 0 && i < len(workload.CpuLimits[0].Samples) {
-					sample := workload.CpuLimits[0].Samples[i]
-					row = append(row, Float642Str(sample.Value))
-					splits := strings.Split(cpuLimitHeaders[i], "-")
-					cpuLimitHeaders[i] = fmt.Sprintf("%v-%v", splits[0]+"-"+splits[1], sample.Timestamp)
-				} else {
-					row = append(row, "null")
-				}
-			}
-			for i := range memLimitHeaders {
-				if len(workload.MemLimits) > 0 && i < len(workload.MemLimits[0].Samples) {
-					sample := workload.MemLimits[0].Samples[i]
-					row = append(row, Float642Str(sample.Value))
-					splits := strings.Split(memLimitHeaders[i], "-")
-					memLimitHeaders[i] = fmt.Sprintf("%v-%v", splits[0]+"-"+splits[1], sample.Timestamp)
-				} else {
-					row = append(row, "null")
-				}
-			}
-			for i := range replicaHeaders {
-				if len(workload.Replicas) > 0 && i < len(workload.Replicas[0].Samples) {
-					sample := workload.Replicas[0].Samples[i]
-					row = append(row, Float642Str(sample.Value))
-					splits := strings.Split(replicaHeaders[i], "-")
-					replicaHeaders[i] = fmt.Sprintf("%v-%v", splits[0]+"-"+splits[1], sample.Timestamp)
-				} else {
-					row = append(row, "null")
-				}
-			}
-			data = append(data, row)
-		}
-	}
-
-	headers = append(headers, cpuUsageHeaders...)
-	headers = append(headers, memUsageHeaders...)
-	headers = append(headers, cpuRequestHeaders...)
-	headers = append(headers, memRequestHeaders...)
-	headers = append(headers, cpuLimitHeaders...)
-	headers = append(headers, memLimitHeaders...)
-	headers = append(headers, replicaHeaders...)
-
-	csvFile, err := os.Create(c.workloadTimeSeriesDataCheckpointName())
-	if err != nil {
-		return err
-	}
-	csvW := csv.NewWriter(csvFile)
-	csvW.Comma = '\t'
-	err = csvW.Write(headers)
-	if err != nil {
-		return err
-	}
-	err = csvW.WriteAll(data)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (c *Comparator) fetchWorkloadMetricData() map[string]map[types.NamespacedName]*RawWorkloadTimeSeriesData {
+func (c *Comparator) fetchWorkloadMetricData(workloads map[string] /*kind*/ map[types.NamespacedName] /*namespace-name*/ spec.CloudPodSpec, qRange promapiv1.Range) map[string]map[types.NamespacedName]*RawWorkloadTimeSeriesData {
 	results := make(map[string]map[types.NamespacedName]*RawWorkloadTimeSeriesData)
-	workloads := c.workloadsSpecCache
-	qRange := c.getQueryRange()
 	for kind := range workloads {
 		if kindWorkloads, ok := workloads[kind]; ok {
 			kindResult, ok := results[kind]
@@ -1268,10 +449,8 @@ func (c *Comparator) fetchWorkloadMetricData() map[string]map[types.NamespacedNa
 	return results
 }
 
-func (c *Comparator) fetchContainerData() map[string] /*kind*/ map[types.NamespacedName] /*namespace-name*/ map[string] /*container*/ *RawContainerTimeSeriesData {
+func (c *Comparator) fetchContainerData(workloads map[string] /*kind*/ map[types.NamespacedName] /*namespace-name*/ spec.CloudPodSpec, qRange promapiv1.Range) map[string] /*kind*/ map[types.NamespacedName] /*namespace-name*/ map[string] /*container*/ *RawContainerTimeSeriesData {
 	results := make(map[string]map[types.NamespacedName]map[string]*RawContainerTimeSeriesData)
-	workloads := c.workloadsSpecCache
-	qRange := c.getQueryRange()
 	for kind := range workloads {
 		if kindWorkloads, ok := workloads[kind]; ok {
 			kindResult, ok := results[kind]
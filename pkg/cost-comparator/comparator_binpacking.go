@@ -0,0 +1,292 @@
+package cost_comparator
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	resourcehelper "k8s.io/kubernetes/pkg/api/v1/resource"
+
+	"github.com/gocrane/fadvisor/pkg/cloud"
+	"github.com/gocrane/fadvisor/pkg/consts"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/simulator"
+	"github.com/gocrane/fadvisor/pkg/spec"
+)
+
+// these labels are different for each node or bound to the instance type, they do not make sense for a node pool
+var nodePoolIgnoredLabels = map[string]bool{
+	v1.LabelHostname:                     true,
+	v1.LabelInstanceType:                 true,
+	v1.LabelInstanceTypeStable:           true,
+	"cloud.tencent.com/node-instance-id": true,
+}
+
+// BinPackingRecommended simulates packing the recommended workloads to the node pools of the cluster,
+// the candidate instance types of each pool come from the standard pricing of the provider, or the pricing of the instances in the cluster if no standard pricing.
+func (c *Comparator) BinPackingRecommended(nodesSpec map[string]spec.CloudNodeSpec, workloadsRecs map[string]map[types.NamespacedName]*spec.WorkloadRecommendedData) *simulator.Result {
+	candidates := c.candidateInstanceTypes()
+	pools := buildNodePools(nodesSpec, candidates, c.podsPerNode())
+	pods, daemonSets := recommendedPods(workloadsRecs)
+	return simulator.Simulate(pools, pods, daemonSets)
+}
+
+// podsPerNode return the number of the pods in scope running on each node, the daemonset pods are not counted
+func (c *Comparator) podsPerNode() map[string]int {
+	counts := make(map[string]int)
+	for _, pod := range c.podsInScope() {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "DaemonSet" {
+			continue
+		}
+		counts[pod.Spec.NodeName]++
+	}
+	return counts
+}
+
+func (c *Comparator) candidateInstanceTypes() []simulator.InstanceType {
+	pricing := c.instanceTypesPricing()
+
+	var results []simulator.InstanceType
+	seen := make(map[string]bool)
+	for key, price := range pricing {
+		it, ok := Price2InstanceType(price)
+		if !ok {
+			klog.V(4).Infof("Ignore instance type %v, no valid price", key)
+			continue
+		}
+		id := it.Zone + "," + it.Name + "," + it.ChargeType
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		results = append(results, it)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].HourlyPrice < results[j].HourlyPrice
+	})
+	return results
+}
+
+// instanceTypesPricing return the pricing of the instance types from the catalog file, or the standard pricing of the provider,
+// or the pricing of the instances in the cluster if no standard pricing.
+func (c *Comparator) instanceTypesPricing() map[string]*cloud.Price {
+	var pricing map[string]*cloud.Price
+	var err error
+	if c.config.InstanceCatalogFile != "" {
+		pricing, err = LoadInstanceCatalog(c.config.InstanceCatalogFile)
+		if err != nil {
+			klog.Errorf("Failed to load instance catalog %v: %v", c.config.InstanceCatalogFile, err)
+		}
+		if converter, ok := c.baselineCloud.(cloud.PricingConverter); ok {
+			pricing = converter.ConvertPricing(pricing)
+		}
+	} else {
+		pricing, err = c.baselineCloud.GetStandardPricing()
+		if err != nil {
+			klog.Errorf("Failed to get standard pricing: %v", err)
+		}
+	}
+	if len(pricing) == 0 {
+		pricing, err = c.baselineCloud.GetNodesPricing()
+		if err != nil {
+			klog.Errorf("Failed to get nodes pricing: %v", err)
+		}
+	}
+	return pricing
+}
+
+// LoadInstanceCatalog load the instance types pricing list from a json file, the file content is a list of cloud.Price
+func LoadInstanceCatalog(filename string) (map[string]*cloud.Price, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var prices []*cloud.Price
+	if err = json.Unmarshal(data, &prices); err != nil {
+		return nil, err
+	}
+	results := make(map[string]*cloud.Price)
+	for _, price := range prices {
+		results[price.Zone+","+price.InstanceType+","+price.ChargeType] = price
+	}
+	return results, nil
+}
+
+// Price2InstanceType convert the cloud price to the instance type, vcpu is in cores and memory is in GB
+func Price2InstanceType(price *cloud.Price) (simulator.InstanceType, bool) {
+	hourlyPrice, ok := price.HourlyCost()
+	if !ok || hourlyPrice <= 0 {
+		return simulator.InstanceType{}, false
+	}
+	cpu, err := strconv.ParseFloat(price.VCpu, 64)
+	if err != nil || cpu <= 0 {
+		return simulator.InstanceType{}, false
+	}
+	mem, err := strconv.ParseFloat(price.Memory, 64)
+	if err != nil || mem <= 0 {
+		return simulator.InstanceType{}, false
+	}
+	return simulator.InstanceType{
+		Name:        price.InstanceType,
+		Family:      price.InstanceFamily,
+		Zone:        price.Zone,
+		ChargeType:  price.ChargeType,
+		Spot:        price.Spot,
+		Cpu:         int64(cpu * 1000),
+		Mem:         int64(mem * consts.GB),
+		HourlyPrice: hourlyPrice,
+	}, true
+}
+
+// buildNodePools groups the real nodes to pools by nodePoolOf, the weight of a pool is the number of the pods on its nodes by podsPerNode.
+func buildNodePools(nodesSpec map[string]spec.CloudNodeSpec, candidates []simulator.InstanceType, podsPerNode map[string]int) []*simulator.NodePool {
+	type poolStat struct {
+		pool        *simulator.NodePool
+		zone        string
+		chargeType  string
+		nodes       int
+		cpuReserved float64
+		memReserved float64
+	}
+	stats := make(map[string]*poolStat)
+	for _, nodeSpec := range nodesSpec {
+		if nodeSpec.VirtualNode || nodeSpec.NodeRef == nil {
+			continue
+		}
+		node := nodeSpec.NodeRef
+		key, name, poolLabels, taints := nodePoolOf(nodeSpec)
+		stat, ok := stats[key]
+		if !ok {
+			stat = &poolStat{
+				pool: &simulator.NodePool{
+					Name:   name,
+					Labels: poolLabels,
+					Taints: taints,
+				},
+				zone:       nodeSpec.Zone,
+				chargeType: nodeSpec.ChargeType,
+			}
+			stats[key] = stat
+		}
+		stat.nodes++
+		stat.pool.Weight += float64(podsPerNode[node.Name])
+		capacityCpu := node.Status.Capacity[v1.ResourceCPU]
+		capacityMem := node.Status.Capacity[v1.ResourceMemory]
+		allocatableCpu := node.Status.Allocatable[v1.ResourceCPU]
+		allocatableMem := node.Status.Allocatable[v1.ResourceMemory]
+		if capacityCpu.MilliValue() > 0 && !allocatableCpu.IsZero() {
+			stat.cpuReserved += float64(capacityCpu.MilliValue()-allocatableCpu.MilliValue()) / float64(capacityCpu.MilliValue())
+		}
+		if capacityMem.Value() > 0 && !allocatableMem.IsZero() {
+			stat.memReserved += float64(capacityMem.Value()-allocatableMem.Value()) / float64(capacityMem.Value())
+		}
+	}
+
+	var pools []*simulator.NodePool
+	for _, stat := range stats {
+		stat.pool.CpuReservedRatio = stat.cpuReserved / float64(stat.nodes)
+		stat.pool.MemReservedRatio = stat.memReserved / float64(stat.nodes)
+		var sameChargeType, sameZone []simulator.InstanceType
+		for _, it := range candidates {
+			if it.Zone != "" && stat.zone != "" && it.Zone != stat.zone {
+				continue
+			}
+			sameZone = append(sameZone, it)
+			if it.ChargeType == stat.chargeType {
+				sameChargeType = append(sameChargeType, it)
+			}
+		}
+		if len(sameChargeType) > 0 {
+			stat.pool.InstanceTypes = sameChargeType
+		} else {
+			stat.pool.InstanceTypes = sameZone
+		}
+		if len(stat.pool.InstanceTypes) == 0 {
+			klog.Warningf("No candidate instance types for node pool %v", stat.pool.Name)
+		}
+		pools = append(pools, stat.pool)
+	}
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].Name < pools[j].Name
+	})
+	return pools
+}
+
+// nodePoolOf return the pool key, name, labels and taints of the node. nodes are grouped by zone, charge type, taints and labels.
+func nodePoolOf(nodeSpec spec.CloudNodeSpec) (string, string, map[string]string, []v1.Taint) {
+	node := nodeSpec.NodeRef
+	poolLabels := make(map[string]string)
+	for k, v := range node.Labels {
+		if nodePoolIgnoredLabels[k] || v == node.Name {
+			continue
+		}
+		poolLabels[k] = v
+	}
+	var taints []v1.Taint
+	for _, taint := range node.Spec.Taints {
+		// taints added by node lifecycle controller are transient
+		if strings.HasPrefix(taint.Key, "node.kubernetes.io/") {
+			continue
+		}
+		taints = append(taints, taint)
+	}
+
+	var parts []string
+	for k, v := range poolLabels {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	var taintParts []string
+	for _, taint := range taints {
+		taintParts = append(taintParts, taint.ToString())
+	}
+	sort.Strings(taintParts)
+	key := nodeSpec.Zone + "|" + nodeSpec.ChargeType + "|" + strings.Join(parts, ",") + "|" + strings.Join(taintParts, ",")
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	name := fmt.Sprintf("%s-%s-%08x", nodeSpec.Zone, strings.ToLower(nodeSpec.ChargeType), h.Sum32())
+	return key, name, poolLabels, taints
+}
+
+// recommendedPods return the recommended pods of the workloads, daemonset pods are returned separately because they run on each node.
+func recommendedPods(workloadsRecs map[string]map[types.NamespacedName]*spec.WorkloadRecommendedData) ([]simulator.Pod, []simulator.Pod) {
+	var pods, daemonSets []simulator.Pod
+	for kind, kindWorkloads := range workloadsRecs {
+		isDaemonSet := strings.ToLower(kind) == "daemonset"
+		for nn, rec := range kindWorkloads {
+			podRef := rec.RecommendedSpec.PodRef
+			if podRef == nil {
+				continue
+			}
+			reqs, _ := resourcehelper.PodRequestsAndLimits(podRef)
+			cpu := reqs[v1.ResourceCPU]
+			mem := reqs[v1.ResourceMemory]
+			pod := simulator.Pod{
+				Name:         nn.String(),
+				Cpu:          cpu.MilliValue(),
+				Mem:          mem.Value(),
+				NodeSelector: podRef.Spec.NodeSelector,
+				Tolerations:  podRef.Spec.Tolerations,
+				Replicas:     rec.RecommendedSpec.GoodsNum,
+			}
+			if isDaemonSet {
+				pod.Replicas = 1
+				daemonSets = append(daemonSets, pod)
+			} else {
+				pods = append(pods, pod)
+			}
+		}
+	}
+	return pods, daemonSets
+}
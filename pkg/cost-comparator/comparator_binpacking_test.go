@@ -0,0 +1,98 @@
+package cost_comparator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+
+	"github.com/gocrane/fadvisor/pkg/cloud"
+	"github.com/gocrane/fadvisor/pkg/spec"
+)
+
+func TestLoadInstanceCatalog(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "catalog.json")
+	err := ioutil.WriteFile(filename, []byte(`[
+{"instanceType": "S5.LARGE8", "chargeType": "POSTPAID_BY_HOUR", "zone": "ap-guangzhou-3", "vcpu": "4", "memory": "8", "cvmPrice": {"unitPrice": 0.8}},
+{"instanceType": "S5.LARGE8", "chargeType": "PREPAID", "zone": "ap-guangzhou-3", "vcpu": "4", "memory": "8", "monthly": true, "cvmPrice": {"originalPrice": 432}},
+{"instanceType": "S5.LARGE8", "chargeType": "SPOTPAID", "zone": "ap-guangzhou-3", "vcpu": "4", "memory": "8", "spot": true, "cvmPrice": {"unitPrice": 0.16}}
+]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pricing, err := LoadInstanceCatalog(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]struct {
+		hourlyPrice float64
+		spot        bool
+	}{
+		"ap-guangzhou-3,S5.LARGE8,POSTPAID_BY_HOUR": {0.8, false},
+		// the monthly price is averaged by 30 days
+		"ap-guangzhou-3,S5.LARGE8,PREPAID":  {0.6, false},
+		"ap-guangzhou-3,S5.LARGE8,SPOTPAID": {0.16, true},
+	} {
+		it, ok := Price2InstanceType(pricing[key])
+		if !ok {
+			t.Fatalf("%v: invalid price %+v", key, pricing[key])
+		}
+		if math.Abs(it.HourlyPrice-expected.hourlyPrice) > 1e-9 || it.Spot != expected.spot || it.Cpu != 4000 {
+			t.Errorf("%v: unexpected instance type %+v", key, it)
+		}
+	}
+}
+
+func TestBinPackingZones(t *testing.T) {
+	node := func(name, zone string) *v1.Node {
+		return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{v1.LabelTopologyZone: zone}}}
+	}
+	nodes := []*v1.Node{node("node-a1", "ap-guangzhou-3"), node("node-a2", "ap-guangzhou-3"), node("node-b1", "ap-guangzhou-4")}
+	nodesSpec := make(map[string]spec.CloudNodeSpec)
+	for _, n := range nodes {
+		nodesSpec[n.Name] = spec.CloudNodeSpec{NodeRef: n, Zone: n.Labels[v1.LabelTopologyZone], ChargeType: "POSTPAID_BY_HOUR"}
+	}
+	var pods []*v1.Pod
+	for i, nodeName := range []string{"node-a1", "node-a2", "node-b1"} {
+		pods = append(pods, &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: fmt.Sprintf("web-%v", i)}, Spec: v1.PodSpec{NodeName: nodeName}})
+	}
+	// the daemonset pods do not change the spread
+	pods = append(pods, &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "agent", OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: "agent", Controller: pointer.Bool(true)}}},
+		Spec:       v1.PodSpec{NodeName: "node-b1"},
+	})
+	clusterCache := &fakeCache{pods: pods, nodes: nodes}
+	instanceType := func(zone string) *cloud.Price {
+		return &cloud.Price{InstanceType: "S5.LARGE8", Zone: zone, ChargeType: "POSTPAID_BY_HOUR", VCpu: "4", Memory: "8", CvmPrice: &cloud.PriceItem{UnitPrice: pointer.Float64(0.8)}}
+	}
+	c := &Comparator{
+		clusterCache: clusterCache,
+		baselineCloud: &fakeCloud{cache: clusterCache, pricing: map[string]*cloud.Price{
+			"gz-3": instanceType("ap-guangzhou-3"),
+			"gz-4": instanceType("ap-guangzhou-4"),
+		}},
+	}
+	web := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-0"}, Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app", Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+		v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1Gi"),
+	}}}}}}
+	workloadsRecs := map[string]map[types.NamespacedName]*spec.WorkloadRecommendedData{"Deployment": {
+		{Namespace: "default", Name: "web"}: {RecommendedSpec: spec.CloudPodSpec{PodRef: web, GoodsNum: 3}},
+	}}
+
+	result := c.BinPackingRecommended(nodesSpec, workloadsRecs)
+	// the three replicas fit one node, but they keep running in both zones like the current pods
+	zones := make(map[string]int)
+	for _, n := range result.Nodes {
+		zones[n.InstanceType.Zone] += len(n.Pods)
+	}
+	if zones["ap-guangzhou-3"] != 2 || zones["ap-guangzhou-4"] != 1 || len(result.Unschedulable) != 0 {
+		t.Fatalf("unexpected replicas of the zones %v, unschedulable %v", zones, result.Unschedulable)
+	}
+}
@@ -0,0 +1,273 @@
+package cost_comparator
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/gocrane/crane/pkg/common"
+	promapiv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/checkpoint"
+	"github.com/gocrane/fadvisor/pkg/spec"
+)
+
+func (c *Comparator) containerTimeSeriesDataCheckpointName() string {
+	return filepath.Join(c.config.DataPath, c.config.ClusterId+"-workloads-container-timeseries.ckpt")
+}
+
+func (c *Comparator) workloadTimeSeriesDataCheckpointName() string {
+	return filepath.Join(c.config.DataPath, c.config.ClusterId+"-workloads-timeseries.ckpt")
+}
+
+// checkpointHeader identifies the time series data of this run, the checkpoint of another cluster, datasource or step is not loaded
+func (c *Comparator) checkpointHeader(kind checkpoint.Kind) checkpoint.Header {
+	return checkpoint.Header{
+		Kind:       kind,
+		ClusterId:  c.config.ClusterId,
+		DataSource: c.config.DataSource,
+		Step:       c.config.History.Step,
+	}
+}
+
+// fetchFunc fetches the time series data of the workloads in the time range as checkpoint entries
+type fetchFunc func(workloads map[string]map[types.NamespacedName]spec.CloudPodSpec, qRange promapiv1.Range) []*checkpoint.Entry
+
+// loadIncrementally extends the checkpoint to the analysis range. Only the head and tail windows not covered by the checkpoint,
+// and the workloads not in the checkpoint are fetched from the datasource, then they are merged with the checkpoint data.
+// The data out of the analysis range is trimmed. If persist, the fetched windows are appended to the checkpoint,
+// and the checkpoint is rewritten without the out of range data once it holds more than twice the history length.
+// In offline mode nothing is fetched, the checkpoint must cover the analysis range and all the workloads.
+func (c *Comparator) loadIncrementally(file string, kind checkpoint.Kind, qRange promapiv1.Range, persist bool, fetch fetchFunc) ([]*checkpoint.Entry, error) {
+	header := c.checkpointHeader(kind)
+	analysisWindow := checkpoint.Window{Start: qRange.Start, End: qRange.End}
+
+	cp, err := checkpoint.Read(file, &header)
+	if err == nil {
+		cpStart, cpEnd := cp.Range()
+		switch {
+		case !cp.Covers(cpStart, cpEnd):
+			err = fmt.Errorf("checkpoint windows %v have gaps", cp.Windows)
+		case cpEnd.Before(qRange.Start) || cpStart.After(qRange.End):
+			err = fmt.Errorf("checkpoint range [%v, %v] does not overlap the analysis range [%v, %v]", cpStart, cpEnd, qRange.Start, qRange.End)
+		}
+	}
+	if err != nil && c.config.Offline {
+		return nil, fmt.Errorf("failed to load %v time series data from checkpoint %v in offline mode: %v", kind, file, err)
+	}
+	if err != nil {
+		klog.Errorf("Failed to load %v time series data from checkpoint: %v, init from datasource", kind, err)
+		entries := fetch(c.workloadsSpecCache, qRange)
+		if persist && len(entries) > 0 {
+			klog.V(2).Infof("Succeed to load %v time series data from data source, checkpointing it", kind)
+			if err = checkpoint.Write(file, header, analysisWindow, entries); err != nil {
+				return entries, err
+			}
+		}
+		return entries, nil
+	}
+
+	cpStart, cpEnd := cp.Range()
+	var windows []checkpoint.Window
+	if cpStart.After(qRange.Start.Add(qRange.Step)) {
+		windows = append(windows, checkpoint.Window{Start: qRange.Start, End: cpStart})
+	}
+	if cpEnd.Add(qRange.Step).Before(qRange.End) {
+		windows = append(windows, checkpoint.Window{Start: cpEnd, End: qRange.End})
+	}
+
+	if c.config.Offline {
+		if len(windows) > 0 {
+			return nil, fmt.Errorf("%v checkpoint range [%v, %v] does not cover the analysis range [%v, %v] in offline mode", kind, cpStart, cpEnd, qRange.Start, qRange.End)
+		}
+		if missing := c.workloadsNotIn(cp.Entries); len(missing) > 0 {
+			return nil, fmt.Errorf("%v checkpoint has no data of the workloads %v in offline mode", kind, workloadNames(missing))
+		}
+	}
+
+	var fetched [][]*checkpoint.Entry
+	for _, window := range windows {
+		klog.Infof("Fetching %v time series data of window [%v, %v] not in checkpoint", kind, window.Start, window.End)
+		fetched = append(fetched, fetch(c.workloadsSpecCache, promapiv1.Range{Start: window.Start, End: window.End, Step: qRange.Step}))
+	}
+	if missing := c.workloadsNotIn(cp.Entries); len(missing) > 0 {
+		klog.Infof("Fetching %v time series data of %v kinds of workloads not in checkpoint", kind, len(missing))
+		windows = append(windows, analysisWindow)
+		fetched = append(fetched, fetch(missing, qRange))
+	}
+
+	if persist {
+		if cpStart.Before(qRange.Start.Add(-c.config.History.Length)) {
+			entries := checkpoint.Merge(append([][]*checkpoint.Entry{cp.Entries}, fetched...)...)
+			checkpoint.Trim(entries, qRange.Start, qRange.End)
+			klog.V(2).Infof("Compacting %v time series data checkpoint to [%v, %v]", kind, qRange.Start, qRange.End)
+			if err = checkpoint.Write(file, header, analysisWindow, entries); err != nil {
+				return entries, err
+			}
+			return entries, nil
+		}
+		for i, window := range windows {
+			if len(fetched[i]) == 0 {
+				continue
+			}
+			if err = checkpoint.Append(file, header, window, fetched[i]); err != nil {
+				break
+			}
+		}
+	}
+
+	entries := checkpoint.Merge(append([][]*checkpoint.Entry{cp.Entries}, fetched...)...)
+	checkpoint.Trim(entries, qRange.Start, qRange.End)
+	return entries, err
+}
+
+// workloadsNotIn returns the workloads which have no data in the entries
+func (c *Comparator) workloadsNotIn(entries []*checkpoint.Entry) map[string]map[types.NamespacedName]spec.CloudPodSpec {
+	existing := make(map[string]map[types.NamespacedName]bool)
+	for _, e := range entries {
+		if existing[e.Kind] == nil {
+			existing[e.Kind] = make(map[types.NamespacedName]bool)
+		}
+		existing[e.Kind][types.NamespacedName{Namespace: e.Namespace, Name: e.Name}] = true
+	}
+	missing := make(map[string]map[types.NamespacedName]spec.CloudPodSpec)
+	for kind, kindWorkloads := range c.workloadsSpecCache {
+		for nn, workload := range kindWorkloads {
+			if existing[kind][nn] {
+				continue
+			}
+			if missing[kind] == nil {
+				missing[kind] = make(map[types.NamespacedName]spec.CloudPodSpec)
+			}
+			missing[kind][nn] = workload
+		}
+	}
+	return missing
+}
+
+// ReadWorkloadTimeSeriesCheckpoint reads the workload time series data checkpoint of any run
+func ReadWorkloadTimeSeriesCheckpoint(file string) (map[string] /*kind*/ map[types.NamespacedName] /*namespace-name*/ *RawWorkloadTimeSeriesData, error) {
+	cp, err := checkpoint.Read(file, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cp.Header.Kind != checkpoint.KindWorkload {
+		return nil, fmt.Errorf("checkpoint kind %v is not %v", cp.Header.Kind, checkpoint.KindWorkload)
+	}
+	return workloadDataFromEntries(cp.Entries), nil
+}
+
+func containerDataToEntries(data map[string]map[types.NamespacedName]map[string]*RawContainerTimeSeriesData) []*checkpoint.Entry {
+	var entries []*checkpoint.Entry
+	for kind, kindWorkloads := range data {
+		for nn, containers := range kindWorkloads {
+			for container, tsData := range containers {
+				if tsData == nil {
+					continue
+				}
+				entries = append(entries, &checkpoint.Entry{
+					Kind:      kind,
+					Namespace: nn.Namespace,
+					Name:      nn.Name,
+					Container: container,
+					Metrics: map[string][]*common.TimeSeries{
+						checkpoint.MetricCpu:         tsData.Cpu,
+						checkpoint.MetricMem:         tsData.Mem,
+						checkpoint.MetricCpuRequests: tsData.CpuRequests,
+						checkpoint.MetricMemRequests: tsData.MemRequests,
+						checkpoint.MetricCpuLimits:   tsData.CpuLimits,
+						checkpoint.MetricMemLimits:   tsData.MemLimits,
+					},
+				})
+			}
+		}
+	}
+	return entries
+}
+
+func containerDataFromEntries(entries []*checkpoint.Entry) map[string]map[types.NamespacedName]map[string]*RawContainerTimeSeriesData {
+	result := make(map[string]map[types.NamespacedName]map[string]*RawContainerTimeSeriesData)
+	for _, e := range entries {
+		kindWorkloads, ok := result[e.Kind]
+		if !ok {
+			kindWorkloads = make(map[types.NamespacedName]map[string]*RawContainerTimeSeriesData)
+			result[e.Kind] = kindWorkloads
+		}
+		nn := types.NamespacedName{Namespace: e.Namespace, Name: e.Name}
+		containers, ok := kindWorkloads[nn]
+		if !ok {
+			containers = make(map[string]*RawContainerTimeSeriesData)
+			kindWorkloads[nn] = containers
+		}
+		containers[e.Container] = &RawContainerTimeSeriesData{
+			Cpu:         e.Metrics[checkpoint.MetricCpu],
+			Mem:         e.Metrics[checkpoint.MetricMem],
+			CpuRequests: e.Metrics[checkpoint.MetricCpuRequests],
+			MemRequests: e.Metrics[checkpoint.MetricMemRequests],
+			CpuLimits:   e.Metrics[checkpoint.MetricCpuLimits],
+			MemLimits:   e.Metrics[checkpoint.MetricMemLimits],
+		}
+	}
+	return result
+}
+
+func workloadDataToEntries(data map[string]map[types.NamespacedName]*RawWorkloadTimeSeriesData) []*checkpoint.Entry {
+	var entries []*checkpoint.Entry
+	for kind, kindWorkloads := range data {
+		for nn, tsData := range kindWorkloads {
+			if tsData == nil {
+				continue
+			}
+			entries = append(entries, &checkpoint.Entry{
+				Kind:      kind,
+				Namespace: nn.Namespace,
+				Name:      nn.Name,
+				Metrics: map[string][]*common.TimeSeries{
+					checkpoint.MetricCpu:         tsData.Cpu,
+					checkpoint.MetricMem:         tsData.Mem,
+					checkpoint.MetricCpuRequests: tsData.CpuRequests,
+					checkpoint.MetricMemRequests: tsData.MemRequests,
+					checkpoint.MetricCpuLimits:   tsData.CpuLimits,
+					checkpoint.MetricMemLimits:   tsData.MemLimits,
+					checkpoint.MetricReplicas:    tsData.Replicas,
+				},
+			})
+		}
+	}
+	return entries
+}
+
+func workloadDataFromEntries(entries []*checkpoint.Entry) map[string]map[types.NamespacedName]*RawWorkloadTimeSeriesData {
+	result := make(map[string]map[types.NamespacedName]*RawWorkloadTimeSeriesData)
+	for _, e := range entries {
+		kindWorkloads, ok := result[e.Kind]
+		if !ok {
+			kindWorkloads = make(map[types.NamespacedName]*RawWorkloadTimeSeriesData)
+			result[e.Kind] = kindWorkloads
+		}
+		kindWorkloads[types.NamespacedName{Namespace: e.Namespace, Name: e.Name}] = &RawWorkloadTimeSeriesData{
+			Cpu:         e.Metrics[checkpoint.MetricCpu],
+			Mem:         e.Metrics[checkpoint.MetricMem],
+			CpuRequests: e.Metrics[checkpoint.MetricCpuRequests],
+			MemRequests: e.Metrics[checkpoint.MetricMemRequests],
+			CpuLimits:   e.Metrics[checkpoint.MetricCpuLimits],
+			MemLimits:   e.Metrics[checkpoint.MetricMemLimits],
+			Replicas:    e.Metrics[checkpoint.MetricReplicas],
+		}
+	}
+	return result
+}
+
+// workloadNames returns the sorted kind/namespace/name of the workloads
+func workloadNames(workloads map[string]map[types.NamespacedName]spec.CloudPodSpec) []string {
+	var names []string
+	for kind, kindWorkloads := range workloads {
+		for nn := range kindWorkloads {
+			names = append(names, kind+"/"+nn.String())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,127 @@
+package cost_comparator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gocrane/crane/pkg/common"
+	promapiv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/checkpoint"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/config"
+	"github.com/gocrane/fadvisor/pkg/spec"
+)
+
+func TestLoadIncrementally(t *testing.T) {
+	c := &Comparator{
+		config: config.Config{
+			ClusterId:  "cls-1",
+			DataPath:   t.TempDir(),
+			DataSource: "prom",
+			History:    config.HistoryAnalyzeConfig{Length: time.Hour, Step: time.Minute},
+		},
+		workloadsSpecCache: map[string]map[types.NamespacedName]spec.CloudPodSpec{
+			"Deployment": {{Namespace: "default", Name: "nginx"}: {}},
+		},
+	}
+	var fetched []promapiv1.Range
+	var fetchedWorkloads []int
+	fetch := func(workloads map[string]map[types.NamespacedName]spec.CloudPodSpec, qRange promapiv1.Range) []*checkpoint.Entry {
+		fetched = append(fetched, qRange)
+		fetchedWorkloads = append(fetchedWorkloads, len(workloads["Deployment"]))
+		var entries []*checkpoint.Entry
+		for nn := range workloads["Deployment"] {
+			ts := common.NewTimeSeries()
+			for timestamp := qRange.Start; !timestamp.After(qRange.End); timestamp = timestamp.Add(qRange.Step) {
+				ts.AppendSample(timestamp.Unix(), 1)
+			}
+			entries = append(entries, &checkpoint.Entry{Kind: "Deployment", Namespace: nn.Namespace, Name: nn.Name, Metrics: map[string][]*common.TimeSeries{checkpoint.MetricCpu: {ts}}})
+		}
+		return entries
+	}
+	t0 := time.Unix(1600000000, 0)
+	load := func(start time.Time) []*checkpoint.Entry {
+		fetched, fetchedWorkloads = nil, nil
+		entries, err := c.loadIncrementally(c.workloadTimeSeriesDataCheckpointName(), checkpoint.KindWorkload,
+			promapiv1.Range{Start: start, End: start.Add(time.Hour), Step: time.Minute}, true, fetch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entries
+	}
+	samples := func(entries []*checkpoint.Entry, name string) []common.Sample {
+		for _, e := range entries {
+			if e.Name == name {
+				return e.Metrics[checkpoint.MetricCpu][0].Samples
+			}
+		}
+		return nil
+	}
+
+	// no checkpoint, fetch the whole range
+	load(t0)
+	if len(fetched) != 1 || !fetched[0].Start.Equal(t0) {
+		t.Fatalf("expected full fetch, got %v", fetched)
+	}
+
+	// only fetch the tail window and trim the head
+	entries := load(t0.Add(10 * time.Minute))
+	if len(fetched) != 1 || !fetched[0].Start.Equal(t0.Add(time.Hour)) || !fetched[0].End.Equal(t0.Add(70*time.Minute)) {
+		t.Fatalf("expected tail fetch, got %v", fetched)
+	}
+	nginx := samples(entries, "nginx")
+	if len(nginx) != 61 || nginx[0].Timestamp != t0.Add(10*time.Minute).Unix() {
+		t.Fatalf("unexpected samples, %v samples from %v", len(nginx), nginx[0].Timestamp)
+	}
+
+	// nothing fetched for the covered range, except the new workload
+	c.workloadsSpecCache["Deployment"][types.NamespacedName{Namespace: "default", Name: "redis"}] = spec.CloudPodSpec{}
+	entries = load(t0.Add(5 * time.Minute))
+	if len(fetched) != 1 || fetchedWorkloads[0] != 1 || len(samples(entries, "redis")) != 61 || len(samples(entries, "nginx")) != 61 {
+		t.Fatalf("expected only the new workload fetched, got %v", fetched)
+	}
+
+	// the checkpoint holding more than twice the history length is compacted
+	load(t0.Add(70 * time.Minute))
+	cp, err := checkpoint.Read(c.workloadTimeSeriesDataCheckpointName(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start, _ := cp.Range(); len(cp.Windows) != 1 || !start.Equal(t0.Add(70*time.Minute)) {
+		t.Fatalf("expected compacted checkpoint, got windows %v", cp.Windows)
+	}
+
+	// no overlap, fetch the whole range again
+	load(t0.Add(5 * time.Hour))
+	if len(fetched) != 1 || !fetched[0].Start.Equal(t0.Add(5*time.Hour)) || fetchedWorkloads[0] != 2 {
+		t.Fatalf("expected full fetch, got %v", fetched)
+	}
+
+	// nothing is fetched offline, the checkpoint must cover the range and the workloads of the same datasource
+	c.config.Offline = true
+	load(t0.Add(5 * time.Hour))
+	if len(fetched) != 0 {
+		t.Fatalf("expected no fetch offline, got %v", fetched)
+	}
+	loadOffline := func(start time.Time) error {
+		_, err := c.loadIncrementally(c.workloadTimeSeriesDataCheckpointName(), checkpoint.KindWorkload,
+			promapiv1.Range{Start: start, End: start.Add(time.Hour), Step: time.Minute}, false, fetch)
+		return err
+	}
+	if err = loadOffline(t0.Add(6 * time.Hour)); err == nil {
+		t.Errorf("expected error of the range not covered offline")
+	}
+	c.workloadsSpecCache["Deployment"][types.NamespacedName{Namespace: "default", Name: "mysql"}] = spec.CloudPodSpec{}
+	if err = loadOffline(t0.Add(5 * time.Hour)); err == nil {
+		t.Errorf("expected error of the workload not in checkpoint offline")
+	}
+	delete(c.workloadsSpecCache["Deployment"], types.NamespacedName{Namespace: "default", Name: "mysql"})
+	c.config.DataSource = "metricserver"
+	if err = loadOffline(t0.Add(5 * time.Hour)); err == nil {
+		t.Errorf("expected error of the checkpoint of another datasource offline")
+	}
+	if len(fetched) != 0 {
+		t.Fatalf("expected no fetch offline, got %v", fetched)
+	}
+}
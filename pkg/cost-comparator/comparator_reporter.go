@@ -1,24 +1,182 @@
 package cost_comparator
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
 
-	"github.com/olekukonko/tablewriter"
+	"github.com/gocrane/crane/pkg/common"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
 	resourcehelper "k8s.io/kubernetes/pkg/api/v1/resource"
 
+	"github.com/gocrane/fadvisor/pkg/cloud"
 	"github.com/gocrane/fadvisor/pkg/consts"
 	"github.com/gocrane/fadvisor/pkg/cost-comparator/config"
 	"github.com/gocrane/fadvisor/pkg/cost-comparator/coster"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/report"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/simulator"
 	"github.com/gocrane/fadvisor/pkg/util"
 )
 
+// newReport creates the report model, all the reporters add their tables to it
+func (c *Comparator) newReport() {
+	c.report = &report.Report{
+		ClusterId:       c.config.ClusterId,
+		ClusterName:     c.config.ClusterName,
+		TimeSpanSeconds: c.config.TimeSpanSeconds,
+		Discount:        c.config.Discount,
+		GeneratedAt:     time.Now(),
+	}
+	if c.baselineCloud != nil {
+		if cfg, err := c.baselineCloud.GetConfig(); err == nil && cfg != nil {
+			c.report.Currency = cfg.Currency
+		}
+	}
+}
+
+// outputEnabled return false in service mode if the output is not enabled, the report is only served by the service then
+func (c *Comparator) outputEnabled(mode string) bool {
+	if c.config.Service.Enabled && !c.config.Service.Output {
+		return false
+	}
+	return c.config.OutputEnabled(mode)
+}
+
+// output adds the table to the report, then renders it to the terminal, csv and parquet files by the output mode.
+// The first error of writing the files is kept and returned by DoAnalysis.
+func (c *Comparator) output(table *report.Table) {
+	c.report.AddTable(table)
+
+	if c.outputEnabled(config.OutputModeStdOut) {
+		fmt.Printf("Reporting, %v.............................................\n", table.Title)
+		report.WriteTable(os.Stdout, table)
+		fmt.Println()
+	}
+	if c.outputEnabled(config.OutputModeCsv) {
+		c.writeFile(c.config.ClusterId+"-"+table.Name+".csv", func(f *os.File) error {
+			return report.WriteCsv(f, table)
+		})
+	}
+	if c.outputEnabled(config.OutputModeParquet) {
+		c.writeFile(c.config.ClusterId+"-"+table.Name+".parquet", func(f *os.File) error {
+			return report.WriteParquet(f, table)
+		})
+	}
+}
+
+// writeReport writes the whole report as a json file or a html page by the output mode
+func (c *Comparator) writeReport() {
+	if c.outputEnabled(config.OutputModeJson) || c.outputEnabled(config.OutputModeHtml) {
+		c.report.Usage = c.workloadsUsage()
+	}
+	if c.outputEnabled(config.OutputModeJson) {
+		c.writeFile(c.config.ClusterId+"-report.json", func(f *os.File) error {
+			return report.WriteJson(f, c.report)
+		})
+	}
+	if c.outputEnabled(config.OutputModeHtml) {
+		c.writeFile(c.config.ClusterId+"-report.html", func(f *os.File) error {
+			return report.WriteHtml(f, c.report)
+		})
+	}
+}
+
+// workloadsUsage sums the cached container usage time series of each workload by timestamp
+func (c *Comparator) workloadsUsage() []*report.WorkloadUsage {
+	sumByTimestamp := func(tsList []*common.TimeSeries, scale float64) []float64 {
+		sums := make(map[int64]float64)
+		for _, ts := range tsList {
+			if ts == nil {
+				continue
+			}
+			for _, sample := range ts.Samples {
+				sums[sample.Timestamp] += sample.Value / scale
+			}
+		}
+		timestamps := make([]int64, 0, len(sums))
+		for timestamp := range sums {
+			timestamps = append(timestamps, timestamp)
+		}
+		sort.Slice(timestamps, func(i, j int) bool {
+			return timestamps[i] < timestamps[j]
+		})
+		values := make([]float64, 0, len(timestamps))
+		for _, timestamp := range timestamps {
+			values = append(values, sums[timestamp])
+		}
+		return values
+	}
+
+	var results []*report.WorkloadUsage
+	for kind, kindWorkloads := range c.containersTimeSeriesDataCache {
+		for nn, containers := range kindWorkloads {
+			var cpu, mem []*common.TimeSeries
+			for _, data := range containers {
+				if data == nil {
+					continue
+				}
+				cpu = append(cpu, data.Cpu...)
+				mem = append(mem, data.Mem...)
+			}
+			results = append(results, &report.WorkloadUsage{
+				Kind:      kind,
+				Namespace: nn.Namespace,
+				Name:      nn.Name,
+				Cpu:       sumByTimestamp(cpu, 1),
+				Mem:       sumByTimestamp(mem, consts.GB),
+			})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Kind != results[j].Kind {
+			return results[i].Kind < results[j].Kind
+		}
+		if results[i].Namespace != results[j].Namespace {
+			return results[i].Namespace < results[j].Namespace
+		}
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
+
+func (c *Comparator) writeFile(name string, write func(f *os.File) error) {
+	if c.outputErr != nil {
+		return
+	}
+	filename := filepath.Join(c.config.DataPath, name)
+	f, err := os.Create(filename)
+	if err != nil {
+		c.outputErr = err
+		return
+	}
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		klog.Errorf("Failed to write %v: %v", filename, err)
+		c.outputErr = fmt.Errorf("failed to write %v: %v", filename, err)
+	}
+}
+
 func (c *Comparator) ReportOriginalWorkloadsResourceDistribution(costerCtx *coster.CosterContext) {
-	data := [][]string{}
+	table := report.NewTable("original-workloads-distribution", "Original Workloads Resource Distribution",
+		report.String("Kind"), report.String("Namespace"), report.String("Name"), report.Float("CpuReq"), report.Float("MemReq"), report.Float("CpuLim"), report.Float("MemLim"),
+		report.Int("Replicas"), report.Bool("Serverless"), report.String("K8SQoS"), report.String("Labels"), report.Float("Cost"))
+	// the serverful workloads cost the share of their requests in the nodes cost, the serverless workloads cost the serverless pods price
+	podsCost := costerCtx.PodsCost
+	if podsCost == nil {
+		var err error
+		podsCost, err = c.baselineCloud.GetPodsCost()
+		if err != nil {
+			klog.Errorf("Failed to get pods cost: %v", err)
+		}
+	}
+	timespanInHour := float64(costerCtx.TimeSpanSeconds) / time.Hour.Seconds()
 	for kind, kindWorkloads := range costerCtx.WorkloadsSpec {
 		for nn, workload := range kindWorkloads {
 
@@ -47,75 +205,18 @@ func (c *Comparator) ReportOriginalWorkloadsResourceDistribution(costerCtx *cost
 				memLimFloat64GB = float64(limMem.Value()) / consts.GB
 			}
 
-			data = append(data,
-				[]string{kind, nn.Namespace, nn.Name, Float642Str(cpuReqFloat64Cores), Float642Str(memReqFloat64GB), Float642Str(cpuLimFloat64Cores), Float642Str(memLimFloat64GB), fmt.Sprintf("%v", workload.GoodsNum), fmt.Sprintf("%v", workload.Serverless), string(workload.QoSClass), labelsStr},
-			)
+			cost := c.workloadHourlyCost(workload, podsCost) * timespanInHour
+			table.Append(kind, nn.Namespace, nn.Name, cpuReqFloat64Cores, memReqFloat64GB, cpuLimFloat64Cores, memLimFloat64GB, workload.GoodsNum, workload.Serverless, string(workload.QoSClass), labelsStr, cost)
 		}
 	}
 
-	fmt.Println("Reporting, Original Workloads Resource Distribution.....................................................................................................")
-	if c.config.OutputMode == "" || c.config.OutputMode == config.OutputModeStdOut {
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeaderLine(true)
-		table.SetAutoFormatHeaders(false)
-		table.SetHeader([]string{"Kind", "Namespace", "Name", "CpuReq", "MemReq", "CpuLim", "MemLim", "Replicas", "Serverless", "K8SQoS", "Labels"})
-		table.SetBorder(false) // Set Border to false
-		table.SetHeaderColor(
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-		)
-
-		table.SetColumnColor(
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-		)
-		table.AppendBulk(data) // Add Bulk Data
-		table.Render()
-	}
-
-	filename := filepath.Join(c.config.DataPath, c.config.ClusterId+"-original-workloads-distribution"+".csv")
-	if c.config.OutputMode == "" || c.config.OutputMode == config.OutputModeCsv {
-		csvFile, err := os.Create(filename)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
-		}
-		csvW := csv.NewWriter(csvFile)
-		csvW.Comma = '\t'
-		err = csvW.Write([]string{"Kind", "Namespace", "Name", "CpuReq", "MemReq", "CpuLim", "MemLim", "Replicas", "Serverless", "K8SQoS", "Labels"})
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
-		}
-		err = csvW.WriteAll(data)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
-		}
-	}
-	fmt.Println()
+	c.output(table)
 }
 
 func (c *Comparator) ReportRecommendedWorkloadsResourceDistribution(costerCtx *coster.CosterContext) {
-	data := [][]string{}
+	table := report.NewTable("recommended-workloads-distribution", "Recommended Workloads Resource Distribution",
+		report.String("Kind"), report.String("Namespace"), report.String("Name"), report.Float("CpuReq"), report.Float("MemReq"), report.Float("CpuLim"), report.Float("MemLim"),
+		report.Int("Replicas"), report.Bool("Serverless"), report.String("K8SQoS"), report.String("ContainerStats"), report.Float("ServerlessCost"))
 	for kind, kindWorkloads := range costerCtx.WorkloadsRecSpec {
 		for nn, workload := range kindWorkloads {
 			cpuReqFloat64Cores := float64(workload.RecommendedSpec.Cpu.MilliValue()) / 1000.
@@ -123,79 +224,17 @@ func (c *Comparator) ReportRecommendedWorkloadsResourceDistribution(costerCtx *c
 			cpuLimFloat64Cores := float64(workload.RecommendedSpec.CpuLimit.MilliValue()) / 1000.
 			memLimFloat64GB := float64(workload.RecommendedSpec.MemLimit.Value()) / consts.GB
 			containerStats, _ := json.Marshal(workload.Containers)
-			data = append(data,
-				[]string{kind, nn.Namespace, nn.Name, Float642Str(cpuReqFloat64Cores), Float642Str(memReqFloat64GB), Float642Str(cpuLimFloat64Cores), Float642Str(memLimFloat64GB), fmt.Sprintf("%v", workload.RecommendedSpec.GoodsNum), fmt.Sprintf("%v", workload.RecommendedSpec.Serverless), string(workload.RecommendedSpec.QoSClass), string(containerStats)},
-			)
-		}
-	}
-
-	fmt.Println("Reporting, Recommended Workloads Resource Distribution.....................................................................................................")
-
-	if c.config.OutputMode == "" || c.config.OutputMode == config.OutputModeStdOut {
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeaderLine(true)
-		table.SetAutoFormatHeaders(false)
-		table.SetHeader([]string{"Kind", "Namespace", "Name", "CpuReq", "MemReq", "CpuLim", "MemLim", "Replicas", "Serverless", "K8SQoS", "ContainerStats"})
-		table.SetBorder(false) // Set Border to false
-		table.SetHeaderColor(
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-		)
-
-		table.SetColumnColor(
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-		)
-
-		table.AppendBulk(data) // Add Bulk Data
-		table.Render()
-	}
-
-	filename := filepath.Join(c.config.DataPath, c.config.ClusterId+"-recommended-workloads-distribution"+".csv")
-	if c.config.OutputMode == "" || c.config.OutputMode == config.OutputModeCsv {
-		csvFile, err := os.Create(filename)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
-		}
-		csvW := csv.NewWriter(csvFile)
-		csvW.Comma = '\t'
-		err = csvW.Write([]string{"Kind", "Namespace", "Name", "CpuReq", "MemReq", "CpuLim", "MemLim", "Replicas", "Serverless", "K8SQoS", "ContainerStats"})
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
-		}
-		err = csvW.WriteAll(data)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
+			table.Append(kind, nn.Namespace, nn.Name, cpuReqFloat64Cores, memReqFloat64GB, cpuLimFloat64Cores, memLimFloat64GB,
+				workload.RecommendedSpec.GoodsNum, workload.RecommendedSpec.Serverless, string(workload.RecommendedSpec.QoSClass), string(containerStats), costerCtx.RecommendedServerlessCost(kind, nn))
 		}
 	}
 
-	fmt.Println()
+	c.output(table)
 }
 
 func (c *Comparator) ReportOriginalResourceSummary() {
 
-	pods := c.clusterCache.GetPods()
+	pods := c.podsInScope()
 	clusterRequestsTotal, clusterLimitsTotal := util.PodsRequestsAndLimitsTotal(pods, func(pod *v1.Pod) bool {
 		return false
 	}, false)
@@ -207,61 +246,25 @@ func (c *Comparator) ReportOriginalResourceSummary() {
 	clusterRealNodesCapacityTotal := util.NodesResourceTotal(nodes, c.baselineCloud.IsVirtualNode, false)
 	clusterVirtualNodesCapacityTotal := util.NodesResourceTotal(nodes, c.baselineCloud.IsVirtualNode, true)
 
-	data := [][]string{
-		{"clusterRequestsTotal", Float642Str(float64(clusterRequestsTotal.Cpu().MilliValue()) / 1000.), Float642Str(float64(clusterRequestsTotal.Memory().Value()) / consts.GB)},
-		{"clusterLimitsTotal", Float642Str(float64(clusterLimitsTotal.Cpu().MilliValue()) / 1000.), Float642Str(float64(clusterLimitsTotal.Memory().Value()) / consts.GB)},
-		{"serverfulRequestsTotal", Float642Str(float64(serverfulRequestsTotal.Cpu().MilliValue()) / 1000.), Float642Str(float64(serverfulRequestsTotal.Memory().Value()) / consts.GB)},
-		{"serverfulLimitsTotal", Float642Str(float64(serverfulLimitsTotal.Cpu().MilliValue()) / 1000.), Float642Str(float64(serverfulLimitsTotal.Memory().Value()) / consts.GB)},
-		{"serverlessRequestsTotal", Float642Str(float64(serverlessRequestsTotal.Cpu().MilliValue()) / 1000.), Float642Str(float64(serverlessRequestsTotal.Memory().Value()) / consts.GB)},
-		{"serverlessLimitsTotal", Float642Str(float64(serverlessLimitsTotal.Cpu().MilliValue()) / 1000.), Float642Str(float64(serverlessLimitsTotal.Memory().Value()) / consts.GB)},
-		{"clusterRealNodesCapacityTotal", Float642Str(float64(clusterRealNodesCapacityTotal.Cpu().MilliValue()) / 1000.), Float642Str(float64(clusterRealNodesCapacityTotal.Memory().Value()) / consts.GB)},
-		{"clusterVirtualNodesCapacityTotal", clusterVirtualNodesCapacityTotal.Cpu().String(), clusterVirtualNodesCapacityTotal.Memory().String()},
-	}
-
-	fmt.Println("Reporting, Original Resource Summary.....................................................................................................")
-
-	if c.config.OutputMode == "" || c.config.OutputMode == config.OutputModeStdOut {
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeaderLine(true)
-		table.SetAutoFormatHeaders(false)
-		table.SetHeader([]string{"Type", "Cpu", "Mem"})
-		table.SetBorder(false) // Set Border to false
-		table.SetHeaderColor(
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor})
-
-		table.SetColumnColor(
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor})
-
-		table.AppendBulk(data) // Add Bulk Data
-		table.Render()
-	}
-
-	filename := filepath.Join(c.config.DataPath, c.config.ClusterId+"-original-resource-summary"+".csv")
-	if c.config.OutputMode == "" || c.config.OutputMode == config.OutputModeCsv {
-		csvFile, err := os.Create(filename)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
-		}
-		csvW := csv.NewWriter(csvFile)
-		csvW.Comma = '\t'
-		err = csvW.Write([]string{"Type", "Cpu", "Mem"})
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
-		}
-		err = csvW.WriteAll(data)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
-		}
+	table := report.NewTable("original-resource-summary", "Original Resource Summary",
+		report.String("Type"), report.Float("Cpu"), report.Float("Mem"))
+	for _, item := range []struct {
+		name      string
+		resources v1.ResourceList
+	}{
+		{"clusterRequestsTotal", clusterRequestsTotal},
+		{"clusterLimitsTotal", clusterLimitsTotal},
+		{"serverfulRequestsTotal", serverfulRequestsTotal},
+		{"serverfulLimitsTotal", serverfulLimitsTotal},
+		{"serverlessRequestsTotal", serverlessRequestsTotal},
+		{"serverlessLimitsTotal", serverlessLimitsTotal},
+		{"clusterRealNodesCapacityTotal", clusterRealNodesCapacityTotal},
+		{"clusterVirtualNodesCapacityTotal", clusterVirtualNodesCapacityTotal},
+	} {
+		table.Append(item.name, float64(item.resources.Cpu().MilliValue())/1000., float64(item.resources.Memory().Value())/consts.GB)
 	}
 
-	fmt.Println()
+	c.output(table)
 }
 
 func Float642Str(a float64) string {
@@ -272,236 +275,270 @@ func (c *Comparator) ReportOriginalCostSummary(costerCtx *coster.CosterContext)
 	serverfulCoster := coster.NewServerfulCoster()
 	originalFee := serverfulCoster.TotalCost(costerCtx)
 
-	data := [][]string{
-		{"tke", Float642Str(originalFee.TotalCost), Float642Str(originalFee.ServerfulCost), Float642Str(originalFee.ServerlessCost), Float642Str(originalFee.ServerfulPlatformCost), Float642Str(originalFee.ServerlessPlatformCost)},
-	}
-
-	fmt.Printf("Reporting, Original Cost Summary(TimeSpan: %v, Discount: %v)............................................................................\n", c.config.TimeSpanSeconds, c.config.Discount)
-
-	if c.config.OutputMode == "" || c.config.OutputMode == config.OutputModeStdOut {
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeaderLine(true)
-		table.SetAutoFormatHeaders(false)
-		table.SetHeader([]string{"Type", "TotalCost", "ServerfulCost", "ServerlessCost", "ServerfulPlatformCost", "ServerlessPlatformCost"})
-		table.SetBorder(false) // Set Border to false
-		table.SetHeaderColor(
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-		)
-
-		table.SetColumnColor(
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-		)
-
-		table.AppendBulk(data) // Add Bulk Data
-		table.Render()
-	}
+	table := report.NewTable("original-cost-summary", fmt.Sprintf("Original Cost Summary(TimeSpan: %v, Discount: %v)", c.config.TimeSpanSeconds, c.config.Discount),
+		report.String("Type"), report.Float("TotalCost"), report.Float("ServerfulCost"), report.Float("ServerlessCost"), report.Float("ServerfulPlatformCost"), report.Float("ServerlessPlatformCost"))
+	table.Append("tke", originalFee.TotalCost, originalFee.ServerfulCost, originalFee.ServerlessCost, originalFee.ServerfulPlatformCost, originalFee.ServerlessPlatformCost)
+	c.report.AddCost("original", originalFee.TotalCost)
 
-	filename := filepath.Join(c.config.DataPath, c.config.ClusterId+"-original-cost-summary"+".csv")
-	if c.config.OutputMode == "" || c.config.OutputMode == config.OutputModeCsv {
-		csvFile, err := os.Create(filename)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
-		}
-		csvW := csv.NewWriter(csvFile)
-		csvW.Comma = '\t'
-		err = csvW.Write([]string{"Type", "TotalCost", "ServerfulCost", "ServerlessCost", "ServerfulPlatformCost", "ServerlessPlatformCost"})
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
-		}
-		err = csvW.WriteAll(data)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
-		}
-	}
-
-	fmt.Println()
+	c.output(table)
 }
 
 func (c *Comparator) ReportRawServerlessCostSummary(costerCtx *coster.CosterContext) {
 	serverlessCoster := coster.NewServerlessCoster()
 	serverlessFee := serverlessCoster.TotalCost(costerCtx)
 
-	data := [][]string{
-		{"eks", Float642Str(serverlessFee.TotalCost), Float642Str(serverlessFee.ServerfulCost), Float642Str(serverlessFee.ServerlessCost), Float642Str(serverlessFee.ServerfulPlatformCost), Float642Str(serverlessFee.ServerlessPlatformCost)},
-	}
-
-	fmt.Printf("Reporting, Direct Migrating to Serverless Cost Summary(TimeSpan: %v, Discount: %v)............................................................................\n", c.config.TimeSpanSeconds, c.config.Discount)
-
-	if c.config.OutputMode == "" || c.config.OutputMode == config.OutputModeStdOut {
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeaderLine(true)
-		table.SetAutoFormatHeaders(false)
-		table.SetHeader([]string{"Type", "TotalCost", "ServerfulCost", "ServerlessCost", "ServerfulPlatformCost", "ServerlessPlatformCost"})
-		table.SetBorder(false) // Set Border to false
-		table.SetHeaderColor(
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-		)
+	table := report.NewTable("direct-migrate-serverless-cost-summary", fmt.Sprintf("Direct Migrating to Serverless Cost Summary(TimeSpan: %v, Discount: %v)", c.config.TimeSpanSeconds, c.config.Discount),
+		report.String("Type"), report.Float("TotalCost"), report.Float("ServerfulCost"), report.Float("ServerlessCost"), report.Float("ServerfulPlatformCost"), report.Float("ServerlessPlatformCost"))
+	table.Append("eks", serverlessFee.TotalCost, serverlessFee.ServerfulCost, serverlessFee.ServerlessCost, serverlessFee.ServerfulPlatformCost, serverlessFee.ServerlessPlatformCost)
+	c.report.AddCost("direct migrating to serverless", serverlessFee.TotalCost)
 
-		table.SetColumnColor(
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-		)
+	c.output(table)
+}
 
-		table.AppendBulk(data) // Add Bulk Data
-		table.Render()
-	}
+func (c *Comparator) ReportRecommendedResourceSummary(costerCtx *coster.CosterContext) {
+	recomendedResourceTotal := ServerlessWorkloadsResourceTotal(costerCtx.WorkloadsRecSpec)
 
-	filename := filepath.Join(c.config.DataPath, c.config.ClusterId+"-direct-migrate-serverless-cost-summary"+".csv")
-	if c.config.OutputMode == "" || c.config.OutputMode == config.OutputModeCsv {
-		csvFile, err := os.Create(filename)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
-		}
-		csvW := csv.NewWriter(csvFile)
-		csvW.Comma = '\t'
-		err = csvW.Write([]string{"Type", "TotalCost", "ServerfulCost", "ServerlessCost", "ServerfulPlatformCost", "ServerlessPlatformCost"})
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
-		}
-		err = csvW.WriteAll(data)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
-		}
-	}
+	table := report.NewTable("recommended-serverless-resource-summary", "Recommended Resource Summary After Migrating to Serverless",
+		report.String("Type"), report.Float("Cpu"), report.Float("Mem"))
+	table.Append("recomendedServerlessResourceTotal", float64(recomendedResourceTotal.Cpu().MilliValue())/1000., float64(recomendedResourceTotal.Memory().Value())/consts.GB)
 
-	fmt.Println()
+	c.output(table)
 }
 
-func (c *Comparator) ReportRecommendedResourceSummary(costerCtx *coster.CosterContext) {
-	recomendedResourceTotal := ServerlessWorkloadsResourceTotal(costerCtx.WorkloadsRecSpec)
+func (c *Comparator) ReportRecommendedCostSummary(costerCtx *coster.CosterContext) {
+	recommendedCoster := coster.NewRecommenderCoster()
+	RecommendedCost, PercentileCost, MaxCost, MaxMarginCost := recommendedCoster.TotalCost(costerCtx)
+
+	table := report.NewTable("recommended-cost-summary", fmt.Sprintf("Recommended Cost Summary After Migrating to Serverless(TimeSpan: %v, Discount: %v)", c.config.TimeSpanSeconds, c.config.Discount),
+		report.String("Type"), report.Float("TotalCost"), report.Float("WorkloadCost"), report.Float("PlatformCost"))
+	table.Append("eks-recommended-by-percentile-margin", RecommendedCost.TotalCost, RecommendedCost.WorkloadCost, RecommendedCost.PlatformCost)
+	table.Append("eks-recommended-by-percentile", PercentileCost.TotalCost, PercentileCost.WorkloadCost, PercentileCost.PlatformCost)
+	table.Append("eks-recommended-by-max-margin", MaxMarginCost.TotalCost, MaxMarginCost.WorkloadCost, MaxMarginCost.PlatformCost)
+	table.Append("eks-recommended-by-max", MaxCost.TotalCost, MaxCost.WorkloadCost, MaxCost.PlatformCost)
+	c.report.AddCost("recommended serverless", RecommendedCost.TotalCost)
+
+	c.output(table)
+}
 
-	data := [][]string{
-		{"recomendedServerlessResourceTotal", Float642Str(float64(recomendedResourceTotal.Cpu().MilliValue()) / 1000.), Float642Str(float64(recomendedResourceTotal.Memory().Value()) / consts.GB)},
+func (c *Comparator) ReportRecommendedBinPackingCostSummary(costerCtx *coster.CosterContext) {
+	result := c.BinPackingRecommended(costerCtx.NodesSpec, costerCtx.WorkloadsRecSpec)
+	timespanInHour := float64(c.config.TimeSpanSeconds) / time.Hour.Seconds()
+
+	type row struct {
+		pool         string
+		instanceType simulator.InstanceType
+		nodes        int
+		cpuUsed      int64
+		memUsed      int64
+		cpuAlloc     int64
+		memAlloc     int64
+	}
+	rows := make(map[string]*row)
+	for _, node := range result.Nodes {
+		key := node.Pool + "," + node.InstanceType.Name
+		r, ok := rows[key]
+		if !ok {
+			r = &row{pool: node.Pool, instanceType: node.InstanceType}
+			rows[key] = r
+		}
+		r.nodes++
+		r.cpuUsed += node.CpuUsed + node.CpuOverhead
+		r.memUsed += node.MemUsed + node.MemOverhead
+		r.cpuAlloc += node.CpuAllocatable
+		r.memAlloc += node.MemAllocatable
+	}
+	keys := make([]string, 0, len(rows))
+	for key := range rows {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	table := report.NewTable("recommended-binpacking-cost-summary",
+		fmt.Sprintf("Recommended Cost Summary By Bin Packing To Serverful Nodes(TimeSpan: %v, Unschedulable Workloads: %v)", c.config.TimeSpanSeconds, len(result.Unschedulable)),
+		report.String("Pool"), report.String("InstanceType"), report.String("ChargeType"), report.Float("Cpu"), report.Float("Mem"), report.Int("Nodes"),
+		report.Float("CpuAllocated"), report.Float("MemAllocated"), report.Float("HourlyPrice"), report.Float("Cost"))
+	for _, key := range keys {
+		r := rows[key]
+		table.Append(
+			r.pool,
+			r.instanceType.Name,
+			r.instanceType.ChargeType,
+			float64(r.instanceType.Cpu)/1000.,
+			float64(r.instanceType.Mem)/consts.GB,
+			r.nodes,
+			float64(r.cpuUsed)/float64(r.cpuAlloc),
+			float64(r.memUsed)/float64(r.memAlloc),
+			r.instanceType.HourlyPrice,
+			r.instanceType.HourlyPrice*float64(r.nodes)*timespanInHour,
+		)
 	}
 
-	fmt.Println("Reporting, Recommended Resource Summary After Migrating to Serverless.....................................................................")
+	nodesNum := int32(len(result.Nodes))
+	platformCost := costerCtx.Pricer.PlatformPrice(cloud.PlatformParameter{Nodes: &nodesNum, Platform: cloud.ServerfulKind})
+	nodesCost := result.HourlyCost * timespanInHour
+	table.Append("platform", nil, nil, nil, nil, nil, nil, nil, nil, platformCost.TotalPrice)
+	table.Append("total", nil, nil, nil, nil, nodesNum, nil, nil, result.HourlyCost, nodesCost+platformCost.TotalPrice)
+	c.report.AddCost("recommended serverful bin packing", nodesCost+platformCost.TotalPrice)
+	for name, replicas := range result.Unschedulable {
+		klog.Warningf("Bin packing simulation, workload %v has %v unschedulable replicas", name, replicas)
+	}
 
-	if c.config.OutputMode == "" || c.config.OutputMode == config.OutputModeStdOut {
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeaderLine(true)
-		table.SetAutoFormatHeaders(false)
-		table.SetHeader([]string{"Type", "Cpu", "Mem"})
-		table.SetBorder(false)
-		// Set Border to false
-		table.SetHeaderColor(
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor})
+	c.output(table)
+}
 
-		table.SetColumnColor(
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor})
+func (c *Comparator) ReportNodesRightsizing(costerCtx *coster.CosterContext) {
+	groups := c.GetNodesRightsizing(costerCtx.NodesSpec)
+	timespanInHour := float64(c.config.TimeSpanSeconds) / time.Hour.Seconds()
 
-		table.AppendBulk(data) // Add Bulk Data
-		table.Render()
+	// ratio is empty for the group without capacity or cost, so no NaN or Inf is reported
+	ratio := func(value, total float64) interface{} {
+		if total == 0 {
+			return nil
+		}
+		return value / total
 	}
-
-	filename := filepath.Join(c.config.DataPath, c.config.ClusterId+"-recommended-serverless-resource-summary"+".csv")
-	if c.config.OutputMode == "" || c.config.OutputMode == config.OutputModeCsv {
-		csvFile, err := os.Create(filename)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
+	utilization := func(usage *int64, capacity int64) interface{} {
+		if usage == nil {
+			return nil
 		}
-		csvW := csv.NewWriter(csvFile)
-		csvW.Comma = '\t'
-		err = csvW.Write([]string{"Type", "Cpu", "Mem"})
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
+		return ratio(float64(*usage), float64(capacity))
+	}
+
+	var rows [][]interface{}
+	totalSavings := 0.
+	for _, group := range groups {
+		suggested := []interface{}{nil, nil, nil, nil, nil, nil}
+		if group.Suggestion != nil {
+			savings := (group.CurrentHourlyCost - group.Suggestion.HourlyCost) * timespanInHour
+			totalSavings += savings
+			suggested = []interface{}{
+				group.Suggestion.InstanceType.Name,
+				group.Suggestion.InstanceType.Family,
+				group.Suggestion.Nodes,
+				group.Suggestion.HourlyCost * timespanInHour,
+				savings,
+				ratio(savings, group.CurrentHourlyCost*timespanInHour),
+			}
 		}
-		err = csvW.WriteAll(data)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
+		row := []interface{}{
+			group.Pool,
+			group.InstanceType,
+			group.ChargeType,
+			group.Nodes,
+			utilization(group.CpuUsage, group.CpuCapacity),
+			utilization(group.MemUsage, group.MemCapacity),
+			ratio(float64(group.CpuRequests), float64(group.CpuCapacity)),
+			ratio(float64(group.MemRequests), float64(group.MemCapacity)),
+			group.CurrentHourlyCost * timespanInHour,
 		}
+		rows = append(rows, append(row, suggested...))
 	}
-	fmt.Println()
-}
 
-func (c *Comparator) ReportRecommendedCostSummary(costerCtx *coster.CosterContext) {
-	recommendedCoster := coster.NewRecommenderCoster()
-	RecommendedCost, PercentileCost, MaxCost, MaxMarginCost := recommendedCoster.TotalCost(costerCtx)
+	table := report.NewTable("nodes-rightsizing", fmt.Sprintf("Nodes Instance Type Rightsizing(TimeSpan: %v, Total Savings: %v)", c.config.TimeSpanSeconds, Float642Str(totalSavings)),
+		report.String("Pool"), report.String("InstanceType"), report.String("ChargeType"), report.Int("Nodes"), report.Float("CpuUtilization"), report.Float("MemUtilization"),
+		report.Float("CpuRequested"), report.Float("MemRequested"), report.Float("Cost"),
+		report.String("SuggestedInstanceType"), report.String("SuggestedFamily"), report.Int("SuggestedNodes"), report.Float("SuggestedCost"), report.Float("Savings"), report.Float("SavingsRatio"))
+	for _, row := range rows {
+		table.Append(row...)
+	}
 
-	data := [][]string{
-		{"eks-recommended-by-percentile-margin", Float642Str(RecommendedCost.TotalCost), Float642Str(RecommendedCost.WorkloadCost), Float642Str(RecommendedCost.PlatformCost)},
-		{"eks-recommended-by-percentile", Float642Str(PercentileCost.TotalCost), Float642Str(PercentileCost.WorkloadCost), Float642Str(PercentileCost.PlatformCost)},
-		{"eks-recommended-by-max-margin", Float642Str(MaxMarginCost.TotalCost), Float642Str(MaxMarginCost.WorkloadCost), Float642Str(MaxMarginCost.PlatformCost)},
-		{"eks-recommended-by-max", Float642Str(MaxCost.TotalCost), Float642Str(MaxCost.WorkloadCost), Float642Str(MaxCost.PlatformCost)},
-	}
-
-	fmt.Printf("Reporting, Recommended Cost Summary After Migrating to Serverless(TimeSpan: %v, Discount: %v).............................................\n", c.config.TimeSpanSeconds, c.config.Discount)
-
-	if c.config.OutputMode == "" || c.config.OutputMode == config.OutputModeStdOut {
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeaderLine(true)
-		table.SetAutoFormatHeaders(false)
-		table.SetHeader([]string{"Type", "TotalCost", "WorkloadCost", "PlatformCost"})
-		table.SetBorder(false) // Set Border to false
-		table.SetHeaderColor(
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-			tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor},
-		)
+	c.output(table)
+}
 
-		table.SetColumnColor(
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
-			tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor},
+func (c *Comparator) ReportSpotSavings(costerCtx *coster.CosterContext) {
+	analyses, result := c.GetWorkloadsSpotAnalysis(costerCtx.WorkloadsSpec)
+	timespanInHour := float64(c.config.TimeSpanSeconds) / time.Hour.Seconds()
+
+	table := report.NewTable("spot-savings",
+		fmt.Sprintf("Spot Savings Of Stateless Workloads(TimeSpan: %v, Min Replicas: %v, Spot Nodes: %v)", c.config.TimeSpanSeconds, c.config.SpotMinReplicas, len(result.Nodes)),
+		report.String("Kind"), report.String("Namespace"), report.String("Name"), report.Int("Replicas"), report.Bool("SpotSafe"), report.String("Reason"),
+		report.Float("CurrentCost"), report.Float("ServerlessCost"), report.Float("SpotCost"), report.Float("SpotSavings"))
+	var spotSafeNum int
+	var currentTotal, serverlessTotal, spotTotal float64
+	for _, analysis := range analyses {
+		var spotCost, savings interface{}
+		if analysis.SpotSafe {
+			spotSafeNum++
+			currentTotal += analysis.CurrentCost
+			serverlessTotal += analysis.ServerlessCost
+			spotTotal += analysis.SpotCost
+			spotCost = analysis.SpotCost * timespanInHour
+			savings = (analysis.CurrentCost - analysis.SpotCost) * timespanInHour
+		}
+		table.Append(
+			analysis.Kind,
+			analysis.Namespace,
+			analysis.Name,
+			analysis.Replicas,
+			analysis.SpotSafe,
+			analysis.Reason,
+			analysis.CurrentCost*timespanInHour,
+			analysis.ServerlessCost*timespanInHour,
+			spotCost,
+			savings,
 		)
-
-		table.AppendBulk(data) // Add Bulk Data
-		table.Render()
 	}
+	// total of the spot safe workloads, Replicas column is the number of spot safe workloads
+	table.Append("total", nil, nil, spotSafeNum, nil, nil,
+		currentTotal*timespanInHour, serverlessTotal*timespanInHour, spotTotal*timespanInHour, (currentTotal-spotTotal)*timespanInHour)
 
-	filename := filepath.Join(c.config.DataPath, c.config.ClusterId+"-recommended-cost-summary"+".csv")
-	if c.config.OutputMode == "" || c.config.OutputMode == config.OutputModeCsv {
-		csvFile, err := os.Create(filename)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
-		}
-		csvW := csv.NewWriter(csvFile)
-		csvW.Comma = '\t'
-		err = csvW.Write([]string{"Type", "TotalCost", "WorkloadCost", "PlatformCost"})
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
+	c.output(table)
+}
+
+func (c *Comparator) ReportReservedInstances(costerCtx *coster.CosterContext) {
+	reservations := c.GetReservedInstancesRecommendation(costerCtx.NodesSpec)
+	timespanInHour := float64(c.config.TimeSpanSeconds) / time.Hour.Seconds()
+
+	table := report.NewTable("reserved-instances",
+		fmt.Sprintf("Reserved Instances Recommendation(TimeSpan: %v, Baseline Percentile: %v)", c.config.TimeSpanSeconds, c.config.ReservedBaselinePercentile),
+		report.String("InstanceType"), report.Int("Nodes"), report.Int("Baseline"), report.Bool("FromHistory"), report.Float("OnDemandHourlyPrice"), report.Int("TermYears"),
+		report.Int("Reserve"), report.Float("EffectiveHourlyPrice"), report.Float("BreakEvenMonths"), report.Float("Savings"), report.Float("TermSavings"))
+
+	type termTotal struct {
+		commitment float64
+		onDemand   float64
+		savings    float64
+	}
+	totals := make(map[int]*termTotal)
+	for _, reservation := range reservations {
+		if len(reservation.Plans) == 0 {
+			table.Append(reservation.InstanceType, reservation.Nodes, reservation.Baseline, reservation.FromHistory, reservation.OnDemandHourlyPrice)
+			continue
 		}
-		err = csvW.WriteAll(data)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(255)
+		for _, plan := range reservation.Plans {
+			total, ok := totals[plan.Years]
+			if !ok {
+				total = &termTotal{}
+				totals[plan.Years] = total
+			}
+			total.commitment += plan.EffectiveHourlyPrice * float64(plan.Count)
+			total.onDemand += reservation.OnDemandHourlyPrice * float64(plan.Count)
+			total.savings += plan.TermSavings
+			table.Append(
+				reservation.InstanceType,
+				reservation.Nodes,
+				reservation.Baseline,
+				reservation.FromHistory,
+				reservation.OnDemandHourlyPrice,
+				plan.Years,
+				plan.Count,
+				plan.EffectiveHourlyPrice,
+				plan.BreakEvenMonths,
+				plan.HourlySavings*timespanInHour,
+				plan.TermSavings,
+			)
 		}
 	}
+	// savings plan view, the total hourly commitment of each term
+	var years []int
+	for y := range totals {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	for _, y := range years {
+		total := totals[y]
+		table.Append("total", nil, nil, nil, total.onDemand, y, nil, total.commitment, nil, (total.onDemand-total.commitment)*timespanInHour, total.savings)
+	}
 
-	fmt.Println()
+	c.output(table)
 }
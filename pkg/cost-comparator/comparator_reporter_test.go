@@ -0,0 +1,190 @@
+package cost_comparator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/fadvisor/pkg/cache"
+	"github.com/gocrane/fadvisor/pkg/cloud"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/config"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/coster"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/report"
+	"github.com/gocrane/fadvisor/pkg/spec"
+)
+
+func TestOutput(t *testing.T) {
+	dir := t.TempDir()
+	c := &Comparator{config: config.Config{ClusterId: "cls-1", DataPath: dir, OutputMode: config.OutputModeCsv}}
+	table := report.NewTable("summary", "Summary", report.String("Type"))
+	table.Append("tke")
+
+	// the report is only served in service mode by default
+	c.config.Service.Enabled = true
+	c.newReport()
+	c.output(table)
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 || len(c.report.Tables) != 1 {
+		t.Fatalf("expect no files written in service mode, got %v", files)
+	}
+
+	c.config.Service.Output = true
+	c.output(table)
+	if _, err := ioutil.ReadFile(filepath.Join(dir, "cls-1-summary.csv")); err != nil || c.outputErr != nil {
+		t.Fatalf("expect the csv written, got %v, %v", err, c.outputErr)
+	}
+
+	// the write error is kept instead of exiting
+	c.config.DataPath = filepath.Join(dir, "missing")
+	c.output(table)
+	if c.outputErr == nil {
+		t.Errorf("expect error of writing to the missing data path")
+	}
+}
+
+type fakeCache struct {
+	cache.Cache
+	pods  []*v1.Pod
+	nodes []*v1.Node
+	pdbs  []*policyv1beta1.PodDisruptionBudget
+	pvs   []*v1.PersistentVolume
+	pvcs  []*v1.PersistentVolumeClaim
+}
+
+func (c *fakeCache) GetPods() []*v1.Pod   { return c.pods }
+func (c *fakeCache) GetNodes() []*v1.Node { return c.nodes }
+func (c *fakeCache) GetPodDisruptionBudgets() []*policyv1beta1.PodDisruptionBudget {
+	return c.pdbs
+}
+func (c *fakeCache) GetPersistentVolumes() []*v1.PersistentVolume           { return c.pvs }
+func (c *fakeCache) GetPersistentVolumeClaims() []*v1.PersistentVolumeClaim { return c.pvcs }
+
+// fakeCloud prices the serverful pods by 0.1 per core and 0.01 per GB hourly, the serverless pods by 0.5 hourly,
+// and the nodes by the hourly price of their instance type in the pricing
+type fakeCloud struct {
+	cloud.Cloud
+	cache   *fakeCache
+	pricing map[string]*cloud.Price
+}
+
+func (f *fakeCloud) IsServerlessPod(pod *v1.Pod) bool         { return pod.Spec.NodeName == "eklet" }
+func (f *fakeCloud) IsVirtualNode(node *v1.Node) bool         { return node.Name == "eklet" }
+func (f *fakeCloud) GetConfig() (*cloud.CustomPricing, error) { return nil, nil }
+func (f *fakeCloud) Pod2Spec(pod *v1.Pod) spec.CloudPodSpec {
+	return spec.CloudPodSpec{PodRef: pod, Serverless: f.IsServerlessPod(pod), GoodsNum: 1}
+}
+func (f *fakeCloud) Pod2ServerlessSpec(pod *v1.Pod) spec.CloudPodSpec {
+	return f.Pod2Spec(pod)
+}
+func (f *fakeCloud) Node2Spec(node *v1.Node) spec.CloudNodeSpec {
+	return spec.CloudNodeSpec{VirtualNode: f.IsVirtualNode(node)}
+}
+func (f *fakeCloud) ServerlessPodPrice(spec.CloudPodSpec) (*cloud.Pod, error) {
+	return &cloud.Pod{BaseInstancePrice: cloud.BaseInstancePrice{Cost: "0.5"}}, nil
+}
+func (f *fakeCloud) PlatformPrice(cloud.PlatformParameter) *cloud.Prices {
+	return &cloud.Prices{TotalPrice: 100}
+}
+func (f *fakeCloud) GetStandardPricing() (map[string]*cloud.Price, error) {
+	return f.pricing, nil
+}
+func (f *fakeCloud) NodePrice(nodeSpec spec.CloudNodeSpec) (*cloud.Node, error) {
+	for _, price := range f.pricing {
+		if price.InstanceType == nodeSpec.InstanceType && price.Zone == nodeSpec.Zone && price.ChargeType == nodeSpec.ChargeType {
+			if hourlyCost, ok := price.HourlyCost(); ok {
+				return &cloud.Node{BaseInstancePrice: cloud.BaseInstancePrice{Cost: fmt.Sprint(hourlyCost)}}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no price of instance type %v", nodeSpec.InstanceType)
+}
+func (f *fakeCloud) GetPodsCost() (map[string]*cloud.Pod, error) {
+	pods := make(map[string]*cloud.Pod)
+	for _, pod := range f.cache.pods {
+		if !f.IsServerlessPod(pod) {
+			pods[klog.KObj(pod).String()] = &cloud.Pod{BaseInstancePrice: cloud.BaseInstancePrice{CpuHourlyCost: "0.1", RamGBHourlyCost: "0.01"}}
+		}
+	}
+	return pods, nil
+}
+
+func TestScopedAnalysis(t *testing.T) {
+	pod := func(namespace, name, node string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec: v1.PodSpec{NodeName: node, Containers: []v1.Container{{Name: "app", Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+				v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("2Gi"),
+			}}}}},
+		}
+	}
+	clusterCache := &fakeCache{
+		pods:  []*v1.Pod{pod("default", "web", "node-1"), pod("default", "eci", "eklet"), pod("team-b", "api", "node-1")},
+		nodes: []*v1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}, {ObjectMeta: metav1.ObjectMeta{Name: "eklet"}}},
+	}
+	c := &Comparator{
+		config:        config.Config{ClusterId: "cls-1", TimeSpanSeconds: 3600, Namespaces: []string{"default"}, OutputMode: config.OutputModeJson},
+		clusterCache:  clusterCache,
+		baselineCloud: &fakeCloud{cache: clusterCache},
+	}
+	analyze := func() []*report.Table {
+		costerCtx, err := c.newCosterContext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.newReport()
+		c.ReportOriginalResourceSummary()
+		c.ReportOriginalCostSummary(costerCtx)
+		return c.report.Tables
+	}
+
+	tables := analyze()
+	// the share of default/web in node-1 and the serverless pod default/eci, no platform cost
+	expected := []interface{}{"tke", 0.1 + 2*0.01 + 0.5, 0.1 + 2*0.01, 0.5, 0., 0.}
+	if cost := tables[1].Rows[0]; math.Abs(cost[1].(float64)-expected[1].(float64)) > 1e-9 || cost[4] != expected[4] {
+		t.Fatalf("expected original cost %v, got %v", expected, cost)
+	}
+
+	// the pods of the namespaces out of scope change no total
+	clusterCache.pods = append(clusterCache.pods, pod("team-b", "api-2", "node-1"), pod("team-c", "job", "eklet"))
+	if got := analyze(); !reflect.DeepEqual(got, tables) {
+		t.Errorf("expected the same tables %v, got %v", tables, got)
+	}
+}
+
+func TestOriginalWorkloadsCost(t *testing.T) {
+	web := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-1"}, Spec: v1.PodSpec{NodeName: "node-1"}}
+	eci := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "eci-1"}, Spec: v1.PodSpec{NodeName: "eklet"}}
+	clusterCache := &fakeCache{pods: []*v1.Pod{web, eci}}
+	c := &Comparator{
+		config:        config.Config{ClusterId: "cls-1", OutputMode: config.OutputModeJson},
+		clusterCache:  clusterCache,
+		baselineCloud: &fakeCloud{cache: clusterCache},
+	}
+	costerCtx := &coster.CosterContext{
+		TimeSpanSeconds: 7200,
+		WorkloadsSpec: map[string]map[types.NamespacedName]spec.CloudPodSpec{"Deployment": {
+			{Namespace: "default", Name: "web"}: {PodRef: web, Cpu: resource.MustParse("1"), Mem: resource.MustParse("2Gi"), GoodsNum: 2, Workload: &unstructured.Unstructured{}},
+			{Namespace: "default", Name: "eci"}: {PodRef: eci, Serverless: true, GoodsNum: 1, Workload: &unstructured.Unstructured{}},
+		}},
+	}
+	c.newReport()
+	c.ReportOriginalWorkloadsResourceDistribution(costerCtx)
+
+	// the serverful workload costs its requests share in the node, the serverless workload costs the serverless pod price
+	expected := map[string]float64{"web": (0.1 + 2*0.01) * 2 * 2, "eci": 0.5 * 2}
+	for _, row := range c.report.Tables[0].Rows {
+		if cost := row[11].(float64); math.Abs(cost-expected[row[2].(string)]) > 1e-9 {
+			t.Errorf("expected cost %v of %v, got %v", expected[row[2].(string)], row[2], cost)
+		}
+	}
+}
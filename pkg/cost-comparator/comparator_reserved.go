@@ -0,0 +1,179 @@
+package cost_comparator
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/fadvisor/pkg/cloud"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/simulator"
+	"github.com/gocrane/fadvisor/pkg/spec"
+)
+
+// InstanceTypeReservation is the reservation recommendation of an instance type
+type InstanceTypeReservation struct {
+	InstanceType string
+	// current node count in the cluster
+	Nodes int
+	// stable node count from the history, it is the percentile of the node count time series
+	Baseline int
+	// false if no history of the node count, the baseline is the current node count
+	FromHistory         bool
+	OnDemandHourlyPrice float64
+	Plans               []simulator.ReservedPlan
+}
+
+// GetReservedInstancesRecommendation recommends how many instances of each instance type to reserve for one, three and five years.
+// The baseline is the configured percentile of the node count by instance type in the history, it is the part of nodes which is always running.
+func (c *Comparator) GetReservedInstancesRecommendation(nodesSpec map[string]spec.CloudNodeSpec) []*InstanceTypeReservation {
+	queryRange := c.getQueryRange()
+	nodeCounts, err := c.metricFetcher.NodeCountByInstanceType(context.TODO(), queryRange.Start, queryRange.End, queryRange.Step)
+	if err != nil {
+		klog.Errorf("Failed to fetch node count by instance type: %v", err)
+	}
+
+	type typeStat struct {
+		nodes     int
+		zones     map[string]int
+		nodesCost float64
+	}
+	stats := make(map[string]*typeStat)
+	for name, nodeSpec := range nodesSpec {
+		if nodeSpec.VirtualNode || nodeSpec.InstanceType == "" {
+			continue
+		}
+		stat, ok := stats[nodeSpec.InstanceType]
+		if !ok {
+			stat = &typeStat{zones: make(map[string]int)}
+			stats[nodeSpec.InstanceType] = stat
+		}
+		stat.nodes++
+		stat.zones[nodeSpec.Zone]++
+		nodePrice, err := c.baselineCloud.NodePrice(nodeSpec)
+		if err != nil {
+			klog.Errorf("Failed to get node %v price: %v", name, err)
+			continue
+		}
+		price, err := strconv.ParseFloat(nodePrice.Cost, 64)
+		if err != nil {
+			klog.V(3).Infof("Could not parse total node price, node: %v, err: %v", name, err)
+			continue
+		}
+		stat.nodesCost += price
+	}
+
+	pricing := c.instanceTypesPricing()
+	var results []*InstanceTypeReservation
+	for instanceType, stat := range stats {
+		reservation := &InstanceTypeReservation{
+			InstanceType: instanceType,
+			Nodes:        stat.nodes,
+			Baseline:     stat.nodes,
+		}
+		if ts, ok := nodeCounts[instanceType]; ok && len(ts.Samples) > 0 {
+			values := make([]float64, 0, len(ts.Samples))
+			for _, sample := range ts.Samples {
+				values = append(values, sample.Value)
+			}
+			reservation.Baseline = int(math.Floor(simulator.Percentile(values, c.config.ReservedBaselinePercentile)))
+			reservation.FromHistory = true
+		} else {
+			klog.Warningf("No node count history of instance type %v, use current node count %v as baseline", instanceType, stat.nodes)
+		}
+
+		onDemand, terms := reservedPricing(pricing, instanceType, stat.zones)
+		if onDemand <= 0 {
+			onDemand = stat.nodesCost / float64(stat.nodes)
+		}
+		reservation.OnDemandHourlyPrice = onDemand
+		reservation.Plans = simulator.PlanReserved(reservation.Baseline, onDemand, terms)
+		results = append(results, reservation)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].InstanceType < results[j].InstanceType
+	})
+	return results
+}
+
+// reservedPricing return the on demand hourly price and the reserved terms price of the instance type in the zones of the nodes,
+// zones is the node count of each zone. The prices are picked deterministically, the zone with most nodes first, then by zone and charge type.
+// The on demand price is the hourly price which is neither monthly nor spot, the terms are of the monthly price. Both are the discount
+// price, or original price if no discount, so the savings are not overstated by comparing the discount terms with the original hourly price.
+func reservedPricing(pricing map[string]*cloud.Price, instanceType string, zones map[string]int) (float64, []simulator.ReservedTerm) {
+	var candidates []*cloud.Price
+	for _, price := range pricing {
+		if price.InstanceType != instanceType || price.CvmPrice == nil {
+			continue
+		}
+		if price.Zone != "" && len(zones) > 0 && zones[price.Zone] == 0 {
+			continue
+		}
+		candidates = append(candidates, price)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if zones[a.Zone] != zones[b.Zone] {
+			return zones[a.Zone] > zones[b.Zone]
+		}
+		if a.Zone != b.Zone {
+			return a.Zone < b.Zone
+		}
+		return a.ChargeType < b.ChargeType
+	})
+
+	var onDemand float64
+	for _, price := range candidates {
+		if price.Monthly || price.Spot {
+			continue
+		}
+		if unitPrice := onDemandPrice(price.CvmPrice); unitPrice > 0 {
+			onDemand = unitPrice
+			break
+		}
+	}
+	for _, price := range candidates {
+		if !price.Monthly {
+			continue
+		}
+		if terms := reservedTerms(price.CvmPrice); len(terms) > 0 {
+			return onDemand, terms
+		}
+	}
+	return onDemand, nil
+}
+
+// onDemandPrice return the hourly discount price, or original price if no discount
+func onDemandPrice(item *cloud.PriceItem) float64 {
+	if item.UnitPriceDiscount != nil && *item.UnitPriceDiscount > 0 {
+		return *item.UnitPriceDiscount
+	}
+	if item.UnitPrice != nil {
+		return *item.UnitPrice
+	}
+	return 0
+}
+
+func reservedTerms(item *cloud.PriceItem) []simulator.ReservedTerm {
+	var terms []simulator.ReservedTerm
+	for _, term := range []struct {
+		years    int
+		discount *float64
+		original *float64
+	}{
+		{1, item.DiscountPriceOneYear, item.OriginalPriceOneYear},
+		{3, item.DiscountPriceThreeYear, item.OriginalPriceThreeYear},
+		{5, item.DiscountPriceFiveYear, item.OriginalPriceFiveYear},
+	} {
+		termPrice := term.discount
+		if termPrice == nil {
+			termPrice = term.original
+		}
+		if termPrice != nil && *termPrice > 0 {
+			terms = append(terms, simulator.ReservedTerm{Years: term.years, Price: *termPrice})
+		}
+	}
+	return terms
+}
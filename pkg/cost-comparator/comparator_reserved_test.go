@@ -0,0 +1,42 @@
+package cost_comparator
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/utils/pointer"
+
+	"github.com/gocrane/fadvisor/pkg/cloud"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/simulator"
+)
+
+func TestReservedPricing(t *testing.T) {
+	price := func(zone, chargeType string, monthly bool, item cloud.PriceItem) *cloud.Price {
+		return &cloud.Price{InstanceType: "S5.LARGE8", Zone: zone, ChargeType: chargeType, Monthly: monthly, CvmPrice: &item}
+	}
+	pricing := map[string]*cloud.Price{
+		"gz-3,hour":  price("ap-guangzhou-3", "POSTPAID_BY_HOUR", false, cloud.PriceItem{UnitPrice: pointer.Float64(0.8), UnitPriceDiscount: pointer.Float64(0.72)}),
+		"gz-4,hour":  price("ap-guangzhou-4", "POSTPAID_BY_HOUR", false, cloud.PriceItem{UnitPrice: pointer.Float64(0.7)}),
+		"gz-4,spot":  price("ap-guangzhou-4", "SPOTPAID", false, cloud.PriceItem{UnitPrice: pointer.Float64(0.2)}),
+		"gz-3,month": price("ap-guangzhou-3", "PREPAID", true, cloud.PriceItem{OriginalPriceOneYear: pointer.Float64(5000), DiscountPriceOneYear: pointer.Float64(4500)}),
+		"gz-4,month": price("ap-guangzhou-4", "PREPAID", true, cloud.PriceItem{OriginalPriceOneYear: pointer.Float64(4000), OriginalPriceThreeYear: pointer.Float64(10000)}),
+		"gz-6,hour":  price("ap-guangzhou-6", "POSTPAID_BY_HOUR", false, cloud.PriceItem{UnitPrice: pointer.Float64(0.1)}),
+		"other-type": {InstanceType: "S5.SMALL2", Zone: "ap-guangzhou-4", ChargeType: "POSTPAID_BY_HOUR", CvmPrice: &cloud.PriceItem{UnitPrice: pointer.Float64(0.1)}},
+	}
+
+	// the prices of the zone with most nodes are picked, the zones without nodes are ignored
+	for i := 0; i < 10; i++ {
+		onDemand, terms := reservedPricing(pricing, "S5.LARGE8", map[string]int{"ap-guangzhou-3": 1, "ap-guangzhou-4": 3})
+		if onDemand != 0.7 || !reflect.DeepEqual(terms, []simulator.ReservedTerm{{Years: 1, Price: 4000}, {Years: 3, Price: 10000}}) {
+			t.Fatalf("unexpected prices %v, %v", onDemand, terms)
+		}
+	}
+	// the zone name breaks the tie, the discount prices of both the hourly price and the term are used
+	onDemand, terms := reservedPricing(pricing, "S5.LARGE8", map[string]int{"ap-guangzhou-3": 2, "ap-guangzhou-4": 2})
+	if onDemand != 0.72 || !reflect.DeepEqual(terms, []simulator.ReservedTerm{{Years: 1, Price: 4500}}) {
+		t.Errorf("unexpected prices %v, %v", onDemand, terms)
+	}
+	if onDemand, terms = reservedPricing(pricing, "S5.LARGE8", map[string]int{"ap-guangzhou-6": 1}); onDemand != 0.1 || terms != nil {
+		t.Errorf("unexpected prices %v, %v", onDemand, terms)
+	}
+}
@@ -0,0 +1,194 @@
+package cost_comparator
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/gocrane/crane/pkg/common"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	resourcehelper "k8s.io/kubernetes/pkg/api/v1/resource"
+
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/simulator"
+	"github.com/gocrane/fadvisor/pkg/spec"
+)
+
+// NodeGroupRightsizing is the utilization and the rightsizing suggestion of a group of nodes with same instance type in the same pool
+type NodeGroupRightsizing struct {
+	Pool         string
+	InstanceType string
+	Zone         string
+	ChargeType   string
+	Nodes        int
+	// capacity of all nodes, milli cores and bytes
+	CpuCapacity int64
+	MemCapacity int64
+	// recommended usage of all nodes by estimator, milli cores and bytes. nil if no usage metric
+	CpuUsage *int64
+	MemUsage *int64
+	// requests of the pods running on the nodes
+	CpuRequests       int64
+	MemRequests       int64
+	CurrentHourlyCost float64
+	Suggestion        *simulator.Suggestion
+}
+
+// GetNodesRightsizing groups the real nodes by pool and instance type, measures their utilization and suggests the cheaper instance types from the candidates.
+// The demand of a group is the max of the recommended usage and the requests of each node, so the suggestion does not break scheduling.
+func (c *Comparator) GetNodesRightsizing(nodesSpec map[string]spec.CloudNodeSpec) []*NodeGroupRightsizing {
+	queryRange := c.getQueryRange()
+	cpuUsed, err := c.metricFetcher.NodeCPUUsed(context.TODO(), queryRange.Start, queryRange.End, queryRange.Step)
+	if err != nil {
+		klog.Errorf("Failed to fetch nodes cpu used: %v", err)
+	}
+	memUsed, err := c.metricFetcher.NodeRAMUsed(context.TODO(), queryRange.Start, queryRange.End, queryRange.Step)
+	if err != nil {
+		klog.Errorf("Failed to fetch nodes ram used: %v", err)
+	}
+
+	nodesRequests := make(map[string]v1.ResourceList)
+	nodesMaxPod := make(map[string]v1.ResourceList)
+	for _, pod := range c.clusterCache.GetPods() {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		reqs, _ := resourcehelper.PodRequestsAndLimits(pod)
+		total, ok := nodesRequests[pod.Spec.NodeName]
+		if !ok {
+			total = v1.ResourceList{}
+			nodesRequests[pod.Spec.NodeName] = total
+		}
+		maxPod, ok := nodesMaxPod[pod.Spec.NodeName]
+		if !ok {
+			maxPod = v1.ResourceList{}
+			nodesMaxPod[pod.Spec.NodeName] = maxPod
+		}
+		for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			req := reqs[name]
+			sum := total[name]
+			sum.Add(req)
+			total[name] = sum
+			if req.Cmp(maxPod[name]) > 0 {
+				maxPod[name] = req
+			}
+		}
+	}
+
+	candidates := c.candidateInstanceTypes()
+	pools := make(map[string]*simulator.NodePool)
+	for _, pool := range buildNodePools(nodesSpec, candidates, nil) {
+		pools[pool.Name] = pool
+	}
+
+	groups := make(map[string]*NodeGroupRightsizing)
+	demands := make(map[string]*simulator.Demand)
+	for name, nodeSpec := range nodesSpec {
+		if nodeSpec.VirtualNode || nodeSpec.NodeRef == nil {
+			continue
+		}
+		_, poolName, _, _ := nodePoolOf(nodeSpec)
+		key := poolName + "," + nodeSpec.InstanceType
+		group, ok := groups[key]
+		if !ok {
+			group = &NodeGroupRightsizing{
+				Pool:         poolName,
+				InstanceType: nodeSpec.InstanceType,
+				Zone:         nodeSpec.Zone,
+				ChargeType:   nodeSpec.ChargeType,
+			}
+			groups[key] = group
+			demands[key] = &simulator.Demand{}
+		}
+		demand := demands[key]
+		group.Nodes++
+		group.CpuCapacity += nodeSpec.Cpu.MilliValue()
+		group.MemCapacity += nodeSpec.Mem.Value()
+
+		nodePrice, err := c.baselineCloud.NodePrice(nodeSpec)
+		if err != nil {
+			klog.Errorf("Failed to get node %v price: %v", name, err)
+		} else if nodePrice.Cost != "" {
+			price, err := strconv.ParseFloat(nodePrice.Cost, 64)
+			if err != nil {
+				klog.V(3).Infof("Could not parse total node price, node: %v, err: %v", name, err)
+			} else {
+				group.CurrentHourlyCost += price
+			}
+		}
+
+		reqs := nodesRequests[name]
+		reqCpu := reqs[v1.ResourceCPU]
+		reqMem := reqs[v1.ResourceMemory]
+		group.CpuRequests += reqCpu.MilliValue()
+		group.MemRequests += reqMem.Value()
+		nodeCpu := reqCpu.MilliValue()
+		nodeMem := reqMem.Value()
+
+		if usage, ok := c.estimateNodeUsage(cpuUsed[name]); ok {
+			usageCpu := int64(usage * 1000)
+			group.CpuUsage = addInt64Ptr(group.CpuUsage, usageCpu)
+			if usageCpu > nodeCpu {
+				nodeCpu = usageCpu
+			}
+		}
+		if usage, ok := c.estimateNodeUsage(memUsed[name]); ok {
+			usageMem := int64(usage)
+			group.MemUsage = addInt64Ptr(group.MemUsage, usageMem)
+			if usageMem > nodeMem {
+				nodeMem = usageMem
+			}
+		}
+		demand.Cpu += nodeCpu
+		demand.Mem += nodeMem
+
+		maxPod := nodesMaxPod[name]
+		maxPodCpu := maxPod[v1.ResourceCPU]
+		maxPodMem := maxPod[v1.ResourceMemory]
+		if maxPodCpu.MilliValue() > demand.MaxPodCpu {
+			demand.MaxPodCpu = maxPodCpu.MilliValue()
+		}
+		if maxPodMem.Value() > demand.MaxPodMem {
+			demand.MaxPodMem = maxPodMem.Value()
+		}
+	}
+
+	var results []*NodeGroupRightsizing
+	for key, group := range groups {
+		if pool, ok := pools[group.Pool]; ok {
+			suggestion, cheaper := simulator.Rightsize(pool, group.InstanceType, group.CurrentHourlyCost, *demands[key])
+			if cheaper {
+				group.Suggestion = suggestion
+			}
+		}
+		results = append(results, group)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Pool != results[j].Pool {
+			return results[i].Pool < results[j].Pool
+		}
+		return results[i].InstanceType < results[j].InstanceType
+	})
+	return results
+}
+
+// estimateNodeUsage return the recommended value of the node usage time series by the estimator
+func (c *Comparator) estimateNodeUsage(ts *common.TimeSeries) (float64, bool) {
+	if ts == nil || len(ts.Samples) == 0 {
+		return 0, false
+	}
+	statistic, err := c.estimator.Estimation(ts, c.estimateConfig)
+	if err != nil || statistic.Recommended == nil {
+		klog.V(4).Infof("Failed to estimate node usage: %v", err)
+		return 0, false
+	}
+	return *statistic.Recommended, true
+}
+
+func addInt64Ptr(a *int64, b int64) *int64 {
+	result := b
+	if a != nil {
+		result += *a
+	}
+	return &result
+}
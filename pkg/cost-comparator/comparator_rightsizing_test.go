@@ -0,0 +1,114 @@
+package cost_comparator
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gocrane/crane/pkg/common"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/gocrane/fadvisor/pkg/cloud"
+	"github.com/gocrane/fadvisor/pkg/consts"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/datafetcher"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/estimator"
+	"github.com/gocrane/fadvisor/pkg/spec"
+)
+
+type fakeMetricFetcher struct {
+	datafetcher.MetricFetcher
+	nodesCpu map[string]*common.TimeSeries
+	nodesMem map[string]*common.TimeSeries
+}
+
+func (f *fakeMetricFetcher) NodeCPUUsed(context.Context, time.Time, time.Time, time.Duration) (map[string]*common.TimeSeries, error) {
+	return f.nodesCpu, nil
+}
+
+func (f *fakeMetricFetcher) NodeRAMUsed(context.Context, time.Time, time.Time, time.Duration) (map[string]*common.TimeSeries, error) {
+	return f.nodesMem, nil
+}
+
+func TestNodesRightsizing(t *testing.T) {
+	instanceType := func(name, zone, cpu, mem string, price float64) *cloud.Price {
+		return &cloud.Price{InstanceType: name, Zone: zone, ChargeType: "POSTPAID_BY_HOUR", VCpu: cpu, Memory: mem, CvmPrice: &cloud.PriceItem{UnitPrice: pointer.Float64(price)}}
+	}
+	pricing := map[string]*cloud.Price{
+		"gz-3-2xlarge": instanceType("S5.2XLARGE16", "ap-guangzhou-3", "8", "16", 1.6),
+		"gz-3-large":   instanceType("S5.LARGE8", "ap-guangzhou-3", "4", "8", 0.8),
+		"gz-4-large":   instanceType("S5.LARGE8", "ap-guangzhou-4", "4", "8", 0.8),
+		"gz-4-medium":  instanceType("S5.MEDIUM4", "ap-guangzhou-4", "2", "4", 0.4),
+	}
+	nodesSpec := make(map[string]spec.CloudNodeSpec)
+	for _, n := range []struct{ name, zone, instanceType, cpu, mem string }{
+		{"node-1", "ap-guangzhou-3", "S5.2XLARGE16", "8", "16Gi"},
+		{"node-2", "ap-guangzhou-3", "S5.2XLARGE16", "8", "16Gi"},
+		{"node-3", "ap-guangzhou-4", "S5.LARGE8", "4", "8Gi"},
+	} {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: n.name, Labels: map[string]string{v1.LabelTopologyZone: n.zone}}}
+		nodesSpec[n.name] = spec.CloudNodeSpec{NodeRef: node, Zone: n.zone, ChargeType: "POSTPAID_BY_HOUR", InstanceType: n.instanceType,
+			Cpu: resource.MustParse(n.cpu), Mem: resource.MustParse(n.mem)}
+	}
+	pod := func(name, node, cpu string, phase v1.PodPhase) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: v1.PodSpec{NodeName: node, Containers: []v1.Container{{Name: "app", Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+				v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse("2Gi"),
+			}}}}},
+			Status: v1.PodStatus{Phase: phase},
+		}
+	}
+	clusterCache := &fakeCache{pods: []*v1.Pod{
+		pod("web", "node-1", "2", v1.PodRunning),
+		pod("api", "node-3", "3", v1.PodRunning),
+		// the finished pods request nothing
+		pod("job", "node-2", "8", v1.PodSucceeded),
+	}}
+	usage := func(value float64) *common.TimeSeries {
+		return &common.TimeSeries{Samples: []common.Sample{{Value: value, Timestamp: 1}, {Value: value, Timestamp: 2}}}
+	}
+	c := &Comparator{
+		clusterCache:  clusterCache,
+		baselineCloud: &fakeCloud{cache: clusterCache, pricing: pricing},
+		// node-3 has no history
+		metricFetcher: &fakeMetricFetcher{
+			nodesCpu: map[string]*common.TimeSeries{"node-1": usage(1), "node-2": usage(1.5)},
+			nodesMem: map[string]*common.TimeSeries{"node-1": usage(2 * consts.GB), "node-2": usage(3 * consts.GB)},
+		},
+		estimator:      estimator.NewStatisticEstimator(),
+		estimateConfig: map[string]interface{}{"percentile": 1.0, "marginFraction": 1.0},
+	}
+
+	groups := c.GetNodesRightsizing(nodesSpec)
+	if len(groups) != 2 {
+		t.Fatalf("expect 2 node groups, got %v", len(groups))
+	}
+
+	// the demand of node-1 is its requests, of node-2 its usage, 3.5 cores and 5GB in all fit one S5.LARGE8
+	gz3 := groups[0]
+	if gz3.InstanceType != "S5.2XLARGE16" || gz3.Nodes != 2 || gz3.CpuCapacity != 16000 || gz3.CpuRequests != 2000 ||
+		gz3.CpuUsage == nil || *gz3.CpuUsage != 2500 || gz3.MemUsage == nil || *gz3.MemUsage != 5*consts.GB {
+		t.Errorf("unexpected group %+v", gz3)
+	}
+	if math.Abs(gz3.CurrentHourlyCost-3.2) > 1e-9 {
+		t.Errorf("expect current cost 3.2, got %v", gz3.CurrentHourlyCost)
+	}
+	suggestion := gz3.Suggestion
+	if suggestion == nil || suggestion.InstanceType.Name != "S5.LARGE8" || suggestion.InstanceType.Zone != "ap-guangzhou-3" ||
+		suggestion.Nodes != 1 || math.Abs(gz3.CurrentHourlyCost-suggestion.HourlyCost-2.4) > 1e-9 {
+		t.Errorf("expect 1 S5.LARGE8 of ap-guangzhou-3 saving 2.4, got %+v", suggestion)
+	}
+
+	// no usage of node-3, the demand is the requests and the 3 cores pod does not fit the cheaper S5.MEDIUM4
+	gz4 := groups[1]
+	if gz4.InstanceType != "S5.LARGE8" || gz4.Zone != "ap-guangzhou-4" || gz4.CpuUsage != nil || gz4.MemUsage != nil || gz4.CpuRequests != 3000 {
+		t.Errorf("unexpected group %+v", gz4)
+	}
+	if math.Abs(gz4.CurrentHourlyCost-0.8) > 1e-9 || gz4.Suggestion != nil {
+		t.Errorf("expect no suggestion of current cost 0.8, got %v, %+v", gz4.CurrentHourlyCost, gz4.Suggestion)
+	}
+}
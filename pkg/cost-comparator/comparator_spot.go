@@ -0,0 +1,241 @@
+package cost_comparator
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/fadvisor/pkg/cloud"
+	"github.com/gocrane/fadvisor/pkg/consts"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/simulator"
+	"github.com/gocrane/fadvisor/pkg/spec"
+)
+
+// stateless workload kinds, the pods can be rescheduled to other nodes at any time
+var statelessKinds = map[string]bool{
+	"deployment":            true,
+	"replicaset":            true,
+	"replicationcontroller": true,
+}
+
+// WorkloadSpotAnalysis is the spot safety and hourly costs of a workload
+type WorkloadSpotAnalysis struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Replicas  uint64
+	SpotSafe  bool
+	// reason why the workload is not safe to run on spot instances
+	Reason string
+	// hourly cost
+	CurrentCost    float64
+	ServerlessCost float64
+	SpotCost       float64
+}
+
+// GetWorkloadsSpotAnalysis classifies the workloads to spot safe or not, then packs the spot safe workloads onto spot instance types.
+// the spot cost of each workload is the share of its requests in the simulated spot nodes cost.
+func (c *Comparator) GetWorkloadsSpotAnalysis(workloadsSpec map[string]map[types.NamespacedName]spec.CloudPodSpec) ([]*WorkloadSpotAnalysis, *simulator.Result) {
+	podsCost, err := c.baselineCloud.GetPodsCost()
+	if err != nil {
+		klog.Errorf("Failed to get pods cost: %v", err)
+	}
+	pdbs := c.clusterCache.GetPodDisruptionBudgets()
+	volumes := c.claimedVolumes()
+
+	var results []*WorkloadSpotAnalysis
+	var spotPods, daemonSets []simulator.Pod
+	var totalCpu, totalMem int64
+	for kind, kindWorkloads := range workloadsSpec {
+		for nn, workloadSpec := range kindWorkloads {
+			if workloadSpec.PodRef == nil {
+				continue
+			}
+			if strings.ToLower(kind) == "daemonset" {
+				daemonSets = append(daemonSets, simulator.Pod{
+					Name:        nn.String(),
+					Cpu:         workloadSpec.Cpu.MilliValue(),
+					Mem:         workloadSpec.Mem.Value(),
+					Tolerations: workloadSpec.PodRef.Spec.Tolerations,
+					Replicas:    1,
+				})
+				continue
+			}
+			analysis := &WorkloadSpotAnalysis{
+				Kind:      kind,
+				Namespace: nn.Namespace,
+				Name:      nn.Name,
+				Replicas:  workloadSpec.GoodsNum,
+			}
+			analysis.SpotSafe, analysis.Reason = c.isSpotSafe(kind, workloadSpec, pdbs, volumes)
+			analysis.CurrentCost = c.workloadHourlyCost(workloadSpec, podsCost)
+			analysis.ServerlessCost = c.workloadServerlessHourlyCost(workloadSpec)
+			if analysis.SpotSafe {
+				spotPods = append(spotPods, simulator.Pod{
+					Name:     nn.String(),
+					Cpu:      workloadSpec.Cpu.MilliValue(),
+					Mem:      workloadSpec.Mem.Value(),
+					Replicas: workloadSpec.GoodsNum,
+				})
+				totalCpu += workloadSpec.Cpu.MilliValue() * int64(workloadSpec.GoodsNum)
+				totalMem += workloadSpec.Mem.Value() * int64(workloadSpec.GoodsNum)
+			}
+			results = append(results, analysis)
+		}
+	}
+
+	spotPool := &simulator.NodePool{Name: "spot"}
+	zones := make(map[string]bool)
+	for _, node := range c.clusterCache.GetNodes() {
+		if c.baselineCloud.IsVirtualNode(node) {
+			continue
+		}
+		nodeSpec := c.baselineCloud.Node2Spec(node)
+		zones[nodeSpec.Zone] = true
+	}
+	for _, it := range c.candidateInstanceTypes() {
+		if !it.Spot {
+			continue
+		}
+		if it.Zone != "" && len(zones) > 0 && !zones[it.Zone] {
+			continue
+		}
+		spotPool.InstanceTypes = append(spotPool.InstanceTypes, it)
+	}
+	if len(spotPool.InstanceTypes) == 0 {
+		klog.Warningf("No spot instance types found in the pricing of the provider")
+	}
+	result := simulator.Simulate([]*simulator.NodePool{spotPool}, spotPods, daemonSets)
+
+	for _, analysis := range results {
+		if !analysis.SpotSafe || totalCpu == 0 || totalMem == 0 {
+			continue
+		}
+		if _, unschedulable := result.Unschedulable[analysis.Namespace+"/"+analysis.Name]; unschedulable {
+			continue
+		}
+		workloadSpec := workloadsSpec[analysis.Kind][types.NamespacedName{Namespace: analysis.Namespace, Name: analysis.Name}]
+		cpuShare := float64(workloadSpec.Cpu.MilliValue()*int64(analysis.Replicas)) / float64(totalCpu)
+		memShare := float64(workloadSpec.Mem.Value()*int64(analysis.Replicas)) / float64(totalMem)
+		analysis.SpotCost = result.HourlyCost * (cpuShare + memShare) / 2
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].SpotSafe != results[j].SpotSafe {
+			return results[i].SpotSafe
+		}
+		return results[i].CurrentCost > results[j].CurrentCost
+	})
+	return results, result
+}
+
+// claimedVolumes return the persistent volumes bound to the claims, key is namespace/claim
+func (c *Comparator) claimedVolumes() map[string]*v1.PersistentVolume {
+	pvs := make(map[string]*v1.PersistentVolume)
+	for _, pv := range c.clusterCache.GetPersistentVolumes() {
+		pvs[pv.Name] = pv
+	}
+	volumes := make(map[string]*v1.PersistentVolume)
+	for _, pvc := range c.clusterCache.GetPersistentVolumeClaims() {
+		if pv, ok := pvs[pvc.Spec.VolumeName]; ok {
+			volumes[pvc.Namespace+"/"+pvc.Name] = pv
+		}
+	}
+	return volumes
+}
+
+// isSpotSafe return true if the workload is stateless, has enough replicas, is protected by pdb and uses no local storage.
+// the claims are resolved to the bound persistent volumes by volumes, the local volumes and the volumes pinned to nodes are local storage,
+// and so is a claim not resolved, such as in the offline analysis.
+func (c *Comparator) isSpotSafe(kind string, workloadSpec spec.CloudPodSpec, pdbs []*policyv1beta1.PodDisruptionBudget, volumes map[string]*v1.PersistentVolume) (bool, string) {
+	if !statelessKinds[strings.ToLower(kind)] {
+		return false, "stateful or non-replicated workload"
+	}
+	if workloadSpec.GoodsNum < c.config.SpotMinReplicas {
+		return false, "replicas less than " + strconv.FormatUint(c.config.SpotMinReplicas, 10)
+	}
+	pod := workloadSpec.PodRef
+	for _, volume := range pod.Spec.Volumes {
+		if volume.HostPath != nil {
+			return false, "hostPath volume " + volume.Name
+		}
+		if volume.EmptyDir != nil && volume.EmptyDir.Medium != v1.StorageMediumMemory {
+			return false, "emptyDir volume " + volume.Name
+		}
+		if volume.PersistentVolumeClaim != nil {
+			claim := volume.PersistentVolumeClaim.ClaimName
+			pv, ok := volumes[pod.Namespace+"/"+claim]
+			if !ok {
+				return false, "unresolved persistentVolumeClaim " + claim
+			}
+			if pv.Spec.Local != nil || pv.Spec.HostPath != nil {
+				return false, "local persistentVolume " + pv.Name
+			}
+			if pv.Spec.NodeAffinity != nil && pv.Spec.NodeAffinity.Required != nil {
+				return false, "persistentVolume " + pv.Name + " pinned by node affinity"
+			}
+		}
+	}
+	for _, pdb := range pdbs {
+		if pdb.Namespace != pod.Namespace || pdb.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			klog.V(4).Infof("Failed to parse pdb %v selector: %v", klog.KObj(pdb), err)
+			continue
+		}
+		if !selector.Empty() && selector.Matches(labels.Set(pod.Labels)) {
+			return true, ""
+		}
+	}
+	return false, "no pod disruption budget"
+}
+
+// workloadHourlyCost return the current hourly cost of the workload, serverful pod cost is broken down from the node it runs on.
+func (c *Comparator) workloadHourlyCost(workloadSpec spec.CloudPodSpec, podsCost map[string]*cloud.Pod) float64 {
+	if workloadSpec.Serverless {
+		return c.workloadServerlessHourlyCost(workloadSpec)
+	}
+	podCost, ok := podsCost[klog.KObj(workloadSpec.PodRef).String()]
+	if !ok {
+		return 0
+	}
+	cpuHourlyCost, err := strconv.ParseFloat(podCost.CpuHourlyCost, 64)
+	if err != nil {
+		return 0
+	}
+	ramGBHourlyCost, err := strconv.ParseFloat(podCost.RamGBHourlyCost, 64)
+	if err != nil {
+		return 0
+	}
+	cpu := float64(workloadSpec.Cpu.MilliValue()) / 1000.
+	mem := float64(workloadSpec.Mem.Value()) / consts.GB
+	return (cpu*cpuHourlyCost + mem*ramGBHourlyCost) * float64(workloadSpec.GoodsNum)
+}
+
+// workloadServerlessHourlyCost return the hourly cost of all the replicas of the workload if it is migrated to serverless pods
+func (c *Comparator) workloadServerlessHourlyCost(workloadSpec spec.CloudPodSpec) float64 {
+	serverlessSpec := c.baselineCloud.Pod2ServerlessSpec(workloadSpec.PodRef)
+	serverlessSpec.GoodsNum = workloadSpec.GoodsNum
+	if serverlessSpec.GoodsNum == 0 {
+		return 0
+	}
+	podPrice, err := c.baselineCloud.ServerlessPodPrice(serverlessSpec)
+	if err != nil {
+		klog.Errorf("Failed to get ServerlessPodPrice for workload %v: %v", klog.KObj(workloadSpec.PodRef), err)
+		return 0
+	}
+	cost, err := strconv.ParseFloat(podPrice.Cost, 64)
+	if err != nil {
+		return 0
+	}
+	return cost
+}
@@ -0,0 +1,130 @@
+package cost_comparator
+
+import (
+	"math"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+
+	"github.com/gocrane/fadvisor/pkg/cloud"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/config"
+	"github.com/gocrane/fadvisor/pkg/spec"
+)
+
+func spotPod(name, node string, volumes ...v1.Volume) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: map[string]string{"app": name}},
+		Spec: v1.PodSpec{NodeName: node, Volumes: volumes, Containers: []v1.Container{{Name: "app", Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+			v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("2Gi"),
+		}}}}},
+	}
+}
+
+func spotPDB(app string) *policyv1beta1.PodDisruptionBudget {
+	return &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: app},
+		Spec:       policyv1beta1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}},
+	}
+}
+
+func claimVolume(claim string) v1.Volume {
+	return v1.Volume{Name: claim, VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: claim}}}
+}
+
+func TestIsSpotSafe(t *testing.T) {
+	c := &Comparator{config: config.Config{SpotMinReplicas: 2}}
+	pdbs := []*policyv1beta1.PodDisruptionBudget{spotPDB("web")}
+	pinned := &v1.VolumeNodeAffinity{Required: &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{{MatchExpressions: []v1.NodeSelectorRequirement{
+		{Key: "topology.kubernetes.io/zone", Operator: v1.NodeSelectorOpIn, Values: []string{"ap-guangzhou-3"}},
+	}}}}}
+	volumes := map[string]*v1.PersistentVolume{
+		"default/nfs":   {ObjectMeta: metav1.ObjectMeta{Name: "pv-nfs"}, Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{NFS: &v1.NFSVolumeSource{Server: "nfs", Path: "/"}}}},
+		"default/local": {ObjectMeta: metav1.ObjectMeta{Name: "pv-local"}, Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{Local: &v1.LocalVolumeSource{Path: "/data"}}}},
+		"default/disk":  {ObjectMeta: metav1.ObjectMeta{Name: "pv-disk"}, Spec: v1.PersistentVolumeSpec{NodeAffinity: pinned}},
+	}
+	memory := v1.Volume{Name: "cache", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{Medium: v1.StorageMediumMemory}}}
+	emptyDir := v1.Volume{Name: "tmp", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}
+	hostPath := v1.Volume{Name: "logs", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/var/log"}}}
+
+	for _, tc := range []struct {
+		name     string
+		kind     string
+		replicas uint64
+		pod      *v1.Pod
+		safe     bool
+		reason   string
+	}{
+		{name: "stateless", kind: "Deployment", replicas: 2, pod: spotPod("web", "node-1", memory, claimVolume("nfs")), safe: true},
+		{name: "stateful", kind: "StatefulSet", replicas: 2, pod: spotPod("web", "node-1"), reason: "stateful or non-replicated workload"},
+		{name: "one replica", kind: "Deployment", replicas: 1, pod: spotPod("web", "node-1"), reason: "replicas less than 2"},
+		{name: "no pdb", kind: "Deployment", replicas: 2, pod: spotPod("api", "node-1"), reason: "no pod disruption budget"},
+		{name: "emptyDir", kind: "Deployment", replicas: 2, pod: spotPod("web", "node-1", emptyDir), reason: "emptyDir volume tmp"},
+		{name: "hostPath", kind: "Deployment", replicas: 2, pod: spotPod("web", "node-1", hostPath), reason: "hostPath volume logs"},
+		{name: "local pv", kind: "Deployment", replicas: 2, pod: spotPod("web", "node-1", claimVolume("local")), reason: "local persistentVolume pv-local"},
+		{name: "pinned pv", kind: "Deployment", replicas: 2, pod: spotPod("web", "node-1", claimVolume("disk")), reason: "persistentVolume pv-disk pinned by node affinity"},
+		{name: "unresolved claim", kind: "Deployment", replicas: 2, pod: spotPod("web", "node-1", claimVolume("pending")), reason: "unresolved persistentVolumeClaim pending"},
+	} {
+		safe, reason := c.isSpotSafe(tc.kind, spec.CloudPodSpec{PodRef: tc.pod, GoodsNum: tc.replicas}, pdbs, volumes)
+		if safe != tc.safe || reason != tc.reason {
+			t.Errorf("%v: expect %v %q, got %v %q", tc.name, tc.safe, tc.reason, safe, reason)
+		}
+	}
+}
+
+func TestWorkloadsSpotAnalysis(t *testing.T) {
+	web, api, db := spotPod("web", "node-1"), spotPod("api", "node-1", claimVolume("data")), spotPod("db", "node-1")
+	clusterCache := &fakeCache{
+		pods: []*v1.Pod{web, api, db},
+		pdbs: []*policyv1beta1.PodDisruptionBudget{spotPDB("web"), spotPDB("api")},
+		pvs:  []*v1.PersistentVolume{{ObjectMeta: metav1.ObjectMeta{Name: "pv-data"}, Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{Local: &v1.LocalVolumeSource{Path: "/data"}}}}},
+		pvcs: []*v1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "data"}, Spec: v1.PersistentVolumeClaimSpec{VolumeName: "pv-data"}}},
+	}
+	instanceType := func(chargeType string, spot bool, price float64) *cloud.Price {
+		return &cloud.Price{InstanceType: "SA2.LARGE8", ChargeType: chargeType, Spot: spot, VCpu: "4", Memory: "8", CvmPrice: &cloud.PriceItem{UnitPrice: pointer.Float64(price)}}
+	}
+	c := &Comparator{
+		config:       config.Config{SpotMinReplicas: 2},
+		clusterCache: clusterCache,
+		baselineCloud: &fakeCloud{cache: clusterCache, pricing: map[string]*cloud.Price{
+			"hour": instanceType("POSTPAID_BY_HOUR", false, 0.8),
+			"spot": instanceType("SPOTPAID", true, 0.2),
+		}},
+	}
+	workloadsSpec := map[string]map[types.NamespacedName]spec.CloudPodSpec{
+		"Deployment": {
+			{Namespace: "default", Name: "web"}: {PodRef: web, Cpu: resource.MustParse("1"), Mem: resource.MustParse("2Gi"), GoodsNum: 2},
+			{Namespace: "default", Name: "api"}: {PodRef: api, Cpu: resource.MustParse("1"), Mem: resource.MustParse("2Gi"), GoodsNum: 3},
+		},
+		"StatefulSet": {
+			{Namespace: "default", Name: "db"}: {PodRef: db, Cpu: resource.MustParse("1"), Mem: resource.MustParse("2Gi"), GoodsNum: 2},
+		},
+	}
+
+	analyses, result := c.GetWorkloadsSpotAnalysis(workloadsSpec)
+	// the two replicas of web are packed onto one spot node
+	if len(result.Nodes) != 1 || !result.Nodes[0].InstanceType.Spot || result.HourlyCost != 0.2 {
+		t.Fatalf("unexpected spot nodes %+v, cost %v", result.Nodes, result.HourlyCost)
+	}
+	if len(analyses) != 3 {
+		t.Fatalf("expect 3 workloads, got %v", len(analyses))
+	}
+	// the spot safe workload first, then by the current cost
+	webAnalysis := analyses[0]
+	if webAnalysis.Name != "web" || !webAnalysis.SpotSafe || math.Abs(webAnalysis.CurrentCost-(0.1+2*0.01)*2) > 1e-9 ||
+		webAnalysis.ServerlessCost != 0.5 || math.Abs(webAnalysis.SpotCost-0.2) > 1e-9 {
+		t.Errorf("unexpected web analysis %+v", webAnalysis)
+	}
+	for _, analysis := range analyses[1:] {
+		if analysis.SpotSafe || analysis.SpotCost != 0 {
+			t.Errorf("expect %v not spot safe, got %+v", analysis.Name, analysis)
+		}
+	}
+	if analyses[1].Name != "api" || analyses[1].Reason != "local persistentVolume pv-data" {
+		t.Errorf("unexpected api analysis %+v", analyses[1])
+	}
+}
@@ -0,0 +1,63 @@
+package cost_comparator
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gocrane/crane/pkg/common"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/checkpoint"
+)
+
+func TestReadWorkloadTimeSeriesCheckpoint(t *testing.T) {
+	series := func(value float64) []*common.TimeSeries {
+		ts := common.NewTimeSeries()
+		ts.AppendSample(60, value)
+		return []*common.TimeSeries{ts}
+	}
+	data := map[string]map[types.NamespacedName]*RawWorkloadTimeSeriesData{
+		"Deployment": {
+			{Namespace: "default", Name: "web"}: {
+				Cpu: series(1), Mem: series(2), CpuRequests: series(3), MemRequests: series(4), CpuLimits: series(5), MemLimits: series(6), Replicas: series(7),
+			},
+		},
+	}
+	file := filepath.Join(t.TempDir(), "workloads-timeseries.ckpt")
+	window := checkpoint.Window{Start: time.Unix(0, 0), End: time.Unix(120, 0)}
+	if err := checkpoint.Write(file, checkpoint.Header{Kind: checkpoint.KindWorkload}, window, workloadDataToEntries(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ReadWorkloadTimeSeriesCheckpoint(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := result["Deployment"][types.NamespacedName{Namespace: "default", Name: "web"}]
+	if ts == nil {
+		t.Fatalf("expect the workload time series, got %v", result)
+	}
+	// every metric is read back to its own series
+	cases := []struct {
+		name   string
+		series []*common.TimeSeries
+		expect float64
+	}{
+		{"Cpu", ts.Cpu, 1},
+		{"Mem", ts.Mem, 2},
+		{"CpuRequests", ts.CpuRequests, 3},
+		{"MemRequests", ts.MemRequests, 4},
+		{"CpuLimits", ts.CpuLimits, 5},
+		{"MemLimits", ts.MemLimits, 6},
+		{"Replicas", ts.Replicas, 7},
+	}
+	for _, c := range cases {
+		if len(c.series[0].Samples) != 1 || c.series[0].Samples[0].Value != c.expect {
+			t.Errorf("expect %v %v, got %v", c.name, c.expect, c.series[0].Samples)
+		}
+	}
+	if ts.CpuLimits[0].Samples[0].Timestamp != 60 {
+		t.Errorf("expect the sample timestamp, got %v", ts.CpuLimits[0].Samples[0].Timestamp)
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"time"
 )
 
@@ -11,10 +12,40 @@ type Config struct {
 	ClusterId                 string
 	OutputMode                string
 	History                   HistoryAnalyzeConfig
+	Service                   ServiceConfig
 	EnableContainerCheckpoint bool
 	EnableWorkloadTimeSeries  bool
 	EnableWorkloadCheckpoint  bool
 	DataPath                  string
+	// DataSource is the name of the time series datasource, it is recorded in the checkpoints
+	DataSource string
+	// InstanceCatalogFile is a json file of instance types pricing list, if specified, the candidate instance types come from it instead of the cloud provider
+	InstanceCatalogFile string
+	// SpotMinReplicas is the min replicas of the workload which is safe to run on spot instances
+	SpotMinReplicas uint64
+	// ReservedBaselinePercentile is the percentile of the node count history used as the baseline of the reserved instances
+	ReservedBaselinePercentile float64
+	// Namespaces is the scope of the workloads to analyze, all namespaces if empty
+	Namespaces []string
+	// EstimateConfig is the config of the estimator, such as percentile and marginFraction
+	EstimateConfig map[string]interface{}
+	// Platforms are the target platforms to compare with the original cost, all if empty
+	Platforms []string
+	// EnableCostAnalysisController enables the controller which reconciles the CostAnalysis custom resources
+	EnableCostAnalysisController bool
+	// Offline means there is no datasource, the time series come only from the checkpoints
+	Offline bool
+}
+
+// ServiceConfig is the config of running the comparator as a long-lived service
+type ServiceConfig struct {
+	Enabled bool
+	// Interval of the scheduled analysis, no scheduled analysis if it is zero
+	Interval time.Duration
+	// MaxRuns is the max number of runs kept in memory
+	MaxRuns int
+	// Output enables writing the report files and printing the tables of each run by the output mode, the report is only served by default
+	Output bool
 }
 
 type HistoryAnalyzeConfig struct {
@@ -24,6 +55,67 @@ type HistoryAnalyzeConfig struct {
 }
 
 const (
-	OutputModeCsv    = "csv"
-	OutputModeStdOut = "stdout"
+	OutputModeCsv     = "csv"
+	OutputModeStdOut  = "stdout"
+	OutputModeJson    = "json"
+	OutputModeParquet = "parquet"
+	OutputModeHtml    = "html"
+)
+
+// target platforms to compare with the original cost
+const (
+	PlatformServerless  = "serverless"
+	PlatformBinPacking  = "binpacking"
+	PlatformRightsizing = "rightsizing"
+	PlatformSpot        = "spot"
+	PlatformReserved    = "reserved"
 )
+
+// PlatformEnabled return true if the platform is in the target platforms, all platforms are enabled if no target platforms
+func (c *Config) PlatformEnabled(platform string) bool {
+	if len(c.Platforms) == 0 {
+		return true
+	}
+	for _, p := range c.Platforms {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsDataSource return true if the platform queries the node metrics of the datasource, it is not analyzed offline
+func NeedsDataSource(platform string) bool {
+	return platform == PlatformRightsizing || platform == PlatformReserved
+}
+
+// Scoped return true if the analysis is scoped by namespaces
+func (c *Config) Scoped() bool {
+	return len(c.Namespaces) > 0
+}
+
+// InScope return true if the namespace is in the scope of the analysis
+func (c *Config) InScope(namespace string) bool {
+	if len(c.Namespaces) == 0 {
+		return true
+	}
+	for _, ns := range c.Namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// OutputEnabled return true if the output mode is enabled, OutputMode is a comma separated list of modes, empty means stdout and csv
+func (c *Config) OutputEnabled(mode string) bool {
+	if c.OutputMode == "" {
+		return mode == OutputModeStdOut || mode == OutputModeCsv
+	}
+	for _, m := range strings.Split(c.OutputMode, ",") {
+		if strings.TrimSpace(m) == mode {
+			return true
+		}
+	}
+	return false
+}
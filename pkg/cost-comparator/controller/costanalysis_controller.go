@@ -0,0 +1,289 @@
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/fadvisor/pkg/apis/analysis/v1alpha1"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/config"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/report"
+)
+
+// AnalyzeFunc runs the comparator pipeline with the config and return the report
+type AnalyzeFunc func(cfg config.Config) (*report.Report, error)
+
+var validPlatforms = map[string]bool{
+	config.PlatformServerless:  true,
+	config.PlatformBinPacking:  true,
+	config.PlatformRightsizing: true,
+	config.PlatformSpot:        true,
+	config.PlatformReserved:    true,
+}
+
+// reportConfigMapKey is the key of the gzip compressed json report in the binary data of the report configmap
+const reportConfigMapKey = "report.json.gz"
+
+// CostAnalysisController reconciles the CostAnalysis by running the comparator pipeline, then writes the summary costs to the status
+// and the full report to the configmap referenced by the status.
+// The analysis is time and memory consuming, so there is only one worker and the analysis runs once for each generation of the spec.
+type CostAnalysisController struct {
+	client     dynamic.Interface
+	kubeClient kubernetes.Interface
+	informer   cache.SharedIndexInformer
+	queue      workqueue.RateLimitingInterface
+	baseConfig config.Config
+	analyze    AnalyzeFunc
+	// analysisLock is shared with the comparator service, so the analyses of both run one by one
+	analysisLock sync.Locker
+}
+
+// NewCostAnalysisController returns the controller, the kube client writes the report configmaps and the analyses hold the analysis lock
+func NewCostAnalysisController(client dynamic.Interface, kubeClient kubernetes.Interface, baseConfig config.Config, analyze AnalyzeFunc, analysisLock sync.Locker) *CostAnalysisController {
+	if analysisLock == nil {
+		analysisLock = &sync.Mutex{}
+	}
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(client, 10*time.Minute)
+	c := &CostAnalysisController{
+		client:       client,
+		kubeClient:   kubeClient,
+		informer:     factory.ForResource(v1alpha1.CostAnalysisResource).Informer(),
+		queue:        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "costanalysis"),
+		baseConfig:   baseConfig,
+		analyze:      analyze,
+		analysisLock: analysisLock,
+	}
+	c.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueue,
+		UpdateFunc: func(_, obj interface{}) {
+			c.enqueue(obj)
+		},
+	})
+	return c
+}
+
+func (c *CostAnalysisController) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *CostAnalysisController) Run(stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	go c.informer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, c.informer.HasSynced) {
+		klog.Errorf("Failed to sync CostAnalysis informer")
+		return
+	}
+	klog.Infof("CostAnalysis controller started")
+	wait.Until(func() {
+		for c.processNextItem() {
+		}
+	}, time.Second, stopCh)
+}
+
+func (c *CostAnalysisController) processNextItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	err := c.Reconcile(key.(string))
+	if err == nil {
+		c.queue.Forget(key)
+		return true
+	}
+	klog.Errorf("Failed to reconcile CostAnalysis %v: %v", key, err)
+	c.queue.AddRateLimited(key)
+	return true
+}
+
+// Reconcile analyzes the CostAnalysis if the generation of the spec is not analyzed yet
+func (c *CostAnalysisController) Reconcile(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	obj, err := c.client.Resource(v1alpha1.CostAnalysisResource).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	ca := &v1alpha1.CostAnalysis{}
+	if err = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, ca); err != nil {
+		return err
+	}
+	if ca.Status.ObservedGeneration == ca.Generation &&
+		(ca.Status.Phase == v1alpha1.CostAnalysisSucceeded || ca.Status.Phase == v1alpha1.CostAnalysisFailed) {
+		return nil
+	}
+
+	now := metav1.Now()
+	status := v1alpha1.CostAnalysisStatus{
+		Phase:              v1alpha1.CostAnalysisRunning,
+		ObservedGeneration: ca.Generation,
+		StartTime:          &now,
+	}
+	cfg, err := AnalysisConfig(c.baseConfig, ca)
+	if err != nil {
+		status.Phase = v1alpha1.CostAnalysisFailed
+		status.CompletionTime = &now
+		status.Message = err.Error()
+		return c.updateStatus(namespace, name, status)
+	}
+
+	// the CostAnalysis keeps its phase while the comparator service is analyzing
+	c.analysisLock.Lock()
+	defer c.analysisLock.Unlock()
+	start := metav1.Now()
+	status.StartTime = &start
+	if err = c.updateStatus(namespace, name, status); err != nil {
+		return err
+	}
+
+	klog.Infof("Analyzing CostAnalysis %v, generation %v", key, ca.Generation)
+	result, err := c.analyze(cfg)
+	completion := metav1.Now()
+	status.CompletionTime = &completion
+	if err != nil {
+		status.Phase = v1alpha1.CostAnalysisFailed
+		status.Message = err.Error()
+		return c.updateStatus(namespace, name, status)
+	}
+	status.Phase = v1alpha1.CostAnalysisSucceeded
+	status.Message = ""
+	for _, item := range result.CostSummary {
+		status.Costs = append(status.Costs, v1alpha1.CostSummary{
+			Name: item.Name,
+			Cost: strconv.FormatFloat(item.Cost, 'f', 5, 64),
+		})
+	}
+	status.ReportLocation, err = c.storeReport(ca, result)
+	if err != nil {
+		// the costs of the status are still valid
+		klog.Errorf("Failed to store the report of CostAnalysis %v: %v", key, err)
+		status.Message = fmt.Sprintf("failed to store the report: %v", err)
+	}
+	return c.updateStatus(namespace, name, status)
+}
+
+// storeReport writes the report to the configmap <name>-report in the namespace of the CostAnalysis and returns its reference.
+// the configmap is owned by the CostAnalysis, so it is kept after restart and deleted with the CostAnalysis.
+func (c *CostAnalysisController) storeReport(ca *v1alpha1.CostAnalysis, result *report.Report) (string, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := report.WriteJson(gz, result); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ca.Namespace,
+			Name:      ca.Name + "-report",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1alpha1.GroupVersion.String(),
+				Kind:       v1alpha1.Kind,
+				Name:       ca.Name,
+				UID:        ca.UID,
+			}},
+		},
+		BinaryData: map[string][]byte{reportConfigMapKey: buf.Bytes()},
+	}
+	configMaps := c.kubeClient.CoreV1().ConfigMaps(ca.Namespace)
+	_, err := configMaps.Update(context.TODO(), cm, metav1.UpdateOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(context.TODO(), cm, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return "", err
+	}
+	return "configmap/" + cm.Name, nil
+}
+
+func (c *CostAnalysisController) updateStatus(namespace, name string, status v1alpha1.CostAnalysisStatus) error {
+	statusObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		return err
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := c.client.Resource(v1alpha1.CostAnalysisResource).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if err = unstructured.SetNestedMap(obj.Object, statusObj, "status"); err != nil {
+			return err
+		}
+		_, err = c.client.Resource(v1alpha1.CostAnalysisResource).Namespace(namespace).UpdateStatus(context.TODO(), obj, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// AnalysisConfig overrides the base comparator config by the spec of the CostAnalysis.
+// The report is kept in memory like the comparator service and stored to the configmap, no files are written.
+func AnalysisConfig(base config.Config, ca *v1alpha1.CostAnalysis) (config.Config, error) {
+	cfg := base
+	cfg.Service = config.ServiceConfig{Enabled: true}
+	cfg.EnableCostAnalysisController = false
+
+	spec := ca.Spec
+	if spec.History.Length != nil {
+		cfg.History.Length = spec.History.Length.Duration
+	}
+	if spec.History.Step != nil {
+		cfg.History.Step = spec.History.Step.Duration
+	}
+	if spec.History.End != nil {
+		cfg.History.EndTime = spec.History.End.Format(time.RFC3339)
+	}
+	if spec.TimeSpanSeconds > 0 {
+		cfg.TimeSpanSeconds = spec.TimeSpanSeconds
+	}
+	cfg.Namespaces = spec.Scope.Namespaces
+	for _, platform := range spec.TargetPlatforms {
+		if !validPlatforms[platform] {
+			return cfg, fmt.Errorf("unknown target platform %v", platform)
+		}
+	}
+	cfg.Platforms = spec.TargetPlatforms
+	if len(spec.Estimator.Config) > 0 {
+		cfg.EstimateConfig = make(map[string]interface{})
+		for k, v := range spec.Estimator.Config {
+			value, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return cfg, fmt.Errorf("estimator config %v is not a float: %v", k, err)
+			}
+			cfg.EstimateConfig[k] = value
+		}
+	}
+	return cfg, nil
+}
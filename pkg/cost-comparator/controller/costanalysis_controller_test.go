@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/gocrane/fadvisor/pkg/apis/analysis/v1alpha1"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/config"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/report"
+)
+
+func newCostAnalysis(name string, platforms ...string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": v1alpha1.GroupVersion.String(),
+		"kind":       v1alpha1.Kind,
+		"metadata": map[string]interface{}{
+			"namespace":  "default",
+			"name":       name,
+			"generation": int64(2),
+		},
+		"spec": map[string]interface{}{
+			"estimator": map[string]interface{}{
+				"config": map[string]interface{}{"percentile": "0.95"},
+			},
+		},
+	}}
+	if len(platforms) > 0 {
+		var values []interface{}
+		for _, p := range platforms {
+			values = append(values, p)
+		}
+		_ = unstructured.SetNestedSlice(obj.Object, values, "spec", "targetPlatforms")
+	}
+	return obj
+}
+
+func getStatus(t *testing.T, c *CostAnalysisController, name string) v1alpha1.CostAnalysisStatus {
+	obj, err := c.client.Resource(v1alpha1.CostAnalysisResource).Namespace("default").Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &v1alpha1.CostAnalysis{}
+	if err = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, ca); err != nil {
+		t.Fatal(err)
+	}
+	return ca.Status
+}
+
+func newClient(t *testing.T, names ...string) *dynamicfake.FakeDynamicClient {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{v1alpha1.CostAnalysisResource: v1alpha1.Kind + "List"})
+	for _, name := range names {
+		if _, err := client.Resource(v1alpha1.CostAnalysisResource).Namespace("default").Create(context.TODO(), newCostAnalysis(name), metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return client
+}
+
+// readReport reads the report stored in the configmap of the report location
+func readReport(t *testing.T, kubeClient *fake.Clientset, location string) (*v1.ConfigMap, *report.Report) {
+	cm, err := kubeClient.CoreV1().ConfigMaps("default").Get(context.TODO(), strings.TrimPrefix(location, "configmap/"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(cm.BinaryData[reportConfigMapKey]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &report.Report{}
+	if err = json.Unmarshal(data, r); err != nil {
+		t.Fatal(err)
+	}
+	return cm, r
+}
+
+func TestReconcile(t *testing.T) {
+	client := newClient(t, "all")
+	if _, err := client.Resource(v1alpha1.CostAnalysisResource).Namespace("default").Create(context.TODO(), newCostAnalysis("invalid", "mainframe"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	kubeClient := fake.NewSimpleClientset()
+
+	var analyzed []config.Config
+	base := config.Config{ClusterId: "cls-1", DataPath: t.TempDir(), OutputMode: config.OutputModeHtml}
+	c := NewCostAnalysisController(client, kubeClient, base, func(cfg config.Config) (*report.Report, error) {
+		analyzed = append(analyzed, cfg)
+		r := &report.Report{ClusterId: cfg.ClusterId}
+		r.AddCost("original", 100)
+		r.AddCost("recommended serverless", 60.5)
+		return r, nil
+	}, nil)
+
+	if err := c.Reconcile("default/all"); err != nil {
+		t.Fatal(err)
+	}
+	status := getStatus(t, c, "all")
+	if status.Phase != v1alpha1.CostAnalysisSucceeded || status.ObservedGeneration != 2 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if len(status.Costs) != 2 || status.Costs[1].Cost != "60.50000" {
+		t.Fatalf("unexpected costs %+v", status.Costs)
+	}
+	// the report is stored in the configmap owned by the CostAnalysis rather than the local data path
+	if status.ReportLocation != "configmap/all-report" {
+		t.Fatalf("expected report location configmap/all-report, got %v", status.ReportLocation)
+	}
+	cm, r := readReport(t, kubeClient, status.ReportLocation)
+	if len(cm.OwnerReferences) != 1 || cm.OwnerReferences[0].Kind != v1alpha1.Kind || cm.OwnerReferences[0].Name != "all" || r.ClusterId != "cls-1" {
+		t.Fatalf("unexpected report configmap %+v, report %+v", cm.ObjectMeta, r)
+	}
+	if files, _ := ioutil.ReadDir(base.DataPath); len(files) != 0 {
+		t.Fatalf("expected no files written, got %v", files)
+	}
+	if len(analyzed) != 1 || !analyzed[0].Service.Enabled || analyzed[0].Service.Output || analyzed[0].EstimateConfig["percentile"] != 0.95 {
+		t.Fatalf("unexpected analysis config %+v", analyzed)
+	}
+
+	// the analyzed generation is not analyzed again
+	if err := c.Reconcile("default/all"); err != nil {
+		t.Fatal(err)
+	}
+	if len(analyzed) != 1 {
+		t.Fatalf("expected analyzed once, got %v", len(analyzed))
+	}
+
+	if err := c.Reconcile("default/invalid"); err != nil {
+		t.Fatal(err)
+	}
+	status = getStatus(t, c, "invalid")
+	if status.Phase != v1alpha1.CostAnalysisFailed || status.Message == "" || len(analyzed) != 1 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	// deleted object is ignored
+	if err := c.Reconcile("default/not-exist"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReconcileReportNotStored(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("configmap too large")
+	})
+	c := NewCostAnalysisController(newClient(t, "all"), kubeClient, config.Config{ClusterId: "cls-1"}, func(cfg config.Config) (*report.Report, error) {
+		r := &report.Report{ClusterId: cfg.ClusterId}
+		r.AddCost("original", 100)
+		return r, nil
+	}, nil)
+	if err := c.Reconcile("default/all"); err != nil {
+		t.Fatal(err)
+	}
+	// the costs are kept without the report
+	status := getStatus(t, c, "all")
+	if status.Phase != v1alpha1.CostAnalysisSucceeded || len(status.Costs) != 1 || status.ReportLocation != "" || !strings.Contains(status.Message, "configmap too large") {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
+
+func TestReconcileAnalysisLock(t *testing.T) {
+	analyzed := make(chan struct{}, 1)
+	lock := &sync.Mutex{}
+	c := NewCostAnalysisController(newClient(t, "all"), fake.NewSimpleClientset(), config.Config{ClusterId: "cls-1"}, func(cfg config.Config) (*report.Report, error) {
+		analyzed <- struct{}{}
+		return &report.Report{ClusterId: cfg.ClusterId}, nil
+	}, lock)
+
+	// the comparator service is analyzing
+	lock.Lock()
+	done := make(chan error)
+	go func() {
+		done <- c.Reconcile("default/all")
+	}()
+	select {
+	case <-analyzed:
+		t.Fatalf("expected no analysis while the lock is held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if status := getStatus(t, c, "all"); status.Phase == v1alpha1.CostAnalysisRunning {
+		t.Fatalf("expected not running while waiting, got %+v", status)
+	}
+	lock.Unlock()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(analyzed) != 1 || getStatus(t, c, "all").Phase != v1alpha1.CostAnalysisSucceeded {
+		t.Fatalf("expected analyzed after the lock is released")
+	}
+}
@@ -17,6 +17,11 @@ type CosterContext struct {
 	WorkloadsSpec    map[string] /*kind*/ map[types.NamespacedName] /*namespace-name*/ spec.CloudPodSpec
 	WorkloadsRecSpec map[string] /*kind*/ map[types.NamespacedName] /*namespace-name*/ *spec.WorkloadRecommendedData
 	Pricer           cloud.Pricer
+	// PodsCost is the breakdown cost of the nodes of the pods, key is namespace/name.
+	// it is set if the analysis is scoped by namespaces, then the serverful cost is the requests share of the pods in scope in the nodes cost
+	PodsCost map[string]*cloud.Pod
+
+	recommendedServerlessCost map[string] /*kind*/ map[types.NamespacedName] /*namespace-name*/ float64
 }
 
 type Cost struct {
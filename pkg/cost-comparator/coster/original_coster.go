@@ -6,8 +6,10 @@ import (
 	"time"
 
 	"github.com/gocrane/fadvisor/pkg/cloud"
+	"github.com/gocrane/fadvisor/pkg/consts"
 
 	"k8s.io/klog/v2"
+	resourcehelper "k8s.io/kubernetes/pkg/api/v1/resource"
 )
 
 // tke or ack
@@ -20,6 +22,9 @@ func NewServerfulCoster() *serverful {
 }
 
 func (s *serverful) TotalCost(costerCtx *CosterContext) Cost {
+	if costerCtx.PodsCost != nil {
+		return s.scopedCost(costerCtx)
+	}
 	nodeTotalCost := 0.
 	var realNodesNum int32 = 0
 	timespanInHour := float64(costerCtx.TimeSpanSeconds) / time.Hour.Seconds()
@@ -48,7 +53,63 @@ func (s *serverful) TotalCost(costerCtx *CosterContext) Cost {
 		nodeTotalCost += nodePrice * timespanInHour
 	}
 
+	serverlessPodsTotalCost := serverlessPodsCost(costerCtx)
+	serverfulPlatformCost := costerCtx.Pricer.PlatformPrice(cloud.PlatformParameter{Nodes: &realNodesNum, Platform: cloud.ServerfulKind})
+	serverlessPlatformCost := costerCtx.Pricer.PlatformPrice(cloud.PlatformParameter{Nodes: &realNodesNum, Platform: cloud.ServerlessKind})
+
+	return Cost{
+		TotalCost:              nodeTotalCost + serverlessPodsTotalCost + serverfulPlatformCost.TotalPrice + serverlessPlatformCost.TotalPrice,
+		ServerfulCost:          nodeTotalCost,
+		ServerlessCost:         serverlessPodsTotalCost,
+		ServerfulPlatformCost:  serverfulPlatformCost.TotalPrice,
+		ServerlessPlatformCost: serverlessPlatformCost.TotalPrice,
+	}
+}
+
+// scopedCost computes the cost of the pods in scope only, the serverful pods cost their requests share in the nodes cost.
+// the platform cost of the cluster is not included, because it is shared by all the namespaces.
+func (s *serverful) scopedCost(costerCtx *CosterContext) Cost {
+	podsTotalCost := 0.
+	timespanInHour := float64(costerCtx.TimeSpanSeconds) / time.Hour.Seconds()
+	for name, podSpec := range costerCtx.PodsSpec {
+		if podSpec.Serverless || podSpec.PodRef == nil {
+			continue
+		}
+		podCost, ok := costerCtx.PodsCost[name]
+		if !ok {
+			klog.V(3).Infof("No node breakdown cost of pod %v", name)
+			continue
+		}
+		cpuHourlyCost, err := strconv.ParseFloat(podCost.CpuHourlyCost, 64)
+		if err != nil {
+			klog.V(3).Infof("Could not parse pod cpu hourly cost, pod: %v, err: %v", name, err)
+			continue
+		}
+		ramGBHourlyCost, err := strconv.ParseFloat(podCost.RamGBHourlyCost, 64)
+		if err != nil {
+			klog.V(3).Infof("Could not parse pod ram hourly cost, pod: %v, err: %v", name, err)
+			continue
+		}
+		req, _ := resourcehelper.PodRequestsAndLimits(podSpec.PodRef)
+		cpu := float64(req.Cpu().MilliValue()) / 1000.
+		mem := float64(req.Memory().Value()) / consts.GB
+		podPrice := cpu*cpuHourlyCost + mem*ramGBHourlyCost
+		if math.IsNaN(podPrice) {
+			podPrice = 0
+		}
+		podsTotalCost += podPrice * timespanInHour
+	}
+	serverlessPodsTotalCost := serverlessPodsCost(costerCtx)
+	return Cost{
+		TotalCost:      podsTotalCost + serverlessPodsTotalCost,
+		ServerfulCost:  podsTotalCost,
+		ServerlessCost: serverlessPodsTotalCost,
+	}
+}
+
+func serverlessPodsCost(costerCtx *CosterContext) float64 {
 	serverlessPodsTotalCost := 0.
+	timespanInHour := float64(costerCtx.TimeSpanSeconds) / time.Hour.Seconds()
 	for name, podSpec := range costerCtx.PodsSpec {
 		if !podSpec.Serverless {
 			continue
@@ -73,14 +134,5 @@ func (s *serverful) TotalCost(costerCtx *CosterContext) Cost {
 		}
 		serverlessPodsTotalCost += podPrice * timespanInHour
 	}
-	serverfulPlatformCost := costerCtx.Pricer.PlatformPrice(cloud.PlatformParameter{Nodes: &realNodesNum, Platform: cloud.ServerfulKind})
-	serverlessPlatformCost := costerCtx.Pricer.PlatformPrice(cloud.PlatformParameter{Nodes: &realNodesNum, Platform: cloud.ServerlessKind})
-
-	return Cost{
-		TotalCost:              nodeTotalCost + serverlessPodsTotalCost + serverfulPlatformCost.TotalPrice + serverlessPlatformCost.TotalPrice,
-		ServerfulCost:          nodeTotalCost,
-		ServerlessCost:         serverlessPodsTotalCost,
-		ServerfulPlatformCost:  serverfulPlatformCost.TotalPrice,
-		ServerlessPlatformCost: serverlessPlatformCost.TotalPrice,
-	}
+	return serverlessPodsTotalCost
 }
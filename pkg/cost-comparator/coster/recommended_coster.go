@@ -50,7 +50,7 @@ func (e *recommender) TotalCost(costerCtx *CosterContext) (RecommendedCost, Reco
 			continue
 		}
 		for nn, workloadRecSpec := range workloadsRecSpec {
-			recWorkloadPrice := workloadCosting(costerCtx.Pricer, timespanInHour, workloadRecSpec.RecommendedSpec, nn, kind)
+			recWorkloadPrice := costerCtx.RecommendedServerlessCost(kind, nn)
 			workloadCost := recWorkloadPrice * timespanInHour
 			recWorkloadKindTotalCost[kind] += workloadCost
 			recServerlessPodsTotalCost += workloadCost
@@ -104,6 +104,23 @@ func (e *recommender) TotalCost(costerCtx *CosterContext) (RecommendedCost, Reco
 	return recCost, percentCost, maxRecCost, maxMarginCost
 }
 
+// RecommendedServerlessCost returns the serverless cost of the recommended spec of the workload in the time span,
+// the workloads are priced once and the costs are cached in the context
+func (c *CosterContext) RecommendedServerlessCost(kind string, nn types.NamespacedName) float64 {
+	if c.recommendedServerlessCost == nil {
+		timespanInHour := float64(c.TimeSpanSeconds) / time.Hour.Seconds()
+		c.recommendedServerlessCost = make(map[string]map[types.NamespacedName]float64)
+		for kind, workloadsRecSpec := range c.WorkloadsRecSpec {
+			costs := make(map[types.NamespacedName]float64, len(workloadsRecSpec))
+			for nn, workloadRecSpec := range workloadsRecSpec {
+				costs[nn] = workloadCosting(c.Pricer, timespanInHour, workloadRecSpec.RecommendedSpec, nn, kind)
+			}
+			c.recommendedServerlessCost[kind] = costs
+		}
+	}
+	return c.recommendedServerlessCost[kind][nn]
+}
+
 func workloadCosting(pricer cloud.Pricer, timespanInHour float64, recommendedSpec spec.CloudPodSpec, nn types.NamespacedName, kind string) float64 {
 	workloadPricing, err := pricer.ServerlessPodPrice(recommendedSpec)
 	if err != nil {
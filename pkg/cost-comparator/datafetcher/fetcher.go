@@ -34,4 +34,7 @@ type MetricFetcher interface {
 	NodeCPUUsed(ctx context.Context, start time.Time, end time.Time, step time.Duration) (map[string]*common.TimeSeries, error)
 	// NodeRAMUsed return node ram used metric unit bytes, result is a map, key is nodename
 	NodeRAMUsed(ctx context.Context, start time.Time, end time.Time, step time.Duration) (map[string]*common.TimeSeries, error)
+
+	// NodeCountByInstanceType return real node count metric, result is a map, key is instance type
+	NodeCountByInstanceType(ctx context.Context, start time.Time, end time.Time, step time.Duration) (map[string]*common.TimeSeries, error)
 }
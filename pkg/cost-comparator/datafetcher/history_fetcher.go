@@ -0,0 +1,115 @@
+package datafetcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/fadvisor/pkg/cache"
+	"github.com/gocrane/fadvisor/pkg/consts"
+	"github.com/gocrane/fadvisor/pkg/datasource"
+	"github.com/gocrane/fadvisor/pkg/metricnaming"
+)
+
+var _ MetricFetcher = &historyFetcher{}
+
+// historyFetcher fetches the metrics of the objects in cluster cache from history datasource one by one
+type historyFetcher struct {
+	clusterId string
+	history   datasource.History
+	cache     cache.Cache
+}
+
+func NewMetricFetcher(clusterId string, history datasource.History, cache cache.Cache) MetricFetcher {
+	return &historyFetcher{
+		clusterId: clusterId,
+		history:   history,
+		cache:     cache,
+	}
+}
+
+func (f *historyFetcher) ContainerCPUUsed(ctx context.Context, start time.Time, end time.Time, step time.Duration) (map[string]*common.TimeSeries, error) {
+	return nil, fmt.Errorf("not implement")
+}
+
+func (f *historyFetcher) ContainerRAMUsed(ctx context.Context, start time.Time, end time.Time, step time.Duration) (map[string]*common.TimeSeries, error) {
+	return nil, fmt.Errorf("not implement")
+}
+
+func (f *historyFetcher) PodCPUUsed(ctx context.Context, start time.Time, end time.Time, step time.Duration) (map[string]*common.TimeSeries, error) {
+	return f.podResourceUsed(ctx, v1.ResourceCPU, start, end, step)
+}
+
+func (f *historyFetcher) PodRAMUsed(ctx context.Context, start time.Time, end time.Time, step time.Duration) (map[string]*common.TimeSeries, error) {
+	return f.podResourceUsed(ctx, v1.ResourceMemory, start, end, step)
+}
+
+func (f *historyFetcher) WorkloadCPUUsed(ctx context.Context, start time.Time, end time.Time, step time.Duration) (map[string]*common.TimeSeries, error) {
+	return nil, fmt.Errorf("not implement")
+}
+
+func (f *historyFetcher) WorkloadRAMUsed(ctx context.Context, start time.Time, end time.Time, step time.Duration) (map[string]*common.TimeSeries, error) {
+	return nil, fmt.Errorf("not implement")
+}
+
+func (f *historyFetcher) NodeCPUUsed(ctx context.Context, start time.Time, end time.Time, step time.Duration) (map[string]*common.TimeSeries, error) {
+	return f.nodeResourceUsed(ctx, v1.ResourceCPU, start, end, step)
+}
+
+func (f *historyFetcher) NodeRAMUsed(ctx context.Context, start time.Time, end time.Time, step time.Duration) (map[string]*common.TimeSeries, error) {
+	return f.nodeResourceUsed(ctx, v1.ResourceMemory, start, end, step)
+}
+
+func (f *historyFetcher) podResourceUsed(ctx context.Context, resourceName v1.ResourceName, start time.Time, end time.Time, step time.Duration) (map[string]*common.TimeSeries, error) {
+	results := make(map[string]*common.TimeSeries)
+	for _, pod := range f.cache.GetPods() {
+		namer := metricnaming.ResourceToPodMetricNamer(f.clusterId, pod.Namespace, pod.Name, resourceName)
+		tsList, err := f.history.QueryTimeSeries(ctx, namer, start, end, step)
+		if err != nil {
+			klog.Errorf("Failed to query pod %v %v used: %v", klog.KObj(pod), resourceName, err)
+			continue
+		}
+		if len(tsList) > 0 {
+			results[pod.Namespace+"/"+pod.Name] = tsList[0]
+		}
+	}
+	return results, nil
+}
+
+func (f *historyFetcher) nodeResourceUsed(ctx context.Context, resourceName v1.ResourceName, start time.Time, end time.Time, step time.Duration) (map[string]*common.TimeSeries, error) {
+	results := make(map[string]*common.TimeSeries)
+	for _, node := range f.cache.GetNodes() {
+		namer := metricnaming.ResourceToNodeMetricNamer(f.clusterId, node, resourceName)
+		tsList, err := f.history.QueryTimeSeries(ctx, namer, start, end, step)
+		if err != nil {
+			klog.Errorf("Failed to query node %v %v used: %v", node.Name, resourceName, err)
+			continue
+		}
+		if len(tsList) > 0 {
+			results[node.Name] = tsList[0]
+		}
+	}
+	return results, nil
+}
+
+func (f *historyFetcher) NodeCountByInstanceType(ctx context.Context, start time.Time, end time.Time, step time.Duration) (map[string]*common.TimeSeries, error) {
+	results := make(map[string]*common.TimeSeries)
+	namer := metricnaming.NodeCountMetricNamer(f.clusterId)
+	tsList, err := f.history.QueryTimeSeries(ctx, namer, start, end, step)
+	if err != nil {
+		return results, err
+	}
+	for _, ts := range tsList {
+		for _, label := range ts.Labels {
+			if label.Name == consts.LabelInstanceType {
+				results[label.Value] = ts
+				break
+			}
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,125 @@
+package datafetcher
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/gocrane/crane/pkg/common"
+	"github.com/gocrane/fadvisor/pkg/cache"
+	"github.com/gocrane/fadvisor/pkg/consts"
+	"github.com/gocrane/fadvisor/pkg/metricnaming"
+)
+
+type fakeCache struct {
+	cache.Cache
+	pods  []*v1.Pod
+	nodes []*v1.Node
+}
+
+func (c *fakeCache) GetPods() []*v1.Pod   { return c.pods }
+func (c *fakeCache) GetNodes() []*v1.Node { return c.nodes }
+
+// fakeHistory returns the time series by the unique key of the metric namer
+type fakeHistory struct {
+	series map[string][]*common.TimeSeries
+	errs   map[string]error
+}
+
+func (h *fakeHistory) QueryTimeSeries(_ context.Context, namer metricnaming.MetricNamer, _ time.Time, _ time.Time, _ time.Duration) ([]*common.TimeSeries, error) {
+	key := namer.BuildUniqueKey()
+	if err := h.errs[key]; err != nil {
+		return nil, err
+	}
+	return h.series[key], nil
+}
+
+func series(value float64, labels ...common.Label) *common.TimeSeries {
+	return &common.TimeSeries{Labels: labels, Samples: []common.Sample{{Value: value, Timestamp: 1}}}
+}
+
+func TestNodeResourceUsed(t *testing.T) {
+	node := func(name string) *v1.Node { return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}} }
+	nodes := []*v1.Node{node("node-1"), node("node-2"), node("node-3")}
+	key := func(n *v1.Node, resourceName v1.ResourceName) string {
+		return metricnaming.ResourceToNodeMetricNamer("cls-1", n, resourceName).BuildUniqueKey()
+	}
+	history := &fakeHistory{
+		series: map[string][]*common.TimeSeries{
+			key(nodes[0], v1.ResourceCPU):    {series(1.5)},
+			key(nodes[0], v1.ResourceMemory): {series(1024)},
+		},
+		// the failed node is skipped, node-3 has no history
+		errs: map[string]error{key(nodes[1], v1.ResourceCPU): fmt.Errorf("timeout")},
+	}
+	f := NewMetricFetcher("cls-1", history, &fakeCache{nodes: nodes})
+
+	cpu, err := f.NodeCPUUsed(context.TODO(), time.Unix(0, 0), time.Unix(3600, 0), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cpu) != 1 || cpu["node-1"].Samples[0].Value != 1.5 {
+		t.Errorf("unexpected nodes cpu used %v", cpu)
+	}
+	mem, err := f.NodeRAMUsed(context.TODO(), time.Unix(0, 0), time.Unix(3600, 0), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mem) != 1 || mem["node-1"].Samples[0].Value != 1024 {
+		t.Errorf("unexpected nodes ram used %v", mem)
+	}
+}
+
+func TestPodResourceUsed(t *testing.T) {
+	pod := func(namespace, name string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	}
+	key := func(p *v1.Pod) string {
+		return metricnaming.ResourceToPodMetricNamer("cls-1", p.Namespace, p.Name, v1.ResourceCPU).BuildUniqueKey()
+	}
+	pods := []*v1.Pod{pod("default", "web"), pod("team-b", "web"), pod("default", "api")}
+	history := &fakeHistory{series: map[string][]*common.TimeSeries{
+		key(pods[0]): {series(0.5)},
+		key(pods[1]): {series(2)},
+	}}
+	f := NewMetricFetcher("cls-1", history, &fakeCache{pods: pods})
+
+	cpu, err := f.PodCPUUsed(context.TODO(), time.Unix(0, 0), time.Unix(3600, 0), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pods of the same name in different namespaces are kept apart
+	if len(cpu) != 2 || cpu["default/web"].Samples[0].Value != 0.5 || cpu["team-b/web"].Samples[0].Value != 2 {
+		t.Errorf("unexpected pods cpu used %v", cpu)
+	}
+}
+
+func TestNodeCountByInstanceType(t *testing.T) {
+	key := metricnaming.NodeCountMetricNamer("cls-1").BuildUniqueKey()
+	history := &fakeHistory{series: map[string][]*common.TimeSeries{key: {
+		series(3, common.Label{Name: consts.LabelInstanceType, Value: "S5.LARGE8"}),
+		series(1, common.Label{Name: consts.LabelInstanceType, Value: "SA2.MEDIUM4"}),
+		// no instance type label
+		series(2),
+	}}}
+	f := NewMetricFetcher("cls-1", history, &fakeCache{})
+
+	counts, err := f.NodeCountByInstanceType(context.TODO(), time.Unix(0, 0), time.Unix(3600, 0), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]*common.TimeSeries{"S5.LARGE8": history.series[key][0], "SA2.MEDIUM4": history.series[key][1]}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expect %v, got %v", expected, counts)
+	}
+
+	history.errs = map[string]error{key: fmt.Errorf("timeout")}
+	if _, err := f.NodeCountByInstanceType(context.TODO(), time.Unix(0, 0), time.Unix(3600, 0), time.Minute); err == nil {
+		t.Errorf("expect the query error returned")
+	}
+}
@@ -0,0 +1,251 @@
+package diff
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/report"
+)
+
+const (
+	StatusAdded     = "added"
+	StatusRemoved   = "removed"
+	StatusChanged   = "changed"
+	StatusUnchanged = "unchanged"
+)
+
+// Diff compares the workloads of the old and new snapshots, and aggregates them by namespace.
+// The threshold is the growth ratio of the requests below which the change is ignored.
+// A workload is a regression if its requests grew while its usage did not grow by the threshold,
+// and it is rightsized if its old requests exceeded the old recommendation but the new requests do not.
+func Diff(old, new *Snapshot, threshold float64) *report.Report {
+	r := &report.Report{GeneratedAt: time.Now()}
+	workloads := report.NewTable("workloads-diff", fmt.Sprintf("Workloads Diff(Old: %v, New: %v, Threshold: %v)", old.Source, new.Source, threshold),
+		report.String("Kind"), report.String("Namespace"), report.String("Name"), report.String("Status"),
+		report.Float("OldReplicas"), report.Float("NewReplicas"),
+		report.Float("OldCpuRequests"), report.Float("NewCpuRequests"), report.Float("CpuRequestsDelta"),
+		report.Float("OldMemRequests"), report.Float("NewMemRequests"), report.Float("MemRequestsDelta"),
+		report.Float("OldRecCpuRequests"), report.Float("NewRecCpuRequests"), report.Float("OldRecMemRequests"), report.Float("NewRecMemRequests"),
+		report.Float("OldCpuUsage"), report.Float("NewCpuUsage"), report.Float("OldMemUsage"), report.Float("NewMemUsage"),
+		report.Float("OldCost"), report.Float("NewCost"), report.Float("CostDelta"),
+		report.Bool("Regression"), report.Bool("Rightsized"), report.String("Reason"))
+	namespaces := report.NewTable("namespaces-diff", fmt.Sprintf("Namespaces Diff(Old: %v, New: %v, Threshold: %v)", old.Source, new.Source, threshold),
+		report.String("Namespace"), report.Int("OldWorkloads"), report.Int("NewWorkloads"),
+		report.Float("OldCpuRequests"), report.Float("NewCpuRequests"), report.Float("CpuRequestsDelta"),
+		report.Float("OldMemRequests"), report.Float("NewMemRequests"), report.Float("MemRequestsDelta"),
+		report.Float("OldCpuUsage"), report.Float("NewCpuUsage"), report.Float("OldMemUsage"), report.Float("NewMemUsage"),
+		report.Float("OldCost"), report.Float("NewCost"), report.Float("CostDelta"),
+		report.Int("Regressions"), report.Int("Rightsized"))
+
+	keys := make(map[string]bool)
+	for key := range old.Workloads {
+		keys[key] = true
+	}
+	for key := range new.Workloads {
+		keys[key] = true
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	namespaceDiffs := make(map[string]*namespaceDiff)
+	for _, key := range sortedKeys {
+		o, n := old.Workloads[key], new.Workloads[key]
+		w := o
+		if w == nil {
+			w = n
+		}
+		oldMetrics, newMetrics := metricsOf(o), metricsOf(n)
+		result := compareWorkload(oldMetrics, newMetrics, threshold)
+		switch {
+		case o == nil:
+			result.status = StatusAdded
+		case n == nil:
+			result.status = StatusRemoved
+		}
+
+		workloads.Append(w.Kind, w.Namespace, w.Name, result.status,
+			oldMetrics.get(MetricReplicas), newMetrics.get(MetricReplicas),
+			oldMetrics.get(MetricCpuRequests), newMetrics.get(MetricCpuRequests), delta(oldMetrics, newMetrics, MetricCpuRequests),
+			oldMetrics.get(MetricMemRequests), newMetrics.get(MetricMemRequests), delta(oldMetrics, newMetrics, MetricMemRequests),
+			oldMetrics.get(MetricRecCpuRequests), newMetrics.get(MetricRecCpuRequests), oldMetrics.get(MetricRecMemRequests), newMetrics.get(MetricRecMemRequests),
+			oldMetrics.get(MetricCpuUsage), newMetrics.get(MetricCpuUsage), oldMetrics.get(MetricMemUsage), newMetrics.get(MetricMemUsage),
+			oldMetrics.get(MetricCost), newMetrics.get(MetricCost), delta(oldMetrics, newMetrics, MetricCost),
+			result.regression, result.rightsized, strings.Join(result.reasons, "; "))
+
+		nsDiff, ok := namespaceDiffs[w.Namespace]
+		if !ok {
+			nsDiff = &namespaceDiff{}
+			namespaceDiffs[w.Namespace] = nsDiff
+		}
+		nsDiff.add(o, n, result)
+	}
+
+	sortedNamespaces := make([]string, 0, len(namespaceDiffs))
+	for namespace := range namespaceDiffs {
+		sortedNamespaces = append(sortedNamespaces, namespace)
+	}
+	sort.Strings(sortedNamespaces)
+	for _, namespace := range sortedNamespaces {
+		nsDiff := namespaceDiffs[namespace]
+		namespaces.Append(namespace, nsDiff.oldWorkloads, nsDiff.newWorkloads,
+			nsDiff.old.get(MetricCpuRequests), nsDiff.new.get(MetricCpuRequests), delta(nsDiff.old, nsDiff.new, MetricCpuRequests),
+			nsDiff.old.get(MetricMemRequests), nsDiff.new.get(MetricMemRequests), delta(nsDiff.old, nsDiff.new, MetricMemRequests),
+			nsDiff.old.get(MetricCpuUsage), nsDiff.new.get(MetricCpuUsage), nsDiff.old.get(MetricMemUsage), nsDiff.new.get(MetricMemUsage),
+			nsDiff.old.get(MetricCost), nsDiff.new.get(MetricCost), delta(nsDiff.old, nsDiff.new, MetricCost),
+			nsDiff.regressions, nsDiff.rightsized)
+	}
+
+	r.AddTable(workloads)
+	r.AddTable(namespaces)
+	r.AddCost("old", sumCost(old))
+	r.AddCost("new", sumCost(new))
+	return r
+}
+
+// metrics of a workload or a namespace, nil if the workload is absent
+type metrics map[string]float64
+
+func metricsOf(w *Workload) metrics {
+	if w == nil {
+		return nil
+	}
+	return w.Metrics
+}
+
+// get returns nil for the absent metric, so it is N/A in the report
+func (m metrics) get(metric string) interface{} {
+	if value, ok := m[metric]; ok {
+		return value
+	}
+	return nil
+}
+
+func delta(old, new metrics, metric string) interface{} {
+	o, oldOk := old[metric]
+	n, newOk := new[metric]
+	switch {
+	case oldOk && newOk:
+		return n - o
+	case newOk && old == nil:
+		return n
+	case oldOk && new == nil:
+		return -o
+	}
+	return nil
+}
+
+type workloadResult struct {
+	status     string
+	regression bool
+	rightsized bool
+	reasons    []string
+}
+
+func compareWorkload(old, new metrics, threshold float64) workloadResult {
+	result := workloadResult{status: StatusUnchanged}
+	if old == nil || new == nil {
+		return result
+	}
+	for _, resource := range []struct {
+		name, requests, recommended, usage string
+	}{
+		{"cpu", MetricCpuRequests, MetricRecCpuRequests, MetricCpuUsage},
+		{"mem", MetricMemRequests, MetricRecMemRequests, MetricMemUsage},
+	} {
+		oldRequests, oldOk := old[resource.requests]
+		newRequests, newOk := new[resource.requests]
+		if !oldOk || !newOk {
+			continue
+		}
+		requestsGrowth := growth(oldRequests, newRequests)
+		if requestsGrowth > threshold || requestsGrowth < -threshold {
+			result.status = StatusChanged
+		}
+
+		oldUsage, oldUsageOk := old[resource.usage]
+		newUsage, newUsageOk := new[resource.usage]
+		if usageGrowth := growth(oldUsage, newUsage); oldUsageOk && newUsageOk && requestsGrowth > threshold && usageGrowth < threshold {
+			result.regression = true
+			result.reasons = append(result.reasons, fmt.Sprintf("%v requests %v while usage %v", resource.name, formatGrowth(requestsGrowth), formatGrowth(usageGrowth)))
+		}
+
+		// the old recommendation is the target of the rightsizing action
+		if recommended, recOk := old[resource.recommended]; recOk &&
+			oldRequests > recommended*(1+threshold) && newRequests <= recommended*(1+threshold) {
+			result.rightsized = true
+			result.reasons = append(result.reasons, fmt.Sprintf("%v requests rightsized from %.3f to %.3f, recommended %.3f", resource.name, oldRequests, newRequests, recommended))
+		}
+	}
+	return result
+}
+
+// growth returns the growth ratio, it is unbounded if the old value is zero and the new one is not
+func growth(old, new float64) float64 {
+	if old == 0 {
+		if new == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (new - old) / old
+}
+
+func formatGrowth(g float64) string {
+	if math.IsInf(g, 1) {
+		return "up from 0"
+	}
+	return fmt.Sprintf("%+.0f%%", g*100)
+}
+
+type namespaceDiff struct {
+	oldWorkloads int
+	newWorkloads int
+	old          metrics
+	new          metrics
+	regressions  int
+	rightsized   int
+}
+
+func (d *namespaceDiff) add(old, new *Workload, result workloadResult) {
+	sum := func(total metrics, w *Workload) {
+		for _, metric := range []string{MetricCpuRequests, MetricMemRequests, MetricCpuUsage, MetricMemUsage, MetricCost} {
+			if value, ok := w.Metrics[metric]; ok {
+				total[metric] += value
+			}
+		}
+	}
+	if old != nil {
+		if d.old == nil {
+			d.old = make(metrics)
+		}
+		d.oldWorkloads++
+		sum(d.old, old)
+	}
+	if new != nil {
+		if d.new == nil {
+			d.new = make(metrics)
+		}
+		d.newWorkloads++
+		sum(d.new, new)
+	}
+	if result.regression {
+		d.regressions++
+	}
+	if result.rightsized {
+		d.rightsized++
+	}
+}
+
+func sumCost(s *Snapshot) float64 {
+	total := 0.
+	for _, w := range s.Workloads {
+		total += w.Metrics[MetricCost]
+	}
+	return total
+}
@@ -0,0 +1,143 @@
+package diff
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gocrane/crane/pkg/common"
+
+	"github.com/gocrane/fadvisor/pkg/consts"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/checkpoint"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/report"
+)
+
+func testReport(workloads [][]interface{}, recommended [][]interface{}, usage []*report.WorkloadUsage) *report.Report {
+	r := &report.Report{}
+	original := report.NewTable("original-workloads-distribution", "",
+		report.String("Kind"), report.String("Namespace"), report.String("Name"), report.Float("CpuReq"), report.Float("MemReq"), report.Int("Replicas"), report.Float("Cost"))
+	for _, row := range workloads {
+		original.Append(row...)
+	}
+	rec := report.NewTable("recommended-workloads-distribution", "",
+		report.String("Kind"), report.String("Namespace"), report.String("Name"), report.Float("CpuReq"), report.Float("MemReq"), report.Int("Replicas"), report.Float("ServerlessCost"))
+	for _, row := range recommended {
+		rec.Append(row...)
+	}
+	r.AddTable(original)
+	r.AddTable(rec)
+	r.Usage = usage
+	return r
+}
+
+func TestDiff(t *testing.T) {
+	old := SnapshotFromReport("old", testReport(
+		[][]interface{}{
+			{"Deployment", "default", "nginx", 2., 4., 2, 10.},
+			{"Deployment", "default", "redis", 1., 2., 1, 5.},
+			{"Deployment", "app", "api", 1., 1., 1, 3.},
+		},
+		[][]interface{}{
+			{"Deployment", "default", "nginx", 0.5, 1., 2, 3.},
+		},
+		[]*report.WorkloadUsage{
+			{Kind: "Deployment", Namespace: "default", Name: "nginx", Cpu: []float64{0.4, 0.6}, Mem: []float64{1, 1}},
+			{Kind: "Deployment", Namespace: "default", Name: "redis", Cpu: []float64{0.5}, Mem: []float64{1}},
+		}))
+	new := SnapshotFromReport("new", testReport(
+		[][]interface{}{
+			{"Deployment", "default", "nginx", 0.5, 1., 2, 3.},
+			{"Deployment", "default", "redis", 2., 2., 1, 8.},
+			{"Deployment", "app", "web", 1., 1., 1, 3.},
+		},
+		nil,
+		[]*report.WorkloadUsage{
+			{Kind: "Deployment", Namespace: "default", Name: "nginx", Cpu: []float64{0.5}, Mem: []float64{1}},
+			{Kind: "Deployment", Namespace: "default", Name: "redis", Cpu: []float64{0.5}, Mem: []float64{1}},
+		}))
+
+	result := Diff(old, new, 0.1)
+	rows := make(map[string]map[string]interface{})
+	forEachWorkload(result.Table("workloads-diff"), func(row map[string]interface{}) {
+		rows[row["Namespace"].(string)+"/"+row["Name"].(string)] = row
+	})
+
+	nginx := rows["default/nginx"]
+	if nginx["Status"] != StatusChanged || nginx["Rightsized"] != true || nginx["Regression"] != false || nginx["CpuRequestsDelta"] != -3. {
+		t.Errorf("unexpected nginx diff %v", nginx)
+	}
+	redis := rows["default/redis"]
+	if redis["Regression"] != true || redis["CostDelta"] != 3. || redis["Reason"] != "cpu requests +100% while usage +0%" {
+		t.Errorf("unexpected redis diff %v", redis)
+	}
+	if rows["app/api"]["Status"] != StatusRemoved || rows["app/api"]["CostDelta"] != -3. {
+		t.Errorf("unexpected api diff %v", rows["app/api"])
+	}
+	if rows["app/web"]["Status"] != StatusAdded || rows["app/web"]["OldCpuRequests"] != nil {
+		t.Errorf("unexpected web diff %v", rows["app/web"])
+	}
+
+	namespaces := result.Table("namespaces-diff")
+	// app then default
+	def := namespaces.Rows[1]
+	if def[0] != "default" || def[3] != 5. || def[4] != 3. || def[16] != int64(1) || def[17] != int64(1) {
+		t.Errorf("unexpected default namespace diff %v", def)
+	}
+}
+
+func TestDiffRequestsFromZero(t *testing.T) {
+	old := SnapshotFromReport("old", testReport(
+		[][]interface{}{{"Deployment", "default", "nginx", 0., 1., 1, 1.}},
+		nil,
+		[]*report.WorkloadUsage{{Kind: "Deployment", Namespace: "default", Name: "nginx", Cpu: []float64{0.5}, Mem: []float64{1}}}))
+	new := SnapshotFromReport("new", testReport(
+		[][]interface{}{{"Deployment", "default", "nginx", 2., 1., 1, 1.}},
+		nil,
+		[]*report.WorkloadUsage{{Kind: "Deployment", Namespace: "default", Name: "nginx", Cpu: []float64{0.5}, Mem: []float64{1}}}))
+
+	// the requests jump from 0 is the largest growth
+	var nginx map[string]interface{}
+	forEachWorkload(Diff(old, new, 0.1).Table("workloads-diff"), func(row map[string]interface{}) {
+		nginx = row
+	})
+	if nginx["Status"] != StatusChanged || nginx["Regression"] != true || nginx["Reason"] != "cpu requests up from 0 while usage +0%" {
+		t.Errorf("unexpected nginx diff %v", nginx)
+	}
+}
+
+func TestLoadCheckpoint(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cls-1-workloads-timeseries.ckpt")
+	series := func(values ...float64) []*common.TimeSeries {
+		ts := common.NewTimeSeries()
+		for i, v := range values {
+			ts.AppendSample(int64(100*(i+1)), v)
+		}
+		return []*common.TimeSeries{ts}
+	}
+	err := checkpoint.Write(file, checkpoint.Header{Kind: checkpoint.KindWorkload, ClusterId: "cls-1"}, checkpoint.Window{}, []*checkpoint.Entry{{
+		Kind:      "Deployment",
+		Namespace: "default",
+		Name:      "nginx",
+		Metrics: map[string][]*common.TimeSeries{
+			checkpoint.MetricCpu:         series(1, 3),
+			checkpoint.MetricMem:         series(consts.GB),
+			checkpoint.MetricCpuRequests: series(4),
+			checkpoint.MetricMemRequests: series(2 * consts.GB),
+			checkpoint.MetricReplicas:    series(2),
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSnapshot(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := s.Workloads["Deployment/default/nginx"]
+	if w == nil || w.Metrics[MetricCpuUsage] != 2 || w.Metrics[MetricMemUsage] != 1 || w.Metrics[MetricCpuRequests] != 4 ||
+		w.Metrics[MetricMemRequests] != 2 || w.Metrics[MetricReplicas] != 2 {
+		t.Fatalf("unexpected workload %+v", w)
+	}
+	if _, ok := w.Metrics[MetricCost]; ok {
+		t.Fatalf("expected no cost in checkpoint")
+	}
+}
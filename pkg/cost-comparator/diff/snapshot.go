@@ -0,0 +1,189 @@
+package diff
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gocrane/crane/pkg/common"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/gocrane/fadvisor/pkg/consts"
+	costcomparator "github.com/gocrane/fadvisor/pkg/cost-comparator"
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/report"
+)
+
+// metrics of a workload in a snapshot, requests and usage are the totals of all replicas
+const (
+	MetricReplicas          = "Replicas"
+	MetricCpuRequests       = "CpuRequests"
+	MetricMemRequests       = "MemRequests"
+	MetricRecCpuRequests    = "RecCpuRequests"
+	MetricRecMemRequests    = "RecMemRequests"
+	MetricCpuUsage          = "CpuUsage"
+	MetricMemUsage          = "MemUsage"
+	MetricCost              = "Cost"
+	MetricRecServerlessCost = "RecServerlessCost"
+)
+
+// Workload is the metrics of a workload in one comparator run, a metric is absent if the run does not output it
+type Workload struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Metrics   map[string]float64
+}
+
+func (w *Workload) Key() string {
+	return w.Kind + "/" + w.Namespace + "/" + w.Name
+}
+
+// Snapshot is the workloads of one comparator run, keyed by kind/namespace/name
+type Snapshot struct {
+	Source    string
+	Workloads map[string]*Workload
+}
+
+func newSnapshot(source string) *Snapshot {
+	return &Snapshot{Source: source, Workloads: make(map[string]*Workload)}
+}
+
+func (s *Snapshot) workload(kind, namespace, name string) *Workload {
+	w := &Workload{Kind: kind, Namespace: namespace, Name: name}
+	if existing, ok := s.Workloads[w.Key()]; ok {
+		return existing
+	}
+	w.Metrics = make(map[string]float64)
+	s.Workloads[w.Key()] = w
+	return w
+}
+
+// LoadSnapshot loads a json report or a workload time series checkpoint of the comparator
+func LoadSnapshot(file string) (*Snapshot, error) {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r, err := report.ReadJson(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read report %v: %v", file, err)
+		}
+		return SnapshotFromReport(file, r), nil
+	case ".ckpt":
+		data, err := costcomparator.ReadWorkloadTimeSeriesCheckpoint(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read checkpoint %v: %v", file, err)
+		}
+		return SnapshotFromCheckpoint(file, data), nil
+	default:
+		return nil, fmt.Errorf("unknown file type of %v, only json report and ckpt workload time series checkpoint are supported", file)
+	}
+}
+
+// SnapshotFromReport takes the requests and costs from the workloads distribution tables, and the mean usage from the workloads usage
+func SnapshotFromReport(source string, r *report.Report) *Snapshot {
+	s := newSnapshot(source)
+	if table := r.Table("original-workloads-distribution"); table != nil {
+		forEachWorkload(table, func(row map[string]interface{}) {
+			w := s.workload(row["Kind"].(string), row["Namespace"].(string), row["Name"].(string))
+			replicas, ok := row["Replicas"].(int64)
+			if !ok {
+				return
+			}
+			w.Metrics[MetricReplicas] = float64(replicas)
+			setTotal(w, MetricCpuRequests, row["CpuReq"], replicas)
+			setTotal(w, MetricMemRequests, row["MemReq"], replicas)
+			if cost, ok := row["Cost"].(float64); ok {
+				w.Metrics[MetricCost] = cost
+			}
+		})
+	}
+	if table := r.Table("recommended-workloads-distribution"); table != nil {
+		forEachWorkload(table, func(row map[string]interface{}) {
+			w := s.workload(row["Kind"].(string), row["Namespace"].(string), row["Name"].(string))
+			replicas, ok := row["Replicas"].(int64)
+			if !ok {
+				return
+			}
+			setTotal(w, MetricRecCpuRequests, row["CpuReq"], replicas)
+			setTotal(w, MetricRecMemRequests, row["MemReq"], replicas)
+			if cost, ok := row["ServerlessCost"].(float64); ok {
+				w.Metrics[MetricRecServerlessCost] = cost
+			}
+		})
+	}
+	for _, usage := range r.Usage {
+		w := s.workload(usage.Kind, usage.Namespace, usage.Name)
+		if len(usage.Cpu) > 0 {
+			w.Metrics[MetricCpuUsage] = mean(usage.Cpu)
+		}
+		if len(usage.Mem) > 0 {
+			w.Metrics[MetricMemUsage] = mean(usage.Mem)
+		}
+	}
+	return s
+}
+
+// SnapshotFromCheckpoint takes the mean requests, replicas and usage of the workload time series, there is no recommendation and cost in the checkpoint
+func SnapshotFromCheckpoint(source string, data map[string]map[types.NamespacedName]*costcomparator.RawWorkloadTimeSeriesData) *Snapshot {
+	s := newSnapshot(source)
+	for kind, kindWorkloads := range data {
+		for nn, tsData := range kindWorkloads {
+			w := s.workload(kind, nn.Namespace, nn.Name)
+			setMean(w, MetricReplicas, tsData.Replicas, 1)
+			setMean(w, MetricCpuRequests, tsData.CpuRequests, 1)
+			setMean(w, MetricMemRequests, tsData.MemRequests, consts.GB)
+			setMean(w, MetricCpuUsage, tsData.Cpu, 1)
+			setMean(w, MetricMemUsage, tsData.Mem, consts.GB)
+		}
+	}
+	return s
+}
+
+func forEachWorkload(table *report.Table, fn func(row map[string]interface{})) {
+	for _, values := range table.Rows {
+		row := make(map[string]interface{}, len(table.Columns))
+		for i, column := range table.Columns {
+			row[column.Name] = values[i]
+		}
+		if _, ok := row["Kind"].(string); !ok {
+			continue
+		}
+		if _, ok := row["Namespace"].(string); !ok {
+			continue
+		}
+		if _, ok := row["Name"].(string); !ok {
+			continue
+		}
+		fn(row)
+	}
+}
+
+func setTotal(w *Workload, metric string, perReplica interface{}, replicas int64) {
+	if value, ok := perReplica.(float64); ok {
+		w.Metrics[metric] = value * float64(replicas)
+	}
+}
+
+func setMean(w *Workload, metric string, tsList []*common.TimeSeries, scale float64) {
+	if len(tsList) == 0 || tsList[0] == nil || len(tsList[0].Samples) == 0 {
+		return
+	}
+	values := make([]float64, 0, len(tsList[0].Samples))
+	for _, sample := range tsList[0].Samples {
+		values = append(values, sample.Value/scale)
+	}
+	w.Metrics[metric] = mean(values)
+}
+
+func mean(values []float64) float64 {
+	sum := 0.
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,195 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+const (
+	sparklineWidth     = 160
+	sparklineHeight    = 28
+	sparklineMaxPoints = 200
+	barChartWidth      = 480
+	barHeight          = 24
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"format":    FormatValue,
+	"sortValue": sortValue,
+	"barChart":  barChart,
+	"sparkline": sparkline,
+	"max":       maxValue,
+}).Parse(htmlReportTemplate))
+
+// WriteHtml render the report to a self-contained html page, no external scripts or styles are needed
+func WriteHtml(w io.Writer, r *Report) error {
+	return htmlTemplate.Execute(w, r)
+}
+
+// sortValue is the value used by the sortable table, numbers are sorted numerically and nil values are sorted last
+func sortValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return fmt.Sprintf("%g", v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func barChart(items []CostItem) template.HTML {
+	var maxCost float64
+	for _, item := range items {
+		if item.Cost > maxCost {
+			maxCost = item.Cost
+		}
+	}
+	labelWidth := 260
+	height := len(items) * (barHeight + 8)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg class="bars" width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">`, labelWidth+barChartWidth+100, height)
+	for i, item := range items {
+		y := i * (barHeight + 8)
+		width := 0.
+		if maxCost > 0 {
+			width = item.Cost / maxCost * barChartWidth
+		}
+		fmt.Fprintf(&sb, `<text x="0" y="%d">%s</text>`, y+barHeight-7, template.HTMLEscapeString(item.Name))
+		fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%.1f" height="%d" class="bar%d"></rect>`, labelWidth, y, width, barHeight, i%4)
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%d">%s</text>`, float64(labelWidth)+width+6, y+barHeight-7, FormatValue(item.Cost))
+	}
+	sb.WriteString(`</svg>`)
+	return template.HTML(sb.String())
+}
+
+func sparkline(values []float64) template.HTML {
+	if len(values) == 0 {
+		return ""
+	}
+	// downsample by max so the peaks are kept
+	if len(values) > sparklineMaxPoints {
+		bucket := (len(values) + sparklineMaxPoints - 1) / sparklineMaxPoints
+		var sampled []float64
+		for i := 0; i < len(values); i += bucket {
+			end := i + bucket
+			if end > len(values) {
+				end = len(values)
+			}
+			sampled = append(sampled, maxValue(values[i:end]))
+		}
+		values = sampled
+	}
+	maxV := maxValue(values)
+	var points []string
+	for i, v := range values {
+		x := 0.
+		if len(values) > 1 {
+			x = float64(i) / float64(len(values)-1) * sparklineWidth
+		}
+		y := float64(sparklineHeight)
+		if maxV > 0 {
+			y = sparklineHeight - v/maxV*(sparklineHeight-2)
+		}
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	return template.HTML(fmt.Sprintf(`<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg"><polyline class="spark" points="%s"></polyline></svg>`,
+		sparklineWidth, sparklineHeight, strings.Join(points, " ")))
+}
+
+func maxValue(values []float64) float64 {
+	var result float64
+	for _, v := range values {
+		if v > result {
+			result = v
+		}
+	}
+	return result
+}
+
+const htmlReportTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Cost Comparator Report - {{.ClusterName}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 24px; color: #222; }
+h1 { font-size: 22px; }
+h2 { font-size: 17px; margin-top: 32px; border-bottom: 1px solid #ddd; padding-bottom: 4px; }
+dl { display: grid; grid-template-columns: max-content auto; gap: 4px 16px; }
+dt { font-weight: bold; }
+table { border-collapse: collapse; font-size: 12px; }
+th, td { border: 1px solid #ddd; padding: 3px 8px; text-align: left; white-space: nowrap; }
+th { background: #f3f3f3; cursor: pointer; user-select: none; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+.scroll { overflow-x: auto; max-height: 600px; }
+svg text { font-size: 12px; }
+.bar0 { fill: #d9534f; } .bar1 { fill: #5bc0de; } .bar2 { fill: #5cb85c; } .bar3 { fill: #f0ad4e; }
+.spark { fill: none; stroke: #337ab7; stroke-width: 1.2; }
+</style>
+</head>
+<body>
+<h1>Cost Comparator Report</h1>
+<dl>
+<dt>Cluster</dt><dd>{{.ClusterName}} ({{.ClusterId}})</dd>
+<dt>TimeSpan Seconds</dt><dd>{{.TimeSpanSeconds}}</dd>
+<dt>Discount</dt><dd>{{.Discount}}</dd>
+{{if .Currency}}<dt>Currency</dt><dd>{{.Currency}}</dd>{{end}}
+<dt>Generated At</dt><dd>{{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}</dd>
+</dl>
+{{if .CostSummary}}
+<h2>Cost Summary</h2>
+{{barChart .CostSummary}}
+{{end}}
+{{range .Tables}}
+<h2>{{.Title}}</h2>
+<div class="scroll">
+<table class="sortable">
+<thead><tr>{{range .Columns}}<th data-type="{{.Type}}">{{.Name}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .Rows}}<tr>{{range .}}<td data-value="{{sortValue .}}"{{if or (eq (printf "%T" .) "float64") (eq (printf "%T" .) "int64")}} class="num"{{end}}>{{format .}}</td>{{end}}</tr>
+{{end}}
+</tbody>
+</table>
+</div>
+{{end}}
+{{if .Usage}}
+<h2>Workloads Usage</h2>
+<div class="scroll">
+<table class="sortable">
+<thead><tr><th data-type="string">Kind</th><th data-type="string">Namespace</th><th data-type="string">Name</th><th data-type="float">MaxCpu</th><th>Cpu</th><th data-type="float">MaxMem</th><th>Mem</th></tr></thead>
+<tbody>
+{{range .Usage}}<tr><td data-value="{{.Kind}}">{{.Kind}}</td><td data-value="{{.Namespace}}">{{.Namespace}}</td><td data-value="{{.Name}}">{{.Name}}</td><td class="num" data-value="{{sortValue (max .Cpu)}}">{{format (max .Cpu)}}</td><td>{{sparkline .Cpu}}</td><td class="num" data-value="{{sortValue (max .Mem)}}">{{format (max .Mem)}}</td><td>{{sparkline .Mem}}</td></tr>
+{{end}}
+</tbody>
+</table>
+</div>
+{{end}}
+<script>
+document.querySelectorAll("table.sortable th[data-type]").forEach(function (th) {
+  th.addEventListener("click", function () {
+    var table = th.closest("table");
+    var index = Array.prototype.indexOf.call(th.parentNode.children, th);
+    var numeric = th.dataset.type !== "string" && th.dataset.type !== "bool";
+    var asc = !th.classList.contains("asc");
+    table.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+    th.classList.add(asc ? "asc" : "desc");
+    var tbody = table.tBodies[0];
+    var rows = Array.prototype.slice.call(tbody.rows);
+    rows.sort(function (a, b) {
+      var x = a.cells[index].dataset.value, y = b.cells[index].dataset.value;
+      if (x === "" || y === "") { return x === y ? 0 : (x === "" ? 1 : -1); }
+      var r = numeric ? parseFloat(x) - parseFloat(y) : x.localeCompare(y);
+      return asc ? r : -r;
+    });
+    rows.forEach(function (row) { tbody.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`
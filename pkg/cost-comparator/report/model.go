@@ -0,0 +1,212 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+type ColumnType string
+
+const (
+	ColumnTypeString ColumnType = "string"
+	ColumnTypeFloat  ColumnType = "float"
+	ColumnTypeInt    ColumnType = "int"
+	ColumnTypeBool   ColumnType = "bool"
+)
+
+type Column struct {
+	Name string     `json:"name"`
+	Type ColumnType `json:"type"`
+}
+
+func String(name string) Column {
+	return Column{Name: name, Type: ColumnTypeString}
+}
+
+func Float(name string) Column {
+	return Column{Name: name, Type: ColumnTypeFloat}
+}
+
+func Int(name string) Column {
+	return Column{Name: name, Type: ColumnTypeInt}
+}
+
+func Bool(name string) Column {
+	return Column{Name: name, Type: ColumnTypeBool}
+}
+
+// Table is one section of the comparator report, such as the cost summary or the workloads distribution.
+// Each value of a row is typed by its column, nil value means not available.
+type Table struct {
+	// Name is the identity of the table, it is also the suffix of the output file name
+	Name string `json:"name"`
+	// Title is a human readable description of the table
+	Title   string          `json:"title"`
+	Columns []Column        `json:"columns"`
+	Rows    [][]interface{} `json:"-"`
+
+	err error
+}
+
+func NewTable(name, title string, columns ...Column) *Table {
+	return &Table{
+		Name:    name,
+		Title:   title,
+		Columns: columns,
+	}
+}
+
+// Append a row to the table, values are converted to the type of the columns.
+// string, float64, int64 and bool are the value types stored for string, float, int and bool columns.
+// NaN and Inf floats are stored as nil, which means not available. The row is not appended if a value does not match its column,
+// the error is also kept by the table and returned by the writers.
+func (t *Table) Append(values ...interface{}) error {
+	if len(values) > len(t.Columns) {
+		return t.fail(fmt.Errorf("table %v: %v values for %v columns", t.Name, len(values), len(t.Columns)))
+	}
+	row := make([]interface{}, len(t.Columns))
+	for i, value := range values {
+		v, err := convert(t.Columns[i].Type, value)
+		if err != nil {
+			return t.fail(fmt.Errorf("table %v column %v: %v", t.Name, t.Columns[i].Name, err))
+		}
+		row[i] = v
+	}
+	t.Rows = append(t.Rows, row)
+	return nil
+}
+
+func (t *Table) fail(err error) error {
+	if t.err == nil {
+		t.err = err
+	}
+	return err
+}
+
+// Err returns the first error of appending the rows
+func (t *Table) Err() error {
+	return t.err
+}
+
+func convert(columnType ColumnType, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	v := reflect.ValueOf(value)
+	switch columnType {
+	case ColumnTypeFloat:
+		var f float64
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			f = v.Float()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f = float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			f = float64(v.Uint())
+		default:
+			return nil, fmt.Errorf("%T value %v is not a float", value, value)
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, nil
+		}
+		return f, nil
+	case ColumnTypeInt:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return v.Int(), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if v.Uint() > math.MaxInt64 {
+				return nil, fmt.Errorf("%v overflows int64", value)
+			}
+			return int64(v.Uint()), nil
+		}
+		return nil, fmt.Errorf("%T value %v is not an int", value, value)
+	case ColumnTypeBool:
+		if v.Kind() == reflect.Bool {
+			return v.Bool(), nil
+		}
+		return nil, fmt.Errorf("%T value %v is not a bool", value, value)
+	case ColumnTypeString:
+		if v.Kind() == reflect.String {
+			return v.String(), nil
+		}
+		if stringer, ok := value.(fmt.Stringer); ok {
+			return stringer.String(), nil
+		}
+		return nil, fmt.Errorf("%T value %v is not a string", value, value)
+	}
+	return nil, fmt.Errorf("unknown column type %v", columnType)
+}
+
+// Validate returns an error if a cost or usage of the report is NaN or Inf, they can not be written as json
+func (r *Report) Validate() error {
+	for _, item := range r.CostSummary {
+		if math.IsNaN(item.Cost) || math.IsInf(item.Cost, 0) {
+			return fmt.Errorf("cost %v is %v", item.Name, item.Cost)
+		}
+	}
+	for _, usage := range r.Usage {
+		for _, series := range [][]float64{usage.Cpu, usage.Mem} {
+			for _, value := range series {
+				if math.IsNaN(value) || math.IsInf(value, 0) {
+					return fmt.Errorf("usage of %v %v/%v is %v", usage.Kind, usage.Namespace, usage.Name, value)
+				}
+			}
+		}
+	}
+	for _, t := range r.Tables {
+		if t.err != nil {
+			return t.err
+		}
+	}
+	return nil
+}
+
+// Report is all tables of a comparator analysis of a cluster
+type Report struct {
+	ClusterId       string    `json:"clusterId"`
+	ClusterName     string    `json:"clusterName"`
+	TimeSpanSeconds int64     `json:"timeSpanSeconds"`
+	Discount        float64   `json:"discount"`
+	Currency        string    `json:"currency,omitempty"`
+	GeneratedAt     time.Time `json:"generatedAt"`
+	// CostSummary is the total cost of each way to run the cluster, such as original, serverless and recommended
+	CostSummary []CostItem `json:"costSummary,omitempty"`
+	Tables      []*Table   `json:"tables"`
+	// Usage is the usage time series of the workloads, it is used to draw sparklines
+	Usage []*WorkloadUsage `json:"usage,omitempty"`
+}
+
+type CostItem struct {
+	Name string  `json:"name"`
+	Cost float64 `json:"cost"`
+}
+
+// WorkloadUsage is the total cpu cores and memory GB usage of all containers of a workload at each step
+type WorkloadUsage struct {
+	Kind      string    `json:"kind"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Cpu       []float64 `json:"cpu"`
+	Mem       []float64 `json:"mem"`
+}
+
+func (r *Report) AddTable(t *Table) {
+	r.Tables = append(r.Tables, t)
+}
+
+func (r *Report) AddCost(name string, cost float64) {
+	r.CostSummary = append(r.CostSummary, CostItem{Name: name, Cost: cost})
+}
+
+// Table return the table by name, nil if not found
+func (r *Report) Table(name string) *Table {
+	for _, t := range r.Tables {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
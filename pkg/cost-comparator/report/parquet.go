@@ -0,0 +1,315 @@
+package report
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// A minimal parquet writer, each table is written as one row group and each column as one uncompressed PLAIN data page.
+// All columns are OPTIONAL so nil values are kept as null. See https://github.com/apache/parquet-format
+
+const parquetMagic = "PAR1"
+
+// parquet physical types
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+)
+
+const (
+	parquetRepetitionOptional = 1
+	parquetConvertedTypeUtf8  = 0
+	parquetEncodingPlain      = 0
+	parquetEncodingRle        = 3
+	parquetCodecUncompressed  = 0
+	parquetPageTypeData       = 0
+)
+
+func parquetType(columnType ColumnType) int32 {
+	switch columnType {
+	case ColumnTypeFloat:
+		return parquetDouble
+	case ColumnTypeInt:
+		return parquetInt64
+	case ColumnTypeBool:
+		return parquetBoolean
+	default:
+		return parquetByteArray
+	}
+}
+
+// WriteParquet write the table as a parquet file
+func WriteParquet(w io.Writer, t *Table) error {
+	if t.err != nil {
+		return t.err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(parquetMagic)
+
+	numRows := int64(len(t.Rows))
+	var chunks []func(*thriftWriter)
+	var totalSize int64
+	for i, column := range t.Columns {
+		page, err := encodeParquetPage(t, i)
+		if err != nil {
+			return err
+		}
+
+		header := newThriftWriter()
+		header.fieldI32(1, parquetPageTypeData)
+		header.fieldI32(2, int32(len(page)))
+		header.fieldI32(3, int32(len(page)))
+		header.fieldStruct(5, func(dph *thriftWriter) {
+			dph.fieldI32(1, int32(numRows))
+			dph.fieldI32(2, parquetEncodingPlain)
+			dph.fieldI32(3, parquetEncodingRle)
+			dph.fieldI32(4, parquetEncodingRle)
+		})
+		header.stop()
+
+		offset := int64(buf.Len())
+		size := int64(header.buf.Len() + len(page))
+		buf.Write(header.buf.Bytes())
+		buf.Write(page)
+		totalSize += size
+
+		name := column.Name
+		typ := parquetType(column.Type)
+		chunks = append(chunks, func(cc *thriftWriter) {
+			cc.fieldI64(2, offset)
+			cc.fieldStruct(3, func(md *thriftWriter) {
+				md.fieldI32(1, typ)
+				md.fieldList(2, thriftI32, 2, func(l *thriftWriter) {
+					l.i32(parquetEncodingPlain)
+					l.i32(parquetEncodingRle)
+				})
+				md.fieldList(3, thriftBinary, 1, func(l *thriftWriter) {
+					l.binary(name)
+				})
+				md.fieldI32(4, parquetCodecUncompressed)
+				md.fieldI64(5, numRows)
+				md.fieldI64(6, size)
+				md.fieldI64(7, size)
+				md.fieldI64(9, offset)
+			})
+		})
+	}
+
+	meta := newThriftWriter()
+	meta.fieldI32(1, 1)
+	meta.fieldList(2, thriftStruct, len(t.Columns)+1, func(l *thriftWriter) {
+		l.structElem(func(root *thriftWriter) {
+			root.fieldBinary(4, "schema")
+			root.fieldI32(5, int32(len(t.Columns)))
+		})
+		for _, column := range t.Columns {
+			column := column
+			l.structElem(func(se *thriftWriter) {
+				se.fieldI32(1, parquetType(column.Type))
+				se.fieldI32(3, parquetRepetitionOptional)
+				se.fieldBinary(4, column.Name)
+				if column.Type == ColumnTypeString {
+					se.fieldI32(6, parquetConvertedTypeUtf8)
+				}
+			})
+		}
+	})
+	meta.fieldI64(3, numRows)
+	meta.fieldList(4, thriftStruct, 1, func(l *thriftWriter) {
+		l.structElem(func(rg *thriftWriter) {
+			rg.fieldList(1, thriftStruct, len(chunks), func(cl *thriftWriter) {
+				for _, chunk := range chunks {
+					cl.structElem(chunk)
+				}
+			})
+			rg.fieldI64(2, totalSize)
+			rg.fieldI64(3, numRows)
+		})
+	})
+	meta.fieldBinary(6, "fadvisor")
+	meta.stop()
+
+	buf.Write(meta.buf.Bytes())
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(meta.buf.Len()))
+	buf.WriteString(parquetMagic)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// encodeParquetPage encodes the definition levels and the non null values of the column
+func encodeParquetPage(t *Table, column int) ([]byte, error) {
+	var levels, values bytes.Buffer
+	columnType := t.Columns[column].Type
+
+	// definition levels, RLE runs of bit width 1
+	var runValue byte
+	var runLength int
+	flush := func() {
+		if runLength > 0 {
+			writeUvarint(&levels, uint64(runLength)<<1)
+			levels.WriteByte(runValue)
+		}
+	}
+	var bits []bool
+	for _, row := range t.Rows {
+		var value interface{}
+		if column < len(row) {
+			value = row[column]
+		}
+		var level byte
+		if value != nil {
+			level = 1
+		}
+		if runLength > 0 && level != runValue {
+			flush()
+			runLength = 0
+		}
+		runValue = level
+		runLength++
+		if value == nil {
+			continue
+		}
+
+		// the rows may be modified after they are appended, so the value types are checked again
+		var ok bool
+		switch columnType {
+		case ColumnTypeFloat:
+			var f float64
+			if f, ok = value.(float64); ok {
+				_ = binary.Write(&values, binary.LittleEndian, math.Float64bits(f))
+			}
+		case ColumnTypeInt:
+			var i int64
+			if i, ok = value.(int64); ok {
+				_ = binary.Write(&values, binary.LittleEndian, i)
+			}
+		case ColumnTypeBool:
+			var b bool
+			if b, ok = value.(bool); ok {
+				bits = append(bits, b)
+			}
+		default:
+			var s string
+			if s, ok = value.(string); ok {
+				_ = binary.Write(&values, binary.LittleEndian, uint32(len(s)))
+				values.WriteString(s)
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("table %v column %v: %T value %v does not match the column type %v", t.Name, t.Columns[column].Name, value, value, columnType)
+		}
+	}
+	flush()
+	// booleans are bit packed, lsb first
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8 && i+j < len(bits); j++ {
+			if bits[i+j] {
+				b |= 1 << uint(j)
+			}
+		}
+		values.WriteByte(b)
+	}
+
+	var page bytes.Buffer
+	_ = binary.Write(&page, binary.LittleEndian, uint32(levels.Len()))
+	page.Write(levels.Bytes())
+	page.Write(values.Bytes())
+	return page.Bytes(), nil
+}
+
+// thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+type thriftWriter struct {
+	buf         *bytes.Buffer
+	lastFieldId int16
+}
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{buf: &bytes.Buffer{}}
+}
+
+func (w *thriftWriter) fieldHeader(id int16, typ byte) {
+	delta := id - w.lastFieldId
+	if delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		writeUvarint(w.buf, zigzag(int64(id)))
+	}
+	w.lastFieldId = id
+}
+
+func (w *thriftWriter) i32(v int32) {
+	writeUvarint(w.buf, zigzag(int64(v)))
+}
+
+func (w *thriftWriter) binary(s string) {
+	writeUvarint(w.buf, uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *thriftWriter) stop() {
+	w.buf.WriteByte(0)
+}
+
+func (w *thriftWriter) fieldI32(id int16, v int32) {
+	w.fieldHeader(id, thriftI32)
+	w.i32(v)
+}
+
+func (w *thriftWriter) fieldI64(id int16, v int64) {
+	w.fieldHeader(id, thriftI64)
+	writeUvarint(w.buf, zigzag(v))
+}
+
+func (w *thriftWriter) fieldBinary(id int16, s string) {
+	w.fieldHeader(id, thriftBinary)
+	w.binary(s)
+}
+
+func (w *thriftWriter) fieldStruct(id int16, fields func(*thriftWriter)) {
+	w.fieldHeader(id, thriftStruct)
+	w.structElem(fields)
+}
+
+func (w *thriftWriter) fieldList(id int16, elemType byte, size int, elems func(*thriftWriter)) {
+	w.fieldHeader(id, thriftList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		w.buf.WriteByte(0xf0 | elemType)
+		writeUvarint(w.buf, uint64(size))
+	}
+	elems(w)
+}
+
+// structElem writes a struct without field header, field ids in the struct start from zero
+func (w *thriftWriter) structElem(fields func(*thriftWriter)) {
+	nested := &thriftWriter{buf: w.buf}
+	fields(nested)
+	nested.stop()
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	buf.Write(b[:n])
+}
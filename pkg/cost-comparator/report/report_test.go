@@ -0,0 +1,314 @@
+package report
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testTable() *Table {
+	t := NewTable("cost-summary", "Cost Summary", String("Type"), Float("TotalCost"), Int("Nodes"), Bool("Serverless"))
+	t.Append("tke", 12.5, 3, false)
+	t.Append("eks", 10.25, nil, true)
+	t.Append("total", nil, uint64(3), nil)
+	return t
+}
+
+func TestJsonRoundTrip(t *testing.T) {
+	r := &Report{ClusterId: "cls-1", TimeSpanSeconds: 3600}
+	r.AddTable(testTable())
+
+	var buf bytes.Buffer
+	if err := WriteJson(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadJson(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := got.Table("cost-summary")
+	if table == nil {
+		t.Fatalf("table not found in %+v", got)
+	}
+	if !reflect.DeepEqual(table.Rows, testTable().Rows) {
+		t.Fatalf("expected rows %v, got %v", testTable().Rows, table.Rows)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	table := NewTable("cost-summary", "Cost Summary", String("Type"), Float("TotalCost"), Int("Nodes"), Bool("Serverless"))
+	if err := table.Append("tke", math.NaN(), int32(3), true); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Append("eks", math.Inf(1), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	// the non-finite floats are not available
+	if !reflect.DeepEqual(table.Rows, [][]interface{}{{"tke", nil, int64(3), true}, {"eks", nil, nil, nil}}) {
+		t.Fatalf("unexpected rows %v", table.Rows)
+	}
+
+	for _, values := range [][]interface{}{
+		{"tke", "12.5"},
+		{"tke", 12.5, 3.5},
+		{"tke", 12.5, 3, "false"},
+		{1},
+		{"tke", 12.5, 3, false, "extra"},
+	} {
+		if err := table.Append(values...); err == nil {
+			t.Errorf("expect error of the values %v", values)
+		}
+	}
+	if len(table.Rows) != 2 || table.Err() == nil {
+		t.Fatalf("expect the mismatched rows are not appended, got %v", table.Rows)
+	}
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, table); err == nil {
+		t.Errorf("expect error of writing the invalid table")
+	}
+
+	// the rows modified after appending are checked by the parquet writer instead of panic
+	table = testTable()
+	table.Rows[0][1] = "12.5"
+	if err := WriteParquet(&buf, table); err == nil {
+		t.Errorf("expect error of the mismatched cell")
+	}
+}
+
+func TestWriteJsonNonFinite(t *testing.T) {
+	r := &Report{ClusterId: "cls-1"}
+	r.AddCost("original", math.NaN())
+	if err := WriteJson(&bytes.Buffer{}, r); err == nil || !strings.Contains(err.Error(), "original") {
+		t.Errorf("expect error of the NaN cost, got %v", err)
+	}
+	r = &Report{ClusterId: "cls-1"}
+	r.Usage = []*WorkloadUsage{{Kind: "Deployment", Namespace: "default", Name: "nginx", Cpu: []float64{0.1, math.Inf(1)}}}
+	if err := WriteJson(&bytes.Buffer{}, r); err == nil {
+		t.Errorf("expect error of the Inf usage")
+	}
+}
+
+func TestWriteCsv(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCsv(&buf, testTable()); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Type\tTotalCost\tNodes\tServerless\ntke\t12.50000\t3\tfalse\neks\t10.25000\t\ttrue\ntotal\t\t3\t\n"
+	if buf.String() != expected {
+		t.Fatalf("expected csv %q, got %q", expected, buf.String())
+	}
+}
+
+func TestWriteHtml(t *testing.T) {
+	r := &Report{ClusterId: "cls-1", ClusterName: "<demo>"}
+	r.AddCost("original", 100)
+	r.AddCost("recommended serverless", 60)
+	r.AddTable(testTable())
+	r.Usage = []*WorkloadUsage{{Kind: "Deployment", Namespace: "default", Name: "nginx", Cpu: []float64{0.1, 0.5, 0.2}, Mem: []float64{1, 1, 2}}}
+
+	var buf bytes.Buffer
+	if err := WriteHtml(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	html := buf.String()
+	for _, expected := range []string{
+		"&lt;demo&gt;",
+		`<th data-type="float">TotalCost</th>`,
+		`<td data-value="12.5" class="num">12.50000</td>`,
+		`class="spark" points="0.0,22.8 80.0,2.0 160.0,17.6"`,
+		"recommended serverless",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("expected %q in html report", expected)
+		}
+	}
+}
+
+func TestWriteParquet(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, testTable()); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		t.Fatalf("invalid magic")
+	}
+	metaLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := &thriftReader{data: data[len(data)-8-metaLen : len(data)-8]}
+	meta := r.readStruct()
+	if r.pos != metaLen {
+		t.Fatalf("footer decoded %v bytes, expected %v", r.pos, metaLen)
+	}
+	if meta[3] != int64(3) {
+		t.Fatalf("expected 3 rows, got %v", meta[3])
+	}
+	schema := meta[2].([]interface{})
+	var names []string
+	for _, se := range schema[1:] {
+		names = append(names, string(se.(map[int16]interface{})[4].([]byte)))
+	}
+	if !reflect.DeepEqual(names, []string{"Type", "TotalCost", "Nodes", "Serverless"}) {
+		t.Fatalf("unexpected schema %v", names)
+	}
+
+	// decode the TotalCost column page
+	rowGroup := meta[4].([]interface{})[0].(map[int16]interface{})
+	chunk := rowGroup[1].([]interface{})[1].(map[int16]interface{})
+	offset := chunk[2].(int64)
+	pr := &thriftReader{data: data[offset:]}
+	pageHeader := pr.readStruct()
+	page := data[int(offset)+pr.pos : int(offset)+pr.pos+int(pageHeader[3].(int32))]
+	levelsLen := binary.LittleEndian.Uint32(page)
+	// two present values then one null
+	if !bytes.Equal(page[4:4+levelsLen], []byte{2 << 1, 1, 1 << 1, 0}) {
+		t.Fatalf("unexpected definition levels %v", page[4:4+levelsLen])
+	}
+	values := page[4+levelsLen:]
+	if len(values) != 16 || math.Float64frombits(binary.LittleEndian.Uint64(values[8:])) != 10.25 {
+		t.Fatalf("unexpected values %v", values)
+	}
+}
+
+// testdata/cost-summary.parquet pins the bytes of the parquet file of testTable so any change of the output is
+// noticed, it does not prove the file is readable, see TestParquetInterop. Set UPDATE_GOLDEN=1 to regenerate it.
+func TestWriteParquetGolden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, testTable()); err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "cost-summary.parquet")
+	if os.Getenv("UPDATE_GOLDEN") != "" {
+		if err := ioutil.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("parquet file differs from %v", golden)
+	}
+}
+
+// readParquetScript reads the parquet file with pyarrow and prints the columns as json
+const readParquetScript = `
+import json, sys
+import pyarrow.parquet as pq
+print(json.dumps(pq.read_table(sys.argv[1]).to_pydict()))
+`
+
+// TestParquetInterop reads the parquet file back with pyarrow, it is skipped when python3 or pyarrow is not installed
+func TestParquetInterop(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("no python3 to read the parquet file")
+	}
+	if err := exec.Command(python, "-c", "import pyarrow.parquet").Run(); err != nil {
+		t.Skip("no pyarrow to read the parquet file")
+	}
+
+	dir, err := ioutil.TempDir("", "parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, testTable()); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "cost-summary.parquet")
+	if err := ioutil.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command(python, "-c", readParquetScript, file).CombinedOutput()
+	if err != nil {
+		t.Fatalf("pyarrow failed to read the parquet file: %v, %s", err, out)
+	}
+	var columns map[string][]interface{}
+	if err := json.Unmarshal(out, &columns); err != nil {
+		t.Fatalf("unexpected output %s: %v", out, err)
+	}
+	expected := map[string][]interface{}{
+		"Type":       {"tke", "eks", "total"},
+		"TotalCost":  {12.5, 10.25, nil},
+		"Nodes":      {3.0, nil, 3.0},
+		"Serverless": {false, true, nil},
+	}
+	if !reflect.DeepEqual(columns, expected) {
+		t.Fatalf("expect %v, got %v", expected, columns)
+	}
+}
+
+// thriftReader decodes thrift compact structs to maps keyed by field id
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) varint() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) readValue(typ byte) interface{} {
+	switch typ {
+	case thriftI32:
+		return int32(r.varint())
+	case thriftI64:
+		return r.varint()
+	case thriftBinary:
+		n := int(r.uvarint())
+		v := r.data[r.pos : r.pos+n]
+		r.pos += n
+		return v
+	case thriftList:
+		header := r.data[r.pos]
+		r.pos++
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		var list []interface{}
+		for i := 0; i < size; i++ {
+			list = append(list, r.readValue(header&0x0f))
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+	panic("unsupported thrift type")
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var lastId int16
+	for {
+		header := r.data[r.pos]
+		r.pos++
+		if header == 0 {
+			return fields
+		}
+		id := lastId + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(r.varint())
+		}
+		fields[id] = r.readValue(header & 0x0f)
+		lastId = id
+	}
+}
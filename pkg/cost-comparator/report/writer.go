@@ -0,0 +1,150 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// FormatValue format the value to a string, float is formatted with 5 decimals, nil is an empty string
+func FormatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return fmt.Sprintf("%.5f", v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func (t *Table) header() []string {
+	header := make([]string, 0, len(t.Columns))
+	for _, column := range t.Columns {
+		header = append(header, column.Name)
+	}
+	return header
+}
+
+func (t *Table) formattedRows() [][]string {
+	data := make([][]string, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		formatted := make([]string, 0, len(row))
+		for _, value := range row {
+			formatted = append(formatted, FormatValue(value))
+		}
+		data = append(data, formatted)
+	}
+	return data
+}
+
+// WriteTable render the table to the terminal
+func WriteTable(w io.Writer, t *Table) {
+	header := t.header()
+	table := tablewriter.NewWriter(w)
+	table.SetHeaderLine(true)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader(header)
+	table.SetBorder(false) // Set Border to false
+	headerColors := make([]tablewriter.Colors, len(header))
+	columnColors := make([]tablewriter.Colors, len(header))
+	for i := range header {
+		headerColors[i] = tablewriter.Colors{tablewriter.FgHiRedColor, tablewriter.Bold, tablewriter.BgBlackColor}
+		columnColors[i] = tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor}
+	}
+	if len(columnColors) > 0 {
+		columnColors[0] = tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor}
+	}
+	table.SetHeaderColor(headerColors...)
+	table.SetColumnColor(columnColors...)
+
+	table.AppendBulk(t.formattedRows()) // Add Bulk Data
+	table.Render()
+}
+
+// WriteCsv write the table as tab separated csv
+func WriteCsv(w io.Writer, t *Table) error {
+	if t.err != nil {
+		return t.err
+	}
+	csvW := csv.NewWriter(w)
+	csvW.Comma = '\t'
+	if err := csvW.Write(t.header()); err != nil {
+		return err
+	}
+	return csvW.WriteAll(t.formattedRows())
+}
+
+// WriteJson write the whole report as json, rows of each table are objects keyed by column name
+func WriteJson(w io.Writer, r *Report) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// ReadJson read the report written by WriteJson
+func ReadJson(rd io.Reader) (*Report, error) {
+	r := &Report{}
+	if err := json.NewDecoder(rd).Decode(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+type jsonTable struct {
+	Name    string                   `json:"name"`
+	Title   string                   `json:"title"`
+	Columns []Column                 `json:"columns"`
+	Rows    []map[string]interface{} `json:"rows"`
+}
+
+func (t *Table) MarshalJSON() ([]byte, error) {
+	jt := jsonTable{
+		Name:    t.Name,
+		Title:   t.Title,
+		Columns: t.Columns,
+		Rows:    make([]map[string]interface{}, 0, len(t.Rows)),
+	}
+	for _, row := range t.Rows {
+		obj := make(map[string]interface{}, len(t.Columns))
+		for i, column := range t.Columns {
+			if i < len(row) {
+				obj[column.Name] = row[i]
+			}
+		}
+		jt.Rows = append(jt.Rows, obj)
+	}
+	return json.Marshal(jt)
+}
+
+func (t *Table) UnmarshalJSON(data []byte) error {
+	jt := jsonTable{}
+	if err := json.Unmarshal(data, &jt); err != nil {
+		return err
+	}
+	t.Name = jt.Name
+	t.Title = jt.Title
+	t.Columns = jt.Columns
+	t.Rows = nil
+	for _, obj := range jt.Rows {
+		values := make([]interface{}, len(t.Columns))
+		for i, column := range t.Columns {
+			value := obj[column.Name]
+			// json numbers are decoded as float64
+			if f, ok := value.(float64); ok && column.Type == ColumnTypeInt {
+				value = int64(f)
+			}
+			values[i] = value
+		}
+		if err := t.Append(values...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
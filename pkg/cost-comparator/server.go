@@ -0,0 +1,76 @@
+package cost_comparator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	componentbaseconfig "k8s.io/component-base/config"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/fadvisor/pkg/util"
+)
+
+func NewServer(service *Service, bind string, debugging componentbaseconfig.DebuggingConfiguration) *Server {
+	return &Server{
+		service:   service,
+		bind:      bind,
+		debugging: debugging,
+		server:    &http.Server{},
+	}
+}
+
+// Server serves the results of the comparator service
+type Server struct {
+	service   *Service
+	bind      string
+	server    *http.Server
+	debugging componentbaseconfig.DebuggingConfiguration
+}
+
+func (s *Server) RegisterHandlers() {
+	baseHandler := util.NewBaseHandler("fadvisor-comparator", s.debugging)
+	baseHandler.Handle("/comparator/runs", s.service.RunsHandler())
+	baseHandler.HandlePrefix("/comparator/runs/", s.service.RunsHandler())
+
+	handler := util.BuildHandlerChain(baseHandler, nil, nil)
+	s.server.Handler = handler
+}
+
+// Serve listens on the bind address and serves until stopCh is closed, the error of listening is returned
+func (s *Server) Serve(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	l, err := net.Listen("tcp", s.bind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start server: %v", err)
+	}
+
+	// Shutdown server gracefully.
+	stoppedCh := make(chan struct{})
+
+	go func() {
+		defer utilruntime.HandleCrash()
+		defer close(stoppedCh)
+		<-stopCh
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		_ = s.server.Shutdown(ctx)
+		cancel()
+	}()
+
+	go func() {
+		defer utilruntime.HandleCrash()
+
+		// block until shutdown or err
+		err := s.server.Serve(l)
+		msg := fmt.Sprintf("Stopped listening on %s", l.Addr().String())
+		select {
+		case <-stopCh:
+			klog.Info(msg)
+		default:
+			panic(fmt.Sprintf("%s due to error: %v", msg, err))
+		}
+	}()
+	return stoppedCh, nil
+}
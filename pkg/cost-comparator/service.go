@@ -0,0 +1,254 @@
+package cost_comparator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/report"
+)
+
+type RunState string
+
+const (
+	RunStatePending   RunState = "Pending"
+	RunStateRunning   RunState = "Running"
+	RunStateSucceeded RunState = "Succeeded"
+	RunStateFailed    RunState = "Failed"
+)
+
+const (
+	RunTriggerSchedule = "schedule"
+	RunTriggerManual   = "manual"
+)
+
+// Run is one analysis of the comparator service
+type Run struct {
+	Id        string         `json:"id"`
+	Trigger   string         `json:"trigger"`
+	State     RunState       `json:"state"`
+	CreatedAt time.Time      `json:"createdAt"`
+	StartTime *time.Time     `json:"startTime,omitempty"`
+	EndTime   *time.Time     `json:"endTime,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Report    *report.Report `json:"report,omitempty"`
+}
+
+// AnalyzeFunc does a full analysis and return the report
+type AnalyzeFunc func() (*report.Report, error)
+
+// Service runs the analysis on schedule or on demand one by one, and keeps the last runs in memory
+type Service struct {
+	analyze  AnalyzeFunc
+	interval time.Duration
+	maxRuns  int
+	// analysisLock is shared with the CostAnalysis controller, so the analyses of both run one by one
+	analysisLock sync.Locker
+
+	lock sync.RWMutex
+	// runs in created order, the oldest finished runs are dropped when exceeding maxRuns
+	runs    []*Run
+	seq     int
+	pending chan *Run
+}
+
+// NewService returns the service running the analysis, the runs hold the analysis lock while analyzing
+func NewService(analyze AnalyzeFunc, interval time.Duration, maxRuns int, analysisLock sync.Locker) *Service {
+	if maxRuns <= 0 {
+		maxRuns = 1
+	}
+	if analysisLock == nil {
+		analysisLock = &sync.Mutex{}
+	}
+	return &Service{
+		analyze:      analyze,
+		interval:     interval,
+		maxRuns:      maxRuns,
+		analysisLock: analysisLock,
+		// only one run can be waiting
+		pending: make(chan *Run, 1),
+	}
+}
+
+// Start runs the analysis immediately, then every interval, until stopCh is closed.
+// A scheduled run is skipped if there is already a run waiting.
+func (s *Service) Start(stopCh <-chan struct{}) {
+	go func() {
+		defer utilruntime.HandleCrash()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case run := <-s.pending:
+				s.execute(run)
+			}
+		}
+	}()
+
+	_, _ = s.Trigger(RunTriggerSchedule)
+	if s.interval <= 0 {
+		return
+	}
+	go func() {
+		defer utilruntime.HandleCrash()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				if _, ok := s.Trigger(RunTriggerSchedule); !ok {
+					klog.Warningf("Comparator run is already pending, skip the scheduled run")
+				}
+			}
+		}
+	}()
+}
+
+// Trigger creates a pending run, it returns false with the existing pending run if there is already one
+func (s *Service) Trigger(trigger string) (*Run, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, run := range s.runs {
+		if run.State == RunStatePending {
+			return run, false
+		}
+	}
+
+	s.seq++
+	now := time.Now()
+	run := &Run{
+		Id:        fmt.Sprintf("%s-%d", now.Format("20060102150405"), s.seq),
+		Trigger:   trigger,
+		State:     RunStatePending,
+		CreatedAt: now,
+	}
+	s.runs = append(s.runs, run)
+	s.gc()
+	s.pending <- run
+	return run, true
+}
+
+func (s *Service) execute(run *Run) {
+	// the run keeps pending while the CostAnalysis controller is analyzing
+	s.analysisLock.Lock()
+	defer s.analysisLock.Unlock()
+
+	start := time.Now()
+	s.lock.Lock()
+	run.State = RunStateRunning
+	run.StartTime = &start
+	s.lock.Unlock()
+	klog.Infof("Comparator run %v started", run.Id)
+
+	result, err := func() (result *report.Report, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("analysis panic: %v", r)
+			}
+		}()
+		return s.analyze()
+	}()
+
+	end := time.Now()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	run.EndTime = &end
+	if err != nil {
+		run.State = RunStateFailed
+		run.Error = err.Error()
+		klog.Errorf("Comparator run %v failed: %v", run.Id, err)
+		return
+	}
+	run.State = RunStateSucceeded
+	run.Report = result
+	klog.Infof("Comparator run %v succeeded, cost %v", run.Id, end.Sub(start))
+}
+
+// gc drops the oldest finished runs exceeding maxRuns, pending and running runs are kept
+func (s *Service) gc() {
+	excess := len(s.runs) - s.maxRuns
+	if excess <= 0 {
+		return
+	}
+	kept := make([]*Run, 0, len(s.runs))
+	for _, run := range s.runs {
+		if excess > 0 && (run.State == RunStateSucceeded || run.State == RunStateFailed) {
+			excess--
+			continue
+		}
+		kept = append(kept, run)
+	}
+	s.runs = kept
+}
+
+// Runs return the runs without reports, newest first
+func (s *Service) Runs() []Run {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	results := make([]Run, 0, len(s.runs))
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		run := *s.runs[i]
+		run.Report = nil
+		results = append(results, run)
+	}
+	return results
+}
+
+func (s *Service) GetRun(id string) (Run, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, run := range s.runs {
+		if run.Id == id {
+			return *run, true
+		}
+	}
+	return Run{}, false
+}
+
+// RunsHandler serves GET and POST /comparator/runs, and GET /comparator/runs/{id}
+func (s *Service) RunsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/comparator/runs"), "/")
+		switch {
+		case id == "" && r.Method == http.MethodGet:
+			writeJson(w, http.StatusOK, s.Runs())
+		case id == "" && r.Method == http.MethodPost:
+			run, created := s.Trigger(RunTriggerManual)
+			if !created {
+				writeJson(w, http.StatusConflict, run)
+				return
+			}
+			writeJson(w, http.StatusAccepted, run)
+		case id != "" && r.Method == http.MethodGet:
+			run, ok := s.GetRun(id)
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(fmt.Sprintf("run %v not found", id)))
+				return
+			}
+			writeJson(w, http.StatusOK, run)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func writeJson(w http.ResponseWriter, code int, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,116 @@
+package cost_comparator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gocrane/fadvisor/pkg/cost-comparator/report"
+)
+
+func TestServiceRuns(t *testing.T) {
+	release := make(chan struct{})
+	calls := 0
+	service := NewService(func() (*report.Report, error) {
+		<-release
+		calls++
+		if calls == 2 {
+			return nil, fmt.Errorf("datasource unavailable")
+		}
+		return &report.Report{ClusterId: "cls-1"}, nil
+	}, 0, 2, nil)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	service.Start(stopCh)
+	handler := service.RunsHandler()
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder
+	}
+	waitState := func(id string, state RunState) Run {
+		for i := 0; i < 100; i++ {
+			if run, ok := service.GetRun(id); ok && run.State == state {
+				return run
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("run %v not in state %v", id, state)
+		return Run{}
+	}
+
+	first := service.Runs()[0]
+	waitState(first.Id, RunStateRunning)
+
+	// the second run is pending until the first one finished, so the third trigger conflicts
+	if code := do(http.MethodPost, "/comparator/runs").Code; code != http.StatusAccepted {
+		t.Fatalf("expected accepted, got %v", code)
+	}
+	if code := do(http.MethodPost, "/comparator/runs").Code; code != http.StatusConflict {
+		t.Fatalf("expected conflict, got %v", code)
+	}
+	second := service.Runs()[0]
+
+	release <- struct{}{}
+	run := waitState(first.Id, RunStateSucceeded)
+	if run.Report == nil || run.Report.ClusterId != "cls-1" {
+		t.Fatalf("unexpected report: %+v", run.Report)
+	}
+	release <- struct{}{}
+	waitState(second.Id, RunStateFailed)
+
+	recorder := do(http.MethodGet, "/comparator/runs")
+	var runs []Run
+	if err := json.Unmarshal(recorder.Body.Bytes(), &runs); err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].Id != second.Id || runs[0].Error != "datasource unavailable" || runs[1].Report != nil {
+		t.Fatalf("unexpected runs: %+v", runs)
+	}
+
+	recorder = do(http.MethodGet, "/comparator/runs/"+first.Id)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected ok, got %v", recorder.Code)
+	}
+	if code := do(http.MethodGet, "/comparator/runs/not-exist").Code; code != http.StatusNotFound {
+		t.Fatalf("expected not found, got %v", code)
+	}
+
+	// the oldest run is dropped when exceeding max runs
+	do(http.MethodPost, "/comparator/runs")
+	if _, ok := service.GetRun(first.Id); ok {
+		t.Fatalf("expected run %v dropped", first.Id)
+	}
+	release <- struct{}{}
+}
+
+func TestServiceAnalysisLock(t *testing.T) {
+	lock := &sync.Mutex{}
+	service := NewService(func() (*report.Report, error) {
+		return &report.Report{ClusterId: "cls-1"}, nil
+	}, 0, 1, lock)
+
+	// the CostAnalysis controller is analyzing
+	lock.Lock()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	service.Start(stopCh)
+	run := service.Runs()[0]
+	time.Sleep(50 * time.Millisecond)
+	if got, _ := service.GetRun(run.Id); got.State != RunStatePending {
+		t.Fatalf("expected the run pending while the lock is held, got %v", got.State)
+	}
+	lock.Unlock()
+	for i := 0; i < 100; i++ {
+		if got, _ := service.GetRun(run.Id); got.State == RunStateSucceeded {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected the run succeeded after the lock is released")
+}
@@ -0,0 +1,404 @@
+package simulator
+
+import (
+	"math"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// InstanceType is a candidate machine which can be launched into a node pool
+type InstanceType struct {
+	Name       string
+	Family     string
+	Zone       string
+	ChargeType string
+	// Spot means the instance type is sold as spot instances
+	Spot bool
+	// cpu capacity, milli cores
+	Cpu int64
+	// memory capacity, bytes
+	Mem         int64
+	HourlyPrice float64
+}
+
+// Pod is the resource requirements and scheduling constraints of the pods of a workload
+type Pod struct {
+	// namespace/name of the workload
+	Name string
+	// cpu requests, milli cores
+	Cpu int64
+	// memory requests, bytes
+	Mem          int64
+	NodeSelector map[string]string
+	Tolerations  []v1.Toleration
+	Replicas     uint64
+}
+
+// NodePool is a group of nodes which has the same labels and taints, pods can only land on the pool when it matches the labels and tolerates the taints.
+type NodePool struct {
+	Name   string
+	Labels map[string]string
+	Taints []v1.Taint
+	// candidate instance types of the pool
+	InstanceTypes []InstanceType
+	// reserved ratio of the capacity for kubelet, system daemons and eviction threshold. allocatable = capacity * (1 - ratio)
+	// if both are zero, DefaultReserved is used
+	CpuReservedRatio float64
+	MemReservedRatio float64
+	// Weight is the share of the pods of the pool, the replicas are spread over the fitting pools by the weights,
+	// so the pods keep their spread over the zones. all the replicas go to the first fitting pool if no pool has weight
+	Weight float64
+}
+
+// Node is a simulated node
+type Node struct {
+	Pool           string
+	InstanceType   InstanceType
+	CpuAllocatable int64
+	MemAllocatable int64
+	// requests of daemonset pods on the node
+	CpuOverhead int64
+	MemOverhead int64
+	CpuUsed     int64
+	MemUsed     int64
+	// namespace/name of the workloads, one entry for each replica
+	Pods []string
+}
+
+type Result struct {
+	Nodes []*Node
+	// namespace/name of the workloads whose replicas can not be scheduled to any pool or instance type
+	Unschedulable map[string]uint64
+	HourlyCost    float64
+}
+
+// InstanceTypeCount return the node count of each pool and instance type
+func (r *Result) InstanceTypeCount() map[string] /*pool*/ map[string] /*instanceType*/ int {
+	result := make(map[string]map[string]int)
+	for _, node := range r.Nodes {
+		poolResult, ok := result[node.Pool]
+		if !ok {
+			poolResult = make(map[string]int)
+			result[node.Pool] = poolResult
+		}
+		poolResult[node.InstanceType.Name]++
+	}
+	return result
+}
+
+// DefaultReserved compute reserved resources of a node by the tiered formula which is used by most of managed kubernetes services.
+func DefaultReserved(cpu, mem int64) (int64, int64) {
+	var cpuReserved int64
+	cpuTiers := []struct {
+		upper int64
+		ratio float64
+	}{
+		{1000, 0.06}, {2000, 0.01}, {4000, 0.005}, {math.MaxInt64, 0.0025},
+	}
+	var lower int64
+	for _, tier := range cpuTiers {
+		if cpu <= lower {
+			break
+		}
+		upper := tier.upper
+		if cpu < upper {
+			upper = cpu
+		}
+		cpuReserved += int64(float64(upper-lower) * tier.ratio)
+		lower = tier.upper
+	}
+
+	const gb = 1024 * 1024 * 1024
+	var memReserved int64
+	memTiers := []struct {
+		upper int64
+		ratio float64
+	}{
+		{4 * gb, 0.25}, {8 * gb, 0.2}, {16 * gb, 0.1}, {128 * gb, 0.06}, {math.MaxInt64, 0.02},
+	}
+	lower = 0
+	for _, tier := range memTiers {
+		if mem <= lower {
+			break
+		}
+		upper := tier.upper
+		if mem < upper {
+			upper = mem
+		}
+		memReserved += int64(float64(upper-lower) * tier.ratio)
+		lower = tier.upper
+	}
+	// eviction threshold
+	memReserved += 100 * 1024 * 1024
+	return cpuReserved, memReserved
+}
+
+func (p *NodePool) allocatable(it InstanceType) (int64, int64) {
+	if p.CpuReservedRatio == 0 && p.MemReservedRatio == 0 {
+		cpuReserved, memReserved := DefaultReserved(it.Cpu, it.Mem)
+		return it.Cpu - cpuReserved, it.Mem - memReserved
+	}
+	return int64(float64(it.Cpu) * (1 - p.CpuReservedRatio)), int64(float64(it.Mem) * (1 - p.MemReservedRatio))
+}
+
+// Fits return true if the pod can be scheduled to the pool, the node selector matches the pool labels and the pod tolerates all the NoSchedule and NoExecute taints
+func (p *NodePool) Fits(pod *Pod) bool {
+	if len(pod.NodeSelector) > 0 && !labels.SelectorFromSet(pod.NodeSelector).Matches(labels.Set(p.Labels)) {
+		return false
+	}
+	for i := range p.Taints {
+		taint := &p.Taints[i]
+		if taint.Effect == v1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for j := range pod.Tolerations {
+			if pod.Tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+type item struct {
+	name string
+	cpu  int64
+	mem  int64
+}
+
+// Simulate packs the pods onto the pools, the replicas of a pod are spread over the fitting pools of the fewest taints by the pool weights.
+// it chooses the cheapest instance type for each pool by first fit decreasing,
+// then tries to replace each node by a cheaper instance type which can hold the pods of the node, so the result is a node mix.
+// daemonSets are the pods running on each node of the pools which they fit.
+func Simulate(pools []*NodePool, pods []Pod, daemonSets []Pod) *Result {
+	result := &Result{
+		Unschedulable: make(map[string]uint64),
+	}
+
+	// prefer the pool with less taints, so the pods tolerating taints do not occupy the dedicated pools if not necessary
+	sortedPools := make([]*NodePool, len(pools))
+	copy(sortedPools, pools)
+	sort.SliceStable(sortedPools, func(i, j int) bool {
+		if len(sortedPools[i].Taints) != len(sortedPools[j].Taints) {
+			return len(sortedPools[i].Taints) < len(sortedPools[j].Taints)
+		}
+		return sortedPools[i].Name < sortedPools[j].Name
+	})
+
+	poolItems := make(map[string][]item)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Replicas == 0 {
+			continue
+		}
+		var fits []*NodePool
+		for _, p := range sortedPools {
+			if !p.Fits(pod) {
+				continue
+			}
+			if len(fits) > 0 && len(p.Taints) != len(fits[0].Taints) {
+				break
+			}
+			fits = append(fits, p)
+		}
+		if len(fits) == 0 {
+			result.Unschedulable[pod.Name] += pod.Replicas
+			continue
+		}
+		for i, replicas := range spread(fits, pod.Replicas) {
+			for r := uint64(0); r < replicas; r++ {
+				poolItems[fits[i].Name] = append(poolItems[fits[i].Name], item{name: pod.Name, cpu: pod.Cpu, mem: pod.Mem})
+			}
+		}
+	}
+
+	for _, pool := range sortedPools {
+		items := poolItems[pool.Name]
+		if len(items) == 0 {
+			continue
+		}
+		var dsCpu, dsMem int64
+		for i := range daemonSets {
+			if pool.Fits(&daemonSets[i]) {
+				dsCpu += daemonSets[i].Cpu
+				dsMem += daemonSets[i].Mem
+			}
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			if items[i].cpu != items[j].cpu {
+				return items[i].cpu > items[j].cpu
+			}
+			return items[i].mem > items[j].mem
+		})
+
+		nodes, unschedulable := packPool(pool, items, dsCpu, dsMem)
+		for name, count := range unschedulable {
+			result.Unschedulable[name] += count
+		}
+		for _, node := range nodes {
+			result.Nodes = append(result.Nodes, node)
+			result.HourlyCost += node.InstanceType.HourlyPrice
+		}
+	}
+	return result
+}
+
+// spread splits the replicas over the pools in proportion to the weights by the largest remainder,
+// all the replicas go to the first pool if no pool has weight
+func spread(pools []*NodePool, replicas uint64) []uint64 {
+	counts := make([]uint64, len(pools))
+	var total float64
+	for _, p := range pools {
+		total += p.Weight
+	}
+	if total <= 0 {
+		counts[0] = replicas
+		return counts
+	}
+	type remainder struct {
+		index int
+		value float64
+	}
+	var remainders []remainder
+	var assigned uint64
+	for i, p := range pools {
+		share := float64(replicas) * p.Weight / total
+		counts[i] = uint64(share)
+		assigned += counts[i]
+		remainders = append(remainders, remainder{index: i, value: share - float64(counts[i])})
+	}
+	sort.SliceStable(remainders, func(i, j int) bool {
+		return remainders[i].value > remainders[j].value
+	})
+	for i := 0; assigned < replicas; i++ {
+		counts[remainders[i%len(remainders)].index]++
+		assigned++
+	}
+	return counts
+}
+
+type candidate struct {
+	instanceType InstanceType
+	cpu          int64
+	mem          int64
+	lowerBound   float64
+}
+
+func packPool(pool *NodePool, items []item, dsCpu, dsMem int64) ([]*Node, map[string]uint64) {
+	var totalCpu, totalMem int64
+	for _, it := range items {
+		totalCpu += it.cpu
+		totalMem += it.mem
+	}
+
+	var candidates []candidate
+	for _, it := range pool.InstanceTypes {
+		cpu, mem := pool.allocatable(it)
+		cpu -= dsCpu
+		mem -= dsMem
+		if cpu <= 0 || mem <= 0 {
+			continue
+		}
+		nodesLowerBound := math.Max(math.Ceil(float64(totalCpu)/float64(cpu)), math.Ceil(float64(totalMem)/float64(mem)))
+		candidates = append(candidates, candidate{instanceType: it, cpu: cpu, mem: mem, lowerBound: nodesLowerBound * it.HourlyPrice})
+	}
+	unschedulable := make(map[string]uint64)
+	if len(candidates) == 0 {
+		for _, it := range items {
+			unschedulable[it.name]++
+		}
+		return nil, unschedulable
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].lowerBound < candidates[j].lowerBound
+	})
+
+	// the largest candidate is used when no candidate can hold all the pods
+	largest := candidates[0]
+	for _, c := range candidates {
+		if c.cpu >= largest.cpu && c.mem >= largest.mem {
+			largest = c
+		}
+	}
+
+	var best []*Node
+	bestCost := math.Inf(1)
+	for _, c := range candidates {
+		if c.lowerBound >= bestCost {
+			break
+		}
+		nodes, ok := firstFitDecreasing(pool.Name, c, items, dsCpu, dsMem, nil)
+		if !ok {
+			continue
+		}
+		cost := float64(len(nodes)) * c.instanceType.HourlyPrice
+		if cost < bestCost {
+			best = nodes
+			bestCost = cost
+		}
+	}
+	if best == nil {
+		best, _ = firstFitDecreasing(pool.Name, largest, items, dsCpu, dsMem, unschedulable)
+	}
+
+	// node mix, replace each node by the cheapest candidate which can hold its pods
+	for _, node := range best {
+		for _, c := range candidates {
+			if c.instanceType.HourlyPrice >= node.InstanceType.HourlyPrice {
+				continue
+			}
+			if node.CpuUsed <= c.cpu && node.MemUsed <= c.mem {
+				node.InstanceType = c.instanceType
+				node.CpuAllocatable = c.cpu + dsCpu
+				node.MemAllocatable = c.mem + dsMem
+			}
+		}
+	}
+	return best, unschedulable
+}
+
+// firstFitDecreasing packs the sorted items to the nodes of the candidate. it returns false if some item can not be packed,
+// the item is recorded to unschedulable if it is not nil, otherwise it stops packing.
+func firstFitDecreasing(pool string, c candidate, items []item, dsCpu, dsMem int64, unschedulable map[string]uint64) ([]*Node, bool) {
+	var nodes []*Node
+	allPacked := true
+	for _, it := range items {
+		if it.cpu > c.cpu || it.mem > c.mem {
+			allPacked = false
+			if unschedulable == nil {
+				return nil, false
+			}
+			unschedulable[it.name]++
+			continue
+		}
+		var target *Node
+		for _, node := range nodes {
+			if node.CpuUsed+it.cpu <= c.cpu && node.MemUsed+it.mem <= c.mem {
+				target = node
+				break
+			}
+		}
+		if target == nil {
+			target = &Node{
+				Pool:           pool,
+				InstanceType:   c.instanceType,
+				CpuAllocatable: c.cpu + dsCpu,
+				MemAllocatable: c.mem + dsMem,
+				CpuOverhead:    dsCpu,
+				MemOverhead:    dsMem,
+			}
+			nodes = append(nodes, target)
+		}
+		target.CpuUsed += it.cpu
+		target.MemUsed += it.mem
+		target.Pods = append(target.Pods, it.name)
+	}
+	return nodes, allPacked
+}
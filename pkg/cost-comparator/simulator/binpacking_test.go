@@ -0,0 +1,137 @@
+package simulator
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+const gb = 1024 * 1024 * 1024
+
+func TestSimulate(t *testing.T) {
+	small := InstanceType{Name: "S.2C4G", Cpu: 2000, Mem: 4 * gb, HourlyPrice: 0.1}
+	large := InstanceType{Name: "S.8C16G", Cpu: 8000, Mem: 16 * gb, HourlyPrice: 0.35}
+
+	pools := []*NodePool{
+		{
+			Name:             "default",
+			Labels:           map[string]string{"pool": "default"},
+			InstanceTypes:    []InstanceType{small, large},
+			CpuReservedRatio: 0.0001,
+		},
+		{
+			Name:             "gpu",
+			Labels:           map[string]string{"pool": "gpu"},
+			Taints:           []v1.Taint{{Key: "gpu", Value: "true", Effect: v1.TaintEffectNoSchedule}},
+			InstanceTypes:    []InstanceType{large},
+			CpuReservedRatio: 0.0001,
+		},
+	}
+	pods := []Pod{
+		{Name: "default/web", Cpu: 1000, Mem: gb, Replicas: 7},
+		{Name: "default/train", Cpu: 1000, Mem: gb, Replicas: 2, NodeSelector: map[string]string{"pool": "gpu"},
+			Tolerations: []v1.Toleration{{Key: "gpu", Operator: v1.TolerationOpEqual, Value: "true", Effect: v1.TaintEffectNoSchedule}}},
+		{Name: "default/pinned", Cpu: 1000, Mem: gb, Replicas: 1, NodeSelector: map[string]string{"pool": "gpu"}},
+		{Name: "default/huge", Cpu: 16000, Mem: gb, Replicas: 1},
+	}
+	daemonSets := []Pod{
+		{Name: "kube-system/agent", Cpu: 500, Mem: 512 * 1024 * 1024, Replicas: 1},
+	}
+
+	result := Simulate(pools, pods, daemonSets)
+
+	// pinned does not tolerate the gpu taint, huge is larger than any instance type
+	if result.Unschedulable["default/pinned"] != 1 || result.Unschedulable["default/huge"] != 1 || len(result.Unschedulable) != 2 {
+		t.Fatalf("unexpected unschedulable: %v", result.Unschedulable)
+	}
+
+	counts := result.InstanceTypeCount()
+	// a large node holds 7.5 cores besides the agent, all the web pods fit on one large node
+	if counts["default"][large.Name] != 1 || len(counts["default"]) != 1 {
+		t.Fatalf("unexpected default pool nodes: %v", counts["default"])
+	}
+	// 2 train pods on the gpu pool, the only candidate is large
+	if counts["gpu"][large.Name] != 1 {
+		t.Fatalf("unexpected gpu pool nodes: %v", counts["gpu"])
+	}
+	if math.Abs(result.HourlyCost-0.7) > 1e-9 {
+		t.Fatalf("unexpected hourly cost: %v", result.HourlyCost)
+	}
+}
+
+func TestSimulateNodeMix(t *testing.T) {
+	small := InstanceType{Name: "S.2C4G", Cpu: 2500, Mem: 4 * gb, HourlyPrice: 0.15}
+	large := InstanceType{Name: "S.8C16G", Cpu: 8000, Mem: 16 * gb, HourlyPrice: 0.3}
+	pools := []*NodePool{
+		{Name: "default", InstanceTypes: []InstanceType{small, large}, CpuReservedRatio: 0.0001},
+	}
+	// 9 cores, cheapest homogeneous plan is 2 large nodes, then the second node holding 2 cores is replaced by a small one
+	pods := []Pod{
+		{Name: "default/web", Cpu: 1000, Mem: gb, Replicas: 9},
+	}
+	result := Simulate(pools, pods, nil)
+	counts := result.InstanceTypeCount()
+	if counts["default"][large.Name] != 1 || counts["default"][small.Name] != 1 {
+		t.Fatalf("unexpected nodes: %v", counts["default"])
+	}
+	if math.Abs(result.HourlyCost-0.45) > 1e-9 {
+		t.Fatalf("unexpected hourly cost: %v", result.HourlyCost)
+	}
+}
+
+func TestSimulateZones(t *testing.T) {
+	it := InstanceType{Name: "S.4C8G", Cpu: 4000, Mem: 8 * gb, HourlyPrice: 0.2}
+	pools := []*NodePool{
+		{Name: "zone-a", InstanceTypes: []InstanceType{it}, CpuReservedRatio: 0.0001, Weight: 2},
+		{Name: "zone-b", InstanceTypes: []InstanceType{it}, CpuReservedRatio: 0.0001, Weight: 1},
+		{Name: "zone-c", InstanceTypes: []InstanceType{it}, CpuReservedRatio: 0.0001},
+	}
+	// the replicas are spread by the current pods of the zones, not all packed into the first zone
+	pods := []Pod{
+		{Name: "default/web", Cpu: 900, Mem: gb, Replicas: 6},
+	}
+	result := Simulate(pools, pods, nil)
+	used := make(map[string]int64)
+	for _, node := range result.Nodes {
+		used[node.Pool] += node.CpuUsed
+	}
+	if used["zone-a"] != 3600 || used["zone-b"] != 1800 || used["zone-c"] != 0 {
+		t.Fatalf("unexpected spread of the replicas: %v", used)
+	}
+	if counts := result.InstanceTypeCount(); counts["zone-a"][it.Name] != 1 || counts["zone-b"][it.Name] != 1 {
+		t.Fatalf("unexpected nodes: %v", counts)
+	}
+}
+
+func TestSpread(t *testing.T) {
+	pools := []*NodePool{{Weight: 5}, {Weight: 3}, {Weight: 2}}
+	for replicas, expected := range map[uint64][]uint64{
+		1:  {1, 0, 0},
+		2:  {1, 1, 0},
+		3:  {1, 1, 1},
+		10: {5, 3, 2},
+	} {
+		if counts := spread(pools, replicas); !reflect.DeepEqual(counts, expected) {
+			t.Errorf("replicas %v: expect %v, got %v", replicas, expected, counts)
+		}
+	}
+	if counts := spread([]*NodePool{{}, {}}, 3); !reflect.DeepEqual(counts, []uint64{3, 0}) {
+		t.Errorf("expect all the replicas in the first pool without weights, got %v", counts)
+	}
+}
+
+func TestDefaultReserved(t *testing.T) {
+	cpu, mem := DefaultReserved(4000, 8*gb)
+	// 60 + 10 + 10 milli cores
+	if cpu != 80 {
+		t.Errorf("unexpected cpu reserved: %v", cpu)
+	}
+	// 25% of first 4G + 20% of next 4G + 100Mi eviction threshold
+	var second int64 = 4 * gb
+	expected := int64(gb) + int64(float64(second)*0.2) + 100*1024*1024
+	if mem != expected {
+		t.Errorf("unexpected mem reserved: %v, expected %v", mem, expected)
+	}
+}
@@ -0,0 +1,71 @@
+package simulator
+
+import (
+	"math"
+	"sort"
+)
+
+const hoursPerYear = 365 * 24
+
+// ReservedTerm is the upfront price of reserving one instance for some years
+type ReservedTerm struct {
+	Years int
+	Price float64
+}
+
+// ReservedPlan is the reservation recommendation of one term
+type ReservedPlan struct {
+	Years int
+	Count int
+	// upfront price of one instance spread to each hour of the term
+	EffectiveHourlyPrice float64
+	// months of on demand usage which cost the same as the upfront price of one instance
+	BreakEvenMonths float64
+	// savings of all reserved instances compared to on demand
+	HourlySavings float64
+	TermSavings   float64
+}
+
+// Percentile return the p percentile of the values by nearest rank, p is in [0, 1]
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// PlanReserved recommends to reserve the baseline count of instances for each term which is cheaper than on demand.
+// terms more expensive than on demand are returned with zero count, so the caller can still show the break even.
+func PlanReserved(baseline int, onDemandHourlyPrice float64, terms []ReservedTerm) []ReservedPlan {
+	var plans []ReservedPlan
+	for _, term := range terms {
+		if term.Years <= 0 || term.Price <= 0 {
+			continue
+		}
+		termHours := float64(term.Years * hoursPerYear)
+		plan := ReservedPlan{
+			Years:                term.Years,
+			EffectiveHourlyPrice: term.Price / termHours,
+		}
+		if onDemandHourlyPrice > 0 {
+			plan.BreakEvenMonths = term.Price / onDemandHourlyPrice / (hoursPerYear / 12.)
+		}
+		if baseline > 0 && plan.EffectiveHourlyPrice < onDemandHourlyPrice {
+			plan.Count = baseline
+			plan.HourlySavings = (onDemandHourlyPrice - plan.EffectiveHourlyPrice) * float64(baseline)
+			plan.TermSavings = plan.HourlySavings * termHours
+		}
+		plans = append(plans, plan)
+	}
+	return plans
+}
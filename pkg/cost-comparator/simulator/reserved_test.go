@@ -0,0 +1,43 @@
+package simulator
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{5, 3, 4, 10, 6, 7, 8, 9, 2, 1}
+	if p := Percentile(values, 0.1); p != 1 {
+		t.Fatalf("expected p10 1, got %v", p)
+	}
+	if p := Percentile(values, 0.5); p != 5 {
+		t.Fatalf("expected p50 5, got %v", p)
+	}
+	if p := Percentile(values, 1); p != 10 {
+		t.Fatalf("expected p100 10, got %v", p)
+	}
+	if p := Percentile(nil, 0.1); p != 0 {
+		t.Fatalf("expected 0 for empty values, got %v", p)
+	}
+}
+
+func TestPlanReserved(t *testing.T) {
+	// on demand costs 8760 a year, one year reservation is 40% off, three year reservation is more expensive
+	plans := PlanReserved(3, 1.0, []ReservedTerm{{Years: 1, Price: 5256}, {Years: 3, Price: 30000}, {Years: 5, Price: 0}})
+	if len(plans) != 2 {
+		t.Fatalf("expected 2 plans, got %+v", plans)
+	}
+	oneYear := plans[0]
+	if oneYear.Count != 3 || math.Abs(oneYear.EffectiveHourlyPrice-0.6) > 1e-9 {
+		t.Fatalf("unexpected one year plan: %+v", oneYear)
+	}
+	if math.Abs(oneYear.BreakEvenMonths-7.2) > 1e-9 {
+		t.Fatalf("expected break even 7.2 months, got %v", oneYear.BreakEvenMonths)
+	}
+	if math.Abs(oneYear.HourlySavings-1.2) > 1e-9 || math.Abs(oneYear.TermSavings-1.2*8760) > 1e-6 {
+		t.Fatalf("unexpected one year savings: %+v", oneYear)
+	}
+	if plans[1].Count != 0 || plans[1].HourlySavings != 0 {
+		t.Fatalf("expected no reservation for expensive term, got %+v", plans[1])
+	}
+}
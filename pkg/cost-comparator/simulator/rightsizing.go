@@ -0,0 +1,48 @@
+package simulator
+
+import (
+	"math"
+)
+
+// Demand is the resources a group of nodes must provide, milli cores and bytes.
+type Demand struct {
+	Cpu int64
+	Mem int64
+	// the largest pod must fit into one node
+	MaxPodCpu int64
+	MaxPodMem int64
+}
+
+// Suggestion is the cheapest instance type of the pool which satisfies the demand
+type Suggestion struct {
+	InstanceType InstanceType
+	Nodes        int
+	HourlyCost   float64
+}
+
+// Rightsize finds the cheapest instance type in the pool candidates to satisfy the demand, the current instance type is excluded.
+// It returns false if no candidate is cheaper than the current hourly cost.
+func Rightsize(pool *NodePool, current string, currentHourlyCost float64, demand Demand) (*Suggestion, bool) {
+	var best *Suggestion
+	for _, it := range pool.InstanceTypes {
+		if it.Name == current {
+			continue
+		}
+		cpu, mem := pool.allocatable(it)
+		if cpu <= 0 || mem <= 0 || demand.MaxPodCpu > cpu || demand.MaxPodMem > mem {
+			continue
+		}
+		nodes := int(math.Max(math.Ceil(float64(demand.Cpu)/float64(cpu)), math.Ceil(float64(demand.Mem)/float64(mem))))
+		if nodes < 1 {
+			nodes = 1
+		}
+		cost := float64(nodes) * it.HourlyPrice
+		if best == nil || cost < best.HourlyCost {
+			best = &Suggestion{InstanceType: it, Nodes: nodes, HourlyCost: cost}
+		}
+	}
+	if best == nil || best.HourlyCost >= currentHourlyCost {
+		return best, false
+	}
+	return best, true
+}
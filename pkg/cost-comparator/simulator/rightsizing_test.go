@@ -0,0 +1,33 @@
+package simulator
+
+import (
+	"testing"
+)
+
+func TestRightsize(t *testing.T) {
+	memHeavy := InstanceType{Name: "M5.2XLARGE32", Family: "M5", Cpu: 8000, Mem: 64 * gb, HourlyPrice: 1.0}
+	standard := InstanceType{Name: "S5.2XLARGE16", Family: "S5", Cpu: 8000, Mem: 16 * gb, HourlyPrice: 0.6}
+	small := InstanceType{Name: "S5.MEDIUM4", Family: "S5", Cpu: 2000, Mem: 4 * gb, HourlyPrice: 0.1}
+	pool := &NodePool{
+		Name:             "default",
+		InstanceTypes:    []InstanceType{memHeavy, standard, small},
+		CpuReservedRatio: 0.05,
+		MemReservedRatio: 0.1,
+	}
+
+	// 3 memory heavy nodes, but only 12 cores and 24G are needed
+	demand := Demand{Cpu: 12000, Mem: 24 * gb, MaxPodCpu: 4000, MaxPodMem: 4 * gb}
+	suggestion, cheaper := Rightsize(pool, memHeavy.Name, 3*memHeavy.HourlyPrice, demand)
+	if !cheaper {
+		t.Fatalf("expected cheaper suggestion, got %+v", suggestion)
+	}
+	// small can not hold the largest pod, standard needs 2 nodes
+	if suggestion.InstanceType.Name != standard.Name || suggestion.Nodes != 2 {
+		t.Fatalf("unexpected suggestion: %+v", suggestion)
+	}
+
+	_, cheaper = Rightsize(pool, standard.Name, 2*standard.HourlyPrice, demand)
+	if cheaper {
+		t.Fatalf("expected no cheaper suggestion")
+	}
+}
@@ -2,18 +2,54 @@ package cloudcost
 
 import (
 	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
 
 	"github.com/gocrane/fadvisor/pkg/cache"
 	"github.com/gocrane/fadvisor/pkg/cloud"
+	"github.com/gocrane/fadvisor/pkg/consts"
 )
 
 type ContainerAllocation struct {
-	Key           string
-	Pod           string
-	Node          string
-	Namespace     string
+	Key            string
+	Container      string
+	Pod            string
+	Node           string
+	Namespace      string
+	Controller     string
+	ControllerKind string
+	Labels         map[string]string
+	// StartTime is the start time of the pod
+	StartTime     time.Time
 	CpuAllocation float64
 	RamAllocation float64
+	GpuAllocation float64
+	// PersistentVolumes is the persistent volumes claimed by the pod
+	PersistentVolumes []PersistentVolumeClaim
+}
+
+// PersistentVolumeClaim is the persistent volume bound to a claim of the pod
+type PersistentVolumeClaim struct {
+	Claim            string
+	PersistentVolume string
+}
+
+// PersistentVolume is the persistent volume with the hourly price of one GB
+type PersistentVolume struct {
+	Name            string
+	StorageClass    string
+	ProviderID      string
+	Bytes           float64
+	GBHourlyCost    float64
+	ClaimNamespace  string
+	ClaimName       string
+	UsesDefaultCost bool
 }
 
 /**
@@ -47,15 +83,28 @@ type CostModel interface {
 	// GetConfig return CustomPricing
 	GetConfig() (*cloud.CustomPricing, error)
 
-	// ContainerAllocation return the container resource allocation. resource allocation is max(request, usage)
+	// ContainerAllocation return the container resource allocation of the running pods, key is namespace/pod/container.
+	// resource allocation is max(request, usage), now the usage is not fetched and the allocation is the request.
 	ContainerAllocation() (map[string]*ContainerAllocation, error)
 
 	GetNodesPricing() (map[string]*cloud.Price, error)
+
+	// GetPersistentVolumesCost return the persistent volumes with the hourly price, key is the pv name
+	GetPersistentVolumesCost() (map[string]*PersistentVolume, error)
+	// GetClusterManagementCost return the hourly cost of the cluster management, such as the tke cluster fee
+	GetClusterManagementCost() (float64, error)
+}
+
+// PriceAdjuster adjusts the estimated prices, such as by the factors reconciled with the bills
+type PriceAdjuster interface {
+	// Factor returns the factor of the price of the instance with the provider id, ok is false if the price is not adjusted
+	Factor(providerID string) (factor float64, ok bool)
 }
 
 type model struct {
 	cache    cache.Cache
 	provider cloud.CloudPrice
+	adjuster PriceAdjuster
 }
 
 func NewCloudCost(cache cache.Cache, provider cloud.CloudPrice) CostModel {
@@ -65,12 +114,50 @@ func NewCloudCost(cache cache.Cache, provider cloud.CloudPrice) CostModel {
 	}
 }
 
+// NewAdjustedCloudCost returns the cost model which scales the breakdown prices of the nodes and pods by the factors of the adjuster
+func NewAdjustedCloudCost(cache cache.Cache, provider cloud.CloudPrice, adjuster PriceAdjuster) CostModel {
+	return &model{
+		cache:    cache,
+		provider: provider,
+		adjuster: adjuster,
+	}
+}
+
 func (m *model) GetNodesCost() (map[string]*cloud.Node, error) {
-	return m.provider.GetNodesCost()
+	nodes, err := m.provider.GetNodesCost()
+	if err != nil || m.adjuster == nil {
+		return nodes, err
+	}
+	results := make(map[string]*cloud.Node, len(nodes))
+	for name, node := range nodes {
+		adjusted := *node
+		m.adjust(&adjusted.BaseInstancePrice)
+		results[name] = &adjusted
+	}
+	return results, nil
 }
 
 func (m *model) GetPodsCost() (map[string]*cloud.Pod, error) {
-	return m.provider.GetPodsCost()
+	pods, err := m.provider.GetPodsCost()
+	if err != nil || m.adjuster == nil {
+		return pods, err
+	}
+	results := make(map[string]*cloud.Pod, len(pods))
+	for key, pod := range pods {
+		adjusted := *pod
+		m.adjust(&adjusted.BaseInstancePrice)
+		results[key] = &adjusted
+	}
+	return results, nil
+}
+
+// adjust scales the costs of the price by the factor of the instance
+func (m *model) adjust(price *cloud.BaseInstancePrice) {
+	factor, ok := m.adjuster.Factor(price.ProviderID)
+	if !ok {
+		return
+	}
+	price.Scale(factor)
 }
 
 func (m *model) UpdateConfigFromConfigMap(cfg map[string]string) (*cloud.CustomPricing, error) {
@@ -83,7 +170,115 @@ func (m *model) GetConfig() (*cloud.CustomPricing, error) {
 
 //todo: this must first fetch container resource usage and request metric from prom. then compute the max of the two.
 func (m *model) ContainerAllocation() (map[string]*ContainerAllocation, error) {
-	return nil, fmt.Errorf("not implement")
+	pvs := make(map[string]string)
+	for _, pvc := range m.cache.GetPersistentVolumeClaims() {
+		if pvc.Spec.VolumeName != "" {
+			pvs[klog.KObj(pvc).String()] = pvc.Spec.VolumeName
+		}
+	}
+
+	results := make(map[string]*ContainerAllocation)
+	for _, pod := range m.cache.GetPods() {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		kind, controller := podController(pod)
+		var claims []PersistentVolumeClaim
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim == nil {
+				continue
+			}
+			if pv, ok := pvs[pod.Namespace+"/"+volume.PersistentVolumeClaim.ClaimName]; ok {
+				claims = append(claims, PersistentVolumeClaim{Claim: volume.PersistentVolumeClaim.ClaimName, PersistentVolume: pv})
+			}
+		}
+		startTime := pod.CreationTimestamp.Time
+		if pod.Status.StartTime != nil {
+			startTime = pod.Status.StartTime.Time
+		}
+		for _, container := range pod.Spec.Containers {
+			key := fmt.Sprintf("%s/%s/%s", pod.Namespace, pod.Name, container.Name)
+			requests := container.Resources.Requests
+			results[key] = &ContainerAllocation{
+				Key:               key,
+				Container:         container.Name,
+				Pod:               pod.Name,
+				Node:              pod.Spec.NodeName,
+				Namespace:         pod.Namespace,
+				Controller:        controller,
+				ControllerKind:    kind,
+				Labels:            pod.Labels,
+				StartTime:         startTime,
+				CpuAllocation:     float64(requests.Cpu().MilliValue()) / 1000.,
+				RamAllocation:     float64(requests.Memory().Value()),
+				GpuAllocation:     float64(requests.Name(v1.ResourceName(consts.ResourceGPU), resource.DecimalSI).Value()),
+				PersistentVolumes: claims,
+			}
+		}
+	}
+	return results, nil
+}
+
+// podController returns the kind and name of the controller of the pod, the deployment is got from the name of the replicaset
+func podController(pod *v1.Pod) (string, string) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "", ""
+	}
+	kind, name := strings.ToLower(owner.Kind), owner.Name
+	if kind == "replicaset" {
+		if hash, ok := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok && strings.HasSuffix(name, "-"+hash) {
+			return "deployment", strings.TrimSuffix(name, "-"+hash)
+		}
+	}
+	return kind, name
+}
+
+func (m *model) GetPersistentVolumesCost() (map[string]*PersistentVolume, error) {
+	cfg, err := m.provider.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	results := make(map[string]*PersistentVolume)
+	for _, pv := range m.cache.GetPersistentVolumes() {
+		capacity := pv.Spec.Capacity[v1.ResourceStorage]
+		volume := &PersistentVolume{
+			Name:            pv.Name,
+			StorageClass:    pv.Spec.StorageClassName,
+			Bytes:           float64(capacity.Value()),
+			GBHourlyCost:    cfg.StorageGBHourlyPrice,
+			UsesDefaultCost: true,
+		}
+		if pv.Spec.CSI != nil {
+			volume.ProviderID = pv.Spec.CSI.VolumeHandle
+		}
+		if pv.Spec.ClaimRef != nil {
+			volume.ClaimNamespace = pv.Spec.ClaimRef.Namespace
+			volume.ClaimName = pv.Spec.ClaimRef.Name
+		}
+		results[pv.Name] = volume
+	}
+	return results, nil
+}
+
+// GetClusterManagementCost return the platform price of the serverful cluster by the number of real nodes,
+// it is zero if the provider has no platform price.
+func (m *model) GetClusterManagementCost() (float64, error) {
+	pricer, ok := m.provider.(cloud.PlatformPricer)
+	if !ok {
+		return 0, nil
+	}
+	var nodes int32
+	for _, node := range m.cache.GetNodes() {
+		if !m.provider.IsVirtualNode(node) {
+			nodes++
+		}
+	}
+	prices := pricer.PlatformPrice(cloud.PlatformParameter{Nodes: &nodes, Platform: cloud.ServerfulKind})
+	if prices == nil {
+		return 0, nil
+	}
+	return prices.TotalPrice, nil
 }
 
 func (m *model) GetNodesPricing() (map[string]*cloud.Price, error) {
@@ -0,0 +1,331 @@
+package opencost
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocrane/fadvisor/pkg/consts"
+	"github.com/gocrane/fadvisor/pkg/cost-exporter/cloudcost"
+)
+
+// UnallocatedSuffix is the aggregate key of the allocations without the aggregated property
+const UnallocatedSuffix = "__unallocated__"
+
+// Config is the configuration of the opencost compatibility
+type Config struct {
+	// Enabled serves the allocation api and emits the opencost metrics
+	Enabled bool
+	// ClusterID is the cluster property of the allocations
+	ClusterID string
+	// Provisioner is the provisioner_name label of the cluster management cost
+	Provisioner string
+}
+
+// AllocationProperties is the properties of the allocation, the properties differ in an aggregated allocation are cleared
+type AllocationProperties struct {
+	Cluster        string            `json:"cluster,omitempty"`
+	Node           string            `json:"node,omitempty"`
+	Container      string            `json:"container,omitempty"`
+	Controller     string            `json:"controller,omitempty"`
+	ControllerKind string            `json:"controllerKind,omitempty"`
+	Namespace      string            `json:"namespace,omitempty"`
+	Pod            string            `json:"pod,omitempty"`
+	ProviderID     string            `json:"providerID,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+}
+
+// PVAllocation is the allocation of a persistent volume
+type PVAllocation struct {
+	ByteHours float64 `json:"byteHours"`
+	Cost      float64 `json:"cost"`
+}
+
+// Allocation is the opencost allocation of the resources and the costs in the window
+type Allocation struct {
+	Name                  string                   `json:"name"`
+	Properties            *AllocationProperties    `json:"properties"`
+	Window                Window                   `json:"window"`
+	Start                 string                   `json:"start"`
+	End                   string                   `json:"end"`
+	Minutes               float64                  `json:"minutes"`
+	CPUCores              float64                  `json:"cpuCores"`
+	CPUCoreRequestAverage float64                  `json:"cpuCoreRequestAverage"`
+	CPUCoreUsageAverage   float64                  `json:"cpuCoreUsageAverage"`
+	CPUCoreHours          float64                  `json:"cpuCoreHours"`
+	CPUCost               float64                  `json:"cpuCost"`
+	CPUCostAdjustment     float64                  `json:"cpuCostAdjustment"`
+	CPUEfficiency         float64                  `json:"cpuEfficiency"`
+	GPUCount              float64                  `json:"gpuCount"`
+	GPUHours              float64                  `json:"gpuHours"`
+	GPUCost               float64                  `json:"gpuCost"`
+	GPUCostAdjustment     float64                  `json:"gpuCostAdjustment"`
+	NetworkCost           float64                  `json:"networkCost"`
+	LoadBalancerCost      float64                  `json:"loadBalancerCost"`
+	PVBytes               float64                  `json:"pvBytes"`
+	PVByteHours           float64                  `json:"pvByteHours"`
+	PVCost                float64                  `json:"pvCost"`
+	PVs                   map[string]*PVAllocation `json:"pvs"`
+	PVCostAdjustment      float64                  `json:"pvCostAdjustment"`
+	RAMBytes              float64                  `json:"ramBytes"`
+	RAMByteRequestAverage float64                  `json:"ramByteRequestAverage"`
+	RAMByteUsageAverage   float64                  `json:"ramByteUsageAverage"`
+	RAMByteHours          float64                  `json:"ramByteHours"`
+	RAMCost               float64                  `json:"ramCost"`
+	RAMCostAdjustment     float64                  `json:"ramCostAdjustment"`
+	RAMEfficiency         float64                  `json:"ramEfficiency"`
+	SharedCost            float64                  `json:"sharedCost"`
+	ExternalCost          float64                  `json:"externalCost"`
+	TotalCost             float64                  `json:"totalCost"`
+	TotalEfficiency       float64                  `json:"totalEfficiency"`
+}
+
+// hours returns the hours of the allocation window
+func (a *Allocation) hours() float64 {
+	return a.Minutes / 60
+}
+
+// complete computes the averages and the total cost by the hours
+func (a *Allocation) complete() {
+	a.Start, a.End = a.Window.Start.Format(time.RFC3339), a.Window.End.Format(time.RFC3339)
+	a.Minutes = a.Window.End.Sub(a.Window.Start).Minutes()
+	if hours := a.hours(); hours > 0 {
+		a.CPUCores = a.CPUCoreHours / hours
+		a.GPUCount = a.GPUHours / hours
+		a.RAMBytes = a.RAMByteHours / hours
+		a.PVBytes = a.PVByteHours / hours
+	}
+	// the allocation is the request, the usage is not queried by the exporter
+	a.CPUCoreRequestAverage, a.RAMByteRequestAverage = a.CPUCores, a.RAMBytes
+	a.TotalCost = a.CPUCost + a.GPUCost + a.RAMCost + a.PVCost + a.NetworkCost + a.LoadBalancerCost + a.SharedCost + a.ExternalCost
+}
+
+// ComputeAllocations computes the allocations of the containers in the window, key is cluster/node/namespace/pod/container.
+// the exporter has no history, so the current requests and prices of the running pods are assumed for the window since the pods started,
+// the callers must only pass the windows ending at now.
+func ComputeAllocations(model cloudcost.CostModel, cluster string, window Window) (map[string]*Allocation, error) {
+	cfg, err := model.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := model.GetNodesCost()
+	if err != nil {
+		return nil, err
+	}
+	containers, err := model.ContainerAllocation()
+	if err != nil {
+		return nil, err
+	}
+	pvs, err := model.GetPersistentVolumesCost()
+	if err != nil {
+		return nil, err
+	}
+
+	// the pod level costs are shared by the containers of the pod
+	podContainers := make(map[string]int)
+	for _, c := range containers {
+		podContainers[c.Namespace+"/"+c.Pod]++
+	}
+
+	results := make(map[string]*Allocation)
+	for _, c := range containers {
+		start := window.Start
+		if c.StartTime.After(start) {
+			start = c.StartTime
+		}
+		if !window.End.After(start) {
+			continue
+		}
+		alloc := &Allocation{
+			Properties: &AllocationProperties{
+				Cluster:        cluster,
+				Node:           c.Node,
+				Container:      c.Container,
+				Controller:     c.Controller,
+				ControllerKind: c.ControllerKind,
+				Namespace:      c.Namespace,
+				Pod:            c.Pod,
+				Labels:         c.Labels,
+			},
+			Window: Window{Start: start, End: window.End},
+			PVs:    make(map[string]*PVAllocation),
+		}
+		hours := alloc.Window.Hours()
+
+		cpuPrice, ramPrice := cfg.CpuHourlyPrice, cfg.RamGBHourlyPrice
+		if node, ok := nodes[c.Node]; ok {
+			cpuPrice = parsePrice(node.CpuHourlyCost, cpuPrice)
+			ramPrice = parsePrice(node.RamGBHourlyCost, ramPrice)
+			alloc.Properties.ProviderID = node.ProviderID
+		}
+		alloc.CPUCoreHours = c.CpuAllocation * hours
+		alloc.CPUCost = alloc.CPUCoreHours * cpuPrice
+		alloc.RAMByteHours = c.RamAllocation * hours
+		alloc.RAMCost = alloc.RAMByteHours / consts.GB * ramPrice
+		// there is no gpu price of the node, only the gpu hours are allocated
+		alloc.GPUHours = c.GpuAllocation * hours
+
+		share := 1. / float64(podContainers[c.Namespace+"/"+c.Pod])
+		for _, claim := range c.PersistentVolumes {
+			pv, ok := pvs[claim.PersistentVolume]
+			if !ok {
+				continue
+			}
+			pvAlloc := &PVAllocation{ByteHours: pv.Bytes * hours * share}
+			pvAlloc.Cost = pvAlloc.ByteHours / consts.GB * pv.GBHourlyCost
+			alloc.PVs[fmt.Sprintf("cluster=%s:name=%s", cluster, pv.Name)] = pvAlloc
+			alloc.PVByteHours += pvAlloc.ByteHours
+			alloc.PVCost += pvAlloc.Cost
+		}
+
+		alloc.Name = strings.Join([]string{cluster, c.Node, c.Namespace, c.Pod, c.Container}, "/")
+		alloc.complete()
+		results[alloc.Name] = alloc
+	}
+	return results, nil
+}
+
+// parsePrice parses the price of the node, the default price is used if the price is invalid
+func parsePrice(value string, defaultPrice float64) float64 {
+	price, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(price) || math.IsInf(price, 0) {
+		return defaultPrice
+	}
+	return price
+}
+
+// ValidateAggregate validates the aggregate properties, the supported properties are
+// cluster, node, namespace, controllerKind, controller, pod, container and label:<name>
+func ValidateAggregate(by []string) error {
+	for _, prop := range by {
+		switch {
+		case prop == "cluster", prop == "node", prop == "namespace", prop == "controllerKind",
+			prop == "controller", prop == "pod", prop == "container":
+		case strings.HasPrefix(prop, "label:") && len(prop) > len("label:"):
+		default:
+			return fmt.Errorf("unsupported aggregate property %q", prop)
+		}
+	}
+	return nil
+}
+
+// aggregateValue returns the value of the property of the allocation, the controller is of the form kind:name
+func aggregateValue(props *AllocationProperties, prop string) string {
+	var value string
+	switch prop {
+	case "cluster":
+		value = props.Cluster
+	case "node":
+		value = props.Node
+	case "namespace":
+		value = props.Namespace
+	case "controllerKind":
+		value = props.ControllerKind
+	case "controller":
+		if props.Controller != "" {
+			value = props.ControllerKind + ":" + props.Controller
+		}
+	case "pod":
+		value = props.Pod
+	case "container":
+		value = props.Container
+	default:
+		value = props.Labels[strings.TrimPrefix(prop, "label:")]
+	}
+	if value == "" {
+		return UnallocatedSuffix
+	}
+	return value
+}
+
+// AggregateAllocations aggregates the allocations by the properties, the key is the values of the properties joined by slash
+func AggregateAllocations(allocs map[string]*Allocation, by []string) (map[string]*Allocation, error) {
+	if len(by) == 0 {
+		return allocs, nil
+	}
+	if err := ValidateAggregate(by); err != nil {
+		return nil, err
+	}
+	results := make(map[string]*Allocation)
+	for _, alloc := range allocs {
+		values := make([]string, 0, len(by))
+		for _, prop := range by {
+			values = append(values, aggregateValue(alloc.Properties, prop))
+		}
+		key := strings.Join(values, "/")
+		agg, ok := results[key]
+		if !ok {
+			props := *alloc.Properties
+			agg = &Allocation{Name: key, Properties: &props, Window: alloc.Window, PVs: make(map[string]*PVAllocation)}
+			results[key] = agg
+		} else {
+			agg.Properties = commonProperties(agg.Properties, alloc.Properties)
+			if alloc.Window.Start.Before(agg.Window.Start) {
+				agg.Window.Start = alloc.Window.Start
+			}
+			if alloc.Window.End.After(agg.Window.End) {
+				agg.Window.End = alloc.Window.End
+			}
+		}
+		agg.CPUCoreHours += alloc.CPUCoreHours
+		agg.CPUCost += alloc.CPUCost
+		agg.GPUHours += alloc.GPUHours
+		agg.GPUCost += alloc.GPUCost
+		agg.RAMByteHours += alloc.RAMByteHours
+		agg.RAMCost += alloc.RAMCost
+		agg.PVByteHours += alloc.PVByteHours
+		agg.PVCost += alloc.PVCost
+		agg.NetworkCost += alloc.NetworkCost
+		agg.LoadBalancerCost += alloc.LoadBalancerCost
+		agg.SharedCost += alloc.SharedCost
+		agg.ExternalCost += alloc.ExternalCost
+		for name, pv := range alloc.PVs {
+			if _, ok := agg.PVs[name]; !ok {
+				agg.PVs[name] = &PVAllocation{}
+			}
+			agg.PVs[name].ByteHours += pv.ByteHours
+			agg.PVs[name].Cost += pv.Cost
+		}
+	}
+	for _, agg := range results {
+		agg.complete()
+	}
+	return results, nil
+}
+
+// commonProperties returns the properties which are the same in both
+func commonProperties(a, b *AllocationProperties) *AllocationProperties {
+	props := &AllocationProperties{}
+	if a.Cluster == b.Cluster {
+		props.Cluster = a.Cluster
+	}
+	if a.Node == b.Node {
+		props.Node = a.Node
+	}
+	if a.Container == b.Container {
+		props.Container = a.Container
+	}
+	if a.Controller == b.Controller && a.ControllerKind == b.ControllerKind {
+		props.Controller, props.ControllerKind = a.Controller, a.ControllerKind
+	}
+	if a.Namespace == b.Namespace {
+		props.Namespace = a.Namespace
+	}
+	if a.Pod == b.Pod {
+		props.Pod = a.Pod
+	}
+	if a.ProviderID == b.ProviderID {
+		props.ProviderID = a.ProviderID
+	}
+	for k, v := range a.Labels {
+		if b.Labels[k] == v {
+			if props.Labels == nil {
+				props.Labels = make(map[string]string)
+			}
+			props.Labels[k] = v
+		}
+	}
+	return props
+}
@@ -0,0 +1,229 @@
+package opencost
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/gocrane/fadvisor/pkg/cache"
+	"github.com/gocrane/fadvisor/pkg/cloud"
+	defaultcloud "github.com/gocrane/fadvisor/pkg/cloudproviders/default"
+	"github.com/gocrane/fadvisor/pkg/cost-exporter/cloudcost"
+)
+
+type fakeCache struct {
+	pods  []*v1.Pod
+	nodes []*v1.Node
+	pvs   []*v1.PersistentVolume
+	pvcs  []*v1.PersistentVolumeClaim
+}
+
+func (c *fakeCache) GetAllHPAs() []*autoscalingv1.HorizontalPodAutoscaler { return nil }
+func (c *fakeCache) GetStatefulSets() []*appsv1.StatefulSet               { return nil }
+func (c *fakeCache) GetDaemonSets() []*appsv1.DaemonSet                   { return nil }
+func (c *fakeCache) GetDeployments() []*appsv1.Deployment                 { return nil }
+func (c *fakeCache) GetPods() []*v1.Pod                                   { return c.pods }
+func (c *fakeCache) GetNodes() []*v1.Node                                 { return c.nodes }
+func (c *fakeCache) GetPodDisruptionBudgets() []*policyv1beta1.PodDisruptionBudget {
+	return nil
+}
+func (c *fakeCache) GetPersistentVolumes() []*v1.PersistentVolume           { return c.pvs }
+func (c *fakeCache) GetPersistentVolumeClaims() []*v1.PersistentVolumeClaim { return c.pvcs }
+func (c *fakeCache) WaitForCacheSync(stopCh <-chan struct{})                {}
+
+func container(name, cpu, mem string) v1.Container {
+	return v1.Container{Name: name, Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+		v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(mem),
+	}}}
+}
+
+func newModel(now time.Time) cloudcost.CostModel {
+	isController := true
+	web := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-5d4f8-x2k9p", Labels: map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: "5d4f8"},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5d4f8", Controller: &isController}}},
+		Spec: v1.PodSpec{NodeName: "node-1", Containers: []v1.Container{container("nginx", "500m", "1Gi"), container("sidecar", "100m", "128Mi")},
+			Volumes: []v1.Volume{{Name: "data", VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "data"}}}}},
+		Status: v1.PodStatus{Phase: v1.PodRunning, StartTime: &metav1.Time{Time: now.Add(-3 * time.Hour)}},
+	}
+	job := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "batch", Name: "report-abcde",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Job", Name: "report", Controller: &isController}}},
+		Spec:   v1.PodSpec{NodeName: "node-1", Containers: []v1.Container{container("report", "1", "2Gi")}},
+		Status: v1.PodStatus{Phase: v1.PodRunning, StartTime: &metav1.Time{Time: now.Add(-time.Hour)}},
+	}
+	c := &fakeCache{
+		pods: []*v1.Pod{web, job},
+		nodes: []*v1.Node{{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status:     v1.NodeStatus{Capacity: v1.ResourceList{v1.ResourceCPU: resource.MustParse("4"), v1.ResourceMemory: resource.MustParse("8Gi")}},
+		}},
+		pvs: []*v1.PersistentVolume{{
+			ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+			Spec:       v1.PersistentVolumeSpec{Capacity: v1.ResourceList{v1.ResourceStorage: resource.MustParse("10Gi")}},
+		}},
+		pvcs: []*v1.PersistentVolumeClaim{{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "data"},
+			Spec:       v1.PersistentVolumeClaimSpec{VolumeName: "pvc-1"},
+		}},
+	}
+	var clusterCache cache.Cache = c
+	priceConfig := cloud.NewProviderConfig(&cloud.CustomPricing{CpuHourlyPrice: 0.03, RamGBHourlyPrice: 0.004, StorageGBHourlyPrice: 0.0001})
+	return cloudcost.NewCloudCost(clusterCache, defaultcloud.NewDefaultCloud(priceConfig, clusterCache))
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestComputeAllocations(t *testing.T) {
+	now := time.Date(2022, 4, 16, 12, 0, 0, 0, time.UTC)
+	model := newModel(now)
+	window, err := ParseWindow("2h", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocs, err := ComputeAllocations(model, "cluster-one", window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(allocs) != 3 {
+		t.Fatalf("expect 3 allocations, got %v", len(allocs))
+	}
+	nginx, ok := allocs["cluster-one/node-1/default/web-5d4f8-x2k9p/nginx"]
+	if !ok {
+		t.Fatalf("allocation of nginx not found: %v", allocs)
+	}
+	if nginx.Properties.Controller != "web" || nginx.Properties.ControllerKind != "deployment" {
+		t.Errorf("expect controller deployment web, got %v %v", nginx.Properties.ControllerKind, nginx.Properties.Controller)
+	}
+	// 0.5 cores and 1GB for 2 hours, the 10GB pv is shared by 2 containers
+	if !almostEqual(nginx.CPUCost, 0.5*2*0.03) || !almostEqual(nginx.RAMCost, 2*0.004) || !almostEqual(nginx.PVCost, 10*2*0.5*0.0001) {
+		t.Errorf("unexpected costs cpu %v, ram %v, pv %v", nginx.CPUCost, nginx.RAMCost, nginx.PVCost)
+	}
+	if !almostEqual(nginx.TotalCost, nginx.CPUCost+nginx.RAMCost+nginx.PVCost) || nginx.CPUCores != 0.5 || nginx.Minutes != 120 {
+		t.Errorf("unexpected allocation %+v", nginx)
+	}
+	if _, ok := nginx.PVs["cluster=cluster-one:name=pvc-1"]; !ok {
+		t.Errorf("expect pv allocation, got %v", nginx.PVs)
+	}
+
+	aggregated, err := AggregateAllocations(allocs, []string{"controller"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	web, job := aggregated["deployment:web"], aggregated["job:report"]
+	if web == nil || job == nil {
+		t.Fatalf("unexpected aggregated allocations %v", aggregated)
+	}
+	if !almostEqual(web.CPUCores, 0.6) || web.Properties.Container != "" || web.Properties.Pod != "web-5d4f8-x2k9p" {
+		t.Errorf("unexpected web allocation %+v, %+v", web, web.Properties)
+	}
+	// the job pod started in the window
+	if job.Minutes != 60 || !almostEqual(job.CPUCost, 0.03) {
+		t.Errorf("unexpected job allocation %+v", job)
+	}
+
+	aggregated, err = AggregateAllocations(allocs, []string{"namespace", "label:app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := aggregated["batch/"+UnallocatedSuffix]; !ok {
+		t.Errorf("expect unallocated label of the job, got %v", aggregated)
+	}
+	if _, err = AggregateAllocations(allocs, []string{"service"}); err == nil {
+		t.Errorf("expect error of unsupported aggregate")
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	now := time.Date(2022, 4, 16, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		window string
+		start  time.Time
+		end    time.Time
+	}{
+		{"today", time.Date(2022, 4, 16, 0, 0, 0, 0, time.UTC), now},
+		{"yesterday", time.Date(2022, 4, 15, 0, 0, 0, 0, time.UTC), time.Date(2022, 4, 16, 0, 0, 0, 0, time.UTC)},
+		{"week", time.Date(2022, 4, 10, 0, 0, 0, 0, time.UTC), now},
+		{"lastmonth", time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"7d", now.AddDate(0, 0, -7), now},
+		{"2022-04-15T00:00:00Z,2022-04-17T00:00:00Z", time.Date(2022, 4, 15, 0, 0, 0, 0, time.UTC), now},
+		{"1649980800,1650024000", time.Unix(1649980800, 0).UTC(), time.Unix(1650024000, 0).UTC()},
+	}
+	for _, test := range tests {
+		w, err := ParseWindow(test.window, now)
+		if err != nil {
+			t.Errorf("window %v: %v", test.window, err)
+			continue
+		}
+		if !w.Start.Equal(test.start) || !w.End.Equal(test.end) {
+			t.Errorf("window %v: expect %v - %v, got %v - %v", test.window, test.start, test.end, w.Start, w.End)
+		}
+	}
+	for _, window := range []string{"", "7x", "2022-04-17T00:00:00Z,2022-04-18T00:00:00Z"} {
+		if _, err := ParseWindow(window, now); err == nil {
+			t.Errorf("expect error of window %q", window)
+		}
+	}
+}
+
+func TestAllocationHandler(t *testing.T) {
+	handler := NewAllocationHandler(newModel(time.Now()), "cluster-one")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/allocation/compute?window=2h&aggregate=namespace&step=2h", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expect status 200, got %v: %v", recorder.Code, recorder.Body.String())
+	}
+	var resp struct {
+		Code int                      `json:"code"`
+		Data []map[string]*Allocation `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	// the step covers the whole window
+	if resp.Code != http.StatusOK || len(resp.Data) != 1 || resp.Data[0]["default"] == nil {
+		t.Errorf("unexpected response %v", recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/allocation/compute?window=2h&step=1h&accumulate=true", nil))
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil || len(resp.Data) != 1 {
+		t.Errorf("expect one accumulated set, got %v, %v", recorder.Body.String(), err)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/allocation/compute?window=2h&aggregate=service", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expect status 400, got %v", recorder.Code)
+	}
+
+	// the earlier step of a two step request would be made up from the running pods, no sets are returned
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/allocation/compute?window=2h&aggregate=namespace&step=1h", nil))
+	resp.Data = nil
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil || recorder.Code != http.StatusBadRequest || len(resp.Data) != 0 {
+		t.Errorf("expect status 400 without sets, got %v: %v", recorder.Code, recorder.Body.String())
+	}
+
+	// no history of the past windows
+	for _, query := range []string{"window=yesterday", "window=2w&step=1m"} {
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/allocation/compute?"+query, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("query %v: expect status 400, got %v", query, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package opencost
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/fadvisor/pkg/cost-exporter/cloudcost"
+)
+
+// Response is the response of the opencost api
+type Response struct {
+	Code    int         `json:"code"`
+	Data    interface{} `json:"data"`
+	Message string      `json:"message,omitempty"`
+}
+
+// AllocationRequest is the query of /allocation/compute
+type AllocationRequest struct {
+	Window     Window
+	Aggregate  []string
+	Accumulate bool
+	Step       time.Duration
+}
+
+// ParseAllocationRequest parses the query parameters window, aggregate, accumulate and step of the allocation api.
+// the exporter has no history, so the window must end at now, the windows in the past such as yesterday are rejected,
+// and so is a step splitting the window, because the sets of the earlier steps would be in the past.
+func ParseAllocationRequest(r *http.Request, now time.Time) (*AllocationRequest, error) {
+	query := r.URL.Query()
+	windowStr := query.Get("window")
+	if windowStr == "" {
+		return nil, fmt.Errorf("missing window parameter")
+	}
+	window, err := ParseWindow(windowStr, now)
+	if err != nil {
+		return nil, err
+	}
+	if window.End.Before(now) {
+		return nil, fmt.Errorf("window %q is in the past, the allocations are computed from the running pods and only the windows ending at now are supported", windowStr)
+	}
+	req := &AllocationRequest{Window: window}
+	if aggregate := query.Get("aggregate"); aggregate != "" {
+		req.Aggregate = strings.Split(aggregate, ",")
+		if err = ValidateAggregate(req.Aggregate); err != nil {
+			return nil, err
+		}
+	}
+	if accumulate := query.Get("accumulate"); accumulate != "" {
+		if req.Accumulate, err = strconv.ParseBool(accumulate); err != nil {
+			return nil, fmt.Errorf("invalid accumulate %q", accumulate)
+		}
+	}
+	if step := query.Get("step"); step != "" {
+		if req.Step, err = ParseDuration(step); err != nil {
+			return nil, err
+		}
+	}
+	if !req.Accumulate && req.Step > 0 && req.Step < window.End.Sub(window.Start) {
+		return nil, fmt.Errorf("step %v splits the window into sets in the past, the exporter has no history, use accumulate=true or a step not shorter than the window", req.Step)
+	}
+	return req, nil
+}
+
+// Compute returns the allocation sets of the request, it is one set of the whole window
+func (req *AllocationRequest) Compute(model cloudcost.CostModel, cluster string) ([]map[string]*Allocation, error) {
+	allocs, err := ComputeAllocations(model, cluster, req.Window)
+	if err != nil {
+		return nil, err
+	}
+	allocs, err = AggregateAllocations(allocs, req.Aggregate)
+	if err != nil {
+		return nil, err
+	}
+	return []map[string]*Allocation{allocs}, nil
+}
+
+// NewAllocationHandler returns the handler of the opencost /allocation/compute api
+func NewAllocationHandler(model cloudcost.CostModel, cluster string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := ParseAllocationRequest(r, time.Now())
+		if err != nil {
+			writeResponse(w, http.StatusBadRequest, Response{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		sets, err := req.Compute(model, cluster)
+		if err != nil {
+			klog.Errorf("Failed to compute allocations: %v", err)
+			writeResponse(w, http.StatusInternalServerError, Response{Code: http.StatusInternalServerError, Message: err.Error()})
+			return
+		}
+		writeResponse(w, http.StatusOK, Response{Code: http.StatusOK, Data: sets})
+	})
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp Response) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,99 @@
+package opencost
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Window is the time range of the allocation
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Hours returns the hours of the window
+func (w Window) Hours() float64 {
+	return w.End.Sub(w.Start).Hours()
+}
+
+var durationRegex = regexp.MustCompile(`^(\d+)(m|h|d|w)$`)
+
+// ParseDuration parses the opencost duration such as 30m, 12h, 7d and 2w
+func ParseDuration(s string) (time.Duration, error) {
+	match := durationRegex.FindStringSubmatch(s)
+	if match == nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, err
+	}
+	unit := map[string]time.Duration{"m": time.Minute, "h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour}[match[2]]
+	return time.Duration(n) * unit, nil
+}
+
+// ParseWindow parses the opencost window, the window is one of
+// today, yesterday, week, lastweek, month, lastmonth, a duration such as 7d which ends at now,
+// or a pair of RFC3339 or unix timestamps separated by comma. the window is in UTC and ends at now at most.
+func ParseWindow(s string, now time.Time) (Window, error) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	week := today.AddDate(0, 0, -int(today.Weekday()))
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	var w Window
+	switch s {
+	case "today":
+		w = Window{Start: today, End: now}
+	case "yesterday":
+		w = Window{Start: today.AddDate(0, 0, -1), End: today}
+	case "week":
+		w = Window{Start: week, End: now}
+	case "lastweek":
+		w = Window{Start: week.AddDate(0, 0, -7), End: week}
+	case "month":
+		w = Window{Start: month, End: now}
+	case "lastmonth":
+		w = Window{Start: month.AddDate(0, -1, 0), End: month}
+	default:
+		if parts := strings.Split(s, ","); len(parts) == 2 {
+			start, err := parseTime(parts[0])
+			if err != nil {
+				return w, err
+			}
+			end, err := parseTime(parts[1])
+			if err != nil {
+				return w, err
+			}
+			w = Window{Start: start, End: end}
+		} else {
+			d, err := ParseDuration(s)
+			if err != nil {
+				return w, fmt.Errorf("invalid window %q", s)
+			}
+			w = Window{Start: now.Add(-d), End: now}
+		}
+	}
+	if w.End.After(now) {
+		w.End = now
+	}
+	if !w.End.After(w.Start) {
+		return w, fmt.Errorf("window %q is empty", s)
+	}
+	return w, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if unix, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(unix, 0).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q, must be RFC3339 or unix timestamp", s)
+	}
+	return t.UTC(), nil
+}
@@ -14,14 +14,16 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/gocrane/fadvisor/pkg/cost-exporter/cloudcost"
+	"github.com/gocrane/fadvisor/pkg/cost-exporter/opencost"
 	"github.com/gocrane/fadvisor/pkg/util"
 )
 
-func NewServer(model cloudcost.CostModel, bind string, debugging componentbaseconfig.DebuggingConfiguration) *Server {
+func NewServer(model cloudcost.CostModel, bind string, debugging componentbaseconfig.DebuggingConfiguration, openCost opencost.Config) *Server {
 	return &Server{
 		model:     model,
 		bind:      bind,
 		debugging: debugging,
+		openCost:  openCost,
 		server:    &http.Server{},
 	}
 }
@@ -31,12 +33,16 @@ type Server struct {
 	bind      string
 	server    *http.Server
 	debugging componentbaseconfig.DebuggingConfiguration
+	openCost  opencost.Config
 }
 
 func (s *Server) RegisterHandlers() {
 	baseHandler := util.NewBaseHandler("fadvisor", s.debugging)
 	baseHandler.Handle("/nodes/cost", s.NodesCostHandler())
 	baseHandler.Handle("/nodes/pricing", s.NodesPriceHandler())
+	if s.openCost.Enabled {
+		baseHandler.Handle("/allocation/compute", opencost.NewAllocationHandler(s.model, s.openCost.ClusterID))
+	}
 
 	handler := util.BuildHandlerChain(baseHandler, nil, nil)
 	s.server.Handler = handler
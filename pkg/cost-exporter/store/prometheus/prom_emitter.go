@@ -12,6 +12,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/gocrane/fadvisor/pkg/cost-exporter/cloudcost"
+	"github.com/gocrane/fadvisor/pkg/cost-exporter/opencost"
 )
 
 var metricsInit sync.Once
@@ -21,39 +22,77 @@ var (
 	nodeRamCostGv   *prometheus.GaugeVec
 	nodeTotalCostGv *prometheus.GaugeVec
 
-	//containerRamAllocGv *prometheus.GaugeVec
-	//containerCpuAllocGv *prometheus.GaugeVec
+	// the metrics compatible with opencost
+	nodeIsSpotGv            *prometheus.GaugeVec
+	containerCpuAllocGv     *prometheus.GaugeVec
+	containerRamAllocGv     *prometheus.GaugeVec
+	containerGpuAllocGv     *prometheus.GaugeVec
+	pvCostGv                *prometheus.GaugeVec
+	podPVCAllocGv           *prometheus.GaugeVec
+	clusterManagementCostGv *prometheus.GaugeVec
 )
 
-func init() {
+// initMetrics registers the metrics, the cost metrics have the currency label only if the currency label is enabled
+func initMetrics(currencyLabel bool) {
+	costLabels := func(names ...string) []string {
+		if currencyLabel {
+			names = append(names, "currency")
+		}
+		return names
+	}
 	metricsInit.Do(func() {
 		nodeCpuCostGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "node_cpu_hourly_cost",
 			Help: "node_cpu_hourly_cost hourly cost for each cpu on the node",
-		}, []string{"instance", "node", "instance_type", "region", "provider_id"})
+		}, costLabels("instance", "node", "instance_type", "region", "provider_id"))
 
 		nodeRamCostGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "node_ram_hourly_cost",
 			Help: "node_ram_hourly_cost hourly cost for each GB of ram on the node",
-		}, []string{"instance", "node", "instance_type", "region", "provider_id"})
+		}, costLabels("instance", "node", "instance_type", "region", "provider_id"))
 
 		nodeTotalCostGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "node_total_hourly_cost",
 			Help: "node_total_hourly_cost total node cost per hour",
+		}, costLabels("instance", "node", "instance_type", "region", "provider_id"))
+
+		nodeIsSpotGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kubecost_node_is_spot",
+			Help: "kubecost_node_is_spot Cloud provider info about node preemptibility",
 		}, []string{"instance", "node", "instance_type", "region", "provider_id"})
 
-		//containerCpuAllocGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		//	Name: "container_cpu_allocation",
-		//	Help: "container_cpu_allocation of container CPU used in a minute",
-		//}, []string{"namespace", "pod", "container", "instance", "node"})
-		//
-		//containerRamAllocGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		//	Name: "container_memory_allocation_bytes",
-		//	Help: "container_memory_allocation_bytes Bytes of container RAM used",
-		//}, []string{"namespace", "pod", "container", "instance", "node"})
+		containerCpuAllocGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "container_cpu_allocation",
+			Help: "container_cpu_allocation Percent of a single CPU used in a minute",
+		}, []string{"namespace", "pod", "container", "instance", "node"})
+
+		containerRamAllocGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "container_memory_allocation_bytes",
+			Help: "container_memory_allocation_bytes Bytes of RAM used",
+		}, []string{"namespace", "pod", "container", "instance", "node"})
+
+		containerGpuAllocGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "container_gpu_allocation",
+			Help: "container_gpu_allocation GPU used",
+		}, []string{"namespace", "pod", "container", "instance", "node"})
+
+		pvCostGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "pv_hourly_cost",
+			Help: "pv_hourly_cost Cost per GB per hour on a persistent disk",
+		}, costLabels("volumename", "persistentvolume", "provider_id"))
+
+		podPVCAllocGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "pod_pvc_allocation",
+			Help: "pod_pvc_allocation Bytes used by a PVC attached to a pod",
+		}, []string{"namespace", "pod", "persistentvolumeclaim", "persistentvolume"})
+
+		clusterManagementCostGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kubecost_cluster_management_cost",
+			Help: "kubecost_cluster_management_cost Hourly cost paid as a cluster management fee",
+		}, costLabels("provisioner_name"))
 
 		prometheus.MustRegister(nodeCpuCostGv, nodeRamCostGv, nodeTotalCostGv)
-		//prometheus.MustRegister(containerCpuAllocGv, containerRamAllocGv)
+		prometheus.MustRegister(nodeIsSpotGv, containerCpuAllocGv, containerRamAllocGv, containerGpuAllocGv, pvCostGv, podPVCAllocGv, clusterManagementCostGv)
 
 	})
 }
@@ -66,21 +105,41 @@ type CostMetricEmitter struct {
 	nodeRamCostGv   *prometheus.GaugeVec
 	nodeTotalCostGv *prometheus.GaugeVec
 
-	//containerRamAllocGv *prometheus.GaugeVec
-	//containerCpuAllocGv *prometheus.GaugeVec
+	openCost opencost.Config
+	// currencyLabel adds the currency label to the cost metrics
+	currencyLabel bool
+	// the opencost metrics, the series not updated are deleted
+	nodeIsSpot            *gaugeSeries
+	containerCpuAlloc     *gaugeSeries
+	containerRamAlloc     *gaugeSeries
+	containerGpuAlloc     *gaugeSeries
+	pvCost                *gaugeSeries
+	podPVCAlloc           *gaugeSeries
+	clusterManagementCost *gaugeSeries
 
 	updateInterval time.Duration
 	stopCh         <-chan struct{}
 }
 
-func NewCostMetricEmitter(costModel cloudcost.CostModel, updateInterval time.Duration, stopCh <-chan struct{}) *CostMetricEmitter {
+// NewCostMetricEmitter returns the emitter of the cost metrics, the currency label is opt-in because it changes the series of the existing metrics
+func NewCostMetricEmitter(costModel cloudcost.CostModel, updateInterval time.Duration, stopCh <-chan struct{}, openCost opencost.Config, currencyLabel bool) *CostMetricEmitter {
+	initMetrics(currencyLabel)
 	return &CostMetricEmitter{
-		costModel:       costModel,
-		updateInterval:  updateInterval,
-		stopCh:          stopCh,
-		nodeCpuCostGv:   nodeCpuCostGv,
-		nodeRamCostGv:   nodeRamCostGv,
-		nodeTotalCostGv: nodeTotalCostGv,
+		costModel:             costModel,
+		updateInterval:        updateInterval,
+		stopCh:                stopCh,
+		nodeCpuCostGv:         nodeCpuCostGv,
+		nodeRamCostGv:         nodeRamCostGv,
+		nodeTotalCostGv:       nodeTotalCostGv,
+		openCost:              openCost,
+		currencyLabel:         currencyLabel,
+		nodeIsSpot:            newGaugeSeries(nodeIsSpotGv),
+		containerCpuAlloc:     newGaugeSeries(containerCpuAllocGv),
+		containerRamAlloc:     newGaugeSeries(containerRamAllocGv),
+		containerGpuAlloc:     newGaugeSeries(containerGpuAllocGv),
+		pvCost:                newGaugeSeries(pvCostGv),
+		podPVCAlloc:           newGaugeSeries(podPVCAllocGv),
+		clusterManagementCost: newGaugeSeries(clusterManagementCostGv),
 	}
 }
 
@@ -131,15 +190,32 @@ func (cme *CostMetricEmitter) Start() {
 
 			nodeType := node.InstanceType
 			nodeRegion := node.Region
+			currency := node.Currency
+			if currency == "" {
+				currency = cfg.Currency
+			}
 
 			totalCost := cpu*cpuCost + ramCost*ram
 
-			cme.nodeCpuCostGv.WithLabelValues(nodeName, nodeName, nodeType, nodeRegion, node.ProviderID).Set(cpuCost)
-			cme.nodeRamCostGv.WithLabelValues(nodeName, nodeName, nodeType, nodeRegion, node.ProviderID).Set(ramCost)
-			cme.nodeTotalCostGv.WithLabelValues(nodeName, nodeName, nodeType, nodeRegion, node.ProviderID).Set(totalCost)
+			labels := cme.withCurrency(currency, nodeName, nodeName, nodeType, nodeRegion, node.ProviderID)
+			cme.nodeCpuCostGv.WithLabelValues(labels...).Set(cpuCost)
+			cme.nodeRamCostGv.WithLabelValues(labels...).Set(ramCost)
+			cme.nodeTotalCostGv.WithLabelValues(labels...).Set(totalCost)
 
-			labelKey := getKeyFromLabelStrings(nodeName, nodeName, nodeType, nodeRegion, node.ProviderID)
+			labelKey := getKeyFromLabelStrings(labels...)
 			nodesLastSeen[labelKey] = true
+
+			if cme.openCost.Enabled {
+				isSpot := 0.
+				if node.Spot {
+					isSpot = 1
+				}
+				cme.nodeIsSpot.set(isSpot, nodeName, nodeName, nodeType, nodeRegion, node.ProviderID)
+			}
+		}
+		if cme.openCost.Enabled {
+			cme.nodeIsSpot.flush()
+			cme.emitOpenCostMetrics(cfg.Currency)
 		}
 
 		for labelString, seen := range nodesLastSeen {
@@ -174,3 +250,85 @@ func (cme *CostMetricEmitter) Start() {
 	}
 
 }
+
+// emitOpenCostMetrics sets the allocation, persistent volume and cluster management metrics of opencost,
+// the costs are in the currency of the custom pricing
+func (cme *CostMetricEmitter) emitOpenCostMetrics(currency string) {
+	allocations, err := cme.costModel.ContainerAllocation()
+	if err != nil {
+		klog.Errorf("Failed to get container allocation: %v", err)
+	} else {
+		pods := make(map[string]*cloudcost.ContainerAllocation)
+		for _, alloc := range allocations {
+			cme.containerCpuAlloc.set(alloc.CpuAllocation, alloc.Namespace, alloc.Pod, alloc.Container, alloc.Node, alloc.Node)
+			cme.containerRamAlloc.set(alloc.RamAllocation, alloc.Namespace, alloc.Pod, alloc.Container, alloc.Node, alloc.Node)
+			cme.containerGpuAlloc.set(alloc.GpuAllocation, alloc.Namespace, alloc.Pod, alloc.Container, alloc.Node, alloc.Node)
+			pods[alloc.Namespace+"/"+alloc.Pod] = alloc
+		}
+		cme.containerCpuAlloc.flush()
+		cme.containerRamAlloc.flush()
+		cme.containerGpuAlloc.flush()
+
+		pvs, err := cme.costModel.GetPersistentVolumesCost()
+		if err != nil {
+			klog.Errorf("Failed to get persistent volumes cost: %v", err)
+		} else {
+			for _, pv := range pvs {
+				cme.pvCost.set(pv.GBHourlyCost, cme.withCurrency(currency, pv.Name, pv.Name, pv.ProviderID)...)
+			}
+			cme.pvCost.flush()
+			for _, pod := range pods {
+				for _, claim := range pod.PersistentVolumes {
+					if pv, ok := pvs[claim.PersistentVolume]; ok {
+						cme.podPVCAlloc.set(pv.Bytes, pod.Namespace, pod.Pod, claim.Claim, claim.PersistentVolume)
+					}
+				}
+			}
+			cme.podPVCAlloc.flush()
+		}
+	}
+
+	managementCost, err := cme.costModel.GetClusterManagementCost()
+	if err != nil {
+		klog.Errorf("Failed to get cluster management cost: %v", err)
+		return
+	}
+	cme.clusterManagementCost.set(managementCost, cme.withCurrency(currency, cme.openCost.Provisioner)...)
+	cme.clusterManagementCost.flush()
+}
+
+// withCurrency appends the currency to the label values if the currency label is enabled
+func (cme *CostMetricEmitter) withCurrency(currency string, labels ...string) []string {
+	if cme.currencyLabel {
+		return append(labels, currency)
+	}
+	return labels
+}
+
+// gaugeSeries records the label values set since the last flush, the series which are not set again are deleted by the flush
+type gaugeSeries struct {
+	gv      *prometheus.GaugeVec
+	seen    map[string][]string
+	current map[string][]string
+}
+
+func newGaugeSeries(gv *prometheus.GaugeVec) *gaugeSeries {
+	return &gaugeSeries{gv: gv, seen: make(map[string][]string), current: make(map[string][]string)}
+}
+
+func (g *gaugeSeries) set(value float64, labels ...string) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		value = 0
+	}
+	g.gv.WithLabelValues(labels...).Set(value)
+	g.current[strings.Join(labels, ",")] = labels
+}
+
+func (g *gaugeSeries) flush() {
+	for key, labels := range g.seen {
+		if _, ok := g.current[key]; !ok {
+			g.gv.DeleteLabelValues(labels...)
+		}
+	}
+	g.seen, g.current = g.current, make(map[string][]string)
+}
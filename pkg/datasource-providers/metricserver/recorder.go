@@ -0,0 +1,578 @@
+package metricserver
+
+import (
+	"compress/gzip"
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+	resourceclient "k8s.io/metrics/pkg/client/clientset/versioned/typed/metrics/v1beta1"
+
+	"github.com/gocrane/crane/pkg/common"
+
+	"github.com/gocrane/fadvisor/pkg/consts"
+	"github.com/gocrane/fadvisor/pkg/datasource"
+	"github.com/gocrane/fadvisor/pkg/metricnaming"
+	"github.com/gocrane/fadvisor/pkg/metricquery"
+	"github.com/gocrane/fadvisor/pkg/querybuilder"
+)
+
+// recorderDataVersion is the version of the recorder data file
+const recorderDataVersion = 1
+
+var _ datasource.Interface = &recorder{}
+
+// seriesKey identifies a recorded series, Node is set for the node series, the others for the container series.
+// Kind and Workload are the workload controlling the pod by the owner references, they are empty if the pod has no controller.
+type seriesKey struct {
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Workload  string `json:"workload,omitempty"`
+	Container string `json:"container,omitempty"`
+	Node      string `json:"node,omitempty"`
+	Metric    string `json:"metric"`
+}
+
+// ring is a ring buffer of the samples of a series, it grows until the capacity, then the oldest sample is overwritten
+type ring struct {
+	timestamps []int64
+	values     []float64
+	capacity   int
+	// next is the index of the oldest sample once it is full, it is 0 while growing
+	next int
+}
+
+func newRing(capacity int) *ring {
+	return &ring{capacity: capacity}
+}
+
+func (r *ring) add(timestamp int64, value float64) {
+	if len(r.timestamps) < r.capacity {
+		if len(r.timestamps) == cap(r.timestamps) {
+			// grow by doubling but never beyond the capacity
+			size := 2 * len(r.timestamps)
+			if size < 8 {
+				size = 8
+			}
+			if size > r.capacity {
+				size = r.capacity
+			}
+			r.timestamps = append(make([]int64, 0, size), r.timestamps...)
+			r.values = append(make([]float64, 0, size), r.values...)
+		}
+		r.timestamps = append(r.timestamps, timestamp)
+		r.values = append(r.values, value)
+		return
+	}
+	r.timestamps[r.next] = timestamp
+	r.values[r.next] = value
+	r.next = (r.next + 1) % r.capacity
+}
+
+func (r *ring) size() int {
+	return len(r.timestamps)
+}
+
+// samples returns the samples in [start, end] in ascending order
+func (r *ring) samples(start, end int64) []common.Sample {
+	var results []common.Sample
+	for i := 0; i < r.size(); i++ {
+		idx := (r.next + i) % r.size()
+		if r.timestamps[idx] >= start && r.timestamps[idx] <= end {
+			results = append(results, common.Sample{Timestamp: r.timestamps[idx], Value: r.values[idx]})
+		}
+	}
+	return results
+}
+
+func (r *ring) latest() int64 {
+	if r.size() == 0 {
+		return 0
+	}
+	return r.timestamps[(r.next-1+r.size())%r.size()]
+}
+
+// recorder samples the resource metrics of the containers and nodes from the metrics server and the requests and limits of the containers
+// from the pods on an interval, the samples are kept in ring buffers of the retention and persisted to the data file, so the metrics server
+// datasource provides the history.
+type recorder struct {
+	*metricsServer
+	resourceClient resourceclient.MetricsV1beta1Interface
+	kubeClient     clientset.Interface
+	config         datasource.MetricServerConfig
+	capacity       int
+	now            func() time.Time
+
+	lock   sync.RWMutex
+	series map[seriesKey]*ring
+}
+
+// NewRecorderProvider return a metrics server data provider which records the metrics server on an interval to provide the history,
+// the recording stops when the context is done.
+func NewRecorderProvider(ctx gocontext.Context, restConfig *rest.Config, config *datasource.MetricServerConfig) (datasource.Interface, error) {
+	provider, err := NewProvider(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	resourceClient, err := resourceclient.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	kubeClient, err := clientset.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newRecorder(provider.(*metricsServer), resourceClient, kubeClient, *config)
+	if err != nil {
+		return nil, err
+	}
+	go r.Run(ctx)
+	return r, nil
+}
+
+func newRecorder(ms *metricsServer, resourceClient resourceclient.MetricsV1beta1Interface, kubeClient clientset.Interface, config datasource.MetricServerConfig) (*recorder, error) {
+	if config.RecorderInterval <= 0 {
+		return nil, fmt.Errorf("invalid metrics server recorder interval %v", config.RecorderInterval)
+	}
+	capacity := int(config.RecorderRetention / config.RecorderInterval)
+	if capacity <= 0 {
+		return nil, fmt.Errorf("metrics server recorder retention %v is less than the interval %v", config.RecorderRetention, config.RecorderInterval)
+	}
+	r := &recorder{
+		metricsServer:  ms,
+		resourceClient: resourceClient,
+		kubeClient:     kubeClient,
+		config:         config,
+		capacity:       capacity,
+		now:            time.Now,
+		series:         make(map[seriesKey]*ring),
+	}
+	if config.RecorderDataFile != "" {
+		if err := r.load(config.RecorderDataFile); err != nil {
+			if !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to load metrics server recorder data %v: %v", config.RecorderDataFile, err)
+			}
+		}
+	}
+	return r, nil
+}
+
+// Run records on the interval until the context is done, the samples are flushed on the flush interval
+// independently of the recording, and once more when the context is done
+func (r *recorder) Run(ctx gocontext.Context) {
+	klog.Infof("Metrics server recorder started, interval %v, retention %v", r.config.RecorderInterval, r.config.RecorderRetention)
+	if r.config.RecorderDataFile != "" {
+		go r.flushLoop(ctx)
+	}
+	wait.UntilWithContext(ctx, func(ctx gocontext.Context) {
+		if err := r.record(ctx); err != nil {
+			klog.Errorf("Failed to record metrics server: %v", err)
+		}
+	}, r.config.RecorderInterval)
+	if r.config.RecorderDataFile != "" {
+		if err := r.flush(r.config.RecorderDataFile); err != nil {
+			klog.Errorf("Failed to flush metrics server recorder data: %v", err)
+		}
+	}
+}
+
+// flushLoop flushes the samples to the data file on the flush interval until the context is done
+func (r *recorder) flushLoop(ctx gocontext.Context) {
+	ticker := time.NewTicker(r.config.RecorderFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.flush(r.config.RecorderDataFile); err != nil {
+				klog.Errorf("Failed to flush metrics server recorder data: %v", err)
+			}
+		}
+	}
+}
+
+// record samples the metrics once, all the samples of a recording have the same timestamp so that they can be aggregated
+func (r *recorder) record(ctx gocontext.Context) error {
+	timestamp := r.now().Unix()
+	samples := make(map[seriesKey]float64)
+
+	// use resourceVersion=0 to avoid traffic for apiserver to etcd
+	pods, err := r.kubeClient.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		return fmt.Errorf("unable to list pods: %v", err)
+	}
+	replicaSets, err := r.kubeClient.AppsV1().ReplicaSets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		return fmt.Errorf("unable to list replicasets: %v", err)
+	}
+	workloads := podWorkloads(pods.Items, replicaSets.Items)
+
+	podMetrics, err := r.resourceClient.PodMetricses(metav1.NamespaceAll).List(ctx, metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		return fmt.Errorf("unable to fetch pod metrics from resource metrics API: %v", err)
+	}
+	for _, pm := range podMetrics.Items {
+		owner := workloads[types.NamespacedName{Namespace: pm.Namespace, Name: pm.Name}]
+		for _, c := range pm.Containers {
+			for _, resource := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+				if usage, ok := c.Usage[resource]; ok {
+					samples[seriesKey{Namespace: pm.Namespace, Pod: pm.Name, Kind: owner.Kind, Workload: owner.Name, Container: c.Name, Metric: resource.String()}] = float64(usage.MilliValue()) / 1000.
+				}
+			}
+		}
+	}
+	nodeMetrics, err := r.resourceClient.NodeMetricses().List(ctx, metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		return fmt.Errorf("unable to fetch node metrics from resource metrics API: %v", err)
+	}
+	for _, nm := range nodeMetrics.Items {
+		for _, resource := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			if usage, ok := nm.Usage[resource]; ok {
+				samples[seriesKey{Node: nm.Name, Metric: resource.String()}] = float64(usage.MilliValue()) / 1000.
+			}
+		}
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != v1.PodRunning {
+			continue
+		}
+		owner := workloads[types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}]
+		for _, c := range pod.Spec.Containers {
+			for metric, value := range containerResources(c) {
+				samples[seriesKey{Namespace: pod.Namespace, Pod: pod.Name, Kind: owner.Kind, Workload: owner.Name, Container: c.Name, Metric: metric}] = value
+			}
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for key, value := range samples {
+		rg, ok := r.series[key]
+		if !ok {
+			rg = newRing(r.capacity)
+			r.series[key] = rg
+		}
+		rg.add(timestamp, value)
+	}
+	// the series of the deleted pods and nodes are removed after all their samples are out of the retention
+	expired := timestamp - int64(r.config.RecorderRetention.Seconds())
+	for key, rg := range r.series {
+		if rg.latest() < expired {
+			delete(r.series, key)
+		}
+	}
+	klog.V(4).Infof("Recorded %v samples of metrics server, %v series", len(samples), len(r.series))
+	return nil
+}
+
+// workloadRef is the kind and name of the workload controlling a pod
+type workloadRef struct {
+	Kind string
+	Name string
+}
+
+// podWorkloads returns the workloads controlling the pods by the owner references, the deployment of the replicaset is resolved
+func podWorkloads(pods []v1.Pod, replicaSets []appsv1.ReplicaSet) map[types.NamespacedName]workloadRef {
+	rsOwners := make(map[types.NamespacedName]workloadRef, len(replicaSets))
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		if ref := metav1.GetControllerOf(rs); ref != nil {
+			rsOwners[types.NamespacedName{Namespace: rs.Namespace, Name: rs.Name}] = workloadRef{Kind: ref.Kind, Name: ref.Name}
+		}
+	}
+	results := make(map[types.NamespacedName]workloadRef, len(pods))
+	for i := range pods {
+		pod := &pods[i]
+		ref := metav1.GetControllerOf(pod)
+		if ref == nil {
+			continue
+		}
+		owner := workloadRef{Kind: ref.Kind, Name: ref.Name}
+		if ref.Kind == "ReplicaSet" {
+			if rsOwner, ok := rsOwners[types.NamespacedName{Namespace: pod.Namespace, Name: ref.Name}]; ok {
+				owner = rsOwner
+			}
+		}
+		results[types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}] = owner
+	}
+	return results
+}
+
+// containerResources returns the requests and limits of the container
+func containerResources(c v1.Container) map[string]float64 {
+	results := make(map[string]float64)
+	set := func(metric string, list v1.ResourceList, resource v1.ResourceName) {
+		if q, ok := list[resource]; ok {
+			results[metric] = float64(q.MilliValue()) / 1000.
+		}
+	}
+	set(consts.MetricCpuRequest, c.Resources.Requests, v1.ResourceCPU)
+	set(consts.MetricCpuLimit, c.Resources.Limits, v1.ResourceCPU)
+	set(consts.MetricMemRequest, c.Resources.Requests, v1.ResourceMemory)
+	set(consts.MetricMemLimit, c.Resources.Limits, v1.ResourceMemory)
+	return results
+}
+
+func (r *recorder) QueryTimeSeries(ctx gocontext.Context, metricNamer metricnaming.MetricNamer, startTime time.Time, endTime time.Time, step time.Duration) ([]*common.TimeSeries, error) {
+	msQuery, err := metricNamer.QueryBuilder().Builder(metricquery.MetricServerMetricSource).BuildQuery(querybuilder.BuildQueryBehavior{})
+	if err != nil {
+		klog.Errorf("Failed to QueryTimeSeries metricNamer %v, err: %v", metricNamer.BuildUniqueKey(), err)
+		return nil, err
+	}
+	metric := msQuery.MetricServer.Metric
+	metricName := strings.ToLower(metric.MetricName)
+	start, end := startTime.Unix(), endTime.Unix()
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	var results []*common.TimeSeries
+	switch metric.Type {
+	case metricquery.NodeMetricType:
+		if metric.Node == nil {
+			return nil, metricquery.NotMatchNodeError
+		}
+		if rg, ok := r.series[seriesKey{Node: metric.Node.Name, Metric: metricName}]; ok {
+			results = appendSeries(results, r.align(rg.samples(start, end), startTime, endTime, step), consts.LabelNode, metric.Node.Name)
+		}
+	case metricquery.PodMetricType:
+		if metric.Pod == nil {
+			return nil, metricquery.NotMatchPodError
+		}
+		samples := r.aggregate(start, end, metricName, sum, func(key seriesKey) bool {
+			return key.Namespace == metric.Pod.Namespace && key.Pod == metric.Pod.Name
+		})
+		results = appendSeries(results, r.align(samples, startTime, endTime, step), consts.LabelNamespace, metric.Pod.Namespace, consts.LabelPodName, metric.Pod.Name)
+	case metricquery.ContainerMetricType:
+		if metric.Container == nil {
+			return nil, metricquery.NotMatchContainerError
+		}
+		c := metric.Container
+		matched := func(key seriesKey) bool {
+			return key.Namespace == c.Namespace && key.Container == c.ContainerName && ownedBy(key, c.Kind, c.WorkloadName)
+		}
+		if metricName == v1.ResourceCPU.String() || metricName == v1.ResourceMemory.String() {
+			// the usage of each pod of the workload
+			for _, key := range r.matchedSeries(metricName, matched) {
+				results = appendSeries(results, r.align(r.series[key].samples(start, end), startTime, endTime, step),
+					consts.LabelNamespace, c.Namespace, consts.LabelPodName, key.Pod, consts.LabelContainerName, c.ContainerName)
+			}
+		} else {
+			samples := r.aggregate(start, end, metricName, avg, matched)
+			results = appendSeries(results, r.align(samples, startTime, endTime, step),
+				consts.LabelNamespace, c.Namespace, consts.LabelWorkloadName, c.WorkloadName, consts.LabelContainerName, c.ContainerName)
+		}
+	case metricquery.WorkloadMetricType:
+		if metric.Workload == nil {
+			return nil, metricquery.NotMatchWorkloadError
+		}
+		w := metric.Workload
+		matched := func(key seriesKey) bool {
+			return key.Namespace == w.Namespace && ownedBy(key, w.Kind, w.Name)
+		}
+		var samples []common.Sample
+		if metricName == consts.MetricWorkloadReplicas {
+			// the pods which have usage are running
+			samples = r.aggregate(start, end, v1.ResourceCPU.String(), countPods, matched)
+		} else {
+			samples = r.aggregate(start, end, metricName, sum, matched)
+		}
+		results = appendSeries(results, r.align(samples, startTime, endTime, step),
+			consts.LabelNamespace, w.Namespace, consts.LabelWorkloadName, w.Name)
+	default:
+		return nil, fmt.Errorf("metric type %v do not support metric server recorder", metric.Type)
+	}
+	klog.V(6).Infof("QueryTimeSeries metricNamer %v, %v time series recorded", metricNamer.BuildUniqueKey(), len(results))
+	return results, nil
+}
+
+// ownedBy returns true if the pod of the series is controlled by the workload, any kind matches if the kind is not specified
+func ownedBy(key seriesKey, kind, name string) bool {
+	return key.Workload != "" && key.Workload == name && (kind == "" || strings.EqualFold(key.Kind, kind))
+}
+
+// matchedSeries returns the keys of the matched container series of the metric sorted by pod
+func (r *recorder) matchedSeries(metricName string, matched func(key seriesKey) bool) []seriesKey {
+	var keys []seriesKey
+	for key := range r.series {
+		if key.Node == "" && key.Metric == metricName && matched(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Pod < keys[j].Pod })
+	return keys
+}
+
+type aggregation func(values []float64, pods int) float64
+
+func sum(values []float64, pods int) float64 {
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func avg(values []float64, pods int) float64 {
+	return sum(values, pods) / float64(len(values))
+}
+
+func countPods(values []float64, pods int) float64 {
+	return float64(pods)
+}
+
+// aggregate aggregates the samples of the matched container series of the metric by timestamp
+func (r *recorder) aggregate(start, end int64, metricName string, agg aggregation, matched func(key seriesKey) bool) []common.Sample {
+	values := make(map[int64][]float64)
+	pods := make(map[int64]map[string]bool)
+	for key, rg := range r.series {
+		if key.Node != "" || key.Metric != metricName || !matched(key) {
+			continue
+		}
+		for _, s := range rg.samples(start, end) {
+			values[s.Timestamp] = append(values[s.Timestamp], s.Value)
+			if pods[s.Timestamp] == nil {
+				pods[s.Timestamp] = make(map[string]bool)
+			}
+			pods[s.Timestamp][key.Pod] = true
+		}
+	}
+	results := make([]common.Sample, 0, len(values))
+	for timestamp, vs := range values {
+		results = append(results, common.Sample{Timestamp: timestamp, Value: agg(vs, len(pods[timestamp]))})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Timestamp < results[j].Timestamp })
+	return results
+}
+
+// align aligns the samples to the steps as the range query does, a step takes the latest sample in the lookback of it
+func (r *recorder) align(samples []common.Sample, startTime, endTime time.Time, step time.Duration) []common.Sample {
+	if step <= 0 {
+		return samples
+	}
+	lookback := 2 * r.config.RecorderInterval
+	if step > lookback {
+		lookback = step
+	}
+	var results []common.Sample
+	i := 0
+	for point := startTime; !point.After(endTime); point = point.Add(step) {
+		for i < len(samples) && samples[i].Timestamp <= point.Unix() {
+			i++
+		}
+		if i > 0 && point.Unix()-samples[i-1].Timestamp < int64(lookback.Seconds()) {
+			results = append(results, common.Sample{Timestamp: point.Unix(), Value: samples[i-1].Value})
+		}
+	}
+	return results
+}
+
+func appendSeries(tsList []*common.TimeSeries, samples []common.Sample, labels ...string) []*common.TimeSeries {
+	if len(samples) == 0 {
+		return tsList
+	}
+	ts := common.NewTimeSeries()
+	for i := 0; i+1 < len(labels); i += 2 {
+		ts.AppendLabel(labels[i], labels[i+1])
+	}
+	ts.SetSamples(samples)
+	return append(tsList, ts)
+}
+
+type recorderData struct {
+	Version int                `json:"version"`
+	Series  []recorderDataItem `json:"series"`
+}
+
+type recorderDataItem struct {
+	Key        seriesKey `json:"key"`
+	Timestamps []int64   `json:"timestamps"`
+	Values     []float64 `json:"values"`
+}
+
+// flush writes the samples to a temporary file and renames it to the data file
+func (r *recorder) flush(file string) error {
+	data := recorderData{Version: recorderDataVersion}
+	r.lock.RLock()
+	for key, rg := range r.series {
+		item := recorderDataItem{Key: key}
+		for _, s := range rg.samples(0, rg.latest()) {
+			item.Timestamps = append(item.Timestamps, s.Timestamp)
+			item.Values = append(item.Values, s.Value)
+		}
+		data.Series = append(data.Series, item)
+	}
+	r.lock.RUnlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	zw := gzip.NewWriter(tmp)
+	if err = json.NewEncoder(zw).Encode(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	klog.V(4).Infof("Flushed %v series of metrics server recorder to %v", len(data.Series), file)
+	return os.Rename(tmp.Name(), file)
+}
+
+// load loads the samples of the data file, the samples out of the retention are dropped
+func (r *recorder) load(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	var data recorderData
+	if err = json.NewDecoder(zr).Decode(&data); err != nil {
+		return err
+	}
+	if data.Version != recorderDataVersion {
+		return fmt.Errorf("unsupported data version %v, expected %v", data.Version, recorderDataVersion)
+	}
+	expired := r.now().Add(-r.config.RecorderRetention).Unix()
+	for _, item := range data.Series {
+		if len(item.Timestamps) != len(item.Values) {
+			return fmt.Errorf("series %+v has %v timestamps but %v values", item.Key, len(item.Timestamps), len(item.Values))
+		}
+		rg := newRing(r.capacity)
+		for i := range item.Timestamps {
+			if item.Timestamps[i] >= expired {
+				rg.add(item.Timestamps[i], item.Values[i])
+			}
+		}
+		if rg.size() > 0 {
+			r.series[item.Key] = rg
+		}
+	}
+	klog.Infof("Loaded %v series of metrics server recorder from %v", len(r.series), file)
+	return nil
+}
@@ -0,0 +1,202 @@
+package metricserver
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	metricsapi "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+
+	"github.com/gocrane/fadvisor/pkg/consts"
+	"github.com/gocrane/fadvisor/pkg/datasource"
+	"github.com/gocrane/fadvisor/pkg/metricnaming"
+	// the metric server builder of the metric namers
+	_ "github.com/gocrane/fadvisor/pkg/querybuilder-providers/metricserver"
+)
+
+func podMetrics(name string, cpu string) runtime.Object {
+	return &metricsapi.PodMetrics{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Containers: []metricsapi.ContainerMetrics{{
+			Name:  "nginx",
+			Usage: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse("100Mi")},
+		}},
+	}
+}
+
+func controllerRef(kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+}
+
+func replicaSet(name, deployment string) runtime.Object {
+	return &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, OwnerReferences: controllerRef("Deployment", deployment)}}
+}
+
+func runningPod(name, replicaSet string) runtime.Object {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, OwnerReferences: controllerRef("ReplicaSet", replicaSet)},
+		Spec: v1.PodSpec{Containers: []v1.Container{{
+			Name: "nginx",
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")},
+				Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+			},
+		}}},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	metricsClient := metricsfake.NewSimpleClientset()
+	// the resources of the metrics are pods and nodes, they can not be guessed from the kinds
+	for _, obj := range []runtime.Object{podMetrics("web-1", "200m"), podMetrics("web-2", "300m"), podMetrics("web-api-1", "1"), podMetrics("other-1", "1")} {
+		if err = metricsClient.Tracker().Create(metricsapi.SchemeGroupVersion.WithResource("pods"), obj, "default"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = metricsClient.Tracker().Create(metricsapi.SchemeGroupVersion.WithResource("nodes"), &metricsapi.NodeMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Usage:      v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("4Gi")},
+	}, ""); err != nil {
+		t.Fatal(err)
+	}
+	// the pods of the deployment web-api have the prefix web-, they are not of the deployment web
+	kubeClient := kubefake.NewSimpleClientset(runningPod("web-1", "web-5d4f8"), runningPod("web-2", "web-5d4f8"), runningPod("web-api-1", "web-api-6c7b9"),
+		replicaSet("web-5d4f8", "web"), replicaSet("web-api-6c7b9", "web-api"))
+
+	config := datasource.MetricServerConfig{
+		RecorderEnabled:   true,
+		RecorderInterval:  time.Minute,
+		RecorderRetention: 10 * time.Minute,
+		RecorderDataFile:  filepath.Join(dir, "recorder.json.gz"),
+	}
+	r, err := newRecorder(&metricsServer{}, metricsClient.MetricsV1beta1(), kubeClient, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the samples are recent so that they are in the retention when they are loaded
+	base := time.Now().Truncate(time.Minute).Add(-14 * time.Minute)
+	now := base
+	r.now = func() time.Time { return now }
+	// record 15 times, the samples older than the retention are overwritten
+	for i := 0; i < 15; i++ {
+		if err = r.record(context.TODO()); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Minute)
+	}
+
+	start, end := base, now
+	workload := &v1.ObjectReference{Kind: "Deployment", Namespace: "default", Name: "web"}
+	tsList, err := r.QueryTimeSeries(context.TODO(), metricnaming.ResourceToWorkloadMetricNamer("cls-1", workload, v1.ResourceCPU, labels.Everything()), start, end, 2*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tsList) != 1 {
+		t.Fatalf("expect 1 series, got %v", len(tsList))
+	}
+	// the samples of the last 10 minutes are kept, aligned by 2 minutes
+	if got := len(tsList[0].Samples); got != 5 {
+		t.Errorf("expect 5 samples, got %v: %v", got, tsList[0].Samples)
+	}
+	if got := tsList[0].Samples[0].Value; got != 0.5 {
+		t.Errorf("expect workload cpu 0.5, got %v", got)
+	}
+
+	tsList, err = r.QueryTimeSeries(context.TODO(), metricnaming.ResourceToContainerMetricNamer("cls-1", "default", "web", "nginx", v1.ResourceCPU), start, end, time.Minute)
+	if err != nil || len(tsList) != 2 {
+		t.Fatalf("expect 2 container series of the pods, got %v, %v", len(tsList), err)
+	}
+	tsList, err = r.QueryTimeSeries(context.TODO(), metricnaming.ContainerMetricNamer("cls-1", "Deployment", "default", "web", "nginx", consts.MetricCpuLimit, labels.Everything()), start, end, time.Minute)
+	if err != nil || len(tsList) != 1 || tsList[0].Samples[0].Value != 1 {
+		t.Fatalf("expect container cpu limit 1, got %v, %v", tsList, err)
+	}
+	tsList, err = r.QueryTimeSeries(context.TODO(), metricnaming.WorkloadMetricNamer("cls-1", workload, consts.MetricWorkloadReplicas, labels.Everything()), start, end, time.Minute)
+	if err != nil || len(tsList) != 1 || tsList[0].Samples[0].Value != 2 {
+		t.Fatalf("expect 2 replicas, got %v, %v", tsList, err)
+	}
+
+	// the samples are loaded from the data file
+	if err = r.flush(config.RecorderDataFile); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := newRecorder(&metricsServer{}, metricsClient.MetricsV1beta1(), kubeClient, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded.now = r.now
+	// end at the last recording
+	end = now.Add(-time.Minute)
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	tsList, err = loaded.QueryTimeSeries(context.TODO(), metricnaming.ResourceToNodeMetricNamer("cls-1", node, v1.ResourceMemory), start, end, time.Minute)
+	if err != nil || len(tsList) != 1 {
+		t.Fatalf("expect 1 node series, got %v, %v", tsList, err)
+	}
+	if got := len(tsList[0].Samples); got != 10 {
+		t.Errorf("expect 10 samples, got %v", got)
+	}
+	if got := tsList[0].Samples[0].Value; got != 4*1024*1024*1024 {
+		t.Errorf("expect node memory 4Gi, got %v", got)
+	}
+}
+
+func TestRing(t *testing.T) {
+	rg := newRing(20)
+	for i := int64(1); i <= 3; i++ {
+		rg.add(i, float64(i))
+	}
+	// it grows lazily
+	if cap(rg.timestamps) >= 20 || rg.latest() != 3 {
+		t.Errorf("expect the ring grows lazily, got capacity %v, latest %v", cap(rg.timestamps), rg.latest())
+	}
+	for i := int64(4); i <= 25; i++ {
+		rg.add(i, float64(i))
+	}
+	samples := rg.samples(0, 100)
+	if cap(rg.timestamps) != 20 || len(samples) != 20 || samples[0].Timestamp != 6 || samples[19].Timestamp != 25 || rg.latest() != 25 {
+		t.Errorf("expect the latest 20 samples in order, got %v", samples)
+	}
+}
+
+func TestRunFlushes(t *testing.T) {
+	config := datasource.MetricServerConfig{
+		RecorderEnabled:       true,
+		RecorderInterval:      time.Hour,
+		RecorderRetention:     24 * time.Hour,
+		RecorderFlushInterval: 10 * time.Millisecond,
+		RecorderDataFile:      filepath.Join(t.TempDir(), "recorder.json.gz"),
+	}
+	r, err := newRecorder(&metricsServer{}, metricsfake.NewSimpleClientset().MetricsV1beta1(), kubefake.NewSimpleClientset(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+	// the data file is flushed on the flush interval while the recorder is running
+	if err = wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		_, err := os.Stat(config.RecorderDataFile)
+		return err == nil, nil
+	}); err != nil {
+		t.Fatalf("expect the data file flushed while running: %v", err)
+	}
+}
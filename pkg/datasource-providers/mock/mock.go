@@ -0,0 +1,198 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/common"
+
+	"github.com/gocrane/fadvisor/pkg/consts"
+	"github.com/gocrane/fadvisor/pkg/datasource"
+	"github.com/gocrane/fadvisor/pkg/metricnaming"
+	"github.com/gocrane/fadvisor/pkg/metricquery"
+	"github.com/gocrane/fadvisor/pkg/querybuilder"
+	// the mock provider always queries with the mock builder
+	_ "github.com/gocrane/fadvisor/pkg/querybuilder-providers/mock"
+)
+
+var _ datasource.Interface = &mock{}
+
+// series is a time series of the seed files, Type is the metric type of the query it answers, empty Type answers all types
+type series struct {
+	Type       metricquery.MetricType
+	MetricName string
+	Labels     map[string]string
+	Samples    []common.Sample
+}
+
+// mock answers the queries from the time series of the seed files, it is for reproducible runs and demonstration without a real monitoring system
+type mock struct {
+	series []*series
+}
+
+// NewProvider return a mock data provider which loads the time series from the seed file, the seed file is a csv, json or openmetrics file, or a directory of them
+func NewProvider(config *datasource.MockConfig) (datasource.Interface, error) {
+	seriesList, err := loadSeedFile(config.SeedFile)
+	if err != nil {
+		return nil, err
+	}
+	klog.Infof("Loaded %v time series from mock seed file %v", len(seriesList), config.SeedFile)
+	return &mock{series: seriesList}, nil
+}
+
+func (m *mock) QueryTimeSeries(ctx context.Context, metricNamer metricnaming.MetricNamer, startTime time.Time, endTime time.Time, step time.Duration) ([]*common.TimeSeries, error) {
+	metric, err := buildMetric(metricNamer)
+	if err != nil {
+		return nil, err
+	}
+	var results []*common.TimeSeries
+	for _, s := range m.match(metric) {
+		ts := newTimeSeries(s)
+		for _, sample := range s.Samples {
+			if sample.Timestamp >= startTime.Unix() && sample.Timestamp <= endTime.Unix() {
+				ts.Samples = append(ts.Samples, sample)
+			}
+		}
+		if len(ts.Samples) > 0 {
+			results = append(results, ts)
+		}
+	}
+	klog.V(6).Infof("QueryTimeSeries metricNamer %v, %v time series matched", metricNamer.BuildUniqueKey(), len(results))
+	return results, nil
+}
+
+func (m *mock) QueryLatestTimeSeries(ctx context.Context, metricNamer metricnaming.MetricNamer) ([]*common.TimeSeries, error) {
+	metric, err := buildMetric(metricNamer)
+	if err != nil {
+		return nil, err
+	}
+	var results []*common.TimeSeries
+	for _, s := range m.match(metric) {
+		if len(s.Samples) == 0 {
+			continue
+		}
+		ts := newTimeSeries(s)
+		ts.Samples = append(ts.Samples, s.Samples[len(s.Samples)-1])
+		results = append(results, ts)
+	}
+	klog.V(6).Infof("QueryLatestTimeSeries metricNamer %v, %v time series matched", metricNamer.BuildUniqueKey(), len(results))
+	return results, nil
+}
+
+func buildMetric(metricNamer metricnaming.MetricNamer) (*metricquery.Metric, error) {
+	query, err := metricNamer.QueryBuilder().Builder(metricquery.MockMetricSource).BuildQuery(querybuilder.BuildQueryBehavior{})
+	if err != nil {
+		klog.Errorf("Failed to build mock query of metricNamer %v, err: %v", metricNamer.BuildUniqueKey(), err)
+		return nil, err
+	}
+	if err = query.Mock.Metric.ValidateMetric(); err != nil {
+		return nil, err
+	}
+	return query.Mock.Metric, nil
+}
+
+// match returns the series of the metric type and name, the identity labels and the selector of the metric must be satisfied.
+// The labels which the series doesn't have are not checked, so a series without labels answers the metric of all objects.
+func (m *mock) match(metric *metricquery.Metric) []*series {
+	identity, selector := identityLabels(metric)
+	var results []*series
+	for _, s := range m.series {
+		if s.Type != "" && s.Type != metric.Type {
+			continue
+		}
+		if !strings.EqualFold(s.MetricName, metric.MetricName) {
+			continue
+		}
+		if matchLabels(s.Labels, identity, selector) {
+			results = append(results, s)
+		}
+	}
+	return results
+}
+
+func identityLabels(metric *metricquery.Metric) (map[string]string, labels.Selector) {
+	identity := make(map[string]string)
+	set := func(key, value string) {
+		if value != "" {
+			identity[key] = value
+		}
+	}
+	var selector labels.Selector
+	switch metric.Type {
+	case metricquery.WorkloadMetricType:
+		set(consts.LabelNamespace, metric.Workload.Namespace)
+		set(consts.LabelWorkloadKind, metric.Workload.Kind)
+		set(consts.LabelWorkloadName, metric.Workload.Name)
+		selector = metric.Workload.Selector
+	case metricquery.ContainerMetricType:
+		set(consts.LabelNamespace, metric.Container.Namespace)
+		set(consts.LabelWorkloadKind, metric.Container.Kind)
+		set(consts.LabelWorkloadName, metric.Container.WorkloadName)
+		set(consts.LabelContainerName, metric.Container.ContainerName)
+		selector = metric.Container.Selector
+	case metricquery.PodMetricType:
+		set(consts.LabelNamespace, metric.Pod.Namespace)
+		set(consts.LabelPodName, metric.Pod.Name)
+		selector = metric.Pod.Selector
+	case metricquery.NodeMetricType:
+		set(consts.LabelNode, metric.Node.Name)
+		selector = metric.Node.Selector
+	case metricquery.PromQLMetricType:
+		set(consts.LabelNamespace, metric.Prom.Namespace)
+		selector = metric.Prom.Selector
+	}
+	return identity, selector
+}
+
+func matchLabels(seriesLabels, identity map[string]string, selector labels.Selector) bool {
+	for key, value := range identity {
+		if v, ok := seriesLabels[key]; ok && v != value {
+			return false
+		}
+	}
+	if selector == nil {
+		return true
+	}
+	requirements, _ := selector.Requirements()
+	for _, r := range requirements {
+		if _, ok := seriesLabels[r.Key()]; !ok {
+			continue
+		}
+		if !r.Matches(labels.Set(seriesLabels)) {
+			return false
+		}
+	}
+	return true
+}
+
+func newTimeSeries(s *series) *common.TimeSeries {
+	ts := common.NewTimeSeries()
+	names := make([]string, 0, len(s.Labels))
+	for name := range s.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ts.AppendLabel(name, s.Labels[name])
+	}
+	return ts
+}
+
+func (s *series) key() string {
+	names := make([]string, 0, len(s.Labels))
+	for name := range s.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", name, s.Labels[name]))
+	}
+	return strings.Join([]string{string(s.Type), strings.ToLower(s.MetricName), strings.Join(pairs, ",")}, "/")
+}
@@ -0,0 +1,146 @@
+package mock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/gocrane/fadvisor/pkg/datasource"
+	"github.com/gocrane/fadvisor/pkg/metricnaming"
+)
+
+const seedCsv = `type,metric,timestamp,value,namespace,workload_name,container_name
+container,cpu,100,0.5,default,web,nginx
+container,cpu,160,0.7,default,web,nginx
+container,cpu,100,1.5,default,db,mysql
+`
+
+const seedJson = `[
+  {"type": "node", "metric": "cpu", "labels": {"node": "node-1"}, "samples": [{"timestamp": 100, "value": 2}, {"timestamp": 160, "value": 3}]}
+]`
+
+const seedOpenMetrics = `# TYPE replicas gauge
+replicas{metric_type="workload",namespace="default",workload_kind="Deployment",workload_name="web"} 3 100
+replicas{metric_type="workload",namespace="default",workload_kind="Deployment",workload_name="web"} 4 160.5
+node_count{instance_type="S5.LARGE8"} 10 100
+# EOF
+`
+
+func newProvider(t *testing.T) datasource.Interface {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"seed.csv": seedCsv, "seed.json": seedJson, "seed.om": seedOpenMetrics, "README.md": "ignored"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	provider, err := NewProvider(&datasource.MockConfig{SeedFile: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestQueryTimeSeries(t *testing.T) {
+	provider := newProvider(t)
+	start, end := time.Unix(0, 0), time.Unix(200, 0)
+
+	tests := []struct {
+		name    string
+		namer   metricnaming.MetricNamer
+		start   time.Time
+		samples []float64
+	}{
+		{
+			name:    "container",
+			namer:   metricnaming.ResourceToContainerMetricNamer("cls-1", "default", "web", "nginx", v1.ResourceCPU),
+			start:   start,
+			samples: []float64{0.5, 0.7},
+		},
+		{
+			name:    "container time range",
+			namer:   metricnaming.ResourceToContainerMetricNamer("cls-1", "default", "web", "nginx", v1.ResourceCPU),
+			start:   time.Unix(150, 0),
+			samples: []float64{0.7},
+		},
+		{
+			name:  "container not found",
+			namer: metricnaming.ResourceToContainerMetricNamer("cls-1", "default", "web", "sidecar", v1.ResourceCPU),
+			start: start,
+		},
+		{
+			name:    "node",
+			namer:   metricnaming.ResourceToNodeMetricNamer("cls-1", &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}, v1.ResourceCPU),
+			start:   start,
+			samples: []float64{2, 3},
+		},
+		{
+			name:    "workload",
+			namer:   metricnaming.WorkloadMetricNamer("cls-1", &v1.ObjectReference{Kind: "Deployment", Namespace: "default", Name: "web"}, "replicas", labels.Everything()),
+			start:   start,
+			samples: []float64{3, 4},
+		},
+		{
+			name:    "promql without type",
+			namer:   metricnaming.QueryExprMetricNamer("cls-1", "node_count", "count(kube_node_labels)"),
+			start:   start,
+			samples: []float64{10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tsList, err := provider.QueryTimeSeries(context.TODO(), tt.namer, tt.start, end, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var samples []float64
+			for _, ts := range tsList {
+				for _, sample := range ts.Samples {
+					samples = append(samples, sample.Value)
+				}
+			}
+			if len(samples) != len(tt.samples) {
+				t.Fatalf("expected samples %v, got %v", tt.samples, samples)
+			}
+			for i := range samples {
+				if samples[i] != tt.samples[i] {
+					t.Fatalf("expected samples %v, got %v", tt.samples, samples)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryLatestTimeSeries(t *testing.T) {
+	provider := newProvider(t)
+	namer := metricnaming.ResourceToContainerMetricNamer("cls-1", "default", "web", "nginx", v1.ResourceCPU)
+	tsList, err := provider.QueryLatestTimeSeries(context.TODO(), namer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tsList) != 1 || len(tsList[0].Samples) != 1 || tsList[0].Samples[0].Timestamp != 160 || tsList[0].Samples[0].Value != 0.7 {
+		t.Fatalf("unexpected latest time series %+v", tsList)
+	}
+}
+
+func TestLoadSeedFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"missing-column.csv": "metric,timestamp,value\ncpu,100,1\n",
+		"no-timestamp.om":    "cpu{node=\"node-1\"} 1\n",
+		"unknown.yaml":       "",
+	} {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadSeedFile(file); err == nil {
+			t.Errorf("expected error of %v", name)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package mock
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gocrane/crane/pkg/common"
+
+	"github.com/gocrane/fadvisor/pkg/metricquery"
+)
+
+// column names of the csv seed file, the other columns are labels
+const (
+	columnType      = "type"
+	columnMetric    = "metric"
+	columnTimestamp = "timestamp"
+	columnValue     = "value"
+)
+
+// LabelMetricType is the label of the metric type in the openmetrics seed file, it is not a label of the series
+const LabelMetricType = "metric_type"
+
+// jsonSeries is the series of the json seed file
+type jsonSeries struct {
+	Type    metricquery.MetricType `json:"type"`
+	Metric  string                 `json:"metric"`
+	Labels  map[string]string      `json:"labels"`
+	Samples []struct {
+		Timestamp int64   `json:"timestamp"`
+		Value     float64 `json:"value"`
+	} `json:"samples"`
+}
+
+// loadSeedFile loads the time series of the seed file by the file extension, all the seed files are loaded if it is a directory.
+// The series of the same type, metric name and labels are merged.
+func loadSeedFile(path string) ([]*series, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && seedFormat(entry.Name()) != "" {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	merged := make(map[string]*series)
+	for _, file := range files {
+		seriesList, err := readSeedFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load mock seed file %v: %v", file, err)
+		}
+		for _, s := range seriesList {
+			if existing, ok := merged[s.key()]; ok {
+				existing.Samples = append(existing.Samples, s.Samples...)
+			} else {
+				merged[s.key()] = s
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(merged))
+	for key := range merged {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	results := make([]*series, 0, len(keys))
+	for _, key := range keys {
+		s := merged[key]
+		s.Samples = sortedSamples(s.Samples)
+		results = append(results, s)
+	}
+	return results, nil
+}
+
+func seedFormat(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".csv":
+		return "csv"
+	case ".json":
+		return "json"
+	case ".om", ".prom", ".txt":
+		return "openmetrics"
+	}
+	return ""
+}
+
+func readSeedFile(file string) ([]*series, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch seedFormat(file) {
+	case "csv":
+		return readCsv(f)
+	case "json":
+		return readJson(f)
+	case "openmetrics":
+		return readOpenMetrics(f)
+	}
+	return nil, fmt.Errorf("unknown seed file format, csv, json and openmetrics(.om, .prom, .txt) are supported")
+}
+
+// readCsv reads the csv with the header, one sample each row. The columns type, metric, timestamp and value are required, the other columns are labels, and the empty label is ignored
+func readCsv(r io.Reader) ([]*series, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %v", err)
+	}
+	index := make(map[string]int)
+	for i, column := range header {
+		index[strings.TrimSpace(column)] = i
+	}
+	for _, column := range []string{columnType, columnMetric, columnTimestamp, columnValue} {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("column %v is required", column)
+		}
+	}
+
+	seriesMap := make(map[string]*series)
+	var results []*series
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		timestamp, err := strconv.ParseInt(record[index[columnTimestamp]], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: invalid timestamp: %v", line, err)
+		}
+		value, err := strconv.ParseFloat(record[index[columnValue]], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: invalid value: %v", line, err)
+		}
+		s := &series{Type: metricquery.MetricType(record[index[columnType]]), MetricName: record[index[columnMetric]], Labels: make(map[string]string)}
+		for i, column := range header {
+			switch column {
+			case columnType, columnMetric, columnTimestamp, columnValue:
+				continue
+			}
+			if record[i] != "" {
+				s.Labels[column] = record[i]
+			}
+		}
+		if existing, ok := seriesMap[s.key()]; ok {
+			s = existing
+		} else {
+			seriesMap[s.key()] = s
+			results = append(results, s)
+		}
+		s.Samples = append(s.Samples, common.Sample{Timestamp: timestamp, Value: value})
+	}
+	return results, nil
+}
+
+// readJson reads the json list of the series, each has the type, metric, labels and samples
+func readJson(r io.Reader) ([]*series, error) {
+	var list []jsonSeries
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, err
+	}
+	results := make([]*series, 0, len(list))
+	for _, item := range list {
+		s := &series{Type: item.Type, MetricName: item.Metric, Labels: item.Labels}
+		if s.Labels == nil {
+			s.Labels = make(map[string]string)
+		}
+		for _, sample := range item.Samples {
+			s.Samples = append(s.Samples, common.Sample{Timestamp: sample.Timestamp, Value: sample.Value})
+		}
+		results = append(results, s)
+	}
+	return results, nil
+}
+
+// readOpenMetrics reads the samples of the openmetrics text format, the timestamp in seconds is required.
+// The metric type is the label metric_type, the series answers all types if it is not set.
+func readOpenMetrics(r io.Reader) ([]*series, error) {
+	seriesMap := make(map[string]*series)
+	var results []*series
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1<<20), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		s, sample, err := parseOpenMetricsLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", line, err)
+		}
+		if existing, ok := seriesMap[s.key()]; ok {
+			s = existing
+		} else {
+			seriesMap[s.key()] = s
+			results = append(results, s)
+		}
+		s.Samples = append(s.Samples, sample)
+	}
+	return results, scanner.Err()
+}
+
+func parseOpenMetricsLine(text string) (*series, common.Sample, error) {
+	var sample common.Sample
+	s := &series{Labels: make(map[string]string)}
+	i := strings.IndexAny(text, "{ ")
+	if i < 0 {
+		return nil, sample, fmt.Errorf("no value")
+	}
+	s.MetricName = text[:i]
+	rest := text[i:]
+
+	if strings.HasPrefix(rest, "{") {
+		end, err := parseLabels(rest, s.Labels)
+		if err != nil {
+			return nil, sample, err
+		}
+		rest = rest[end:]
+	}
+	if metricType, ok := s.Labels[LabelMetricType]; ok {
+		s.Type = metricquery.MetricType(metricType)
+		delete(s.Labels, LabelMetricType)
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) < 2 {
+		return nil, sample, fmt.Errorf("value and timestamp are required")
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, sample, fmt.Errorf("invalid value: %v", err)
+	}
+	timestamp, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, sample, fmt.Errorf("invalid timestamp: %v", err)
+	}
+	sample.Value = value
+	sample.Timestamp = int64(math.Floor(timestamp))
+	return s, sample, nil
+}
+
+// parseLabels parses the labels in braces, returns the index after the closing brace
+func parseLabels(text string, labels map[string]string) (int, error) {
+	i := 1
+	for {
+		for i < len(text) && (text[i] == ' ' || text[i] == ',') {
+			i++
+		}
+		if i >= len(text) {
+			return 0, fmt.Errorf("unclosed labels")
+		}
+		if text[i] == '}' {
+			return i + 1, nil
+		}
+		eq := strings.IndexByte(text[i:], '=')
+		if eq < 0 {
+			return 0, fmt.Errorf("invalid labels %v", text)
+		}
+		name := strings.TrimSpace(text[i : i+eq])
+		i += eq + 1
+		if i >= len(text) || text[i] != '"' {
+			return 0, fmt.Errorf("label %v value is not quoted", name)
+		}
+		var value strings.Builder
+		i++
+		for ; i < len(text) && text[i] != '"'; i++ {
+			if text[i] == '\\' && i+1 < len(text) {
+				i++
+				switch text[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(text[i])
+				}
+				continue
+			}
+			value.WriteByte(text[i])
+		}
+		if i >= len(text) {
+			return 0, fmt.Errorf("label %v value is not closed", name)
+		}
+		i++
+		labels[name] = value.String()
+	}
+}
+
+// sortedSamples sorts the samples by timestamp, the last sample of the same timestamp wins
+func sortedSamples(samples []common.Sample) []common.Sample {
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Timestamp < samples[j].Timestamp
+	})
+	results := samples[:0]
+	for _, sample := range samples {
+		if len(results) > 0 && results[len(results)-1].Timestamp == sample.Timestamp {
+			results[len(results)-1] = sample
+			continue
+		}
+		results = append(results, sample)
+	}
+	return results
+}
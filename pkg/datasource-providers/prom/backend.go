@@ -0,0 +1,216 @@
+package prom
+
+import (
+	"bufio"
+	"bytes"
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	prometheus "github.com/prometheus/client_golang/api"
+	promapiv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/common"
+
+	"github.com/gocrane/fadvisor/pkg/datasource"
+)
+
+// prometheus compatible backends
+const (
+	BackendPrometheus      = "prometheus"
+	BackendThanos          = "thanos"
+	BackendMimir           = "mimir"
+	BackendCortex          = "cortex"
+	BackendVictoriaMetrics = "victoriametrics"
+)
+
+// tenant headers of the backends
+const (
+	HeaderScopeOrgID   = "X-Scope-OrgID"
+	HeaderThanosTenant = "THANOS-TENANT"
+	HeaderAccountID    = "AccountID"
+	HeaderProjectID    = "ProjectID"
+)
+
+// the lookback of prometheus, a step point takes the latest raw sample in the lookback
+const defaultLookback = 5 * time.Minute
+
+// requestHeaders returns the custom headers and the tenant headers of the backend
+func requestHeaders(config *datasource.PromConfig) map[string]string {
+	headers := make(map[string]string)
+	for key, value := range config.Headers {
+		headers[key] = value
+	}
+	if config.TenantID == "" {
+		return headers
+	}
+	switch {
+	case config.TenantHeader != "":
+		headers[config.TenantHeader] = config.TenantID
+	case strings.ToLower(config.Backend) == BackendVictoriaMetrics:
+		parts := strings.SplitN(config.TenantID, ":", 2)
+		headers[HeaderAccountID] = parts[0]
+		if len(parts) > 1 {
+			headers[HeaderProjectID] = parts[1]
+		}
+	case strings.ToLower(config.Backend) == BackendThanos:
+		headers[HeaderThanosTenant] = config.TenantID
+	default:
+		headers[HeaderScopeOrgID] = config.TenantID
+	}
+	return headers
+}
+
+// Capabilities is the detected backend and the apis it supports
+type Capabilities struct {
+	Backend string
+	Version string
+	// Export means the victoriametrics export api is available
+	Export bool
+}
+
+// DetectCapabilities probes the backend by the build info and the backend specific apis, it is prometheus if nothing is detected
+func DetectCapabilities(ctx gocontext.Context, client prometheus.Client) Capabilities {
+	caps := Capabilities{Backend: BackendPrometheus}
+
+	if body, err := get(ctx, client, "/api/v1/status/buildinfo"); err == nil {
+		var buildInfo struct {
+			Data struct {
+				Application string `json:"application"`
+				Version     string `json:"version"`
+			} `json:"data"`
+		}
+		if err = json.Unmarshal(body, &buildInfo); err == nil {
+			caps.Version = buildInfo.Data.Version
+			application := strings.ToLower(buildInfo.Data.Application)
+			switch {
+			case strings.Contains(application, BackendMimir):
+				caps.Backend = BackendMimir
+				return caps
+			case strings.Contains(application, BackendCortex):
+				caps.Backend = BackendCortex
+				return caps
+			}
+		}
+	}
+	if _, err := get(ctx, client, "/api/v1/status/active_queries"); err == nil {
+		caps.Backend = BackendVictoriaMetrics
+		caps.Export = true
+		return caps
+	}
+	if _, err := get(ctx, client, "/api/v1/stores"); err == nil {
+		caps.Backend = BackendThanos
+	}
+	return caps
+}
+
+func get(ctx gocontext.Context, client prometheus.Client, ep string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, client.URL(ep, nil).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, body, err := client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%v returns status %v", ep, resp.StatusCode)
+	}
+	return body, nil
+}
+
+var seriesSelectorRegexp = regexp.MustCompile(`^\s*([a-zA-Z_:][a-zA-Z0-9_:]*)?\s*(\{[^{}]*\})?\s*$`)
+
+// isSeriesSelector returns true if the query is only a series selector without functions and operators, only the raw samples of it can be exported
+func isSeriesSelector(query string) bool {
+	return strings.TrimSpace(query) != "" && seriesSelectorRegexp.MatchString(query)
+}
+
+// exporter fetches the raw samples by the victoriametrics export api, it has no points limit of the range query
+type exporter struct {
+	client prometheus.Client
+}
+
+// exportedSeries is a json line of the export api
+type exportedSeries struct {
+	Metric     map[string]string `json:"metric"`
+	Values     []float64         `json:"values"`
+	Timestamps []int64           `json:"timestamps"`
+}
+
+// Export exports the raw samples of the series selector, and aligns them to the steps of the range as the range query does
+func (e *exporter) Export(ctx gocontext.Context, selector string, r promapiv1.Range) ([]*common.TimeSeries, error) {
+	lookback := defaultLookback
+	if r.Step > lookback {
+		lookback = r.Step
+	}
+	args := url.Values{}
+	args.Set("match[]", selector)
+	args.Set("start", strconv.FormatInt(r.Start.Add(-lookback).Unix(), 10))
+	args.Set("end", strconv.FormatInt(r.End.Unix(), 10))
+	req, err := http.NewRequest(http.MethodPost, e.client.URL("/api/v1/export", nil).String(), strings.NewReader(args.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, body, err := e.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("export returns status %v: %s", resp.StatusCode, body)
+	}
+	return parseExported(body, r, lookback)
+}
+
+func parseExported(body []byte, r promapiv1.Range, lookback time.Duration) ([]*common.TimeSeries, error) {
+	var results []*common.TimeSeries
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 1<<20), 64<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var s exportedSeries
+		if err := json.Unmarshal(line, &s); err != nil {
+			return nil, fmt.Errorf("invalid exported series: %v", err)
+		}
+		if len(s.Values) != len(s.Timestamps) {
+			return nil, fmt.Errorf("exported series %v has %v values but %v timestamps", s.Metric, len(s.Values), len(s.Timestamps))
+		}
+		ts := common.NewTimeSeries()
+		for key, val := range s.Metric {
+			ts.AppendLabel(key, val)
+		}
+		// the raw samples are sorted by timestamp in milliseconds
+		i := 0
+		for point := r.Start; !point.After(r.End); point = point.Add(r.Step) {
+			pointMs := point.UnixNano() / int64(time.Millisecond)
+			for i < len(s.Timestamps) && s.Timestamps[i] <= pointMs {
+				i++
+			}
+			if i > 0 && pointMs-s.Timestamps[i-1] <= lookback.Milliseconds() {
+				ts.AppendSample(point.Unix(), s.Values[i-1])
+			}
+			if r.Step <= 0 {
+				break
+			}
+		}
+		if len(ts.Samples) > 0 {
+			results = append(results, ts)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	klog.V(6).InfoS("Exported series", "count", len(results))
+	return results, nil
+}
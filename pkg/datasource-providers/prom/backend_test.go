@@ -0,0 +1,179 @@
+package prom
+
+import (
+	gocontext "context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gocrane/fadvisor/pkg/datasource"
+	"github.com/gocrane/fadvisor/pkg/metricnaming"
+	_ "github.com/gocrane/fadvisor/pkg/querybuilder-providers/prometheus"
+)
+
+func TestRequestHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		config datasource.PromConfig
+		want   map[string]string
+	}{
+		{
+			name:   "mimir",
+			config: datasource.PromConfig{Backend: BackendMimir, TenantID: "team-a", Headers: map[string]string{"X-Custom": "1"}},
+			want:   map[string]string{HeaderScopeOrgID: "team-a", "X-Custom": "1"},
+		},
+		{
+			name:   "victoriametrics",
+			config: datasource.PromConfig{Backend: BackendVictoriaMetrics, TenantID: "12:3"},
+			want:   map[string]string{HeaderAccountID: "12", HeaderProjectID: "3"},
+		},
+		{
+			name:   "thanos",
+			config: datasource.PromConfig{Backend: BackendThanos, TenantID: "team-a"},
+			want:   map[string]string{HeaderThanosTenant: "team-a"},
+		},
+		{
+			name:   "override",
+			config: datasource.PromConfig{Backend: BackendMimir, TenantID: "team-a", TenantHeader: "X-Tenant"},
+			want:   map[string]string{"X-Tenant": "team-a"},
+		},
+		{
+			name:   "no tenant",
+			config: datasource.PromConfig{Backend: BackendMimir},
+			want:   map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := requestHeaders(&tt.config)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected headers %v, got %v", tt.want, got)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Fatalf("expected headers %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectCapabilities(t *testing.T) {
+	tests := []struct {
+		name     string
+		handlers map[string]string
+		want     string
+	}{
+		{
+			name:     "mimir",
+			handlers: map[string]string{"/api/v1/status/buildinfo": `{"status":"success","data":{"application":"Grafana Mimir","version":"2.4.0"}}`},
+			want:     BackendMimir,
+		},
+		{
+			name: "victoriametrics",
+			handlers: map[string]string{
+				"/api/v1/status/buildinfo":      `{"status":"success","data":{"version":"2.24.0"}}`,
+				"/api/v1/status/active_queries": `{"status":"ok","data":[]}`,
+			},
+			want: BackendVictoriaMetrics,
+		},
+		{
+			name:     "thanos",
+			handlers: map[string]string{"/api/v1/stores": `{"status":"success","data":{}}`},
+			want:     BackendThanos,
+		},
+		{
+			name:     "prometheus",
+			handlers: map[string]string{"/api/v1/status/buildinfo": `{"status":"success","data":{"version":"2.37.0"}}`},
+			want:     BackendPrometheus,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			for path, body := range tt.handlers {
+				body := body
+				mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, body)
+				})
+			}
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			client, err := NewPrometheusClient(&datasource.PromConfig{Address: server.URL, Timeout: time.Second})
+			if err != nil {
+				t.Fatal(err)
+			}
+			caps := DetectCapabilities(gocontext.TODO(), client)
+			if caps.Backend != tt.want || caps.Export != (tt.want == BackendVictoriaMetrics) {
+				t.Fatalf("expected backend %v, got %+v", tt.want, caps)
+			}
+		})
+	}
+}
+
+func TestIsSeriesSelector(t *testing.T) {
+	for query, want := range map[string]bool{
+		`up`: true,
+		`container_cpu_usage_seconds_total{namespace="default", pod=~"web-.*"}`: true,
+		`{__name__="up"}`: true,
+		`sum(up)`:         false,
+		`rate(up[5m])`:    false,
+		`up / 2`:          false,
+		``:                false,
+	} {
+		if got := isSeriesSelector(query); got != want {
+			t.Errorf("isSeriesSelector(%q) = %v, expected %v", query, got, want)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	var headers http.Header
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/export", func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		if err := r.ParseForm(); err != nil || r.Form.Get("match[]") != `kube_pod_info{namespace="default"}` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, `{"metric":{"__name__":"kube_pod_info","pod":"web-1"},"values":[1,2,3],"timestamps":[59000,120000,600000]}`)
+		fmt.Fprintln(w, `{"metric":{"__name__":"kube_pod_info","pod":"web-2"},"values":[5],"timestamps":[1000000]}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	provider, err := NewProvider(&datasource.PromConfig{
+		Address:                     server.URL,
+		Timeout:                     time.Second,
+		Backend:                     BackendVictoriaMetrics,
+		TenantID:                    "7",
+		ExportEnabled:               true,
+		MaxPointsLimitPerTimeSeries: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	namer := metricnaming.QueryExprMetricNamer("", "pods", `kube_pod_info{namespace="default"}`)
+	tsList, err := provider.QueryTimeSeries(gocontext.TODO(), namer, time.Unix(60, 0), time.Unix(480, 0), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if headers.Get(HeaderAccountID) != "7" {
+		t.Errorf("expected tenant header, got %v", headers)
+	}
+	// web-1 is aligned to the steps with 5m lookback, web-2 has no sample in the range
+	if len(tsList) != 1 {
+		t.Fatalf("expected 1 time series, got %v", len(tsList))
+	}
+	var got []string
+	for _, sample := range tsList[0].Samples {
+		got = append(got, fmt.Sprintf("%v=%v", sample.Timestamp, sample.Value))
+	}
+	want := "[60=1 120=2 180=2 240=2 300=2 360=2 420=2]"
+	if fmt.Sprint(got) != want {
+		t.Fatalf("expected samples %v, got %v", want, got)
+	}
+}
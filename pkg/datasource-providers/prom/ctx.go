@@ -24,6 +24,8 @@ const (
 type context struct {
 	api                    promapiv1.API
 	maxPointsPerTimeSeries int
+	// exporter is set if the backend supports the export api, it exports the series selector instead of querying by shards
+	exporter *exporter
 }
 
 // Test use
@@ -66,6 +68,10 @@ func (c *context) QueryRangeSync(ctx gocontext.Context, query string, start, end
 
 		return c.convertPromResultsToTimeSeries(results)
 	}
+	if c.exporter != nil && isSeriesSelector(query) {
+		klog.V(4).InfoS("Exporting series selector instead of querying by shards", "query", query, "shards", len(shards.windows))
+		return c.exporter.Export(ctx, query, r)
+	}
 	return c.queryByShards(ctx, shards)
 }
 
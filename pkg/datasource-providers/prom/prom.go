@@ -3,6 +3,7 @@ package prom
 import (
 	gocontext "context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -26,7 +27,10 @@ type ThanosConfig struct {
 // NewPrometheusClient returns a prometheus.Client
 func NewPrometheusClient(config *datasource.PromConfig) (prometheus.Client, error) {
 
-	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
 
 	pc := prometheus.Config{
 		Address: config.Address,
@@ -40,72 +44,77 @@ func NewPrometheusClient(config *datasource.PromConfig) (prometheus.Client, erro
 			TLSClientConfig:     tlsConfig,
 		},
 	}
+	decorator := &requestDecorator{
+		auth:    &config.Auth,
+		thanos:  &ThanosConfig{Dedup: config.ThanosDedup, Partial: config.ThanosPartial},
+		headers: requestHeaders(config),
+	}
 	if config.BRateLimit {
-		return newPrometheusRateLimitClient(PrometheusClientID, pc, &config.Auth, config.QueryConcurrency, &ThanosConfig{Dedup: config.ThanosDedup, Partial: config.ThanosPartial})
+		return newPrometheusRateLimitClient(PrometheusClientID, pc, decorator, config.QueryConcurrency)
 	}
-	return newPrometheusAuthClient(PrometheusClientID, pc, &config.Auth, &ThanosConfig{Dedup: config.ThanosDedup, Partial: config.ThanosPartial})
-
-}
+	return newPrometheusAuthClient(PrometheusClientID, pc, decorator)
 
-// prometheusAuthClient wraps the prometheus api raw client with authentication info
-type prometheusAuthClient struct {
-	id     string
-	auth   *datasource.ClientAuth
-	client prometheus.Client
-	// hack here, later maybe a datasource for thanos
-	thanos *ThanosConfig
 }
 
-func newPrometheusAuthClient(id string, config prometheus.Config, auth *datasource.ClientAuth, thanos *ThanosConfig) (prometheus.Client, error) {
-	c, err := prometheus.NewClient(config)
-	if err != nil {
-		return nil, err
+func newTLSConfig(config *datasource.PromConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify, ServerName: config.TLS.ServerName}
+	if config.TLS.CAFile != "" {
+		ca, err := ioutil.ReadFile(config.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prometheus ca file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in prometheus ca file %v", config.TLS.CAFile)
+		}
+		tlsConfig.RootCAs = pool
 	}
-
-	client := &prometheusAuthClient{
-		thanos: thanos,
-		id:     id,
-		client: c,
-		auth:   auth,
+	if config.TLS.CertFile != "" || config.TLS.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLS.CertFile, config.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load prometheus client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
 	}
-
-	return client, nil
+	return tlsConfig, nil
 }
 
-// URL implements prometheus client interface
-func (pc *prometheusAuthClient) URL(ep string, args map[string]string) *url.URL {
-	return pc.client.URL(ep, args)
+// requestDecorator applies the auth info, the tenant and custom headers, and the thanos params to the requests
+type requestDecorator struct {
+	auth    *datasource.ClientAuth
+	headers map[string]string
+	// hack here, later maybe a datasource for thanos
+	thanos *ThanosConfig
 }
 
-// Do implements prometheus client interface, wrapped with an auth info
-func (pc *prometheusAuthClient) Do(ctx gocontext.Context, req *http.Request) (*http.Response, []byte, error) {
+func (d *requestDecorator) decorate(req *http.Request) (*http.Request, error) {
 	newReq := req
 	// hacking for thanos here, intercept the request and modify param
-	if pc.thanos != nil && (pc.thanos.Dedup || pc.thanos.Partial) {
+	if d.thanos != nil && (d.thanos.Dedup || d.thanos.Partial) {
 		var q url.Values
 		var err error
 		var bodyData []byte
 		if req.Method == http.MethodPost {
 			bodyReader, err := req.GetBody()
 			if err != nil {
-				return nil, nil, err
+				return nil, err
 			}
 			bodyData, err = ioutil.ReadAll(bodyReader)
 			if err != nil {
-				return nil, nil, err
+				return nil, err
 			}
 			q, err = url.ParseQuery(string(bodyData))
 			if err != nil {
-				return nil, nil, err
+				return nil, err
 			}
 		} else if req.Method == http.MethodGet {
 			q = req.URL.Query()
 		}
 
-		if pc.thanos.Partial {
+		if d.thanos.Partial {
 			q.Set("partial_response", "true")
 		}
-		if pc.thanos.Dedup {
+		if d.thanos.Dedup {
 			q.Set("dedup", "true")
 		}
 
@@ -116,23 +125,60 @@ func (pc *prometheusAuthClient) Do(ctx gocontext.Context, req *http.Request) (*h
 		} else if req.Method == http.MethodPost {
 			newReq, err = http.NewRequest(req.Method, req.URL.String(), strings.NewReader(q.Encode()))
 			if err != nil {
-				return nil, nil, err
+				return nil, err
 			}
 			newReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 		}
 	}
 
-	pc.auth.Apply(newReq)
+	for key, value := range d.headers {
+		newReq.Header.Set(key, value)
+	}
+	d.auth.Apply(newReq)
 	klog.V(6).InfoS("Query url", "url", newReq.URL, "newReq", newReq, "oldReq", req)
+	return newReq, nil
+}
+
+// prometheusAuthClient wraps the prometheus api raw client with authentication info
+type prometheusAuthClient struct {
+	id        string
+	client    prometheus.Client
+	decorator *requestDecorator
+}
+
+func newPrometheusAuthClient(id string, config prometheus.Config, decorator *requestDecorator) (prometheus.Client, error) {
+	c, err := prometheus.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &prometheusAuthClient{
+		id:        id,
+		client:    c,
+		decorator: decorator,
+	}
+
+	return client, nil
+}
+
+// URL implements prometheus client interface
+func (pc *prometheusAuthClient) URL(ep string, args map[string]string) *url.URL {
+	return pc.client.URL(ep, args)
+}
+
+// Do implements prometheus client interface, wrapped with an auth info
+func (pc *prometheusAuthClient) Do(ctx gocontext.Context, req *http.Request) (*http.Response, []byte, error) {
+	newReq, err := pc.decorator.decorate(req)
+	if err != nil {
+		return nil, nil, err
+	}
 	return pc.client.Do(ctx, newReq)
 }
 
 type prometheusRateLimitClient struct {
-	id     string
-	auth   *datasource.ClientAuth
-	client prometheus.Client
-	// hack here, later maybe a datasource for thanos
-	thanos *ThanosConfig
+	id        string
+	client    prometheus.Client
+	decorator *requestDecorator
 
 	lock            *sync.Mutex
 	cond            *sync.Cond
@@ -140,7 +186,7 @@ type prometheusRateLimitClient struct {
 	currentInFlight int
 }
 
-func newPrometheusRateLimitClient(id string, config prometheus.Config, auth *datasource.ClientAuth, maxInFlight int, thanos *ThanosConfig) (prometheus.Client, error) {
+func newPrometheusRateLimitClient(id string, config prometheus.Config, decorator *requestDecorator, maxInFlight int) (prometheus.Client, error) {
 	c, err := prometheus.NewClient(config)
 	if err != nil {
 		return nil, err
@@ -150,11 +196,10 @@ func newPrometheusRateLimitClient(id string, config prometheus.Config, auth *dat
 	client := &prometheusRateLimitClient{
 		id:          id,
 		client:      c,
-		auth:        auth,
+		decorator:   decorator,
 		maxInFlight: maxInFlight,
 		lock:        lock,
 		cond:        sync.NewCond(lock),
-		thanos:      thanos,
 	}
 
 	return client, nil
@@ -167,51 +212,10 @@ func (pc *prometheusRateLimitClient) URL(ep string, args map[string]string) *url
 
 // Do implements prometheus client interface, wrapped with an auth info
 func (pc *prometheusRateLimitClient) Do(ctx gocontext.Context, req *http.Request) (*http.Response, []byte, error) {
-	newReq := req
-	// hacking for thanos here, intercept the request and modify param
-	if pc.thanos != nil && (pc.thanos.Dedup || pc.thanos.Partial) {
-		var q url.Values
-		var err error
-		var bodyData []byte
-		if req.Method == http.MethodPost {
-			bodyReader, err := req.GetBody()
-			if err != nil {
-				return nil, nil, err
-			}
-			bodyData, err = ioutil.ReadAll(bodyReader)
-			if err != nil {
-				return nil, nil, err
-			}
-			q, err = url.ParseQuery(string(bodyData))
-			if err != nil {
-				return nil, nil, err
-			}
-		} else if req.Method == http.MethodGet {
-			q = req.URL.Query()
-		}
-
-		if pc.thanos.Partial {
-			q.Set("partial_response", "true")
-		}
-		if pc.thanos.Dedup {
-			q.Set("dedup", "true")
-		}
-
-		klog.V(6).InfoS("Hacking thanos", "originalQueryBody", string(bodyData), "newQuery", q.Encode())
-
-		if req.Method == http.MethodGet {
-			req.URL.RawQuery = q.Encode()
-		} else if req.Method == http.MethodPost {
-			newReq, err = http.NewRequest(req.Method, req.URL.String(), strings.NewReader(q.Encode()))
-			if err != nil {
-				return nil, nil, err
-			}
-			newReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-		}
+	newReq, err := pc.decorator.decorate(req)
+	if err != nil {
+		return nil, nil, err
 	}
-
-	pc.auth.Apply(newReq)
-	klog.V(6).InfoS("Query url", "url", newReq.URL, "newReq", newReq, "oldReq", req)
 	klog.V(4).InfoS("Prometheus rate limit", "ratelimit", pc.Runtime())
 	// block wait until at least one InFlight request finished if current inflighting requests reach the max limit, avoid many time consuming requests hit the prometheus.
 	// we use inflight to record the number of inflighting requests, because prometheus query is time-consuming when the range is large
@@ -2,6 +2,7 @@ package prom
 
 import (
 	gocontext "context"
+	"strings"
 	"time"
 
 	"github.com/gocrane/fadvisor/pkg/querybuilder"
@@ -20,21 +21,38 @@ type prom struct {
 }
 
 // NewProvider return a prometheus data provider
+// The backend is detected if not specified, the tenant header and the export api depend on it.
 func NewProvider(config *datasource.PromConfig) (datasource.Interface, error) {
+	cfg := *config
+	caps := Capabilities{Backend: strings.ToLower(cfg.Backend), Export: strings.ToLower(cfg.Backend) == BackendVictoriaMetrics}
+	if cfg.Backend == "" {
+		probeClient, err := NewPrometheusClient(&cfg)
+		if err != nil {
+			return nil, err
+		}
+		timeoutCtx, cancelFunc := gocontext.WithTimeout(gocontext.Background(), 30*time.Second)
+		caps = DetectCapabilities(timeoutCtx, probeClient)
+		cancelFunc()
+		cfg.Backend = caps.Backend
+	}
+	klog.Infof("Prometheus datasource backend %v, version %v, export %v", caps.Backend, caps.Version, caps.Export && cfg.ExportEnabled)
 
-	client, err := NewPrometheusClient(config)
+	client, err := NewPrometheusClient(&cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx := NewContext(client, config.MaxPointsLimitPerTimeSeries)
+	ctx := NewContext(client, cfg.MaxPointsLimitPerTimeSeries)
+	if caps.Export && cfg.ExportEnabled {
+		ctx.exporter = &exporter{client: client}
+	}
 
-	return &prom{ctx: ctx, config: config}, nil
+	return &prom{ctx: ctx, config: &cfg}, nil
 }
 
 func (p *prom) QueryTimeSeries(ctx gocontext.Context, namer metricnaming.MetricNamer, startTime time.Time, endTime time.Time, step time.Duration) ([]*common.TimeSeries, error) {
 	promBuilder := namer.QueryBuilder().Builder(metricquery.PrometheusMetricSource)
-	promQuery, err := promBuilder.BuildQuery(querybuilder.BuildQueryBehavior{FederatedClusterScope: p.config.FederatedClusterScope})
+	promQuery, err := promBuilder.BuildQuery(querybuilder.BuildQueryBehavior{FederatedClusterScope: p.config.FederatedClusterScope, Templates: p.config.QueryTemplates})
 	if err != nil {
 		klog.Errorf("Failed to BuildQuery: %v", err)
 		return nil, err
@@ -52,7 +70,7 @@ func (p *prom) QueryTimeSeries(ctx gocontext.Context, namer metricnaming.MetricN
 
 func (p *prom) QueryLatestTimeSeries(ctx gocontext.Context, namer metricnaming.MetricNamer) ([]*common.TimeSeries, error) {
 	promBuilder := namer.QueryBuilder().Builder(metricquery.PrometheusMetricSource)
-	promQuery, err := promBuilder.BuildQuery(querybuilder.BuildQueryBehavior{FederatedClusterScope: p.config.FederatedClusterScope})
+	promQuery, err := promBuilder.BuildQuery(querybuilder.BuildQueryBehavior{FederatedClusterScope: p.config.FederatedClusterScope, Templates: p.config.QueryTemplates})
 	if err != nil {
 		klog.Errorf("Failed to BuildQuery: %v", err)
 		return nil, err
@@ -0,0 +1,137 @@
+package qcloudmonitor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/crane/pkg/common"
+
+	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/qmonitor"
+	"github.com/gocrane/fadvisor/pkg/consts"
+	"github.com/gocrane/fadvisor/pkg/metricquery"
+)
+
+// the periods supported by the cvm metrics
+var cvmPeriods = []time.Duration{time.Minute, 5 * time.Minute, time.Hour, 24 * time.Hour}
+
+// the max points of one GetMonitorData request
+const maxPointsPerRequest = 1440
+
+// providerID of the tke node is of the form qcloud:///800005/ins-2jv4wpmr
+var providerIDRegex = regexp.MustCompile("qcloud:///[^/]+/([^/]+)")
+
+// instanceID returns the cvm instance id of the node by the provider id, the node name is the instance id if the provider id is unknown
+func instanceID(node *metricquery.NodeNamerInfo) (string, error) {
+	if match := providerIDRegex.FindStringSubmatch(node.ProviderID); len(match) == 2 {
+		return match[1], nil
+	}
+	if strings.HasPrefix(node.Name, "ins-") {
+		return node.Name, nil
+	}
+	return "", fmt.Errorf("can not get the instance id of node %v from provider id %q", node.Name, node.ProviderID)
+}
+
+// cvmPeriod returns the max period supported by cvm which is not larger than the step
+func cvmPeriod(step time.Duration) time.Duration {
+	period := cvmPeriods[0]
+	for _, p := range cvmPeriods {
+		if p <= step {
+			period = p
+		}
+	}
+	return period
+}
+
+// nodeMetric queries the node usage by the metrics of the cvm instance of the node.
+// cvm CPUUsage is the percentage, it is converted to cores by the cpu capacity of the node, MemUsed is MB.
+func (qm *qcloudmonitor) nodeMetric(ctx context.Context, metric *metricquery.Metric, startTime time.Time, endTime time.Time, step time.Duration) ([]*common.TimeSeries, error) {
+	if metric.Node == nil {
+		return nil, fmt.Errorf("metric type %v, but no NodeNamerInfo provided", metric.Type)
+	}
+	id, err := instanceID(metric.Node)
+	if err != nil {
+		return nil, err
+	}
+
+	var metricName string
+	var scale float64
+	switch strings.ToLower(metric.MetricName) {
+	case v1.ResourceCPU.String():
+		cpu, ok := metric.Node.Capacity[v1.ResourceCPU]
+		if !ok || cpu.IsZero() {
+			return nil, fmt.Errorf("cpu capacity of node %v is unknown, can not convert cpu usage percentage to cores", metric.Node.Name)
+		}
+		metricName = qmonitor.CVMCpuUsageMetric
+		scale = float64(cpu.MilliValue()) / 1000. / 100.
+	case v1.ResourceMemory.String():
+		metricName = qmonitor.CVMMemUsedMetric
+		scale = 1024. * 1024.
+	default:
+		return nil, fmt.Errorf("not supported metric name %v for qcloud monitor", metric.MetricName)
+	}
+
+	samples, err := qm.getInstanceSamples(ctx, metricName, id, startTime, endTime, step)
+	if err != nil {
+		return nil, err
+	}
+	ts := common.NewTimeSeries()
+	ts.AppendLabel(consts.LabelNode, metric.Node.Name)
+	ts.AppendLabel(qmonitor.LabelInstanceId, id)
+	for _, sample := range samples {
+		ts.AppendSample(sample.Timestamp, sample.Value*scale)
+	}
+	if len(ts.Samples) == 0 {
+		return []*common.TimeSeries{}, nil
+	}
+	return []*common.TimeSeries{ts}, nil
+}
+
+// getInstanceSamples gets the cvm metric of the instance, the range is split by the points limit of the api,
+// and the samples are down sampled to the step if the step is not a supported period
+func (qm *qcloudmonitor) getInstanceSamples(ctx context.Context, metricName, id string, startTime time.Time, endTime time.Time, step time.Duration) ([]common.Sample, error) {
+	period := cvmPeriod(step)
+	var samples []common.Sample
+	last := int64(-1)
+	for start := startTime; !start.After(endTime); {
+		end := start.Add(period * (maxPointsPerRequest - 1))
+		if end.After(endTime) {
+			end = endTime
+		}
+		req := &qmonitor.GetInstanceDataParam{
+			Namespace:  qmonitor.CVMNamespace,
+			MetricName: metricName,
+			Instances:  [][]qmonitor.Dimension{{{Name: qmonitor.LabelInstanceId, Value: id}}},
+			Period:     uint64(period.Seconds()),
+			StartTime:  start.Format(time.RFC3339),
+			EndTime:    end.Format(time.RFC3339),
+		}
+		result, err := qm.cmClient.GetInstanceMonitorData(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		for _, datum := range result.Data {
+			for _, point := range datum.Points {
+				for _, value := range point.Values {
+					if value.Timestamp == nil || value.Value == nil {
+						continue
+					}
+					timestamp := int64(*value.Timestamp)
+					if last >= 0 && timestamp-last < int64(step.Seconds()) {
+						continue
+					}
+					samples = append(samples, common.Sample{Timestamp: timestamp, Value: *value.Value})
+					last = timestamp
+				}
+			}
+		}
+		start = end.Add(period)
+	}
+	klog.V(6).InfoS("Got cvm instance metric", "instance", id, "metric", metricName, "samples", len(samples))
+	return samples, nil
+}
@@ -0,0 +1,128 @@
+package qcloudmonitor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/gocrane/crane/pkg/common"
+
+	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/credential"
+	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/qmonitor"
+	"github.com/gocrane/fadvisor/pkg/metricnaming"
+	// the qcloudmonitor builder of the metric namers
+	_ "github.com/gocrane/fadvisor/pkg/querybuilder-providers/qcloudmonitor"
+)
+
+type fakeMonitorClient struct {
+	instanceReqs  []*qmonitor.GetInstanceDataParam
+	statisticReqs []*qmonitor.GetDataParam
+}
+
+func (f *fakeMonitorClient) UpdateCredential(cred credential.QCloudCredential) {}
+
+func (f *fakeMonitorClient) EnableDebug() bool {
+	return true
+}
+
+func (f *fakeMonitorClient) DescribeStatisticData(ctx context.Context, req *qmonitor.GetDataParam) (*qmonitor.GetDataResult, error) {
+	f.statisticReqs = append(f.statisticReqs, req)
+	return &qmonitor.GetDataResult{}, nil
+}
+
+// GetInstanceMonitorData returns the value 50 at each period of the range
+func (f *fakeMonitorClient) GetInstanceMonitorData(ctx context.Context, req *qmonitor.GetInstanceDataParam) (*qmonitor.GetDataResult, error) {
+	f.instanceReqs = append(f.instanceReqs, req)
+	start, _ := time.Parse(time.RFC3339, req.StartTime)
+	end, _ := time.Parse(time.RFC3339, req.EndTime)
+	var point qmonitor.MetricDataPoint
+	for t := start; !t.After(end); t = t.Add(time.Duration(req.Period) * time.Second) {
+		timestamp, value := uint64(t.Unix()), 50.
+		point.Values = append(point.Values, qmonitor.Point{Timestamp: &timestamp, Value: &value})
+	}
+	return &qmonitor.GetDataResult{Data: []qmonitor.MetricDatum{{MetricName: req.MetricName, Points: []qmonitor.MetricDataPoint{point}}}}, nil
+}
+
+func TestNodeMetric(t *testing.T) {
+	client := &fakeMonitorClient{}
+	qm := &qcloudmonitor{cmClient: client, step: DefaultStep}
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "10.0.0.1"},
+		Spec:       v1.NodeSpec{ProviderID: "qcloud:///800005/ins-2jv4wpmr"},
+		Status:     v1.NodeStatus{Capacity: v1.ResourceList{v1.ResourceCPU: resource.MustParse("4")}},
+	}
+	start := time.Unix(1650000000, 0)
+	end := start.Add(144 * time.Hour)
+
+	tsList, err := qm.QueryTimeSeries(context.TODO(), metricnaming.ResourceToNodeMetricNamer("cls-1", node, v1.ResourceCPU), start, end, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tsList) != 1 {
+		t.Fatalf("expect 1 series, got %v", len(tsList))
+	}
+	// 6 days of 5m period exceed the points limit of one request
+	if len(client.instanceReqs) != 2 {
+		t.Errorf("expect 2 requests, got %v", len(client.instanceReqs))
+	}
+	req := client.instanceReqs[0]
+	if req.Namespace != qmonitor.CVMNamespace || req.MetricName != qmonitor.CVMCpuUsageMetric || req.Period != 300 || req.Instances[0][0].Value != "ins-2jv4wpmr" {
+		t.Errorf("unexpected request %+v", req)
+	}
+	if got := len(tsList[0].Samples); got != 144*12+1 {
+		t.Errorf("expect %v samples, got %v", 144*12+1, got)
+	}
+	// 50% of 4 cores
+	if got := tsList[0].Samples[0].Value; got != 2 {
+		t.Errorf("expect 2 cores, got %v", got)
+	}
+
+	// the samples of 1m period are down sampled to the step
+	tsList, err = qm.QueryTimeSeries(context.TODO(), metricnaming.ResourceToNodeMetricNamer("cls-1", node, v1.ResourceMemory), start, start.Add(time.Hour), 2*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(tsList[0].Samples); got != 31 {
+		t.Errorf("expect 31 samples, got %v", got)
+	}
+	if got := tsList[0].Samples[0].Value; got != 50*1024*1024 {
+		t.Errorf("expect 50MB, got %v", got)
+	}
+
+	// no instance id
+	node.Spec.ProviderID = ""
+	if _, err = qm.QueryTimeSeries(context.TODO(), metricnaming.ResourceToNodeMetricNamer("cls-1", node, v1.ResourceCPU), start, end, time.Minute); err == nil {
+		t.Errorf("expect error without instance id")
+	}
+}
+
+func TestPromQLMetric(t *testing.T) {
+	client := &fakeMonitorClient{}
+	qm := &qcloudmonitor{cmClient: client, step: DefaultStep}
+	namer := metricnaming.QueryExprMetricNamer("cls-1", "node_cpu", `K8sNodeCpuUsage{node="10.0.0.1",node_role=~"Node|Master"}`)
+	if _, err := qm.QueryTimeSeries(context.TODO(), namer, time.Unix(1650000000, 0), time.Unix(1650003600, 0), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	req := client.statisticReqs[0]
+	if !reflect.DeepEqual(req.MetricNames, []string{"K8sNodeCpuUsage"}) {
+		t.Errorf("unexpected metric names %v", req.MetricNames)
+	}
+	want := []qmonitor.MidQueryCondition{
+		{Key: "node", Operator: string(common.OperatorEqual), Value: []string{"10.0.0.1"}},
+		{Key: "node_role", Operator: string(common.OperatorIn), Value: []string{"Node", "Master"}},
+		{Key: qmonitor.LabelClusterId, Operator: string(common.OperatorEqual), Value: []string{"cls-1"}},
+	}
+	if !reflect.DeepEqual(req.Conditions, want) {
+		t.Errorf("expect conditions %v, got %v", want, req.Conditions)
+	}
+
+	namer = metricnaming.QueryExprMetricNamer("cls-1", "node_count", `count(kube_node_labels) by (label_instance_type)`)
+	if _, err := qm.QueryTimeSeries(context.TODO(), namer, time.Unix(1650000000, 0), time.Unix(1650003600, 0), time.Minute); err == nil {
+		t.Errorf("expect error of the promql which is not a series selector")
+	}
+}
@@ -0,0 +1,99 @@
+package qcloudmonitor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gocrane/crane/pkg/common"
+
+	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/qmonitor"
+	"github.com/gocrane/fadvisor/pkg/consts"
+	"github.com/gocrane/fadvisor/pkg/metricquery"
+)
+
+var (
+	selectorRegex = regexp.MustCompile(`^\s*([a-zA-Z_:][a-zA-Z0-9_:]*)\s*(?:\{(.*)\})?\s*$`)
+	matcherRegex  = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!=|=)\s*"((?:[^"\\]|\\.)*)"\s*$`)
+)
+
+// parseSelector parses the series selector such as K8sNodeCpuUsage{node="n1",namespace=~"a|b"} to the metric name and the conditions,
+// only the selector with equal, not equal and the alternation of regex match is supported, the other promql can not pass through.
+func parseSelector(query string) (string, []common.QueryCondition, error) {
+	match := selectorRegex.FindStringSubmatch(query)
+	if match == nil {
+		return "", nil, fmt.Errorf("qcloudmonitor only support series selector of the tke metric, %q is not supported", query)
+	}
+	var conditions []common.QueryCondition
+	if strings.TrimSpace(match[2]) == "" {
+		return match[1], conditions, nil
+	}
+	for _, m := range splitMatchers(match[2]) {
+		if strings.TrimSpace(m) == "" {
+			continue
+		}
+		parts := matcherRegex.FindStringSubmatch(m)
+		if parts == nil {
+			return "", nil, fmt.Errorf("invalid matcher %q of %q", m, query)
+		}
+		key, value := parts[1], strings.ReplaceAll(parts[3], `\"`, `"`)
+		switch parts[2] {
+		case "=":
+			conditions = append(conditions, common.QueryCondition{Key: key, Operator: common.OperatorEqual, Value: []string{value}})
+		case "!=":
+			conditions = append(conditions, common.QueryCondition{Key: key, Operator: common.OperatorNotEqual, Value: []string{value}})
+		case "=~":
+			if strings.ContainsAny(value, `.*+?()[]{}^$\`) {
+				return "", nil, fmt.Errorf("only the alternation of the values is supported by regex matcher, %q is not supported", m)
+			}
+			conditions = append(conditions, common.QueryCondition{Key: key, Operator: common.OperatorIn, Value: strings.Split(value, "|")})
+		}
+	}
+	return match[1], conditions, nil
+}
+
+// splitMatchers splits the matchers by the comma out of the quoted values
+func splitMatchers(s string) []string {
+	var results []string
+	quoted, escaped := false, false
+	start := 0
+	for i, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			results = append(results, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(results, s[start:])
+}
+
+// promQLMetric passes the series selector of the tke metric through to the cloud monitor, the cluster id of the selector is added as a condition
+func (qm *qcloudmonitor) promQLMetric(ctx context.Context, metric *metricquery.Metric, startTime time.Time, endTime time.Time, step time.Duration) ([]*common.TimeSeries, error) {
+	if metric.Prom == nil {
+		return nil, fmt.Errorf("metric type %v, but no PromNamerInfo provided", metric.Type)
+	}
+	metricName, conds, err := parseSelector(metric.Prom.QueryExpr)
+	if err != nil {
+		return nil, err
+	}
+	hasCluster := false
+	for _, cond := range conds {
+		if cond.Key == qmonitor.LabelClusterId {
+			hasCluster = true
+		}
+	}
+	if !hasCluster && metric.Prom.Selector != nil {
+		if id, exists := metric.Prom.Selector.RequiresExactMatch(consts.LabelClusterId); exists {
+			conds = append(conds, common.QueryCondition{Key: qmonitor.LabelClusterId, Operator: common.OperatorEqual, Value: []string{id}})
+		}
+	}
+	return qm.getMonitorData(ctx, metricName, conds, startTime, endTime, step)
+}
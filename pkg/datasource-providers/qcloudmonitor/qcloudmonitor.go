@@ -10,6 +10,7 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/util/flowcontrol"
 	"k8s.io/klog/v2"
 
@@ -31,14 +32,21 @@ const (
 var _ datasource.Interface = &qcloudmonitor{}
 
 type qcloudmonitor struct {
-	cmClient *qmonitor.QCloudMonitorClient
+	cmClient qmonitor.QCloudMonitor
 	step     time.Duration
 }
 
-// NewProvider return a QCloud Monitor data provider
-func NewProvider(config *datasource.QCloudMonitorConfig) (datasource.Interface, error) {
+// NewProvider return a QCloud Monitor data provider, the client reads the credential of the kubernetes secret,
+// the credential is refreshed until stopCh is closed
+func NewProvider(config *datasource.QCloudMonitorConfig, client kubernetes.Interface, stopCh <-chan struct{}) (datasource.Interface, error) {
 	cm := &qcloudmonitor{}
-	cred := credential.NewQCloudCredential(config.ClusterId, config.AppId, config.SecretId, config.SecretKey, 1*time.Hour)
+	credConfig := config.Credentials.Config
+	credConfig.DomainSuffix = config.DomainSuffix
+	credConfig.Scheme = config.Scheme
+	cred, err := credential.NewCredential(credConfig, client, stopCh)
+	if err != nil {
+		return nil, err
+	}
 	qcp := qcloud.QCloudClientProfile{
 		Region:          config.Region,
 		DomainSuffix:    config.DomainSuffix,
@@ -106,9 +114,9 @@ func (qm *qcloudmonitor) query(ctx context.Context, metric *metricquery.Metric,
 	case metricquery.ContainerMetricType:
 		return qm.containerMetric(ctx, metric, startTime, endTime, step)
 	case metricquery.NodeMetricType:
-		fallthrough
+		return qm.nodeMetric(ctx, metric, startTime, endTime, step)
 	case metricquery.PromQLMetricType:
-		return nil, fmt.Errorf("qcloudmonitor do not support metric type %v", metric.Type)
+		return qm.promQLMetric(ctx, metric, startTime, endTime, step)
 	default:
 		return nil, fmt.Errorf("unknown metric type %v", metric.Type)
 	}
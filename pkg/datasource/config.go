@@ -1,8 +1,13 @@
 package datasource
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
+	"text/template"
 	"time"
+
+	"github.com/gocrane/fadvisor/pkg/cloudsdk/qcloud/credential"
 )
 
 // PromConfig represents the config of prometheus
@@ -20,6 +25,29 @@ type PromConfig struct {
 	// for thanos query, it must when use thanos as query source https://thanos.io/tip/components/query.md/#partial-response
 	ThanosPartial bool
 	ThanosDedup   bool
+
+	// Backend is the prometheus compatible backend, prometheus, thanos, mimir, cortex or victoriametrics, it is detected if not specified
+	Backend string
+	// TenantID is sent as the tenant header of the backend. X-Scope-OrgID for mimir and cortex, THANOS-TENANT for thanos,
+	// AccountID and ProjectID for victoriametrics with the format accountID[:projectID]
+	TenantID string
+	// TenantHeader overrides the tenant header of the backend
+	TenantHeader string
+	// Headers are the custom headers sent with each request
+	Headers map[string]string
+	TLS     TLSConfig
+	// ExportEnabled uses the victoriametrics export api to fetch the series selector which is too long for one range query
+	ExportEnabled bool
+	// QueryTemplates override the built-in queries of the metrics, see the prometheus query builder
+	QueryTemplates map[string]*template.Template
+}
+
+// TLSConfig is the tls config of the client, the client certificate is used for mTLS
+type TLSConfig struct {
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
 }
 
 // ClientAuth holds the HTTP client identity info.
@@ -45,6 +73,20 @@ func (auth *ClientAuth) Apply(req *http.Request) {
 	}
 }
 
+// MetricServerConfig represents the config of the metrics server datasource, the recorder samples the metrics server
+// periodically and keeps the samples as the history of the datasource
+type MetricServerConfig struct {
+	RecorderEnabled bool
+	// RecorderInterval is the interval of sampling the metrics server
+	RecorderInterval time.Duration
+	// RecorderRetention is how long the samples are kept
+	RecorderRetention time.Duration
+	// RecorderFlushInterval is the interval of persisting the samples to the data file
+	RecorderFlushInterval time.Duration
+	// RecorderDataFile persists the samples, the samples are only kept in memory if it is empty
+	RecorderDataFile string
+}
+
 // MockConfig represents the config of an in-memory provider, which is for demonstration or testing purpose.
 type MockConfig struct {
 	SeedFile string
@@ -55,12 +97,11 @@ type QCloudMonitorConfig struct {
 	ClientProfile `name:"clientProfile" value:"optional"`
 }
 
-// Credentials use user defined SecretId and SecretKey
+// Credentials use user defined SecretId and SecretKey, or the secret, the sts role and the cam role of the credential config
 type Credentials struct {
 	ClusterId string
 	AppId     string
-	SecretId  string
-	SecretKey string
+	credential.Config
 }
 
 type ClientProfile struct {
@@ -81,3 +122,19 @@ const (
 	MetricServerDataSource  DataSourceType = "metricserver"
 	QCloudMonitorDataSource DataSourceType = "qcloudmonitor"
 )
+
+// ParseDataSourceType returns the datasource type of the name or the alias of it
+func ParseDataSourceType(name string) (DataSourceType, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "prometheus", "prom":
+		return PrometheusDataSource, nil
+	case "qmonitor", "qcloudmonitor", "qm":
+		return QCloudMonitorDataSource, nil
+	case "metricserver", "ms":
+		return MetricServerDataSource, nil
+	case "mock":
+		return MockDataSource, nil
+	default:
+		return "", fmt.Errorf("unknown datasource %v", name)
+	}
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"k8s.io/klog/v2"
+
 	"github.com/gocrane/crane/pkg/common"
 	"github.com/gocrane/fadvisor/pkg/metricnaming"
+	"github.com/gocrane/fadvisor/pkg/metricquery"
 )
 
 var _ RealTime = &RealTimeDataProxy{}
@@ -68,30 +72,110 @@ func (r *RealTimeDataProxy) getSortedProviders() []RealTime {
 
 var _ History = &HistoryDataProxy{}
 
+// HistoryPolicy is the policy of the history proxy to query the datasource-providers
+type HistoryPolicy string
+
+const (
+	// HistoryPolicyFailover returns the time series of the first provider by priority which returns without error,
+	// the provider returning no time series is skipped if FailoverOnEmpty
+	HistoryPolicyFailover HistoryPolicy = "failover"
+	// HistoryPolicyMerge queries all the providers and fills the gaps of the time series of the higher priority provider by the lower ones
+	HistoryPolicyMerge HistoryPolicy = "merge"
+)
+
+// HistoryProxyConfig is the config of the history proxy
+type HistoryProxyConfig struct {
+	// Priority is the providers in priority order, the providers not in it are after them and sorted by name
+	Priority []DataSourceType
+	// Policy is failover if not specified
+	Policy HistoryPolicy
+	// Routes are the providers in priority order of the metric type, such as node metrics from qcloudmonitor
+	Routes map[metricquery.MetricType][]DataSourceType
+	// Retentions are the retention of the providers, the provider is only queried for the window in its retention
+	Retentions map[DataSourceType]time.Duration
+	// FailoverOnEmpty fails over to the next provider if the provider returns no time series of the failover policy
+	FailoverOnEmpty bool
+	// MergeIgnoredLabels are not compared when matching the time series of the merge policy,
+	// such as the external labels replica, prometheus and cluster added by thanos or mimir
+	MergeIgnoredLabels []string
+}
+
 type HistoryDataProxy struct {
 	sync.Mutex
 	historyProviders map[DataSourceType]History
+	config           HistoryProxyConfig
+	now              func() time.Time
 }
 
-// NewHistoryDataProxy return a proxy for all history datasource-providers, now it has no selecting policy configurable.
-// Default policy is traversing all datasource-providers one by one until no error return.
+// NewHistoryDataProxy return a proxy for all history datasource-providers with the default failover policy,
+// it traverses all datasource-providers one by one sorted by name until no error return.
 func NewHistoryDataProxy(historyProviders map[DataSourceType]History) *HistoryDataProxy {
+	return NewHistoryDataProxyWithConfig(historyProviders, HistoryProxyConfig{})
+}
+
+// NewHistoryDataProxyWithConfig return a proxy for all history datasource-providers with the priority, routes and policy of the config
+func NewHistoryDataProxyWithConfig(historyProviders map[DataSourceType]History, config HistoryProxyConfig) *HistoryDataProxy {
+	if config.Policy == "" {
+		config.Policy = HistoryPolicyFailover
+	}
 	return &HistoryDataProxy{
 		historyProviders: historyProviders,
+		config:           config,
+		now:              time.Now,
 	}
 }
 
 func (h *HistoryDataProxy) QueryTimeSeries(ctx context.Context, metricNamer metricnaming.MetricNamer, startTime time.Time, endTime time.Time, step time.Duration) ([]*common.TimeSeries, error) {
 	var errs []error
-	for _, provider := range h.getSortedProviders() {
-		res, err := provider.QueryTimeSeries(ctx, metricNamer, startTime, endTime, step)
+	var results []*common.TimeSeries
+	queried := false
+	for _, p := range h.getSortedProviders(metricNamer) {
+		start, ok := h.retentionStart(p.name, startTime, endTime, step)
+		if !ok {
+			klog.V(6).InfoS("Skip history provider out of retention", "provider", p.name, "metricNamer", metricNamer.BuildUniqueKey())
+			continue
+		}
+		res, err := p.provider.QueryTimeSeries(ctx, metricNamer, start, endTime, step)
 		if err != nil {
-			errs = append(errs, err)
+			errs = append(errs, fmt.Errorf("%v: %v", p.name, err))
 			continue
 		}
-		return res, nil
+		queried = true
+		if h.config.Policy != HistoryPolicyMerge {
+			if len(res) > 0 || !h.config.FailoverOnEmpty {
+				return res, nil
+			}
+			continue
+		}
+		results = MergeTimeSeries(results, res, step, h.config.MergeIgnoredLabels...)
 	}
-	return nil, fmt.Errorf("no history data source is available now, errs: %+v", errs)
+	if !queried {
+		return nil, fmt.Errorf("no history data source is available now, errs: %+v", errs)
+	}
+	if len(errs) > 0 {
+		klog.V(4).InfoS("Some history data sources failed", "metricNamer", metricNamer.BuildUniqueKey(), "errs", errs)
+	}
+	return results, nil
+}
+
+// retentionStart returns the start time in the retention of the provider aligned to the step, false if the whole window is out of the retention
+func (h *HistoryDataProxy) retentionStart(name DataSourceType, startTime, endTime time.Time, step time.Duration) (time.Time, bool) {
+	retention := h.config.Retentions[name]
+	if retention <= 0 {
+		return startTime, true
+	}
+	earliest := h.now().Add(-retention)
+	if endTime.Before(earliest) {
+		return startTime, false
+	}
+	if !startTime.Before(earliest) {
+		return startTime, true
+	}
+	if step <= 0 {
+		return earliest, true
+	}
+	steps := (earliest.Sub(startTime) + step - 1) / step
+	return startTime.Add(steps * step), true
 }
 
 func (h *HistoryDataProxy) RegisterHistoryProvider(name DataSourceType, provider History) {
@@ -109,17 +193,128 @@ func (h *HistoryDataProxy) DeleteHistoryProvider(name DataSourceType) {
 	delete(h.historyProviders, name)
 }
 
-func (h *HistoryDataProxy) getSortedProviders() []History {
+type namedHistory struct {
+	name     DataSourceType
+	provider History
+}
+
+// getSortedProviders returns the providers of the route of the metric type, or all providers by priority if the metric type has no route
+func (h *HistoryDataProxy) getSortedProviders(metricNamer metricnaming.MetricNamer) []namedHistory {
 	h.Lock()
 	defer h.Unlock()
+	if namer, ok := metricNamer.(*metricnaming.GeneralMetricNamer); ok && namer.Metric != nil {
+		if route, ok := h.config.Routes[namer.Metric.Type]; ok {
+			var providers []namedHistory
+			for _, name := range route {
+				if provider, ok := h.historyProviders[name]; ok {
+					providers = append(providers, namedHistory{name: name, provider: provider})
+				}
+			}
+			return providers
+		}
+	}
+
+	var providers []namedHistory
+	added := make(map[DataSourceType]bool)
+	for _, name := range h.config.Priority {
+		if provider, ok := h.historyProviders[name]; ok && !added[name] {
+			providers = append(providers, namedHistory{name: name, provider: provider})
+			added[name] = true
+		}
+	}
 	var names []string
-	var providers []History
 	for name := range h.historyProviders {
-		names = append(names, string(name))
+		if !added[name] {
+			names = append(names, string(name))
+		}
 	}
 	sort.Strings(names)
 	for _, name := range names {
-		providers = append(providers, h.historyProviders[DataSourceType(name)])
+		providers = append(providers, namedHistory{name: DataSourceType(name), provider: h.historyProviders[DataSourceType(name)]})
 	}
 	return providers
 }
+
+// MergeTimeSeries fills the gaps of the primary time series by the secondary ones, a secondary sample is added if there is no primary
+// sample within half a step of it. Only the series with the same labels except the ignored labels are merged, the secondary series
+// without a primary series of the same labels are dropped, they may be the same object labeled differently by another provider.
+func MergeTimeSeries(primary, secondary []*common.TimeSeries, step time.Duration, ignoredLabels ...string) []*common.TimeSeries {
+	if len(primary) == 0 {
+		return secondary
+	}
+	ignored := make(map[string]bool, len(ignoredLabels))
+	for _, name := range ignoredLabels {
+		ignored[name] = true
+	}
+	byLabels := make(map[string]*common.TimeSeries, len(primary))
+	for _, ts := range primary {
+		key := labelsKey(ts.Labels, ignored)
+		if _, ok := byLabels[key]; !ok {
+			byLabels[key] = ts
+		}
+	}
+	merged := 0
+	for _, ts := range secondary {
+		target, ok := byLabels[labelsKey(ts.Labels, ignored)]
+		if !ok {
+			klog.V(6).InfoS("Drop the secondary time series without a primary time series of the same labels", "labels", labelsKey(ts.Labels, ignored))
+			continue
+		}
+		fillGaps(target, ts.Samples, step)
+		merged++
+	}
+	if merged == 0 && len(secondary) > 0 {
+		klog.Warningf("All the %v secondary time series are dropped, no primary time series has the same labels %v, ignore the external labels of the datasources if they differ",
+			len(secondary), labelsKey(secondary[0].Labels, ignored))
+	}
+	return primary
+}
+
+func fillGaps(ts *common.TimeSeries, samples []common.Sample, step time.Duration) {
+	ts.SortSampleAsc()
+	existing := make([]int64, 0, len(ts.Samples))
+	for _, sample := range ts.Samples {
+		existing = append(existing, sample.Timestamp)
+	}
+	tolerance := int64(step.Seconds()) / 2
+	filled := false
+	for _, sample := range samples {
+		i := sort.Search(len(existing), func(i int) bool { return existing[i] >= sample.Timestamp-tolerance })
+		if i < len(existing) && existing[i] <= sample.Timestamp+tolerance {
+			continue
+		}
+		ts.Samples = append(ts.Samples, sample)
+		filled = true
+	}
+	if filled {
+		ts.SortSampleAsc()
+	}
+}
+
+// labelsKey returns the sorted labels except the ignored ones
+func labelsKey(labels []common.Label, ignored map[string]bool) string {
+	pairs := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if ignored[label.Name] {
+			continue
+		}
+		pairs = append(pairs, label.Name+"="+label.Value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+var _ Interface = &HybridDataProxy{}
+
+// HybridDataProxy combines a realtime datasource and a history datasource, such as the history proxy of several providers
+type HybridDataProxy struct {
+	RealTime
+	History
+}
+
+func NewHybridDataProxy(realtime RealTime, history History) *HybridDataProxy {
+	return &HybridDataProxy{
+		RealTime: realtime,
+		History:  history,
+	}
+}
@@ -0,0 +1,179 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gocrane/crane/pkg/common"
+
+	"github.com/gocrane/fadvisor/pkg/metricnaming"
+	"github.com/gocrane/fadvisor/pkg/metricquery"
+)
+
+type fakeHistory struct {
+	series  []*common.TimeSeries
+	err     error
+	queried []time.Time
+}
+
+func (f *fakeHistory) QueryTimeSeries(ctx context.Context, metricNamer metricnaming.MetricNamer, startTime time.Time, endTime time.Time, step time.Duration) ([]*common.TimeSeries, error) {
+	f.queried = append(f.queried, startTime)
+	if f.err != nil {
+		return nil, f.err
+	}
+	var results []*common.TimeSeries
+	for _, s := range f.series {
+		ts := common.NewTimeSeries()
+		ts.SetLabels(s.Labels)
+		for _, sample := range s.Samples {
+			if sample.Timestamp >= startTime.Unix() && sample.Timestamp <= endTime.Unix() {
+				ts.AppendSample(sample.Timestamp, sample.Value)
+			}
+		}
+		results = append(results, ts)
+	}
+	return results, nil
+}
+
+func newSeries(name string, timestamps ...int64) *common.TimeSeries {
+	ts := common.NewTimeSeries()
+	ts.AppendLabel("source", name)
+	for _, t := range timestamps {
+		ts.AppendSample(t, float64(t))
+	}
+	return ts
+}
+
+func timestamps(ts *common.TimeSeries) []int64 {
+	var results []int64
+	for _, s := range ts.Samples {
+		results = append(results, s.Timestamp)
+	}
+	return results
+}
+
+func TestHistoryDataProxy(t *testing.T) {
+	now := time.Unix(10000, 0)
+	step := time.Minute
+	namer := func(metricType metricquery.MetricType) metricnaming.MetricNamer {
+		return &metricnaming.GeneralMetricNamer{Metric: &metricquery.Metric{Type: metricType, MetricName: "cpu"}}
+	}
+
+	prom := &fakeHistory{series: []*common.TimeSeries{newSeries("prom", 7600, 7660, 7720, 7840)}}
+	qm := &fakeHistory{series: []*common.TimeSeries{newSeries("qm", 7480, 7540, 7600, 7660, 7720, 7780, 7840)}}
+	broken := &fakeHistory{err: fmt.Errorf("unavailable")}
+	providers := map[DataSourceType]History{PrometheusDataSource: prom, QCloudMonitorDataSource: qm, MockDataSource: broken}
+
+	// merge fills the older window out of the prometheus retention and the missing point from the qcloudmonitor series of the same labels,
+	// the series of other labels is dropped
+	qmSameLabels := newSeries("prom", 7480, 7540, 7600, 7660, 7720, 7780, 7840)
+	for i := range qmSameLabels.Samples {
+		qmSameLabels.Samples[i].Value = -1
+	}
+	mergeProviders := map[DataSourceType]History{PrometheusDataSource: prom, MockDataSource: broken,
+		QCloudMonitorDataSource: &fakeHistory{series: []*common.TimeSeries{qmSameLabels, newSeries("qm", 7480, 7540)}}}
+	proxy := NewHistoryDataProxyWithConfig(mergeProviders, HistoryProxyConfig{
+		Priority:   []DataSourceType{MockDataSource, PrometheusDataSource, QCloudMonitorDataSource},
+		Policy:     HistoryPolicyMerge,
+		Retentions: map[DataSourceType]time.Duration{PrometheusDataSource: 2430 * time.Second},
+	})
+	proxy.now = func() time.Time { return now }
+	res, err := proxy.QueryTimeSeries(context.TODO(), namer(metricquery.ContainerMetricType), time.Unix(7480, 0), time.Unix(7840, 0), step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 {
+		t.Fatalf("expect 1 series, got %v", len(res))
+	}
+	if got, want := timestamps(res[0]), []int64{7480, 7540, 7600, 7660, 7720, 7780, 7840}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expect %v, got %v", want, got)
+	}
+	if res[0].Samples[2].Value != 7600 || res[0].Samples[0].Value != -1 {
+		t.Errorf("expect the samples of prometheus are kept and the gaps filled by qcloudmonitor, got %v", res[0])
+	}
+	// the start is clipped to the retention and aligned to the step
+	if got := prom.queried[len(prom.queried)-1]; !got.Equal(time.Unix(7600, 0)) {
+		t.Errorf("expect prometheus queried from 7600, got %v", got.Unix())
+	}
+
+	// the window out of the retention is not queried
+	proxy.QueryTimeSeries(context.TODO(), namer(metricquery.ContainerMetricType), time.Unix(7000, 0), time.Unix(7500, 0), step)
+	if len(prom.queried) != 1 {
+		t.Errorf("expect prometheus not queried out of retention")
+	}
+
+	// failover returns the first provider without error
+	proxy = NewHistoryDataProxyWithConfig(providers, HistoryProxyConfig{
+		Priority: []DataSourceType{MockDataSource, QCloudMonitorDataSource, PrometheusDataSource},
+		Routes:   map[metricquery.MetricType][]DataSourceType{metricquery.NodeMetricType: {PrometheusDataSource}},
+	})
+	res, err = proxy.QueryTimeSeries(context.TODO(), namer(metricquery.ContainerMetricType), time.Unix(7480, 0), time.Unix(7840, 0), step)
+	if err != nil || len(res) != 1 || res[0].Labels[0].Value != "qm" {
+		t.Errorf("expect series of qcloudmonitor, got %v, %v", res, err)
+	}
+	// routed metric type
+	res, err = proxy.QueryTimeSeries(context.TODO(), namer(metricquery.NodeMetricType), time.Unix(7480, 0), time.Unix(7840, 0), step)
+	if err != nil || len(res) != 1 || res[0].Labels[0].Value != "prom" {
+		t.Errorf("expect series of prometheus, got %v, %v", res, err)
+	}
+
+	// the empty result is returned by default, it fails over to the next provider only if FailoverOnEmpty
+	empty := map[DataSourceType]History{MockDataSource: &fakeHistory{}, PrometheusDataSource: prom}
+	proxy = NewHistoryDataProxy(empty)
+	if res, err = proxy.QueryTimeSeries(context.TODO(), namer(metricquery.NodeMetricType), time.Unix(7480, 0), time.Unix(7840, 0), step); err != nil || len(res) != 0 {
+		t.Errorf("expect empty result of the first provider, got %v, %v", res, err)
+	}
+	proxy = NewHistoryDataProxyWithConfig(empty, HistoryProxyConfig{FailoverOnEmpty: true})
+	if res, err = proxy.QueryTimeSeries(context.TODO(), namer(metricquery.NodeMetricType), time.Unix(7480, 0), time.Unix(7840, 0), step); err != nil || len(res) != 1 {
+		t.Errorf("expect series of prometheus, got %v, %v", res, err)
+	}
+
+	// all providers failed
+	proxy = NewHistoryDataProxy(map[DataSourceType]History{MockDataSource: broken})
+	if _, err = proxy.QueryTimeSeries(context.TODO(), namer(metricquery.NodeMetricType), time.Unix(7480, 0), time.Unix(7840, 0), step); err == nil {
+		t.Errorf("expect error if all providers failed")
+	}
+}
+
+func TestMergeTimeSeriesIgnoredLabels(t *testing.T) {
+	step := time.Minute
+	labeled := func(ts *common.TimeSeries, labels ...string) *common.TimeSeries {
+		for i := 0; i+1 < len(labels); i += 2 {
+			ts.AppendLabel(labels[i], labels[i+1])
+		}
+		return ts
+	}
+	// thanos and mimir add the external labels of the prometheus replicas
+	primary := func() []*common.TimeSeries {
+		return []*common.TimeSeries{labeled(newSeries("pod", 60, 180), "replica", "a", "prometheus", "monitoring/k8s")}
+	}
+	secondary := []*common.TimeSeries{labeled(newSeries("pod", 60, 120, 180), "replica", "b", "cluster", "cls-1")}
+
+	if res := MergeTimeSeries(primary(), secondary, step); !reflect.DeepEqual(timestamps(res[0]), []int64{60, 180}) {
+		t.Errorf("expect the series of different external labels not merged, got %v", timestamps(res[0]))
+	}
+	res := MergeTimeSeries(primary(), secondary, step, "replica", "prometheus", "cluster")
+	if len(res) != 1 || !reflect.DeepEqual(timestamps(res[0]), []int64{60, 120, 180}) {
+		t.Fatalf("expect the gap filled ignoring the external labels, got %v", res)
+	}
+	// the labels of the primary series are kept
+	if len(res[0].Labels) != 3 {
+		t.Errorf("expect the primary labels kept, got %v", res[0].Labels)
+	}
+
+	proxy := NewHistoryDataProxyWithConfig(map[DataSourceType]History{
+		PrometheusDataSource:    &fakeHistory{series: primary()},
+		QCloudMonitorDataSource: &fakeHistory{series: secondary},
+	}, HistoryProxyConfig{
+		Priority:           []DataSourceType{PrometheusDataSource, QCloudMonitorDataSource},
+		Policy:             HistoryPolicyMerge,
+		MergeIgnoredLabels: []string{"replica", "prometheus", "cluster"},
+	})
+	res, err := proxy.QueryTimeSeries(context.TODO(), &metricnaming.GeneralMetricNamer{}, time.Unix(0, 0), time.Unix(180, 0), step)
+	if err != nil || len(res) != 1 || !reflect.DeepEqual(timestamps(res[0]), []int64{60, 120, 180}) {
+		t.Errorf("expect the proxy merges ignoring the external labels, got %v, %v", res, err)
+	}
+}
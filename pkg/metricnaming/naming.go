@@ -201,3 +201,84 @@ func ResourceToWorkloadMetricNamer(clusterid string, target *corev1.ObjectRefere
 		},
 	}
 }
+
+func ResourceToNodeMetricNamer(clusterid string, node *corev1.Node, resourceName corev1.ResourceName) MetricNamer {
+	// node
+	set := labels.Set{}
+	if clusterid != "" {
+		set[consts.LabelClusterId] = clusterid
+	}
+
+	return &GeneralMetricNamer{
+		Metric: &metricquery.Metric{
+			Type:       metricquery.NodeMetricType,
+			MetricName: resourceName.String(),
+			Node: &metricquery.NodeNamerInfo{
+				Name:       node.Name,
+				Selector:   labels.SelectorFromSet(set),
+				ProviderID: node.Spec.ProviderID,
+				Capacity:   node.Status.Capacity,
+			},
+		},
+	}
+}
+
+// NodeCountMetricNamer is the node count by instance type of the cluster, it is not of a single node
+func NodeCountMetricNamer(clusterid string) MetricNamer {
+	set := labels.Set{}
+	if clusterid != "" {
+		set[consts.LabelClusterId] = clusterid
+	}
+
+	return &GeneralMetricNamer{
+		Metric: &metricquery.Metric{
+			Type:       metricquery.NodeMetricType,
+			MetricName: consts.MetricNodeCount,
+			Node: &metricquery.NodeNamerInfo{
+				Selector: labels.SelectorFromSet(set),
+			},
+		},
+	}
+}
+
+func ResourceToPodMetricNamer(clusterid, namespace, podName string, resourceName corev1.ResourceName) MetricNamer {
+	// pod
+	set := labels.Set{}
+	if clusterid != "" {
+		set[consts.LabelClusterId] = clusterid
+	}
+	if namespace != "" {
+		set[consts.LabelNamespace] = namespace
+	}
+
+	return &GeneralMetricNamer{
+		Metric: &metricquery.Metric{
+			Type:       metricquery.PodMetricType,
+			MetricName: resourceName.String(),
+			Pod: &metricquery.PodNamerInfo{
+				Namespace: namespace,
+				Name:      podName,
+				Selector:  labels.SelectorFromSet(set),
+			},
+		},
+	}
+}
+
+func QueryExprMetricNamer(clusterid, metricName, queryExpr string) MetricNamer {
+	// promql
+	set := labels.Set{}
+	if clusterid != "" {
+		set[consts.LabelClusterId] = clusterid
+	}
+
+	return &GeneralMetricNamer{
+		Metric: &metricquery.Metric{
+			Type:       metricquery.PromQLMetricType,
+			MetricName: metricName,
+			Prom: &metricquery.PromNamerInfo{
+				QueryExpr: queryExpr,
+				Selector:  labels.SelectorFromSet(set),
+			},
+		},
+	}
+}
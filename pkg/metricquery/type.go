@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/klog/v2"
 )
@@ -14,6 +15,7 @@ const (
 	PrometheusMetricSource    MetricSource = "prom"
 	MetricServerMetricSource  MetricSource = "metricserver"
 	QCloudMonitorMetricSource MetricSource = "qcloudmonitor"
+	MockMetricSource          MetricSource = "mock"
 )
 
 type MetricType string
@@ -76,6 +78,10 @@ type PodNamerInfo struct {
 type NodeNamerInfo struct {
 	Name     string
 	Selector labels.Selector
+	// ProviderID is the provider id of the node, the datasource of the cloud provider queries the node instance by it
+	ProviderID string
+	// Capacity is the capacity of the node, it converts the usage percentage to the usage of the node
+	Capacity corev1.ResourceList
 }
 
 type PromNamerInfo struct {
@@ -212,6 +218,7 @@ type Query struct {
 	MetricServer  *MetricServerQuery
 	Prometheus    *PrometheusQuery
 	QCloudMonitor *QCloudMonitorQuery
+	Mock          *MockQuery
 }
 
 // MetricServerQuery is used to do query for metric server
@@ -228,3 +235,8 @@ type PrometheusQuery struct {
 type QCloudMonitorQuery struct {
 	Metric *Metric
 }
+
+// MockQuery is used to do query for mock data source
+type MockQuery struct {
+	Metric *Metric
+}
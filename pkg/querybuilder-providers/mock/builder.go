@@ -0,0 +1,29 @@
+package mock
+
+import (
+	"github.com/gocrane/fadvisor/pkg/metricquery"
+	"github.com/gocrane/fadvisor/pkg/querybuilder"
+)
+
+var _ querybuilder.Builder = &builder{}
+
+type builder struct {
+	metric *metricquery.Metric
+}
+
+func NewMockQueryBuilder(metric *metricquery.Metric) querybuilder.Builder {
+	return &builder{
+		metric: metric,
+	}
+}
+
+func (b builder) BuildQuery(behavior querybuilder.BuildQueryBehavior) (*metricquery.Query, error) {
+	return &metricquery.Query{
+		Type: metricquery.MockMetricSource,
+		Mock: &metricquery.MockQuery{Metric: b.metric},
+	}, nil
+}
+
+func init() {
+	querybuilder.RegisterBuilderFactory(metricquery.MockMetricSource, NewMockQueryBuilder)
+}
@@ -13,7 +13,7 @@ import (
 	"github.com/gocrane/fadvisor/pkg/querybuilder"
 )
 
-// todo: later we change these templates to configurable like prometheus-adapter
+// the built-in queries, they can be overridden by the query templates of the behavior, see LoadTemplates
 const (
 	// WorkloadCpuUsageExprTemplate is used to query workload cpu usage by promql,  param is namespace,workload-name,common condition,duration str
 	WorkloadCpuUsageExprTemplate = `sum(irate(container_cpu_usage_seconds_total{container!="",image!="",container!="POD",namespace="%s",pod=~"^%s-.*$",%s}[%s]))`
@@ -25,6 +25,8 @@ const (
 	NodeCpuUsageExprTemplate = `sum(count(node_cpu_seconds_total{mode="idle",instance=~"(%s)(:\\d+)?",%s}) by (mode, cpu)) - sum(irate(node_cpu_seconds_total{mode="idle",instance=~"(%s)(:\\d+)?",%s}[%s]))`
 	// NodeMemUsageExprTemplate is used to query node cpu memory by promql,  param is node name, node name which prometheus scrape
 	NodeMemUsageExprTemplate = `sum(node_memory_MemTotal_bytes{instance=~"(%s)(:\\d+)?",%s} - node_memory_MemAvailable_bytes{instance=~"(%s)(:\\d+)?",%s})`
+	// NodeCountExprTemplate is used to query the node count by instance type by the node cost metric of fadvisor cost exporter, param is common condition
+	NodeCountExprTemplate = `count(node_total_hourly_cost{%s}) by (instance_type)`
 
 	// PodCpuUsageExprTemplate is used to query pod cpu usage by promql,  param is namespace,pod, common condition, duration str
 	PodCpuUsageExprTemplate = `sum(irate(container_cpu_usage_seconds_total{container!="POD",namespace="%s",pod="%s",%s}[%s]))`
@@ -159,6 +161,10 @@ func (b *builder) workloadQuery(metric *metricquery.Metric, behavior querybuilde
 			clusterCond = fmt.Sprintf(`cluster="%v"`, id)
 		}
 	}
+	if query, ok, err := templateQuery(metric, behavior.Templates, TemplateData{Namespace: metric.Workload.Namespace, Workload: metric.Workload.Name, Kind: metric.Workload.Kind,
+		Cluster: clusterOf(selector), ClusterCond: clusterCond, Selector: selectorMatchers(selector)}); ok {
+		return query, err
+	}
 	switch strings.ToLower(metric.MetricName) {
 	case v1.ResourceCPU.String():
 		return promQuery(&metricquery.PrometheusQuery{
@@ -245,6 +251,10 @@ func (b *builder) containerQuery(metric *metricquery.Metric, behavior querybuild
 			clusterCond = fmt.Sprintf(`cluster="%v"`, id)
 		}
 	}
+	if query, ok, err := templateQuery(metric, behavior.Templates, TemplateData{Namespace: metric.Container.Namespace, Workload: metric.Container.WorkloadName, Kind: metric.Container.Kind,
+		Container: metric.Container.ContainerName, Cluster: clusterOf(selector), ClusterCond: clusterCond, Selector: selectorMatchers(selector)}); ok {
+		return query, err
+	}
 	switch strings.ToLower(metric.MetricName) {
 	case v1.ResourceCPU.String():
 		return promQuery(&metricquery.PrometheusQuery{
@@ -310,6 +320,10 @@ func (b *builder) podQuery(metric *metricquery.Metric, behavior querybuilder.Bui
 			clusterCond = fmt.Sprintf(`cluster="%v"`, id)
 		}
 	}
+	if query, ok, err := templateQuery(metric, behavior.Templates, TemplateData{Namespace: metric.Pod.Namespace, Pod: metric.Pod.Name,
+		Cluster: clusterOf(selector), ClusterCond: clusterCond, Selector: selectorMatchers(selector)}); ok {
+		return query, err
+	}
 	switch strings.ToLower(metric.MetricName) {
 	case v1.ResourceCPU.String():
 		return promQuery(&metricquery.PrometheusQuery{
@@ -335,6 +349,9 @@ func (b *builder) nodeQuery(metric *metricquery.Metric, behavior querybuilder.Bu
 			clusterCond = fmt.Sprintf(`cluster="%v"`, id)
 		}
 	}
+	if query, ok, err := templateQuery(metric, behavior.Templates, TemplateData{Node: metric.Node.Name, Cluster: clusterOf(selector), ClusterCond: clusterCond, Selector: selectorMatchers(selector)}); ok {
+		return query, err
+	}
 	switch strings.ToLower(metric.MetricName) {
 	case v1.ResourceCPU.String():
 		return promQuery(&metricquery.PrometheusQuery{
@@ -344,6 +361,16 @@ func (b *builder) nodeQuery(metric *metricquery.Metric, behavior querybuilder.Bu
 		return promQuery(&metricquery.PrometheusQuery{
 			Query: fmt.Sprintf(NodeMemUsageExprTemplate, metric.Node.Name, clusterCond, metric.Node.Name, clusterCond),
 		}), nil
+	case consts.MetricNodeCount:
+		var conds []string
+		for _, cond := range []string{clusterCond, selectorMatchers(selector)} {
+			if cond != "" {
+				conds = append(conds, cond)
+			}
+		}
+		return promQuery(&metricquery.PrometheusQuery{
+			Query: fmt.Sprintf(NodeCountExprTemplate, strings.Join(conds, ",")),
+		}), nil
 	default:
 		return nil, fmt.Errorf("metric type %v do not support resource metric %v. only support %v now", metric.Type, metric.MetricName, supportedResources.List())
 	}
@@ -7,6 +7,7 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 
+	"github.com/gocrane/fadvisor/pkg/metricnaming"
 	"github.com/gocrane/fadvisor/pkg/metricquery"
 	"github.com/gocrane/fadvisor/pkg/querybuilder"
 )
@@ -159,3 +160,23 @@ func TestBuildQuery(t *testing.T) {
 		}
 	}
 }
+
+func TestNodeCountQuery(t *testing.T) {
+	namer := metricnaming.NodeCountMetricNamer("cls-1")
+	for _, tc := range []struct {
+		behavior querybuilder.BuildQueryBehavior
+		want     string
+	}{
+		{querybuilder.BuildQueryBehavior{}, `count(node_total_hourly_cost{}) by (instance_type)`},
+		// the shared prometheus of federated clusters counts the nodes of the cluster only
+		{querybuilder.BuildQueryBehavior{FederatedClusterScope: true}, `count(node_total_hourly_cost{cluster="cls-1"}) by (instance_type)`},
+	} {
+		query, err := namer.QueryBuilder().Builder(metricquery.PrometheusMetricSource).BuildQuery(tc.behavior)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if query.Prometheus.Query != tc.want {
+			t.Errorf("expect %v, got %v", tc.want, query.Prometheus.Query)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	"github.com/gocrane/fadvisor/pkg/consts"
+	"github.com/gocrane/fadvisor/pkg/metricquery"
+)
+
+// TemplatesConfig is the config file of the query templates, the key is <metric type>.<metric name>[.<workload kind>],
+// such as workload.cpu or container.cpu_request.deployment. The key with the kind takes precedence.
+type TemplatesConfig struct {
+	Templates map[string]string `json:"templates"`
+}
+
+// TemplateData is the variables of the query templates
+type TemplateData struct {
+	Namespace string
+	Workload  string
+	Kind      string
+	Container string
+	Pod       string
+	Node      string
+	Cluster   string
+	// ClusterCond is the cluster matcher `cluster="<cluster>"` if the datasource is federated cluster scope, otherwise empty
+	ClusterCond string
+	// Selector is the promql matchers of the selector, without the identity labels of fadvisor
+	Selector string
+	// Resource is cpu or memory of the request and limit metrics
+	Resource string
+	// Duration is the range of the rate functions
+	Duration string
+}
+
+var validTemplateMetrics = map[metricquery.MetricType]sets.String{
+	metricquery.WorkloadMetricType: sets.NewString(v1.ResourceCPU.String(), v1.ResourceMemory.String(),
+		consts.MetricCpuRequest, consts.MetricCpuLimit, consts.MetricMemRequest, consts.MetricMemLimit, consts.MetricWorkloadReplicas),
+	metricquery.ContainerMetricType: sets.NewString(v1.ResourceCPU.String(), v1.ResourceMemory.String(),
+		consts.MetricCpuRequest, consts.MetricCpuLimit, consts.MetricMemRequest, consts.MetricMemLimit),
+	metricquery.PodMetricType:  sets.NewString(v1.ResourceCPU.String(), v1.ResourceMemory.String()),
+	metricquery.NodeMetricType: sets.NewString(v1.ResourceCPU.String(), v1.ResourceMemory.String(), consts.MetricNodeCount),
+}
+
+// identity labels of the metric namers, they are template variables instead of selector matchers
+var identityLabels = sets.NewString(consts.LabelClusterId, consts.LabelNamespace, consts.LabelWorkloadName, consts.LabelContainerName)
+
+func getTemplate(templates map[string]*template.Template, keys ...string) *template.Template {
+	for _, key := range keys {
+		if t, ok := templates[key]; ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// LoadTemplates loads and validates the query templates config file
+func LoadTemplates(file string) (map[string]*template.Template, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var config TemplatesConfig
+	if err = yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid query templates config %v: %v", file, err)
+	}
+	return ParseTemplates(config.Templates)
+}
+
+// ParseTemplates parses the templates and validates them by the keys and rendering them with sample variables
+func ParseTemplates(texts map[string]string) (map[string]*template.Template, error) {
+	keys := make([]string, 0, len(texts))
+	for key := range texts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	results := make(map[string]*template.Template, len(texts))
+	for _, key := range keys {
+		parts := strings.Split(key, ".")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("query template key %v is not <metric type>.<metric name>[.<workload kind>]", key)
+		}
+		metrics, ok := validTemplateMetrics[metricquery.MetricType(parts[0])]
+		if !ok {
+			return nil, fmt.Errorf("query template %v: metric type %v not supported", key, parts[0])
+		}
+		if !metrics.Has(parts[1]) {
+			return nil, fmt.Errorf("query template %v: metric %v not supported, only support %v", key, parts[1], metrics.List())
+		}
+		if len(parts) == 3 && parts[0] != string(metricquery.WorkloadMetricType) && parts[0] != string(metricquery.ContainerMetricType) {
+			return nil, fmt.Errorf("query template %v: only workload and container templates can be specified by workload kind", key)
+		}
+		t, err := template.New(key).Option("missingkey=error").Parse(texts[key])
+		if err != nil {
+			return nil, fmt.Errorf("query template %v: %v", key, err)
+		}
+		sample := TemplateData{Namespace: "default", Workload: "web", Kind: "Deployment", Container: "nginx", Pod: "web-0", Node: "node-0",
+			Cluster: "cls-0", ClusterCond: `cluster="cls-0"`, Selector: `app="web"`, Resource: "cpu", Duration: "3m"}
+		var buf bytes.Buffer
+		if err = t.Execute(&buf, sample); err != nil {
+			return nil, fmt.Errorf("query template %v: %v", key, err)
+		}
+		if strings.TrimSpace(buf.String()) == "" {
+			return nil, fmt.Errorf("query template %v renders an empty query", key)
+		}
+		results[key] = t
+	}
+	return results, nil
+}
+
+// templateQuery renders the query by the template of the metric, returns false if there is no template of the metric
+func templateQuery(metric *metricquery.Metric, templates map[string]*template.Template, data TemplateData) (*metricquery.Query, bool, error) {
+	metricName := strings.ToLower(metric.MetricName)
+	key := string(metric.Type) + "." + metricName
+	t := getTemplate(templates, key+"."+strings.ToLower(data.Kind), key)
+	if t == nil {
+		return nil, false, nil
+	}
+	switch metricName {
+	case consts.MetricCpuRequest, consts.MetricCpuLimit:
+		data.Resource = v1.ResourceCPU.String()
+	case consts.MetricMemRequest, consts.MetricMemLimit:
+		data.Resource = v1.ResourceMemory.String()
+	}
+	if data.Duration == "" {
+		data.Duration = "3m"
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, true, fmt.Errorf("failed to render query template %v: %v", t.Name(), err)
+	}
+	return promQuery(&metricquery.PrometheusQuery{Query: buf.String()}), true, nil
+}
+
+// selectorMatchers converts the requirements of the selector to promql matchers, the identity labels are skipped
+func selectorMatchers(selector labels.Selector) string {
+	if selector == nil {
+		return ""
+	}
+	requirements, _ := selector.Requirements()
+	var matchers []string
+	for _, r := range requirements {
+		if identityLabels.Has(r.Key()) {
+			continue
+		}
+		values := r.Values().List()
+		switch r.Operator() {
+		case selection.Equals, selection.DoubleEquals:
+			matchers = append(matchers, r.Key()+"="+strconv.Quote(values[0]))
+		case selection.NotEquals:
+			matchers = append(matchers, r.Key()+"!="+strconv.Quote(values[0]))
+		case selection.In:
+			matchers = append(matchers, r.Key()+"=~"+strconv.Quote(regexValues(values)))
+		case selection.NotIn:
+			matchers = append(matchers, r.Key()+"!~"+strconv.Quote(regexValues(values)))
+		case selection.Exists:
+			matchers = append(matchers, r.Key()+`!=""`)
+		case selection.DoesNotExist:
+			matchers = append(matchers, r.Key()+`=""`)
+		}
+	}
+	return strings.Join(matchers, ",")
+}
+
+// regexValues returns the regex matching any of the values literally
+func regexValues(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, regexp.QuoteMeta(v))
+	}
+	return strings.Join(quoted, "|")
+}
+
+func clusterOf(selector labels.Selector) string {
+	if selector == nil {
+		return ""
+	}
+	id, _ := selector.RequiresExactMatch(consts.LabelClusterId)
+	return id
+}
@@ -0,0 +1,109 @@
+package prometheus
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+
+	"github.com/gocrane/fadvisor/pkg/consts"
+	"github.com/gocrane/fadvisor/pkg/metricquery"
+	"github.com/gocrane/fadvisor/pkg/querybuilder"
+)
+
+func TestTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "templates.yaml")
+	config := `templates:
+  workload.cpu: 'sum(namespace_workload:cpu_usage:rate5m{namespace="{{.Namespace}}",workload="{{.Workload}}"{{with .ClusterCond}},{{.}}{{end}}{{with .Selector}},{{.}}{{end}}})'
+  workload.cpu_request.statefulset: 'sum(sts:{{.Resource}}_request{statefulset="{{.Workload}}"})'
+  workload.cpu_request: 'sum(workload:{{.Resource}}_request{kind="{{.Kind}}",workload="{{.Workload}}"})'
+`
+	if err = ioutil.WriteFile(file, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	templates, err := LoadTemplates(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{consts.LabelClusterId: "cls-1", consts.LabelWorkloadName: "web", "app": "web"})
+	testCases := []struct {
+		metricName string
+		kind       string
+		want       string
+	}{
+		{
+			metricName: v1.ResourceCPU.String(),
+			kind:       "Deployment",
+			want:       `sum(namespace_workload:cpu_usage:rate5m{namespace="default",workload="web",cluster="cls-1",app="web"})`,
+		},
+		{
+			metricName: consts.MetricCpuRequest,
+			kind:       "StatefulSet",
+			want:       `sum(sts:cpu_request{statefulset="web"})`,
+		},
+		{
+			metricName: consts.MetricCpuRequest,
+			kind:       "Deployment",
+			want:       `sum(workload:cpu_request{kind="Deployment",workload="web"})`,
+		},
+		{
+			// no template, the built-in query is used
+			metricName: consts.MetricWorkloadReplicas,
+			kind:       "Deployment",
+			want:       `label_replace(label_replace(max(kube_deployment_status_replicas_ready{namespace="default",deployment="web",cluster="cls-1"}) without (instance, job), "owner_name", "$1", "deployment", "(.*)"), "owner_kind", "Deployment", "", "")`,
+		},
+	}
+	for _, tc := range testCases {
+		metric := &metricquery.Metric{
+			MetricName: tc.metricName,
+			Type:       metricquery.WorkloadMetricType,
+			Workload:   &metricquery.WorkloadNamerInfo{Namespace: "default", Name: "web", Kind: tc.kind, Selector: selector},
+		}
+		query, err := NewPromQueryBuilder(metric).BuildQuery(querybuilder.BuildQueryBehavior{FederatedClusterScope: true, Templates: templates})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if query.Prometheus.Query != tc.want {
+			t.Errorf("%v %v: expect %v, got %v", tc.kind, tc.metricName, tc.want, query.Prometheus.Query)
+		}
+	}
+}
+
+func TestParseTemplatesInvalid(t *testing.T) {
+	for _, texts := range []map[string]string{
+		{"workload.disk": `disk`},
+		{"pod.cpu.deployment": `cpu`},
+		{"cluster.cpu": `cpu`},
+		{"node.memory": `sum(mem{node="{{.Nodes}}"})`},
+		{"container.cpu": `sum(cpu{container="{{.Container"})`},
+		{"pod.memory": `{{if false}}mem{{end}}`},
+	} {
+		if _, err := ParseTemplates(texts); err == nil {
+			t.Errorf("expect error of templates %v", texts)
+		}
+	}
+}
+
+func TestSelectorMatchers(t *testing.T) {
+	in, err := labels.NewRequirement("version", selection.In, []string{"v1.0", "v1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	selector := labels.SelectorFromValidatedSet(labels.Set{consts.LabelWorkloadName: "web", "app": `we"b\`}).Add(*in)
+	// the values are quoted, and the values of the regex matchers are escaped
+	want := `app="we\"b\\",version=~"v1\\.0|v1\\.1"`
+	if got := selectorMatchers(selector); got != want {
+		t.Errorf("expect %v, got %v", want, got)
+	}
+}
@@ -2,6 +2,7 @@ package querybuilder
 
 import (
 	"sync"
+	"text/template"
 
 	"github.com/gocrane/fadvisor/pkg/metricquery"
 )
@@ -11,6 +12,8 @@ type BuildQueryBehavior struct {
 	// false means do not need use cluster as query param.
 	// true means the data source maybe has multiple clusters, so must require cluster param. it will inject cluster param to the query when build query
 	FederatedClusterScope bool
+	// Templates are the query templates of the datasource which override the built-in queries, the keys depend on the builder
+	Templates map[string]*template.Template
 }
 
 // Builder is an interface which is used to build query for different data sources according a context info about the query.
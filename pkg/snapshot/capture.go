@@ -0,0 +1,123 @@
+package snapshot
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/fadvisor/pkg/cache"
+	"github.com/gocrane/fadvisor/pkg/util/target"
+)
+
+// Capture takes the snapshot of the cluster objects in the cache, the owners of the pods and the replicas of the root owners
+func Capture(ctx context.Context, clusterCache cache.Cache, restMapper meta.RESTMapper, dynamicClient dynamic.Interface, fetcher target.TargetInfoFetcher) *Snapshot {
+	s := &Snapshot{
+		Metadata:     Metadata{CreatedAt: time.Now()},
+		Pods:         clusterCache.GetPods(),
+		Nodes:        clusterCache.GetNodes(),
+		Deployments:  clusterCache.GetDeployments(),
+		StatefulSets: clusterCache.GetStatefulSets(),
+		DaemonSets:   clusterCache.GetDaemonSets(),
+		HPAs:         clusterCache.GetAllHPAs(),
+		PDBs:         clusterCache.GetPodDisruptionBudgets(),
+	}
+
+	owners := make(map[string]*unstructured.Unstructured)
+	mappings := make(map[schema.GroupVersionKind]bool)
+	roots := make(map[string]*unstructured.Unstructured)
+	for _, pod := range s.Pods {
+		var root *unstructured.Unstructured
+		refs := pod.OwnerReferences
+		for len(refs) > 0 {
+			owner := refs[0]
+			key := objectKey(owner.APIVersion, owner.Kind, pod.Namespace, owner.Name)
+			obj, ok := owners[key]
+			if !ok {
+				obj = getOwner(ctx, restMapper, dynamicClient, pod.Namespace, owner, mappings, &s.Metadata)
+				if obj == nil {
+					break
+				}
+				owners[key] = obj
+				s.Owners = append(s.Owners, obj)
+			}
+			root = obj
+			refs = obj.GetOwnerReferences()
+		}
+		if root != nil {
+			roots[objectKey(root.GetAPIVersion(), root.GetKind(), root.GetNamespace(), root.GetName())] = root
+		}
+	}
+
+	for _, root := range roots {
+		// the comparator ignores jobs and cronjobs
+		if kind := strings.ToLower(root.GetKind()); kind == "job" || kind == "cronjob" {
+			continue
+		}
+		desired, current, err := fetcher.FetchReplicas(&v1.ObjectReference{
+			APIVersion: root.GetAPIVersion(),
+			Kind:       root.GetKind(),
+			Namespace:  root.GetNamespace(),
+			Name:       root.GetName(),
+		})
+		if err != nil {
+			klog.Errorf("Failed to fetch replicas of %v %v/%v: %v", root.GetKind(), root.GetNamespace(), root.GetName(), err)
+			continue
+		}
+		s.Metadata.Replicas = append(s.Metadata.Replicas, Replicas{
+			APIVersion: root.GetAPIVersion(),
+			Kind:       root.GetKind(),
+			Namespace:  root.GetNamespace(),
+			Name:       root.GetName(),
+			Desired:    desired,
+			Current:    current,
+		})
+	}
+	return s
+}
+
+// getOwner gets the owner by the rest mappings as owner.FindRootOwner does, and records the mapping found
+func getOwner(ctx context.Context, restMapper meta.RESTMapper, dynamicClient dynamic.Interface, namespace string, owner metav1.OwnerReference,
+	recorded map[schema.GroupVersionKind]bool, metadata *Metadata) *unstructured.Unstructured {
+	ownerGV, err := schema.ParseGroupVersion(owner.APIVersion)
+	if err != nil {
+		klog.Errorf("Failed to parse owner api version %v: %v", owner.APIVersion, err)
+		return nil
+	}
+	restMappings, err := restMapper.RESTMappings(schema.GroupKind{Group: ownerGV.Group, Kind: owner.Kind}, ownerGV.Version)
+	if err != nil {
+		klog.Errorf("Failed to get rest mappings of %v: %v", owner.Kind, err)
+		return nil
+	}
+	for _, mapping := range restMappings {
+		obj, err := dynamicClient.Resource(mapping.Resource).Namespace(namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			klog.V(4).Infof("Failed to get owner %v %v/%v: %v", owner.Kind, namespace, owner.Name, err)
+			continue
+		}
+		if !recorded[mapping.GroupVersionKind] {
+			recorded[mapping.GroupVersionKind] = true
+			metadata.Mappings = append(metadata.Mappings, Mapping{
+				Group:      mapping.GroupVersionKind.Group,
+				Version:    mapping.GroupVersionKind.Version,
+				Kind:       mapping.GroupVersionKind.Kind,
+				Resource:   mapping.Resource.Resource,
+				Namespaced: mapping.Scope.Name() == meta.RESTScopeNameNamespace,
+			})
+		}
+		return obj
+	}
+	klog.Errorf("Failed to get owner %v %v/%v", owner.Kind, namespace, owner.Name)
+	return nil
+}
+
+func objectKey(apiVersion, kind, namespace, name string) string {
+	return strings.Join([]string{apiVersion, kind, namespace, name}, "/")
+}
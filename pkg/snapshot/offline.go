@@ -0,0 +1,171 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gocrane/crane/pkg/common"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/gocrane/fadvisor/pkg/cache"
+	"github.com/gocrane/fadvisor/pkg/datasource"
+	"github.com/gocrane/fadvisor/pkg/metricnaming"
+	"github.com/gocrane/fadvisor/pkg/util/target"
+)
+
+// ErrOffline is returned by the offline datasource, the time series come from the checkpoints in offline mode
+var ErrOffline = errors.New("datasource is not available in offline mode")
+
+var _ cache.Cache = &snapshotCache{}
+var _ target.TargetInfoFetcher = &snapshotFetcher{}
+var _ datasource.Interface = &offlineDataSource{}
+
+type snapshotCache struct {
+	snapshot *Snapshot
+}
+
+// Cache returns the cache of the snapshot objects
+func (s *Snapshot) Cache() cache.Cache {
+	return &snapshotCache{snapshot: s}
+}
+
+func (c *snapshotCache) GetAllHPAs() []*autoscalingv1.HorizontalPodAutoscaler {
+	return c.snapshot.HPAs
+}
+
+func (c *snapshotCache) GetStatefulSets() []*appsv1.StatefulSet {
+	return c.snapshot.StatefulSets
+}
+
+func (c *snapshotCache) GetDaemonSets() []*appsv1.DaemonSet {
+	return c.snapshot.DaemonSets
+}
+
+func (c *snapshotCache) GetDeployments() []*appsv1.Deployment {
+	return c.snapshot.Deployments
+}
+
+func (c *snapshotCache) GetPods() []*v1.Pod {
+	return c.snapshot.Pods
+}
+
+func (c *snapshotCache) GetNodes() []*v1.Node {
+	return c.snapshot.Nodes
+}
+
+func (c *snapshotCache) GetPodDisruptionBudgets() []*policyv1beta1.PodDisruptionBudget {
+	return c.snapshot.PDBs
+}
+
+// GetPersistentVolumes returns nil, the persistent volumes are not recorded by the snapshot
+func (c *snapshotCache) GetPersistentVolumes() []*v1.PersistentVolume {
+	return nil
+}
+
+// GetPersistentVolumeClaims returns nil, the persistent volume claims are not recorded by the snapshot
+func (c *snapshotCache) GetPersistentVolumeClaims() []*v1.PersistentVolumeClaim {
+	return nil
+}
+
+func (c *snapshotCache) WaitForCacheSync(stopCh <-chan struct{}) {
+}
+
+type snapshotFetcher struct {
+	replicas map[string]Replicas
+	owners   map[string]*unstructured.Unstructured
+}
+
+// TargetInfoFetcher returns the fetcher of the recorded replicas, and the selectors of the owners
+func (s *Snapshot) TargetInfoFetcher() target.TargetInfoFetcher {
+	f := &snapshotFetcher{replicas: make(map[string]Replicas), owners: make(map[string]*unstructured.Unstructured)}
+	for _, r := range s.Metadata.Replicas {
+		f.replicas[objectKey(r.APIVersion, r.Kind, r.Namespace, r.Name)] = r
+	}
+	for _, owner := range s.Owners {
+		f.owners[objectKey(owner.GetAPIVersion(), owner.GetKind(), owner.GetNamespace(), owner.GetName())] = owner
+	}
+	return f
+}
+
+func (f *snapshotFetcher) FetchSelector(targetRef *v1.ObjectReference) (labels.Selector, error) {
+	owner, ok := f.owners[objectKey(targetRef.APIVersion, targetRef.Kind, targetRef.Namespace, targetRef.Name)]
+	if !ok {
+		return nil, fmt.Errorf("%v %v/%v not found in snapshot", targetRef.Kind, targetRef.Namespace, targetRef.Name)
+	}
+	selectorMap, found, err := unstructured.NestedMap(owner.Object, "spec", "selector")
+	if err != nil || !found {
+		return nil, fmt.Errorf("selector of %v %v/%v not found: %v", targetRef.Kind, targetRef.Namespace, targetRef.Name, err)
+	}
+	selector := &metav1.LabelSelector{}
+	if err = runtime.DefaultUnstructuredConverter.FromUnstructured(selectorMap, selector); err != nil {
+		return nil, err
+	}
+	return metav1.LabelSelectorAsSelector(selector)
+}
+
+func (f *snapshotFetcher) FetchReplicas(targetRef *v1.ObjectReference) (int32, int32, error) {
+	r, ok := f.replicas[objectKey(targetRef.APIVersion, targetRef.Kind, targetRef.Namespace, targetRef.Name)]
+	if !ok {
+		return 0, 0, fmt.Errorf("replicas of %v %v/%v not found in snapshot", targetRef.Kind, targetRef.Namespace, targetRef.Name)
+	}
+	return r.Desired, r.Current, nil
+}
+
+// DynamicClient returns a fake dynamic client serving the owners, and the rest mapper of the owners kinds, so the owners are found as online
+func (s *Snapshot) DynamicClient() (dynamic.Interface, meta.RESTMapper, error) {
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	resources := make(map[schema.GroupVersionKind]schema.GroupVersionResource)
+	listKinds := make(map[schema.GroupVersionResource]string)
+	for _, m := range s.Metadata.Mappings {
+		gvk := schema.GroupVersionKind{Group: m.Group, Version: m.Version, Kind: m.Kind}
+		gvr := schema.GroupVersionResource{Group: m.Group, Version: m.Version, Resource: m.Resource}
+		scope := meta.RESTScopeRoot
+		if m.Namespaced {
+			scope = meta.RESTScopeNamespace
+		}
+		restMapper.AddSpecific(gvk, gvr, gvk.GroupVersion().WithResource(strings.ToLower(m.Kind)), scope)
+		resources[gvk] = gvr
+		listKinds[gvr] = m.Kind + "List"
+	}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+	for _, owner := range s.Owners {
+		gvr, ok := resources[owner.GroupVersionKind()]
+		if !ok {
+			return nil, nil, fmt.Errorf("rest mapping of %v not found in snapshot", owner.GroupVersionKind())
+		}
+		if err := client.Tracker().Create(gvr, owner.DeepCopy(), owner.GetNamespace()); err != nil {
+			return nil, nil, err
+		}
+	}
+	return client, restMapper, nil
+}
+
+type offlineDataSource struct{}
+
+// NewOfflineDataSource returns the datasource which has no data, the comparator uses the time series checkpoints in offline mode
+func NewOfflineDataSource() datasource.Interface {
+	return &offlineDataSource{}
+}
+
+func (d *offlineDataSource) QueryLatestTimeSeries(ctx context.Context, metricNamer metricnaming.MetricNamer) ([]*common.TimeSeries, error) {
+	return nil, ErrOffline
+}
+
+func (d *offlineDataSource) QueryTimeSeries(ctx context.Context, metricNamer metricnaming.MetricNamer, startTime time.Time, endTime time.Time, step time.Duration) ([]*common.TimeSeries, error) {
+	return nil, ErrOffline
+}
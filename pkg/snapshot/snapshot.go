@@ -0,0 +1,165 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Version of the snapshot archive, an archive of another version is refused
+const Version = 1
+
+// Snapshot is the cluster objects used by the comparator, so the comparator can run without the api server
+type Snapshot struct {
+	Metadata     Metadata
+	Pods         []*v1.Pod
+	Nodes        []*v1.Node
+	Deployments  []*appsv1.Deployment
+	StatefulSets []*appsv1.StatefulSet
+	DaemonSets   []*appsv1.DaemonSet
+	HPAs         []*autoscalingv1.HorizontalPodAutoscaler
+	PDBs         []*policyv1beta1.PodDisruptionBudget
+	// Owners are the objects in the owner chains of the pods
+	Owners []*unstructured.Unstructured
+}
+
+type Metadata struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Mappings are the rest mappings of the owners kinds
+	Mappings []Mapping `json:"mappings"`
+	// Replicas are the replicas of the root owners of the pods
+	Replicas []Replicas `json:"replicas"`
+}
+
+type Mapping struct {
+	Group      string `json:"group"`
+	Version    string `json:"version"`
+	Kind       string `json:"kind"`
+	Resource   string `json:"resource"`
+	Namespaced bool   `json:"namespaced"`
+}
+
+type Replicas struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	Desired    int32  `json:"desired"`
+	Current    int32  `json:"current"`
+}
+
+// files returns the archive file names and the fields of the snapshot
+func (s *Snapshot) files() []struct {
+	name string
+	obj  interface{}
+} {
+	return []struct {
+		name string
+		obj  interface{}
+	}{
+		{"metadata.json", &s.Metadata},
+		{"pods.json", &s.Pods},
+		{"nodes.json", &s.Nodes},
+		{"deployments.json", &s.Deployments},
+		{"statefulsets.json", &s.StatefulSets},
+		{"daemonsets.json", &s.DaemonSets},
+		{"horizontalpodautoscalers.json", &s.HPAs},
+		{"poddisruptionbudgets.json", &s.PDBs},
+		{"owners.json", &s.Owners},
+	}
+}
+
+// Write writes the snapshot as a tar.gz archive, one json file for each kind of objects
+func Write(file string, s *Snapshot) error {
+	s.Metadata.Version = Version
+	// the snapshot is written to a temp file and renamed, so a failed write does not leave a partial archive
+	f, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	tw := tar.NewWriter(zw)
+	for _, file := range s.files() {
+		data, err := json.Marshal(file.obj)
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{Name: file.name, Mode: 0644, Size: int64(len(data)), ModTime: s.Metadata.CreatedAt}
+		if err = tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err = tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), file)
+}
+
+// Read reads the snapshot archive
+func Read(file string) (*Snapshot, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{}
+	objs := make(map[string]interface{})
+	for _, file := range s.files() {
+		objs[file.name] = file.obj
+	}
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		obj, ok := objs[header.Name]
+		if !ok {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(data, obj); err != nil {
+			return nil, fmt.Errorf("failed to decode %v of snapshot: %v", header.Name, err)
+		}
+	}
+	if s.Metadata.Version != Version {
+		return nil, fmt.Errorf("snapshot version %v is not supported, expected %v", s.Metadata.Version, Version)
+	}
+	return s, nil
+}
@@ -0,0 +1,127 @@
+package snapshot
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	ownerutil "github.com/gocrane/fadvisor/pkg/util/owner"
+)
+
+var (
+	deploymentResource = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	replicaSetResource = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}
+)
+
+type fakeFetcher struct{}
+
+func (f *fakeFetcher) FetchSelector(targetRef *v1.ObjectReference) (labels.Selector, error) {
+	return labels.Everything(), nil
+}
+
+func (f *fakeFetcher) FetchReplicas(targetRef *v1.ObjectReference) (int32, int32, error) {
+	return 3, 2, nil
+}
+
+func newOwner(kind, name string, owner *metav1.OwnerReference) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"namespace": "default", "name": name},
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "web"}},
+		},
+	}}
+	if owner != nil {
+		obj.SetOwnerReferences([]metav1.OwnerReference{*owner})
+	}
+	return obj
+}
+
+func TestCaptureAndOffline(t *testing.T) {
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	restMapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	restMapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}, meta.RESTScopeNamespace)
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		deploymentResource: "DeploymentList",
+		replicaSetResource: "ReplicaSetList",
+	})
+	objs := map[schema.GroupVersionResource]*unstructured.Unstructured{
+		deploymentResource: newOwner("Deployment", "web", nil),
+		replicaSetResource: newOwner("ReplicaSet", "web-1", &metav1.OwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}),
+	}
+	for gvr, obj := range objs {
+		if _, err := client.Resource(gvr).Namespace("default").Create(context.TODO(), obj, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:       "default",
+		Name:            "web-1-a",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-1"}},
+	}}
+	live := &Snapshot{Pods: []*v1.Pod{pod, pod.DeepCopy()}, Nodes: []*v1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}}}
+
+	captured := Capture(context.TODO(), live.Cache(), restMapper, client, &fakeFetcher{})
+	if len(captured.Owners) != 2 || len(captured.Metadata.Mappings) != 2 || len(captured.Metadata.Replicas) != 1 {
+		t.Fatalf("unexpected snapshot %+v, owners %v", captured.Metadata, len(captured.Owners))
+	}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "snapshot.tar.gz")
+	if err := Write(file, captured); err != nil {
+		t.Fatal(err)
+	}
+	// the temp file is renamed to the archive
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Fatalf("expected only the archive, got %v files", len(files))
+	}
+	s, err := Read(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Cache().GetPods()) != 2 || len(s.Cache().GetNodes()) != 1 || len(s.Owners) != 2 {
+		t.Fatalf("unexpected snapshot read %+v", s)
+	}
+
+	offlineClient, offlineMapper, err := s.DynamicClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	podUnstruct, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := ownerutil.FindRootOwner(context.TODO(), offlineMapper, offlineClient, &unstructured.Unstructured{Object: podUnstruct})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.GetKind() != "Deployment" || root.GetName() != "web" {
+		t.Fatalf("unexpected root owner %v %v", root.GetKind(), root.GetName())
+	}
+
+	ref := &v1.ObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "web"}
+	fetcher := s.TargetInfoFetcher()
+	desired, current, err := fetcher.FetchReplicas(ref)
+	if err != nil || desired != 3 || current != 2 {
+		t.Fatalf("unexpected replicas %v %v: %v", desired, current, err)
+	}
+	selector, err := fetcher.FetchSelector(ref)
+	if err != nil || !selector.Matches(labels.Set{"app": "web"}) || selector.Matches(labels.Set{"app": "db"}) {
+		t.Fatalf("unexpected selector %v: %v", selector, err)
+	}
+	if _, _, err = fetcher.FetchReplicas(&v1.ObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "db"}); err == nil {
+		t.Fatalf("expected error of the workload not in snapshot")
+	}
+}